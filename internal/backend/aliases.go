@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"strings"
+	"sync"
+)
+
+// Configured model aliases map friendly names (e.g., "opus", "gpt4") to
+// full model IDs. Per-backend aliases are keyed by backend name; global
+// aliases (key "") apply to every backend.
+var (
+	modelAliasesMu sync.RWMutex
+	modelAliases   = make(map[string]map[string]string)
+)
+
+// SetModelAliases replaces the configured aliases for a backend.
+// An empty backendName sets the global aliases.
+func SetModelAliases(backendName string, aliases map[string]string) {
+	normalized := make(map[string]string, len(aliases))
+	for alias, model := range aliases {
+		normalized[strings.ToLower(alias)] = model
+	}
+
+	modelAliasesMu.Lock()
+	defer modelAliasesMu.Unlock()
+	modelAliases[backendName] = normalized
+}
+
+// ResetModelAliasesForTesting clears all configured aliases.
+// This is intended for use in tests only.
+func ResetModelAliasesForTesting() {
+	modelAliasesMu.Lock()
+	defer modelAliasesMu.Unlock()
+	modelAliases = make(map[string]map[string]string)
+}
+
+// ResolveModel maps a friendly model name to a full model ID for a backend.
+// Lookup order: configured aliases for the backend, the backend's built-in
+// aliases, then configured global aliases. Unknown names pass through.
+func ResolveModel(backendName, model string, builtin map[string]string) string {
+	if model == "" {
+		return model
+	}
+	key := strings.ToLower(model)
+
+	modelAliasesMu.RLock()
+	configured := modelAliases[backendName][key]
+	global := modelAliases[""][key]
+	modelAliasesMu.RUnlock()
+
+	if configured != "" {
+		return configured
+	}
+	if id, ok := builtin[key]; ok {
+		return id
+	}
+	if global != "" {
+		return global
+	}
+	return model
+}
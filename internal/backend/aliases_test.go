@@ -0,0 +1,27 @@
+package backend
+
+import "testing"
+
+func TestResolveModelPrecedence(t *testing.T) {
+	t.Cleanup(ResetModelAliasesForTesting)
+	builtin := map[string]string{"opus": "builtin-opus"}
+
+	SetModelAliases("", map[string]string{"opus": "global-opus", "fast": "global-fast"})
+	if got := ResolveModel("claude", "opus", builtin); got != "builtin-opus" {
+		t.Errorf("built-in alias should beat global, got %q", got)
+	}
+	if got := ResolveModel("claude", "FAST", builtin); got != "global-fast" {
+		t.Errorf("global alias should apply case-insensitively, got %q", got)
+	}
+
+	SetModelAliases("claude", map[string]string{"Opus": "configured-opus"})
+	if got := ResolveModel("claude", "opus", builtin); got != "configured-opus" {
+		t.Errorf("configured backend alias should win, got %q", got)
+	}
+	if got := ResolveModel("openai", "opus", nil); got != "global-opus" {
+		t.Errorf("other backends should fall back to global, got %q", got)
+	}
+	if got := ResolveModel("claude", "claude-custom-id", builtin); got != "claude-custom-id" {
+		t.Errorf("unknown names should pass through, got %q", got)
+	}
+}
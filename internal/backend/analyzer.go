@@ -2,7 +2,10 @@
 package backend
 
 import (
+	"fmt"
+	"log"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -57,19 +60,75 @@ func (t ModelTier) String() string {
 	}
 }
 
+// ParseModelTier parses a tier name ("simple", "moderate", "complex").
+// TierCLI isn't accepted since no API model can serve it.
+func ParseModelTier(s string) (ModelTier, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "simple":
+		return TierSimple, nil
+	case "moderate":
+		return TierModerate, nil
+	case "complex":
+		return TierComplex, nil
+	default:
+		return 0, fmt.Errorf("unknown tier %q: must be simple, moderate, or complex", s)
+	}
+}
+
+// MarshalText encodes the tier by name.
+func (t ModelTier) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes a tier name (see ParseModelTier).
+func (t *ModelTier) UnmarshalText(text []byte) error {
+	tier, err := ParseModelTier(string(text))
+	if err != nil {
+		return err
+	}
+	*t = tier
+	return nil
+}
+
 // ModelCapability defines what a model can handle confidently.
 type ModelCapability struct {
-	Backend    string
-	Model      string
-	Tier       ModelTier
-	CostPer1K  float64 // Approximate cost per 1K tokens (input + output avg)
-	SpeedScore int     // 1-10, higher = faster
+	Backend    string    `json:"backend"`
+	Model      string    `json:"model"`
+	Tier       ModelTier `json:"tier"`
+	CostPer1K  float64   `json:"cost_per_1k"` // Approximate cost per 1K tokens (input + output avg)
+	SpeedScore int       `json:"speed_score"` // 1-10, higher = faster
+}
+
+// KnownBackends lists the backend names a ModelCapability may reference.
+var KnownBackends = []string{"claude", "openai", "grok", "bedrock", "ollama"}
+
+// Validate checks that a capability names a known backend and a model,
+// and that its tier, cost, and speed are in range.
+func (c ModelCapability) Validate() error {
+	if !contains(KnownBackends, c.Backend) {
+		return fmt.Errorf("unknown backend %q: must be one of %s", c.Backend, strings.Join(KnownBackends, ", "))
+	}
+	if c.Model == "" {
+		return fmt.Errorf("model is required")
+	}
+	if c.Tier < TierSimple || c.Tier > TierComplex {
+		return fmt.Errorf("tier %s is not an API tier", c.Tier)
+	}
+	if c.CostPer1K < 0 {
+		return fmt.Errorf("cost_per_1k must not be negative")
+	}
+	if c.SpeedScore < 1 || c.SpeedScore > 10 {
+		return fmt.Errorf("speed_score %d must be from 1 to 10", c.SpeedScore)
+	}
+	return nil
 }
 
 // ModelCapabilities defines the capability and cost profile of each model.
 // Ordered by cost (cheapest first within each tier).
 var ModelCapabilities = []ModelCapability{
 	// Tier Simple - fast and cheap, good for basic tasks
+	// (a local Ollama daemon is free, so cheap intent prefers it when registered)
+	{Backend: "ollama", Model: "llama3.2", Tier: TierSimple, CostPer1K: 0, SpeedScore: 5},
 	{Backend: "grok", Model: "grok-3-mini", Tier: TierSimple, CostPer1K: 0.0001, SpeedScore: 9},
 	{Backend: "bedrock", Model: "haiku", Tier: TierSimple, CostPer1K: 0.001, SpeedScore: 8},
 
@@ -81,12 +140,209 @@ var ModelCapabilities = []ModelCapability{
 	{Backend: "bedrock", Model: "opus", Tier: TierComplex, CostPer1K: 0.045, SpeedScore: 4},
 }
 
+// ModelOverride adjusts the speed/cost profile of a ModelCapabilities entry.
+// Nil fields leave the built-in value unchanged; a set field replaces it,
+// so an explicit cost of 0 (e.g., a self-hosted model) is honored.
+type ModelOverride struct {
+	SpeedScore *int     `json:"speed_score,omitempty"`
+	CostPer1K  *float64 `json:"cost_per_1k,omitempty"`
+}
+
+// MergeModelCapabilities returns a copy of caps with extra entries merged
+// in by backend+model: an entry matching an existing model replaces it, and
+// any other entry is appended. Entries are not validated here.
+func MergeModelCapabilities(caps, extra []ModelCapability) []ModelCapability {
+	result := make([]ModelCapability, len(caps), len(caps)+len(extra))
+	copy(result, caps)
+
+	for _, e := range extra {
+		replaced := false
+		for i := range result {
+			if result[i].Backend == e.Backend && result[i].Model == e.Model {
+				result[i] = e
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, e)
+		}
+	}
+
+	return result
+}
+
+// ApplyModelOverrides returns a copy of caps with overrides applied.
+// Overrides are keyed by "backend/model" (e.g., "grok/grok-3-mini").
+// An override that would leave the entry invalid is logged and ignored.
+func ApplyModelOverrides(caps []ModelCapability, overrides map[string]ModelOverride) []ModelCapability {
+	result := make([]ModelCapability, len(caps))
+	copy(result, caps)
+
+	for i := range result {
+		key := result[i].Backend + "/" + result[i].Model
+		o, ok := overrides[key]
+		if !ok {
+			continue
+		}
+		c := result[i]
+		if o.SpeedScore != nil {
+			c.SpeedScore = *o.SpeedScore
+		}
+		if o.CostPer1K != nil {
+			c.CostPer1K = *o.CostPer1K
+		}
+		if err := c.Validate(); err != nil {
+			log.Printf("[router] Ignoring model override for %s: %v", key, err)
+			continue
+		}
+		result[i] = c
+	}
+
+	return result
+}
+
+// AnalyzerPatterns are keywords the task analyzer scores. Keywords are
+// matched case-insensitively as substrings; tool phrases as whole words.
+type AnalyzerPatterns struct {
+	// Complex keywords add their weight to the complexity score.
+	Complex map[string]int `json:"complex,omitempty"`
+
+	// Simple keywords lower the score by their weight. Only the heaviest
+	// matching simple keyword counts.
+	Simple map[string]int `json:"simple,omitempty"`
+
+	// Tool phrases mark a task as needing CLI tools (file operations,
+	// commands) when they appear in an imperative sentence.
+	Tool []string `json:"tool,omitempty"`
+}
+
+// DefaultAnalyzerPatterns are the built-in keywords.
+var DefaultAnalyzerPatterns = AnalyzerPatterns{
+	Complex: map[string]int{
+		"implement":     30,
+		"refactor":      30,
+		"architect":     40,
+		"design":        25,
+		"debug":         20,
+		"optimize":      20,
+		"migrate":       25,
+		"integrate":     20,
+		"multi-step":    20,
+		"comprehensive": 15,
+	},
+	Simple: map[string]int{
+		"summarize":  10,
+		"explain":    10,
+		"what is":    10,
+		"list":       10,
+		"format":     10,
+		"convert":    10,
+		"translate":  10,
+		"classify":   10,
+		"categorize": 10,
+	},
+	Tool: []string{
+		"create file",
+		"create a file",
+		"write to file",
+		"edit file",
+		"modify file",
+		"delete file",
+		"run test",
+		"run the test",
+		"execute command",
+		"git commit",
+		"git push",
+		"git pull",
+		"npm install",
+		"npm run",
+		"pip install",
+		"make build",
+		"deploy to",
+		"restart service",
+		"ssh into",
+		"docker build",
+		"docker run",
+		"docker compose",
+		"kubectl apply",
+		"kubectl create",
+	},
+}
+
+// MergeAnalyzerPatterns returns base with extra merged over it. An extra
+// keyword replaces the base weight; a weight of zero or less removes the
+// keyword. Tool phrases accumulate. Keywords are lowercased.
+func MergeAnalyzerPatterns(base, extra *AnalyzerPatterns) *AnalyzerPatterns {
+	result := &AnalyzerPatterns{
+		Complex: make(map[string]int),
+		Simple:  make(map[string]int),
+	}
+	seenTools := make(map[string]bool)
+	for _, p := range []*AnalyzerPatterns{base, extra} {
+		if p == nil {
+			continue
+		}
+		mergeKeywordWeights(result.Complex, p.Complex)
+		mergeKeywordWeights(result.Simple, p.Simple)
+		for _, phrase := range p.Tool {
+			phrase = strings.ToLower(strings.TrimSpace(phrase))
+			if phrase != "" && !seenTools[phrase] {
+				seenTools[phrase] = true
+				result.Tool = append(result.Tool, phrase)
+			}
+		}
+	}
+	return result
+}
+
+// mergeKeywordWeights copies weights into dst, deleting keywords whose
+// weight is zero or less.
+func mergeKeywordWeights(dst, weights map[string]int) {
+	for keyword, weight := range weights {
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		if keyword == "" {
+			continue
+		}
+		if weight <= 0 {
+			delete(dst, keyword)
+			continue
+		}
+		dst[keyword] = weight
+	}
+}
+
 // TaskAnalyzer analyzes tasks to determine complexity and routing.
-type TaskAnalyzer struct{}
+type TaskAnalyzer struct {
+	complexKeywords []string // sorted, so signals come out in a stable order
+	complexWeights  map[string]int
+	simpleKeywords  []string // sorted
+	simpleWeights   map[string]int
+	toolPatterns    []*regexp.Regexp
+}
 
-// NewTaskAnalyzer creates a new task analyzer.
-func NewTaskAnalyzer() *TaskAnalyzer {
-	return &TaskAnalyzer{}
+// NewTaskAnalyzer creates a task analyzer scoring DefaultAnalyzerPatterns
+// with patterns merged over them (see MergeAnalyzerPatterns). patterns may
+// be nil.
+func NewTaskAnalyzer(patterns *AnalyzerPatterns) *TaskAnalyzer {
+	merged := MergeAnalyzerPatterns(&DefaultAnalyzerPatterns, patterns)
+	return &TaskAnalyzer{
+		complexKeywords: sortedKeys(merged.Complex),
+		complexWeights:  merged.Complex,
+		simpleKeywords:  sortedKeys(merged.Simple),
+		simpleWeights:   merged.Simple,
+		toolPatterns:    compileToolPatterns(merged.Tool),
+	}
+}
+
+// sortedKeys returns a weight map's keywords in order.
+func sortedKeys(weights map[string]int) []string {
+	keys := make([]string, 0, len(weights))
+	for k := range weights {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // Analyze examines a task and returns its complexity profile.
@@ -98,7 +354,8 @@ func (a *TaskAnalyzer) Analyze(title, description string, labels []string) *Task
 	combined := strings.ToLower(title + " " + description)
 
 	// Check for tool use requirements (must use CLI)
-	if a.requiresToolUse(combined) {
+	// Title and description stay separate clauses for tool-use detection
+	if a.requiresToolUse(strings.ToLower(title + "\n" + description)) {
 		result.RequiresToolUse = true
 		result.MinTier = TierCLI
 		result.Score = 100
@@ -122,22 +379,10 @@ func (a *TaskAnalyzer) Analyze(title, description string, labels []string) *Task
 	}
 
 	// Complex task indicators
-	complexPatterns := map[string]int{
-		"implement":     30,
-		"refactor":      30,
-		"architect":     40,
-		"design":        25,
-		"debug":         20,
-		"optimize":      20,
-		"migrate":       25,
-		"integrate":     20,
-		"multi-step":    20,
-		"comprehensive": 15,
-	}
-	for pattern, points := range complexPatterns {
-		if strings.Contains(combined, pattern) {
-			score += points
-			result.Signals = append(result.Signals, "complex:"+pattern)
+	for _, keyword := range a.complexKeywords {
+		if strings.Contains(combined, keyword) {
+			score += a.complexWeights[keyword]
+			result.Signals = append(result.Signals, "complex:"+keyword)
 		}
 	}
 
@@ -166,25 +411,29 @@ func (a *TaskAnalyzer) Analyze(title, description string, labels []string) *Task
 		result.Signals = append(result.Signals, "numbered-list")
 	}
 
-	// Simple task indicators (reduce score)
-	simplePatterns := []string{
-		"summarize",
-		"explain",
-		"what is",
-		"list",
-		"format",
-		"convert",
-		"translate",
-		"classify",
-		"categorize",
+	// Pasted code: more embedded lines mean more to reason about
+	if codeLines, ok := fencedCodeLines(description); ok {
+		score += min(5+codeLines/5, 50)
+		result.Signals = append(result.Signals, "has-code-block")
 	}
-	for _, pattern := range simplePatterns {
-		if strings.Contains(combined, pattern) {
-			score -= 10
-			result.Signals = append(result.Signals, "simple:"+pattern)
-			break
+
+	// Simple task indicators (reduce score by the heaviest match)
+	heaviest := ""
+	for _, keyword := range a.simpleKeywords {
+		if strings.Contains(combined, keyword) && (heaviest == "" || a.simpleWeights[keyword] > a.simpleWeights[heaviest]) {
+			heaviest = keyword
 		}
 	}
+	if heaviest != "" {
+		score -= a.simpleWeights[heaviest]
+		result.Signals = append(result.Signals, "simple:"+heaviest)
+	}
+
+	// Stack traces mean debugging, which needs at least a moderate model
+	if hasStackTrace(title + "\n" + description) {
+		score = max(score, 25)
+		result.Signals = append(result.Signals, "has-stack-trace")
+	}
 
 	// Check for explicit tier hints in labels
 	for _, label := range labels {
@@ -214,44 +463,71 @@ func (a *TaskAnalyzer) Analyze(title, description string, labels []string) *Task
 	return result
 }
 
-// requiresToolUse checks if the task needs CLI tool capabilities.
-func (a *TaskAnalyzer) requiresToolUse(text string) bool {
-	// Patterns that indicate actual tool/command execution
-	toolPatterns := []string{
-		"create file",
-		"create a file",
-		"write to file",
-		"edit file",
-		"modify file",
-		"delete file",
-		"run test",
-		"run the test",
-		"execute command",
-		"git commit",
-		"git push",
-		"git pull",
-		"npm install",
-		"npm run",
-		"pip install",
-		"make build",
-		"deploy to",
-		"restart service",
-		"ssh into",
-		"docker build",
-		"docker run",
-		"docker compose",
-		"kubectl apply",
-		"kubectl create",
+// stackTraceLine matches a frame of a Java/JavaScript ("at fn (file:12)")
+// or Python ('File "x.py", line 12') stack trace.
+var stackTraceLine = regexp.MustCompile(`(?m)^\s*at .*\(.*:\d+\)|File ".*", line \d+`)
+
+// fencedCodeLines counts the lines inside ``` fenced code blocks. An
+// unclosed fence runs to the end of the text. ok reports whether any fence
+// was found.
+func fencedCodeLines(text string) (lines int, ok bool) {
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			ok = true
+			continue
+		}
+		if inFence {
+			lines++
+		}
+	}
+	return lines, ok
+}
+
+// hasStackTrace reports whether text contains a stack trace frame.
+func hasStackTrace(text string) bool {
+	return stackTraceLine.MatchString(text)
+}
+
+// explanatoryWords mark a clause as asking about a tool rather than asking
+// for it to be run ("describe how git commit works").
+var explanatoryWords = regexp.MustCompile(`\b(?:how|what|why|explain|describe|understand|about|difference|meaning|means)\b`)
+
+// compileToolPatterns matches each phrase as whole words, allowing a plural
+// ("run tests").
+func compileToolPatterns(phrases []string) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, len(phrases))
+	for i, phrase := range phrases {
+		patterns[i] = regexp.MustCompile(`\b` + regexp.QuoteMeta(phrase) + `s?\b`)
 	}
+	return patterns
+}
 
-	for _, pattern := range toolPatterns {
-		if strings.Contains(text, pattern) {
-			return true
+// requiresToolUse checks if the task needs CLI tool capabilities. A tool
+// phrase only counts as whole words in an imperative sentence, so "explain
+// how to create file descriptors" and "describe how git commit works" don't
+// force CLI routing.
+func (a *TaskAnalyzer) requiresToolUse(text string) bool {
+	for _, sentence := range strings.FieldsFunc(text, func(r rune) bool {
+		return r == '\n' || r == '.' || r == '!' || r == '?' || r == ';'
+	}) {
+		for _, pattern := range a.toolPatterns {
+			if loc := pattern.FindStringIndex(sentence); loc != nil && isImperativeClause(sentence, loc[0]) {
+				return true
+			}
 		}
 	}
 	return false
 }
 
+// isImperativeClause reports whether the tool phrase starting at
+// sentence[start] is something to do rather than something to explain:
+// nothing explanatory comes before it in the sentence.
+func isImperativeClause(sentence string, start int) bool {
+	return !explanatoryWords.MatchString(sentence[:start])
+}
+
 // scoreToTier converts a complexity score to minimum required tier.
 // Note: CLI routing is primarily based on tool use detection, not score.
 // High complexity tasks should use Opus, not CLI.
@@ -307,6 +583,33 @@ func ExtractIntent(labels []string) Intent {
 
 // SelectModel chooses the best model based on complexity, intent, and availability.
 func SelectModel(complexity *TaskComplexity, intent Intent, availableBackends []string) *ModelCapability {
+	return SelectModelFrom(ModelCapabilities, complexity, intent, availableBackends)
+}
+
+// SelectModelFrom is SelectModel over an explicit capability table.
+func SelectModelFrom(caps []ModelCapability, complexity *TaskComplexity, intent Intent, availableBackends []string) *ModelCapability {
+	return selectModelWeighted(caps, complexity, intent, availableBackends, DefaultBalancedCostWeight)
+}
+
+// DefaultBalancedCostWeight is how much cost (vs. speed) counts toward the
+// IntentBalanced score: 0.5 weighs them equally.
+const DefaultBalancedCostWeight = 0.5
+
+// balancedScore rates a model for IntentBalanced (lower is better).
+// Cost is normalized against the priciest candidate and speed against the
+// 1-10 scale, so a slightly pricier but much faster model can win.
+func balancedScore(c ModelCapability, maxCost, costWeight float64) float64 {
+	costNorm := 0.0
+	if maxCost > 0 {
+		costNorm = c.CostPer1K / maxCost
+	}
+	speedNorm := 1 - float64(c.SpeedScore)/10
+	return costWeight*costNorm + (1-costWeight)*speedNorm
+}
+
+// selectModelWeighted is SelectModelFrom with an explicit IntentBalanced
+// cost weight (0-1; out-of-range values use DefaultBalancedCostWeight).
+func selectModelWeighted(caps []ModelCapability, complexity *TaskComplexity, intent Intent, availableBackends []string, costWeight float64) *ModelCapability {
 	// If tool use required, must use CLI
 	if complexity.RequiresToolUse {
 		return nil
@@ -337,9 +640,13 @@ func SelectModel(complexity *TaskComplexity, intent Intent, availableBackends []
 
 	// Find cheapest model that meets minimum tier
 	var candidates []ModelCapability
-	for _, cap := range ModelCapabilities {
+	maxCost := 0.0
+	for _, cap := range caps {
 		if cap.Tier >= minTier && available[cap.Backend] {
 			candidates = append(candidates, cap)
+			if cap.CostPer1K > maxCost {
+				maxCost = cap.CostPer1K
+			}
 		}
 	}
 
@@ -347,7 +654,12 @@ func SelectModel(complexity *TaskComplexity, intent Intent, availableBackends []
 		return nil
 	}
 
-	// Sort by cost for cheap intent, by speed for fast intent
+	if costWeight < 0 || costWeight > 1 {
+		costWeight = DefaultBalancedCostWeight
+	}
+
+	// Sort by cost for cheap intent, by speed for fast intent,
+	// and by weighted cost+speed for balanced intent
 	best := candidates[0]
 	for _, c := range candidates[1:] {
 		switch intent {
@@ -355,6 +667,10 @@ func SelectModel(complexity *TaskComplexity, intent Intent, availableBackends []
 			if c.SpeedScore > best.SpeedScore {
 				best = c
 			}
+		case IntentBalanced:
+			if balancedScore(c, maxCost, costWeight) < balancedScore(best, maxCost, costWeight) {
+				best = c
+			}
 		default:
 			// Default: cheapest that meets tier
 			if c.CostPer1K < best.CostPer1K {
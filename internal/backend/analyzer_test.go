@@ -1,11 +1,13 @@
 package backend
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 )
 
 func TestTaskAnalyzerSimpleTasks(t *testing.T) {
-	analyzer := NewTaskAnalyzer()
+	analyzer := NewTaskAnalyzer(nil)
 
 	tests := []struct {
 		name        string
@@ -52,7 +54,7 @@ func TestTaskAnalyzerSimpleTasks(t *testing.T) {
 }
 
 func TestTaskAnalyzerComplexTasks(t *testing.T) {
-	analyzer := NewTaskAnalyzer()
+	analyzer := NewTaskAnalyzer(nil)
 
 	tests := []struct {
 		name        string
@@ -98,7 +100,7 @@ func TestTaskAnalyzerComplexTasks(t *testing.T) {
 }
 
 func TestTaskAnalyzerToolUse(t *testing.T) {
-	analyzer := NewTaskAnalyzer()
+	analyzer := NewTaskAnalyzer(nil)
 
 	tests := []struct {
 		name        string
@@ -130,11 +132,41 @@ func TestTaskAnalyzerToolUse(t *testing.T) {
 			description: "Build the image with docker build and docker run it",
 			wantToolUse: true,
 		},
+		{
+			name:        "plural run tests",
+			description: "Fix the parser, then run tests",
+			wantToolUse: true,
+		},
 		{
 			name:        "no tool use",
 			description: "Explain how Docker works",
 			wantToolUse: false,
 		},
+		{
+			name:        "explaining file descriptors",
+			description: "Explain how to create file descriptors in Linux",
+			wantToolUse: false,
+		},
+		{
+			name:        "describing git commit",
+			description: "Describe how git commit works",
+			wantToolUse: false,
+		},
+		{
+			name:        "asking about docker build",
+			description: "What does docker build do?",
+			wantToolUse: false,
+		},
+		{
+			name:        "phrase inside a longer word",
+			description: "Summarize the npm installer changelog",
+			wantToolUse: false,
+		},
+		{
+			name:        "question then instruction",
+			description: "Why is CI red? Run the tests and fix what fails",
+			wantToolUse: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -147,8 +179,123 @@ func TestTaskAnalyzerToolUse(t *testing.T) {
 	}
 }
 
+func TestTaskAnalyzerCodeBlocks(t *testing.T) {
+	analyzer := NewTaskAnalyzer(nil)
+
+	small := "Summarize what this does:\n```go\nfunc add(a, b int) int { return a + b }\n```"
+	result := analyzer.Analyze("Summarize", small, nil)
+	if !hasSignal(result, "has-code-block") {
+		t.Errorf("signals = %v, want has-code-block", result.Signals)
+	}
+	if result.MinTier != TierSimple {
+		t.Errorf("one-line snippet tier = %s, want simple", result.MinTier)
+	}
+
+	large := "Summarize what this does:\n```go\n" + strings.Repeat("x := compute(x)\n", 200) + "```"
+	result = analyzer.Analyze("Summarize", large, nil)
+	if result.MinTier < TierModerate {
+		t.Errorf("200-line paste scored %d (%s), want at least moderate", result.Score, result.MinTier)
+	}
+	if result.Score > 100 {
+		t.Errorf("score = %d, want clamped to 100", result.Score)
+	}
+
+	// An unclosed fence counts to the end of the text
+	if lines, ok := fencedCodeLines("```\na\nb\nc"); !ok || lines != 3 {
+		t.Errorf("fencedCodeLines(unclosed) = %d, %v, want 3, true", lines, ok)
+	}
+	if _, ok := fencedCodeLines("no code here"); ok {
+		t.Error("fencedCodeLines found a fence in plain text")
+	}
+}
+
+func TestTaskAnalyzerStackTraces(t *testing.T) {
+	analyzer := NewTaskAnalyzer(nil)
+
+	traces := map[string]string{
+		"java":       "Why does this fail?\nException in thread main\n    at com.example.App.run(App.java:42)",
+		"javascript": "Why does this fail?\nTypeError: x is undefined\n    at handler (/srv/app/index.js:17:5)",
+		"python":     "Why does this fail?\nTraceback (most recent call last):\n  File \"app.py\", line 12, in <module>",
+	}
+	for name, desc := range traces {
+		result := analyzer.Analyze("Explain error", desc, nil)
+		if !hasSignal(result, "has-stack-trace") {
+			t.Errorf("%s: signals = %v, want has-stack-trace", name, result.Signals)
+		}
+		if result.MinTier < TierModerate {
+			t.Errorf("%s: tier = %s, want at least moderate", name, result.MinTier)
+		}
+	}
+
+	result := analyzer.Analyze("Explain", "Meet at the cafe (near 5th:30)", nil)
+	if hasSignal(result, "has-stack-trace") {
+		t.Errorf("prose flagged as a stack trace: %v", result.Signals)
+	}
+}
+
+// hasSignal reports whether the analysis recorded signal.
+func hasSignal(c *TaskComplexity, signal string) bool {
+	for _, s := range c.Signals {
+		if s == signal {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMergeAnalyzerPatterns(t *testing.T) {
+	merged := MergeAnalyzerPatterns(&DefaultAnalyzerPatterns, &AnalyzerPatterns{
+		Complex: map[string]int{"ETL": 25, "design": 0, "refactor": 10},
+		Simple:  map[string]int{"tl;dr": 15},
+		Tool:    []string{"Terraform Apply", "git push"},
+	})
+
+	if merged.Complex["etl"] != 25 {
+		t.Errorf("Complex[etl] = %d, want 25 (keywords are lowercased)", merged.Complex["etl"])
+	}
+	if _, ok := merged.Complex["design"]; ok {
+		t.Error("a zero weight should remove the built-in design keyword")
+	}
+	if merged.Complex["refactor"] != 10 || merged.Complex["implement"] != 30 {
+		t.Errorf("Complex = %v, want refactor reweighted and implement kept", merged.Complex)
+	}
+	if merged.Simple["tl;dr"] != 15 || merged.Simple["summarize"] != 10 {
+		t.Errorf("Simple = %v", merged.Simple)
+	}
+	if n := len(merged.Tool); n != len(DefaultAnalyzerPatterns.Tool)+1 {
+		t.Errorf("Tool has %d phrases, want the built-ins plus terraform apply", n)
+	}
+	if DefaultAnalyzerPatterns.Complex["design"] != 25 {
+		t.Error("MergeAnalyzerPatterns modified the built-in patterns")
+	}
+}
+
+func TestTaskAnalyzerCustomPatterns(t *testing.T) {
+	patterns := &AnalyzerPatterns{
+		Complex: map[string]int{"etl": 20, "backfill": 20, "pipeline": 15},
+		Tool:    []string{"terraform apply"},
+	}
+	title, desc := "Nightly ETL", "Backfill the orders pipeline for last quarter"
+
+	if got := NewTaskAnalyzer(nil).Analyze(title, desc, nil); got.MinTier != TierSimple {
+		t.Fatalf("built-in analysis = %s (score %d), want simple", got.MinTier, got.Score)
+	}
+	result := NewTaskAnalyzer(patterns).Analyze(title, desc, nil)
+	if result.MinTier != TierComplex {
+		t.Errorf("tier = %s (score %d), want complex", result.MinTier, result.Score)
+	}
+	want := []string{"complex:backfill", "complex:etl", "complex:pipeline"}
+	if strings.Join(result.Signals, ",") != strings.Join(want, ",") {
+		t.Errorf("signals = %v, want %v", result.Signals, want)
+	}
+
+	if !NewTaskAnalyzer(patterns).Analyze("Infra", "Terraform apply the staging changes", nil).RequiresToolUse {
+		t.Error("configured tool phrase should require tool use")
+	}
+}
+
 func TestTaskAnalyzerIntentLabels(t *testing.T) {
-	analyzer := NewTaskAnalyzer()
+	analyzer := NewTaskAnalyzer(nil)
 
 	tests := []struct {
 		name     string
@@ -283,3 +430,201 @@ func TestSelectModelFallback(t *testing.T) {
 		t.Errorf("Backend = %s, want bedrock (fallback)", result.Backend)
 	}
 }
+
+func TestApplyModelOverridesChangesSelection(t *testing.T) {
+	complexity := &TaskComplexity{MinTier: TierSimple}
+	available := []string{"grok", "bedrock"}
+
+	// Baseline: grok-3-mini is both cheapest and fastest
+	if got := SelectModel(complexity, IntentCheap, available); got == nil || got.Model != "grok-3-mini" {
+		t.Fatalf("baseline cheap selection = %+v, want grok-3-mini", got)
+	}
+	if got := SelectModel(complexity, IntentFast, available); got == nil || got.Model != "grok-3-mini" {
+		t.Fatalf("baseline fast selection = %+v, want grok-3-mini", got)
+	}
+
+	caps := ApplyModelOverrides(ModelCapabilities, map[string]ModelOverride{
+		"bedrock/haiku": {SpeedScore: intPtr(10), CostPer1K: floatPtr(0.00005)},
+	})
+
+	if got := SelectModelFrom(caps, complexity, IntentCheap, available); got == nil || got.Model != "haiku" {
+		t.Errorf("cheap selection with override = %+v, want haiku", got)
+	}
+	if got := SelectModelFrom(caps, complexity, IntentFast, available); got == nil || got.Model != "haiku" {
+		t.Errorf("fast selection with override = %+v, want haiku", got)
+	}
+
+	// The built-in table must not be mutated
+	for _, c := range ModelCapabilities {
+		if c.Backend == "bedrock" && c.Model == "haiku" && c.SpeedScore == 10 {
+			t.Error("ApplyModelOverrides mutated ModelCapabilities")
+		}
+	}
+}
+
+func TestApplyModelOverridesUnsetKeepsDefault(t *testing.T) {
+	caps := ApplyModelOverrides(ModelCapabilities, map[string]ModelOverride{
+		"grok/grok-3": {SpeedScore: intPtr(2)},
+	})
+
+	for _, c := range caps {
+		if c.Backend == "grok" && c.Model == "grok-3" {
+			if c.SpeedScore != 2 {
+				t.Errorf("SpeedScore = %d, want 2", c.SpeedScore)
+			}
+			if c.CostPer1K != 0.01 {
+				t.Errorf("CostPer1K = %v, want unchanged 0.01", c.CostPer1K)
+			}
+		}
+	}
+}
+
+func TestApplyModelOverridesExplicitZeroCost(t *testing.T) {
+	caps := ApplyModelOverrides(ModelCapabilities, map[string]ModelOverride{
+		"grok/grok-3": {CostPer1K: floatPtr(0)},
+	})
+
+	for _, c := range caps {
+		if c.Backend == "grok" && c.Model == "grok-3" && c.CostPer1K != 0 {
+			t.Errorf("CostPer1K = %v, want explicit 0", c.CostPer1K)
+		}
+	}
+}
+
+func TestApplyModelOverridesIgnoresInvalid(t *testing.T) {
+	caps := ApplyModelOverrides(ModelCapabilities, map[string]ModelOverride{
+		"grok/grok-3": {SpeedScore: intPtr(0), CostPer1K: floatPtr(0.5)},
+	})
+
+	for _, c := range caps {
+		if c.Backend == "grok" && c.Model == "grok-3" && (c.SpeedScore == 0 || c.CostPer1K != 0.01) {
+			t.Errorf("grok/grok-3 = %+v, want invalid override ignored", c)
+		}
+	}
+}
+
+func intPtr(v int) *int { return &v }
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestMergeModelCapabilities(t *testing.T) {
+	caps := MergeModelCapabilities(ModelCapabilities, []ModelCapability{
+		{Backend: "bedrock", Model: "haiku", Tier: TierSimple, CostPer1K: 0.002, SpeedScore: 7},
+		{Backend: "openai", Model: "gpt-4o-mini", Tier: TierSimple, CostPer1K: 0.0003, SpeedScore: 8},
+	})
+
+	if len(caps) != len(ModelCapabilities)+1 {
+		t.Fatalf("merged %d entries, want %d (one replaced, one appended)", len(caps), len(ModelCapabilities)+1)
+	}
+	for _, c := range caps {
+		if c.Backend == "bedrock" && c.Model == "haiku" && (c.CostPer1K != 0.002 || c.SpeedScore != 7) {
+			t.Errorf("bedrock/haiku = %+v, want replaced", c)
+		}
+	}
+	if last := caps[len(caps)-1]; last.Model != "gpt-4o-mini" {
+		t.Errorf("last entry = %+v, want appended gpt-4o-mini", last)
+	}
+	for _, c := range ModelCapabilities {
+		if c.Backend == "bedrock" && c.Model == "haiku" && c.CostPer1K != 0.001 {
+			t.Error("MergeModelCapabilities mutated ModelCapabilities")
+		}
+	}
+}
+
+func TestModelCapabilityValidate(t *testing.T) {
+	valid := ModelCapability{Backend: "ollama", Model: "qwen2.5", Tier: TierModerate, CostPer1K: 0, SpeedScore: 4}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate(%+v) = %v", valid, err)
+	}
+
+	invalid := []ModelCapability{
+		{Backend: "gemini", Model: "x", Tier: TierSimple, SpeedScore: 5},
+		{Backend: "grok", Model: "", Tier: TierSimple, SpeedScore: 5},
+		{Backend: "grok", Model: "x", Tier: TierCLI, SpeedScore: 5},
+		{Backend: "grok", Model: "x", Tier: TierSimple, CostPer1K: -1, SpeedScore: 5},
+		{Backend: "grok", Model: "x", Tier: TierSimple, SpeedScore: 0},
+	}
+	for _, c := range invalid {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want error", c)
+		}
+	}
+}
+
+func TestModelTierJSON(t *testing.T) {
+	var c ModelCapability
+	if err := json.Unmarshal([]byte(`{"backend":"grok","model":"grok-4","tier":"complex","cost_per_1k":0.03,"speed_score":5}`), &c); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if c.Tier != TierComplex {
+		t.Errorf("Tier = %s, want complex", c.Tier)
+	}
+	data, _ := json.Marshal(c)
+	if !strings.Contains(string(data), `"tier":"complex"`) {
+		t.Errorf("Marshal = %s, want tier by name", data)
+	}
+	if err := json.Unmarshal([]byte(`{"tier":"huge"}`), &c); err == nil {
+		t.Error("Unmarshal with unknown tier should fail")
+	}
+}
+
+func TestSelectModelBalancedDiffersFromCheap(t *testing.T) {
+	caps := []ModelCapability{
+		{Backend: "bedrock", Model: "slow-cheap", Tier: TierSimple, CostPer1K: 0.0010, SpeedScore: 3},
+		{Backend: "bedrock", Model: "fast-pricier", Tier: TierSimple, CostPer1K: 0.0012, SpeedScore: 9},
+		{Backend: "bedrock", Model: "premium", Tier: TierSimple, CostPer1K: 0.0300, SpeedScore: 10},
+	}
+	complexity := &TaskComplexity{MinTier: TierSimple}
+	available := []string{"bedrock"}
+
+	if got := SelectModelFrom(caps, complexity, IntentCheap, available); got == nil || got.Model != "slow-cheap" {
+		t.Errorf("cheap selection = %+v, want slow-cheap", got)
+	}
+	if got := SelectModelFrom(caps, complexity, IntentFast, available); got == nil || got.Model != "premium" {
+		t.Errorf("fast selection = %+v, want premium", got)
+	}
+	if got := SelectModelFrom(caps, complexity, IntentBalanced, available); got == nil || got.Model != "fast-pricier" {
+		t.Errorf("balanced selection = %+v, want fast-pricier", got)
+	}
+}
+
+func TestSelectModelBalancedWeight(t *testing.T) {
+	caps := []ModelCapability{
+		{Backend: "bedrock", Model: "slow-cheap", Tier: TierSimple, CostPer1K: 0.001, SpeedScore: 3},
+		{Backend: "bedrock", Model: "fast-pricey", Tier: TierSimple, CostPer1K: 0.002, SpeedScore: 9},
+	}
+	complexity := &TaskComplexity{MinTier: TierSimple}
+	available := []string{"bedrock"}
+
+	tests := []struct {
+		weight float64
+		want   string
+	}{
+		{-1, "fast-pricey"},  // out of range: default weighting
+		{0, "fast-pricey"},   // speed only
+		{1, "slow-cheap"},    // cost only
+		{0.9, "slow-cheap"},  // cost dominates
+		{0.1, "fast-pricey"}, // speed dominates
+	}
+	for _, tt := range tests {
+		got := selectModelWeighted(caps, complexity, IntentBalanced, available, tt.weight)
+		if got == nil || got.Model != tt.want {
+			t.Errorf("weight %.1f: selection = %+v, want %s", tt.weight, got, tt.want)
+		}
+	}
+}
+
+func TestSelectModelPrefersOllamaForCheap(t *testing.T) {
+	complexity := &TaskComplexity{MinTier: TierSimple}
+
+	got := SelectModel(complexity, IntentCheap, []string{"ollama", "grok", "bedrock"})
+	if got == nil || got.Backend != "ollama" {
+		t.Errorf("cheap selection with ollama = %+v, want ollama", got)
+	}
+
+	// Without the daemon registered, the cheapest hosted model wins
+	got = SelectModel(complexity, IntentCheap, []string{"grok", "bedrock"})
+	if got == nil || got.Backend != "grok" {
+		t.Errorf("cheap selection without ollama = %+v, want grok", got)
+	}
+}
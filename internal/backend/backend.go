@@ -5,6 +5,7 @@ package backend
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 )
@@ -21,12 +22,58 @@ const (
 	CapVision
 	// CapLongContext indicates the backend has >100k context window.
 	CapLongContext
+	// CapStructuredOutput indicates the backend honors InvokeOptions.ResponseFormat.
+	CapStructuredOutput
 )
 
+// capabilityNames labels each capability flag, in bit order.
+var capabilityNames = []struct {
+	cap  Capability
+	name string
+}{
+	{CapStreaming, "streaming"},
+	{CapTools, "tools"},
+	{CapVision, "vision"},
+	{CapLongContext, "long-context"},
+	{CapStructuredOutput, "structured-output"},
+}
+
+// Names returns the human-readable names of the flags set in c.
+func (c Capability) Names() []string {
+	var names []string
+	for _, cn := range capabilityNames {
+		if c&cn.cap != 0 {
+			names = append(names, cn.name)
+		}
+	}
+	return names
+}
+
+// CheckInvokeOptions returns an error wrapping ErrCapabilityUnsupported if
+// opts asks for a feature the backend doesn't advertise, so callers fail
+// clearly instead of having the option silently ignored.
+func CheckInvokeOptions(b AgentBackend, opts InvokeOptions) error {
+	caps := b.Capabilities()
+	if opts.ResponseFormat != nil && caps&CapStructuredOutput == 0 {
+		return fmt.Errorf("%w: %s backend does not support structured output", ErrCapabilityUnsupported, b.Name())
+	}
+	if len(opts.Tools) > 0 && caps&CapTools == 0 {
+		return fmt.Errorf("%w: %s backend does not support tools", ErrCapabilityUnsupported, b.Name())
+	}
+	return nil
+}
+
 // Message represents a conversation message for API backends.
 type Message struct {
-	Role    string `json:"role"`    // "user", "assistant", "system"
+	Role    string `json:"role"` // "user", "assistant", "system", "tool"
 	Content string `json:"content"`
+
+	// ToolCalls are the calls an assistant message made (see
+	// InvokeResult.ToolCalls), replayed so the model sees its own calls.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// ToolCallID is the call a "tool" message answers.
+	ToolCallID string `json:"tool_call_id,omitempty"`
 }
 
 // InvokeOptions configures a backend invocation.
@@ -45,6 +92,19 @@ type InvokeOptions struct {
 
 	// Stream requests a streaming response.
 	Stream bool `json:"stream,omitempty"`
+
+	// ResponseFormat constrains the response to JSON (optional).
+	// Backends validate the content and return ErrInvalidStructuredOutput
+	// when it doesn't match.
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	// Tools are the tools the model may call (backends with CapTools).
+	Tools []ToolDef `json:"tools,omitempty"`
+
+	// SearchParameters is passed through as xAI's search_parameters to
+	// enable live search, e.g. {"mode": "on"}. Only the grok backend
+	// uses it; others ignore it.
+	SearchParameters json.RawMessage `json:"search_parameters,omitempty"`
 }
 
 // InvokeResult contains the backend response.
@@ -61,9 +121,18 @@ type InvokeResult struct {
 	// OutputTokens is the token count for the response.
 	OutputTokens int `json:"output_tokens"`
 
-	// FinishReason indicates why generation stopped.
-	// Common values: "stop", "length", "content_filter"
+	// FinishReason indicates why generation stopped, as the provider
+	// reported it. Common values: "stop", "length", "content_filter", and
+	// "tool_calls"/"tool_use" when the model called tools.
 	FinishReason string `json:"finish_reason"`
+
+	// ToolCalls are the tools the model asked to call, in order.
+	// Only Invoke reports them; streams carry text only.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// Citations are the source URLs a live search drew on (grok only;
+	// nil for other backends).
+	Citations []string `json:"citations,omitempty"`
 }
 
 // StreamChunk is a piece of a streaming response.
@@ -71,6 +140,24 @@ type StreamChunk struct {
 	Content string
 	Done    bool
 	Error   error
+
+	// InputTokens and OutputTokens report usage on the final (Done) chunk,
+	// for backends whose streams include it (zero otherwise).
+	InputTokens  int
+	OutputTokens int
+
+	// FinishReason, Citations, and ToolCalls are set on the final (Done)
+	// chunk, as in InvokeResult, for backends whose streams report them.
+	FinishReason string
+	Citations    []string
+	ToolCalls    []ToolCall
+}
+
+// TruncatedByLength reports whether a finish reason means the response was
+// cut off at its token limit: "max_tokens" (Anthropic, Bedrock) or
+// "length" (OpenAI, xAI, Ollama).
+func TruncatedByLength(finishReason string) bool {
+	return finishReason == "max_tokens" || finishReason == "length"
 }
 
 // CostEstimate contains pricing information.
@@ -120,7 +207,8 @@ type AgentBackend interface {
 	// MaxContextTokens returns the context window size for a model.
 	MaxContextTokens(model string) int
 
-	// Healthy checks if the backend is reachable.
+	// Healthy checks if the backend is reachable. Hosted backends only
+	// make a live API call when ctx has a deadline (see LiveCheckAllowed).
 	Healthy(ctx context.Context) error
 }
 
@@ -209,6 +297,9 @@ type RouteResult struct {
 
 	// FallbackToCLI indicates whether to fall back to CLI on API error.
 	FallbackToCLI bool `json:"fallback_to_cli,omitempty"`
+
+	// Rule is the routing rule that matched the task, if any.
+	Rule *RoutingRule `json:"rule,omitempty"`
 }
 
 // TierToBackend maps tier hints to recommended backends/models.
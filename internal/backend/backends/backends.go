@@ -0,0 +1,141 @@
+// Package backends lists the API backends gt knows and builds each one
+// from its settings/backend.json entry, so the dispatcher and gt doctor
+// configure them the same way.
+package backends
+
+import (
+	"sort"
+
+	"github.com/steveyegge/gastown/internal/backend/bedrock"
+	"github.com/steveyegge/gastown/internal/backend/claude"
+	"github.com/steveyegge/gastown/internal/backend/grok"
+	"github.com/steveyegge/gastown/internal/backend/ollama"
+	"github.com/steveyegge/gastown/internal/backend/openai"
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+// Spec describes a known API backend.
+type Spec struct {
+	// Name is the backend's key in settings/backend.json.
+	Name string
+
+	// Label names the backend in log messages.
+	Label string
+
+	// Check builds the backend from its entry without registering it,
+	// reporting missing credentials or rejected settings. It makes no
+	// network calls.
+	Check func(entry *config.BackendEntry) error
+
+	// Register builds the backend from its entry and adds it to the
+	// global registry. Ollama is only registered if its daemon is up.
+	Register func(entry *config.BackendEntry) error
+}
+
+// Known is every API backend gt can register, in registration order.
+var Known = []Spec{
+	{
+		Name:  "claude",
+		Label: "Claude",
+		Check: func(e *config.BackendEntry) error {
+			_, err := claude.New(claudeOptions(e)...)
+			return err
+		},
+		Register: func(e *config.BackendEntry) error { return claude.Register(claudeOptions(e)...) },
+	},
+	{
+		Name:  "openai",
+		Label: "OpenAI",
+		Check: func(e *config.BackendEntry) error {
+			_, err := openai.New(openaiOptions(e)...)
+			return err
+		},
+		Register: func(e *config.BackendEntry) error { return openai.Register(openaiOptions(e)...) },
+	},
+	{
+		Name:  "grok",
+		Label: "Grok",
+		Check: func(e *config.BackendEntry) error {
+			_, err := grok.New(grokOptions(e)...)
+			return err
+		},
+		Register: func(e *config.BackendEntry) error { return grok.Register(grokOptions(e)...) },
+	},
+	{
+		Name:  "bedrock",
+		Label: "Bedrock",
+		Check: func(e *config.BackendEntry) error {
+			_, err := bedrock.New(bedrockOptions(e)...)
+			return err
+		},
+		Register: func(e *config.BackendEntry) error { return bedrock.Register(bedrockOptions(e)...) },
+	},
+	{
+		Name:  "ollama",
+		Label: "Ollama",
+		Check: func(e *config.BackendEntry) error {
+			ollama.New(ollamaOptions(e)...)
+			return nil
+		},
+		Register: func(e *config.BackendEntry) error { return ollama.Register(ollamaOptions(e)...) },
+	},
+}
+
+// Lookup returns the known backend with the given name.
+func Lookup(name string) (Spec, bool) {
+	for _, spec := range Known {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return Spec{}, false
+}
+
+// Names returns the known backend names, sorted.
+func Names() []string {
+	names := make([]string, 0, len(Known))
+	for _, spec := range Known {
+		names = append(names, spec.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func claudeOptions(e *config.BackendEntry) []claude.Option {
+	return []claude.Option{
+		claude.WithRateLimit(e.RateLimitRPM),
+		claude.WithModelLimits(e.ModelLimits),
+		claude.WithAPIVersion(e.APIVersion),
+		claude.WithBetaFeatures(e.BetaFeatures...),
+	}
+}
+
+func openaiOptions(e *config.BackendEntry) []openai.Option {
+	return []openai.Option{
+		openai.WithRateLimit(e.RateLimitRPM),
+		openai.WithModelLimits(e.ModelLimits),
+		openai.WithBaseURL(e.BaseURL),
+		openai.WithCompatibilityMode(e.CompatibilityMode),
+	}
+}
+
+func grokOptions(e *config.BackendEntry) []grok.Option {
+	return []grok.Option{
+		grok.WithRateLimit(e.RateLimitRPM),
+		grok.WithModelLimits(e.ModelLimits),
+		grok.WithLiveSearch(e.LiveSearch),
+	}
+}
+
+func bedrockOptions(e *config.BackendEntry) []bedrock.Option {
+	return []bedrock.Option{
+		bedrock.WithRateLimit(e.RateLimitRPM),
+		bedrock.WithModelLimits(e.ModelLimits),
+		bedrock.WithRegion(e.Region),
+		bedrock.WithInferenceProfiles(e.InferenceProfiles),
+	}
+}
+
+func ollamaOptions(e *config.BackendEntry) []ollama.Option {
+	return []ollama.Option{ollama.WithBaseURL(e.BaseURL)}
+}
@@ -0,0 +1,39 @@
+package backends
+
+import (
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+func TestKnownBackends(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, spec := range Known {
+		if seen[spec.Name] {
+			t.Errorf("backend %q listed twice", spec.Name)
+		}
+		seen[spec.Name] = true
+		if spec.Label == "" || spec.Check == nil || spec.Register == nil {
+			t.Errorf("backend %q is missing its label, Check, or Register", spec.Name)
+		}
+		if got, ok := Lookup(spec.Name); !ok || got.Name != spec.Name {
+			t.Errorf("Lookup(%q) = %+v, %v", spec.Name, got, ok)
+		}
+	}
+	if _, ok := Lookup("gemini"); ok {
+		t.Error("Lookup found an unknown backend")
+	}
+}
+
+func TestCheckReportsMissingKey(t *testing.T) {
+	t.Setenv("XAI_API_KEY", "")
+	spec, _ := Lookup("grok")
+	if err := spec.Check(&config.BackendEntry{Enabled: true}); err == nil {
+		t.Error("expected grok Check to fail without XAI_API_KEY")
+	}
+
+	t.Setenv("XAI_API_KEY", "xai-test")
+	if err := spec.Check(&config.BackendEntry{Enabled: true, LiveSearch: "auto"}); err != nil {
+		t.Errorf("grok Check: %v", err)
+	}
+}
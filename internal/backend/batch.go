@@ -0,0 +1,143 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultBatchConcurrency is how many batch requests run at once when the
+// caller doesn't say.
+const DefaultBatchConcurrency = 4
+
+// BatchRequest is one invocation in a batch.
+type BatchRequest struct {
+	Messages []Message
+	Options  InvokeOptions
+}
+
+// BatchResult is the outcome of one batch request.
+// Exactly one of Result and Err is set.
+type BatchResult struct {
+	// Result is the response when the request succeeded.
+	Result *InvokeResult
+
+	// Cost is the cost of a successful request (zero on failure).
+	Cost CostEstimate
+
+	// Err is why the request failed (rate limit, context length, ...).
+	Err error
+}
+
+// BatchResponse collects per-request outcomes in request order.
+type BatchResponse struct {
+	// Results has one entry per request, at the request's index.
+	Results []BatchResult
+
+	// TotalCost is the summed cost of successful requests only.
+	TotalCost float64
+}
+
+// Failed returns the indices of requests that failed.
+func (r *BatchResponse) Failed() []int {
+	var failed []int
+	for i, res := range r.Results {
+		if res.Err != nil {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// Succeeded returns how many requests succeeded.
+func (r *BatchResponse) Succeeded() int {
+	return len(r.Results) - len(r.Failed())
+}
+
+// recomputeCost re-sums TotalCost from the successful results.
+func (r *BatchResponse) recomputeCost() {
+	r.TotalCost = 0
+	for _, res := range r.Results {
+		if res.Err == nil {
+			r.TotalCost += res.Cost.TotalCost
+		}
+	}
+}
+
+// InvokeBatch runs requests against b with up to concurrency in flight
+// (DefaultBatchConcurrency if <= 0). Individual failures are recorded on
+// their BatchResult rather than failing the batch, so callers get mixed
+// outcomes back; only a nil backend is an error.
+func InvokeBatch(ctx context.Context, b AgentBackend, requests []BatchRequest, concurrency int) (*BatchResponse, error) {
+	if b == nil {
+		return nil, fmt.Errorf("batch invocation requires a backend")
+	}
+
+	resp := &BatchResponse{Results: make([]BatchResult, len(requests))}
+	indices := make([]int, len(requests))
+	for i := range requests {
+		indices[i] = i
+	}
+	invokeIndices(ctx, b, requests, indices, concurrency, resp)
+	return resp, nil
+}
+
+// RetryFailed re-invokes the requests that failed in resp, updating their
+// results in place. Requests that succeed on retry contribute to TotalCost.
+// Returns the indices that still failed.
+func RetryFailed(ctx context.Context, b AgentBackend, requests []BatchRequest, resp *BatchResponse, concurrency int) ([]int, error) {
+	if b == nil {
+		return nil, fmt.Errorf("batch invocation requires a backend")
+	}
+	if len(resp.Results) != len(requests) {
+		return nil, fmt.Errorf("batch has %d results for %d requests", len(resp.Results), len(requests))
+	}
+
+	invokeIndices(ctx, b, requests, resp.Failed(), concurrency, resp)
+	return resp.Failed(), nil
+}
+
+// invokeIndices invokes the given requests and stores each outcome at its
+// index in resp, then refreshes resp.TotalCost.
+func invokeIndices(ctx context.Context, b AgentBackend, requests []BatchRequest, indices []int, concurrency int, resp *BatchResponse) {
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, i := range indices {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			resp.Results[i] = invokeOne(ctx, b, requests[i])
+		}(i)
+	}
+	wg.Wait()
+
+	resp.recomputeCost()
+}
+
+// invokeOne runs a single batch request.
+func invokeOne(ctx context.Context, b AgentBackend, req BatchRequest) BatchResult {
+	if err := ctx.Err(); err != nil {
+		return BatchResult{Err: err}
+	}
+
+	result, err := b.Invoke(ctx, req.Messages, req.Options)
+	if err != nil {
+		return BatchResult{Err: err}
+	}
+
+	model := result.Model
+	if model == "" {
+		model = req.Options.Model
+	}
+	return BatchResult{
+		Result: result,
+		Cost:   b.EstimateCost(result.InputTokens, result.OutputTokens, model),
+	}
+}
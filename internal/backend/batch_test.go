@@ -0,0 +1,140 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// flakyBackend fails requests whose message content is in failures,
+// consuming one failure per attempt.
+type flakyBackend struct {
+	mockBackend
+	mu       sync.Mutex
+	failures map[string]int // content -> remaining failures
+	calls    map[string]int
+}
+
+func (f *flakyBackend) EstimateCost(input, output int, model string) CostEstimate {
+	return CostEstimate{TotalCost: 0.01, Currency: "USD", Model: model}
+}
+
+func (f *flakyBackend) Invoke(_ context.Context, messages []Message, opts InvokeOptions) (*InvokeResult, error) {
+	content := messages[0].Content
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[content]++
+	if f.failures[content] > 0 {
+		f.failures[content]--
+		return nil, fmt.Errorf("rate limited on %s", content)
+	}
+	return &InvokeResult{Content: "ok " + content, Model: opts.Model, InputTokens: 10, OutputTokens: 5}, nil
+}
+
+func batchRequests(n int) []BatchRequest {
+	reqs := make([]BatchRequest, n)
+	for i := range reqs {
+		reqs[i] = BatchRequest{
+			Messages: []Message{{Role: "user", Content: fmt.Sprintf("req-%d", i)}},
+			Options:  InvokeOptions{Model: "haiku"},
+		}
+	}
+	return reqs
+}
+
+func TestInvokeBatchPartialFailure(t *testing.T) {
+	b := &flakyBackend{
+		mockBackend: mockBackend{name: "flaky"},
+		failures:    map[string]int{"req-1": 1, "req-3": 1},
+	}
+
+	resp, err := InvokeBatch(context.Background(), b, batchRequests(5), 2)
+	if err != nil {
+		t.Fatalf("InvokeBatch returned error for mixed outcomes: %v", err)
+	}
+
+	if len(resp.Results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(resp.Results))
+	}
+	failed := resp.Failed()
+	if len(failed) != 2 || failed[0] != 1 || failed[1] != 3 {
+		t.Errorf("Failed() = %v, want [1 3]", failed)
+	}
+	for i, res := range resp.Results {
+		if i == 1 || i == 3 {
+			if res.Err == nil || res.Result != nil {
+				t.Errorf("result %d: expected error only, got %+v", i, res)
+			}
+			continue
+		}
+		if res.Err != nil || res.Result == nil || res.Result.Content != fmt.Sprintf("ok req-%d", i) {
+			t.Errorf("result %d: expected success in request order, got %+v", i, res)
+		}
+	}
+	if resp.Succeeded() != 3 {
+		t.Errorf("Succeeded() = %d, want 3", resp.Succeeded())
+	}
+	if resp.TotalCost < 0.0299 || resp.TotalCost > 0.0301 {
+		t.Errorf("TotalCost = %.4f, want 0.03 (successes only)", resp.TotalCost)
+	}
+}
+
+func TestRetryFailed(t *testing.T) {
+	b := &flakyBackend{
+		mockBackend: mockBackend{name: "flaky"},
+		failures:    map[string]int{"req-0": 1, "req-2": 2},
+	}
+	reqs := batchRequests(3)
+
+	resp, err := InvokeBatch(context.Background(), b, reqs, 0)
+	if err != nil {
+		t.Fatalf("InvokeBatch: %v", err)
+	}
+
+	still, err := RetryFailed(context.Background(), b, reqs, resp, 0)
+	if err != nil {
+		t.Fatalf("RetryFailed: %v", err)
+	}
+	if len(still) != 1 || still[0] != 2 {
+		t.Errorf("still failed = %v, want [2]", still)
+	}
+	if b.calls["req-1"] != 1 {
+		t.Errorf("successful request re-invoked: %d calls", b.calls["req-1"])
+	}
+	if resp.TotalCost < 0.0199 || resp.TotalCost > 0.0201 {
+		t.Errorf("TotalCost = %.4f, want 0.02", resp.TotalCost)
+	}
+
+	still, err = RetryFailed(context.Background(), b, reqs, resp, 0)
+	if err != nil {
+		t.Fatalf("RetryFailed: %v", err)
+	}
+	if len(still) != 0 {
+		t.Errorf("expected all requests to succeed, still failed: %v", still)
+	}
+}
+
+func TestInvokeBatchCanceledContext(t *testing.T) {
+	b := &flakyBackend{mockBackend: mockBackend{name: "flaky"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	resp, err := InvokeBatch(ctx, b, batchRequests(2), 1)
+	if err != nil {
+		t.Fatalf("InvokeBatch: %v", err)
+	}
+	for i, res := range resp.Results {
+		if !errors.Is(res.Err, context.Canceled) {
+			t.Errorf("result %d: err = %v, want context.Canceled", i, res.Err)
+		}
+	}
+	if resp.TotalCost != 0 {
+		t.Errorf("TotalCost = %.4f, want 0", resp.TotalCost)
+	}
+}
@@ -4,27 +4,27 @@ package bedrock
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 	"github.com/steveyegge/gastown/internal/backend"
 )
 
 // Model definitions mapping friendly names to Bedrock model IDs.
 var (
-	// BedrockModels maps tier names to Bedrock model IDs.
-	BedrockModels = map[string]string{
-		"opus":   "us.anthropic.claude-opus-4-5-20251101-v1:0",
-		"sonnet": "us.anthropic.claude-sonnet-4-5-20250929-v1:0",
-		"haiku":  "us.anthropic.claude-3-5-haiku-20241022-v1:0",
-		// Full model IDs also supported
-		"us.anthropic.claude-opus-4-5-20251101-v1:0":   "us.anthropic.claude-opus-4-5-20251101-v1:0",
-		"us.anthropic.claude-sonnet-4-5-20250929-v1:0": "us.anthropic.claude-sonnet-4-5-20250929-v1:0",
-		"us.anthropic.claude-3-5-haiku-20241022-v1:0":  "us.anthropic.claude-3-5-haiku-20241022-v1:0",
+	// FoundationModels maps tier names to Bedrock foundation model IDs.
+	// Requests use the inference profile for the backend's region (see
+	// inferenceProfileID).
+	FoundationModels = map[string]string{
+		"opus":   "anthropic.claude-opus-4-5-20251101-v1:0",
+		"sonnet": "anthropic.claude-sonnet-4-5-20250929-v1:0",
+		"haiku":  "anthropic.claude-3-5-haiku-20241022-v1:0",
 	}
 
 	// ContextWindows for each model tier.
@@ -50,31 +50,87 @@ const (
 
 // Backend implements backend.AgentBackend for AWS Bedrock.
 type Backend struct {
-	client      *bedrockruntime.Client
-	region      string
-	rateLimiter *rateLimiter
+	client   modelInvoker
+	region   string
+	limits   backend.Limits
+	limiter  *backend.Limiter
+	retry    backend.RetryPolicy
+	profiles map[string]string
+}
+
+// modelInvoker is the part of the Bedrock runtime client the backend uses,
+// so tests can fake it.
+type modelInvoker interface {
+	InvokeModel(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error)
 }
 
 // Option configures the Bedrock backend.
 type Option func(*Backend)
 
-// WithRegion sets the AWS region.
+// WithRegion sets the AWS region. Model tiers use the inference profiles
+// of its geography (us, eu, or apac). An empty region keeps the default.
 func WithRegion(region string) Option {
 	return func(b *Backend) {
-		b.region = region
+		if region != "" {
+			b.region = region
+		}
+	}
+}
+
+// WithInferenceProfiles maps models to the inference profile ID or ARN to
+// invoke, replacing the profile derived from the region (e.g. an
+// application inference profile, or a model's profile in another
+// geography). Keys may be aliases; they're resolved to model names.
+func WithInferenceProfiles(profiles map[string]string) Option {
+	return func(b *Backend) {
+		b.profiles = make(map[string]string, len(profiles))
+		for model, profile := range profiles {
+			b.profiles[backend.ResolveModel("bedrock", model, nil)] = profile
+		}
+	}
+}
+
+// WithRateLimit sets the rate limit (requests per minute).
+func WithRateLimit(rpm int) Option {
+	return func(b *Backend) {
+		b.limits.RequestsPerMinute = rpm
+	}
+}
+
+// WithModelLimits sets per-model request rates and concurrency caps. Keys
+// may be aliases; they're resolved to model names.
+func WithModelLimits(limits map[string]backend.ModelLimits) Option {
+	return func(b *Backend) {
+		b.limits.Models = make(map[string]backend.ModelLimits, len(limits))
+		for model, ml := range limits {
+			b.limits.Models[backend.ResolveModel("bedrock", model, nil)] = ml
+		}
+	}
+}
+
+// WithRetry sets how many attempts a throttled or unavailable request gets
+// and the base delay for exponential backoff between them.
+func WithRetry(attempts int, baseDelay time.Duration) Option {
+	return func(b *Backend) {
+		b.retry.MaxAttempts = attempts
+		b.retry.BaseDelay = baseDelay
 	}
 }
 
 // New creates a new Bedrock backend using AWS credentials from environment/config.
 func New(opts ...Option) (*Backend, error) {
 	b := &Backend{
-		region:      "us-east-1",
-		rateLimiter: newRateLimiter(60, time.Minute),
+		region: "us-east-1",
+		retry:  backend.DefaultRetryPolicy,
 	}
 
 	for _, opt := range opts {
 		opt(b)
 	}
+	if _, err := regionGeography(b.region); err != nil {
+		return nil, err
+	}
+	b.limiter = backend.NewLimiter(b.limits)
 
 	// Load AWS config using default credential chain (env vars, profile, etc.)
 	cfg, err := config.LoadDefaultConfig(context.Background(),
@@ -96,7 +152,7 @@ func (b *Backend) Name() string {
 
 // Capabilities returns feature flags.
 func (b *Backend) Capabilities() backend.Capability {
-	return backend.CapStreaming | backend.CapTools | backend.CapVision | backend.CapLongContext
+	return backend.CapStreaming | backend.CapTools | backend.CapVision | backend.CapLongContext | backend.CapStructuredOutput
 }
 
 // AvailableModels returns supported model IDs.
@@ -121,11 +177,27 @@ func (b *Backend) MaxContextTokens(model string) int {
 
 // bedrockRequest is the request body for Bedrock Claude models.
 type bedrockRequest struct {
-	AnthropicVersion string           `json:"anthropic_version"`
-	MaxTokens        int              `json:"max_tokens"`
-	Messages         []bedrockMessage `json:"messages"`
-	System           string           `json:"system,omitempty"`
-	Temperature      float64          `json:"temperature,omitempty"`
+	AnthropicVersion string             `json:"anthropic_version"`
+	MaxTokens        int                `json:"max_tokens"`
+	Messages         []bedrockMessage   `json:"messages"`
+	System           string             `json:"system,omitempty"`
+	Temperature      float64            `json:"temperature,omitempty"`
+	Tools            []bedrockTool      `json:"tools,omitempty"`
+	ToolChoice       *bedrockToolChoice `json:"tool_choice,omitempty"`
+}
+
+// bedrockTool defines a tool the model may call. Structured output is a
+// single forced tool call whose input schema is the requested schema.
+type bedrockTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// bedrockToolChoice forces the model to call a specific tool.
+type bedrockToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
 }
 
 type bedrockMessage struct {
@@ -139,8 +211,10 @@ type bedrockResponse struct {
 	Type         string `json:"type"`
 	Role         string `json:"role"`
 	Content      []struct {
-		Type string `json:"type"`
-		Text string `json:"text,omitempty"`
+		Type  string          `json:"type"`
+		Text  string          `json:"text,omitempty"`
+		Name  string          `json:"name,omitempty"`
+		Input json.RawMessage `json:"input,omitempty"`
 	} `json:"content"`
 	Model        string `json:"model"`
 	StopReason   string `json:"stop_reason"`
@@ -152,20 +226,21 @@ type bedrockResponse struct {
 
 // Invoke sends a prompt and returns the response.
 func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts backend.InvokeOptions) (*backend.InvokeResult, error) {
-	// Wait for rate limiter
-	if err := b.rateLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit: %w", err)
-	}
-
-	// Resolve model
-	model := opts.Model
+	// Resolve model (configured aliases first, then tier names)
+	model := backend.ResolveModel("bedrock", opts.Model, nil)
 	if model == "" {
 		model = defaultModel
 	}
-	modelID, ok := BedrockModels[model]
-	if !ok {
-		// Try using the model string directly as a Bedrock model ID
-		modelID = model
+
+	// Wait for the model's rate and concurrency limits
+	release, err := b.limiter.Acquire(ctx, model)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit: %w", err)
+	}
+	defer release()
+	modelID, err := b.modelID(model)
+	if err != nil {
+		return nil, err
 	}
 
 	maxTokens := opts.MaxTokens
@@ -205,6 +280,16 @@ func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts b
 		Temperature:      temp,
 	}
 
+	// Structured output: force a tool call whose input is the response
+	if f := opts.ResponseFormat; f != nil {
+		reqBody.Tools = []bedrockTool{{
+			Name:        f.SchemaName(),
+			Description: "Respond with structured output matching this schema.",
+			InputSchema: f.ObjectSchema(),
+		}}
+		reqBody.ToolChoice = &bedrockToolChoice{Type: "tool", Name: f.SchemaName()}
+	}
+
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("marshaling request: %w", err)
@@ -218,21 +303,19 @@ func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts b
 		Accept:      aws.String("application/json"),
 	}
 
+	// Retry throttling and transient unavailability; anything else, such
+	// as a validation or access error, fails at once
 	var output *bedrockruntime.InvokeModelOutput
-	var lastErr error
-	for attempt := 0; attempt < 3; attempt++ {
-		output, err = b.client.InvokeModel(ctx, input)
-		if err != nil {
-			lastErr = err
-			// Check for throttling
-			time.Sleep(time.Duration(attempt+1) * time.Second)
-			continue
+	err = backend.Retry(ctx, b.retry, isRetryable, func() error {
+		var invokeErr error
+		output, invokeErr = b.client.InvokeModel(ctx, input)
+		return invokeErr
+	})
+	if err != nil {
+		if isModelNotFound(err) {
+			return nil, fmt.Errorf("%w: %s: %v", backend.ErrModelUnavailable, modelID, err)
 		}
-		break
-	}
-
-	if output == nil {
-		return nil, fmt.Errorf("request failed after retries: %w", lastErr)
+		return nil, b.invokeError(err)
 	}
 
 	// Parse response
@@ -241,14 +324,24 @@ func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts b
 		return nil, fmt.Errorf("parsing response: %w", err)
 	}
 
-	// Extract text content
+	// Extract text content (or the forced tool input for structured output)
 	var content string
 	for _, block := range resp.Content {
+		if opts.ResponseFormat != nil {
+			if block.Type == "tool_use" && block.Name == opts.ResponseFormat.SchemaName() {
+				content = string(block.Input)
+			}
+			continue
+		}
 		if block.Type == "text" {
 			content += block.Text
 		}
 	}
 
+	if err := backend.ValidateStructuredOutput(content, opts.ResponseFormat); err != nil {
+		return nil, err
+	}
+
 	return &backend.InvokeResult{
 		Content:      content,
 		Model:        modelID,
@@ -258,24 +351,17 @@ func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts b
 	}, nil
 }
 
+// Limits returns the request limits in effect.
+func (b *Backend) Limits() backend.Limits {
+	return b.limiter.Limits()
+}
+
 // InvokeStream returns a streaming response channel.
 func (b *Backend) InvokeStream(ctx context.Context, messages []backend.Message, opts backend.InvokeOptions) (<-chan backend.StreamChunk, error) {
-	// Implement as non-streaming with single chunk for now
-	ch := make(chan backend.StreamChunk, 1)
-
-	go func() {
-		defer close(ch)
-
-		result, err := b.Invoke(ctx, messages, opts)
-		if err != nil {
-			ch <- backend.StreamChunk{Error: err, Done: true}
-			return
-		}
-
-		ch <- backend.StreamChunk{Content: result.Content, Done: true}
-	}()
-
-	return ch, nil
+	// For now, implement as non-streaming with single chunk
+	return backend.StreamInvoke(ctx, func(ctx context.Context) (*backend.InvokeResult, error) {
+		return b.Invoke(ctx, messages, opts)
+	}), nil
 }
 
 // EstimateCost estimates the cost for given token counts.
@@ -303,13 +389,13 @@ func (b *Backend) EstimateCost(inputTokens, outputTokens int, model string) back
 }
 
 // CountTokens estimates token count for messages.
+// Delegates to the shared token counter for the model's family.
 func (b *Backend) CountTokens(messages []backend.Message, model string) (int, error) {
-	var totalChars int
-	for _, msg := range messages {
-		totalChars += len(msg.Content)
-		totalChars += len(msg.Role) + 10
+	model = normalizeTier(model)
+	if model == "" {
+		model = defaultModel
 	}
-	return totalChars / 4, nil
+	return backend.CountTokens(messages, model)
 }
 
 // Healthy checks if the backend is reachable.
@@ -321,74 +407,74 @@ func (b *Backend) Healthy(ctx context.Context) error {
 	return nil
 }
 
-// normalizeTier converts model IDs to tier names.
-func normalizeTier(model string) string {
-	switch model {
-	case "opus", "us.anthropic.claude-opus-4-5-20251101-v1:0":
-		return "opus"
-	case "sonnet", "us.anthropic.claude-sonnet-4-5-20250929-v1:0":
-		return "sonnet"
-	case "haiku", "us.anthropic.claude-3-5-haiku-20241022-v1:0":
-		return "haiku"
-	default:
-		return model
+// modelID returns the Bedrock model ID to invoke for a resolved model: a
+// configured inference profile, the region's profile for a tier, or the
+// model string itself.
+func (b *Backend) modelID(model string) (string, error) {
+	if profile, ok := b.profiles[model]; ok {
+		return profile, nil
 	}
+	return inferenceProfileID(model, b.region)
 }
 
-// rateLimiter implements a simple token bucket rate limiter.
-type rateLimiter struct {
-	mu             sync.Mutex
-	tokens         int
-	maxTokens      int
-	refillInterval time.Duration
-	lastRefill     time.Time
+// normalizeTier converts model IDs (or configured aliases) to tier names.
+// Inference profile IDs of any geography map to their tier.
+func normalizeTier(model string) string {
+	model = backend.ResolveModel("bedrock", model, nil)
+	base := stripGeography(model)
+	for tier, id := range FoundationModels {
+		if model == tier || base == id {
+			return tier
+		}
+	}
+	return model
 }
 
-func newRateLimiter(maxTokens int, interval time.Duration) *rateLimiter {
-	return &rateLimiter{
-		tokens:         maxTokens,
-		maxTokens:      maxTokens,
-		refillInterval: interval,
-		lastRefill:     time.Now(),
-	}
+// isRetryable reports whether a Bedrock error is worth retrying: the
+// request was throttled, the service was unavailable, or the model timed
+// out.
+func isRetryable(err error) bool {
+	var throttled *types.ThrottlingException
+	var unavailable *types.ServiceUnavailableException
+	var timeout *types.ModelTimeoutException
+	return errors.As(err, &throttled) || errors.As(err, &unavailable) || errors.As(err, &timeout)
 }
 
-func (r *rateLimiter) Wait(ctx context.Context) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	now := time.Now()
-	elapsed := now.Sub(r.lastRefill)
-	if elapsed >= r.refillInterval {
-		r.tokens = r.maxTokens
-		r.lastRefill = now
-	} else {
-		refillAmount := int(float64(r.maxTokens) * (float64(elapsed) / float64(r.refillInterval)))
-		r.tokens = min(r.maxTokens, r.tokens+refillAmount)
-		if refillAmount > 0 {
-			r.lastRefill = now
-		}
+// invokeError names the AWS error type (e.g. ThrottlingException or
+// AccessDeniedException) so a throttled request reads differently from a
+// missing model grant.
+func (b *Backend) invokeError(err error) error {
+	var coded interface{ ErrorCode() string }
+	if !errors.As(err, &coded) {
+		return fmt.Errorf("invoking model: %w", err)
 	}
-
-	if r.tokens > 0 {
-		r.tokens--
-		return nil
+	if isRetryable(err) {
+		return fmt.Errorf("invoking model: %s after %d attempts: %w", coded.ErrorCode(), max(b.retry.MaxAttempts, 1), err)
 	}
+	return fmt.Errorf("invoking model: %s: %w", coded.ErrorCode(), err)
+}
 
-	waitTime := r.refillInterval - elapsed
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-time.After(waitTime):
-		r.tokens = r.maxTokens - 1
-		r.lastRefill = time.Now()
-		return nil
-	}
+// isModelNotFound reports whether a Bedrock error means the model ID is
+// unknown or has reached end of life. Bedrock signals this with
+// ResourceNotFoundException or a ValidationException about the model.
+func isModelNotFound(err error) bool {
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return true
+	}
+	var validation *types.ValidationException
+	if errors.As(err, &validation) {
+		msg := strings.ToLower(validation.ErrorMessage())
+		return strings.Contains(msg, "model identifier is invalid") ||
+			strings.Contains(msg, "end of its life") ||
+			strings.Contains(msg, "model is not supported")
+	}
+	return false
 }
 
 // Register registers the Bedrock backend with the global registry.
-func Register() error {
-	b, err := New()
+func Register(opts ...Option) error {
+	b, err := New(opts...)
 	if err != nil {
 		return err
 	}
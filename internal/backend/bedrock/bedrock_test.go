@@ -0,0 +1,185 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/steveyegge/gastown/internal/backend"
+)
+
+func TestIsModelNotFound(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "resource not found",
+			err:  &types.ResourceNotFoundException{Message: aws.String("Model not found")},
+			want: true,
+		},
+		{
+			name: "invalid model identifier",
+			err:  fmt.Errorf("operation error: %w", &types.ValidationException{Message: aws.String("The provided model identifier is invalid.")}),
+			want: true,
+		},
+		{
+			name: "end of life",
+			err:  &types.ValidationException{Message: aws.String("This model version has reached the end of its life.")},
+			want: true,
+		},
+		{
+			name: "other validation error",
+			err:  &types.ValidationException{Message: aws.String("max_tokens: must be positive")},
+			want: false,
+		},
+		{
+			name: "throttling",
+			err:  &types.ThrottlingException{Message: aws.String("Too many requests")},
+			want: false,
+		},
+		{
+			name: "plain error",
+			err:  errors.New("connection reset"),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isModelNotFound(tt.err); got != tt.want {
+				t.Errorf("isModelNotFound(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInferenceProfileID(t *testing.T) {
+	tests := []struct {
+		model   string
+		region  string
+		want    string
+		wantErr bool
+	}{
+		{"opus", "us-east-1", "us.anthropic.claude-opus-4-5-20251101-v1:0", false},
+		{"sonnet", "us-west-2", "us.anthropic.claude-sonnet-4-5-20250929-v1:0", false},
+		{"sonnet", "eu-central-1", "eu.anthropic.claude-sonnet-4-5-20250929-v1:0", false},
+		{"opus", "eu-west-1", "eu.anthropic.claude-opus-4-5-20251101-v1:0", false},
+		{"sonnet", "ap-northeast-1", "apac.anthropic.claude-sonnet-4-5-20250929-v1:0", false},
+		{"haiku", "eu-west-1", "", true},
+		{"opus", "ap-southeast-2", "", true},
+		{"sonnet", "mars-north-1", "", true},
+		// Full IDs and ARNs pass through untouched
+		{"eu.anthropic.claude-3-7-sonnet-20250219-v1:0", "eu-west-1", "eu.anthropic.claude-3-7-sonnet-20250219-v1:0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model+"/"+tt.region, func(t *testing.T) {
+			got, err := inferenceProfileID(tt.model, tt.region)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("inferenceProfileID(%q, %q) error = %v, wantErr %v", tt.model, tt.region, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("inferenceProfileID(%q, %q) = %q, want %q", tt.model, tt.region, got, tt.want)
+			}
+		})
+	}
+
+	_, err := inferenceProfileID("haiku", "eu-west-1")
+	if !errors.Is(err, backend.ErrModelUnavailable) || !strings.Contains(err.Error(), "available in us") {
+		t.Errorf("unavailable model error = %v, want ErrModelUnavailable naming where it is available", err)
+	}
+}
+
+func TestModelIDUsesConfiguredProfiles(t *testing.T) {
+	const arn = "arn:aws:bedrock:eu-west-1:123456789012:application-inference-profile/abc123"
+	b := &Backend{region: "eu-west-1"}
+	WithInferenceProfiles(map[string]string{"haiku": arn})(b)
+
+	if got, err := b.modelID("haiku"); err != nil || got != arn {
+		t.Errorf("modelID(haiku) = %q, %v; want the configured profile", got, err)
+	}
+	if got, err := b.modelID("sonnet"); err != nil || got != "eu.anthropic.claude-sonnet-4-5-20250929-v1:0" {
+		t.Errorf("modelID(sonnet) = %q, %v; want the eu profile", got, err)
+	}
+}
+
+func TestNormalizeTierAcrossGeographies(t *testing.T) {
+	for _, id := range []string{
+		"us.anthropic.claude-sonnet-4-5-20250929-v1:0",
+		"eu.anthropic.claude-sonnet-4-5-20250929-v1:0",
+		"apac.anthropic.claude-sonnet-4-5-20250929-v1:0",
+		"anthropic.claude-sonnet-4-5-20250929-v1:0",
+		"sonnet",
+	} {
+		if got := normalizeTier(id); got != "sonnet" {
+			t.Errorf("normalizeTier(%q) = %q, want sonnet", id, got)
+		}
+	}
+}
+
+// fakeInvoker returns errs in order, then a successful response.
+type fakeInvoker struct {
+	errs  []error
+	calls int
+}
+
+func (f *fakeInvoker) InvokeModel(_ context.Context, _ *bedrockruntime.InvokeModelInput, _ ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return nil, f.errs[f.calls-1]
+	}
+	return &bedrockruntime.InvokeModelOutput{
+		Body: []byte(`{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":1}}`),
+	}, nil
+}
+
+func TestInvokeRetriesOnlyTransientErrors(t *testing.T) {
+	throttled := fmt.Errorf("operation error Bedrock Runtime: InvokeModel: %w", &types.ThrottlingException{Message: aws.String("Too many requests")})
+	unavailable := &types.ServiceUnavailableException{Message: aws.String("Service unavailable")}
+	timeout := &types.ModelTimeoutException{Message: aws.String("Model timed out")}
+	denied := &types.AccessDeniedException{Message: aws.String("You don't have access to the model")}
+	invalid := &types.ValidationException{Message: aws.String("max_tokens: must be positive")}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   string // substring; empty means success
+	}{
+		{"throttled then ok", []error{throttled}, 2, ""},
+		{"unavailable then ok", []error{unavailable}, 2, ""},
+		{"model timeout then ok", []error{timeout}, 2, ""},
+		{"throttled every attempt", []error{throttled, throttled, throttled}, 3, "ThrottlingException after 3 attempts"},
+		{"access denied", []error{denied}, 1, "AccessDeniedException"},
+		{"validation error", []error{invalid}, 1, "ValidationException"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeInvoker{errs: tt.errs}
+			b := &Backend{client: fake, region: "us-east-1", limiter: backend.NewLimiter(backend.Limits{})}
+			WithRetry(3, 0)(b)
+
+			result, err := b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}}, backend.InvokeOptions{Model: "haiku"})
+			if fake.calls != tt.wantCalls {
+				t.Errorf("InvokeModel called %d times, want %d", fake.calls, tt.wantCalls)
+			}
+			if tt.wantErr == "" {
+				if err != nil || result.Content != "ok" {
+					t.Errorf("Invoke = %+v, %v; want ok", result, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Invoke error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
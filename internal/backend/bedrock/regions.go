@@ -0,0 +1,97 @@
+package bedrock
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/backend"
+)
+
+// Cross-region inference profiles route a request to any region in a
+// geography. Their IDs are the foundation model ID with the geography as a
+// prefix, e.g. "eu.anthropic.claude-sonnet-4-5-20250929-v1:0".
+var (
+	// RegionGeographies maps the AWS regions gt knows to the geography
+	// prefix of their inference profiles.
+	RegionGeographies = map[string]string{
+		"us-east-1": "us",
+		"us-east-2": "us",
+		"us-west-1": "us",
+		"us-west-2": "us",
+
+		"eu-central-1": "eu",
+		"eu-central-2": "eu",
+		"eu-north-1":   "eu",
+		"eu-south-1":   "eu",
+		"eu-south-2":   "eu",
+		"eu-west-1":    "eu",
+		"eu-west-2":    "eu",
+		"eu-west-3":    "eu",
+
+		"ap-northeast-1": "apac",
+		"ap-northeast-2": "apac",
+		"ap-northeast-3": "apac",
+		"ap-south-1":     "apac",
+		"ap-south-2":     "apac",
+		"ap-southeast-1": "apac",
+		"ap-southeast-2": "apac",
+		"ap-southeast-3": "apac",
+		"ap-southeast-4": "apac",
+	}
+
+	// ModelGeographies lists the geographies with an inference profile for
+	// each model tier.
+	ModelGeographies = map[string][]string{
+		"opus":   {"us", "eu"},
+		"sonnet": {"us", "eu", "apac"},
+		"haiku":  {"us"},
+	}
+)
+
+// regionGeography returns the inference profile geography for region, or
+// an error naming the known regions.
+func regionGeography(region string) (string, error) {
+	if geo, ok := RegionGeographies[region]; ok {
+		return geo, nil
+	}
+	regions := make([]string, 0, len(RegionGeographies))
+	for r := range RegionGeographies {
+		regions = append(regions, r)
+	}
+	sort.Strings(regions)
+	return "", fmt.Errorf("unsupported Bedrock region %q (known regions: %s)", region, strings.Join(regions, ", "))
+}
+
+// inferenceProfileID returns the inference profile for a model tier in
+// region's geography. Model strings that aren't tiers are returned as-is,
+// so full model IDs, profile IDs, and ARNs pass through.
+func inferenceProfileID(model, region string) (string, error) {
+	base, ok := FoundationModels[model]
+	if !ok {
+		return model, nil
+	}
+
+	geo, err := regionGeography(region)
+	if err != nil {
+		return "", err
+	}
+	for _, available := range ModelGeographies[model] {
+		if available == geo {
+			return geo + "." + base, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s has no inference profile in %s (%s); it is available in %s, or map it to a profile with inference_profiles",
+		backend.ErrModelUnavailable, model, region, geo, strings.Join(ModelGeographies[model], ", "))
+}
+
+// stripGeography removes an inference profile's geography prefix from a
+// model ID.
+func stripGeography(modelID string) string {
+	for _, geo := range []string{"us.", "eu.", "apac."} {
+		if strings.HasPrefix(modelID, geo+"anthropic.") {
+			return strings.TrimPrefix(modelID, geo)
+		}
+	}
+	return modelID
+}
@@ -2,6 +2,7 @@
 package claude
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -9,7 +10,7 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/steveyegge/gastown/internal/backend"
@@ -38,8 +39,25 @@ var (
 		"claude-3-sonnet-20240229": {3.00, 15.00},
 		"claude-3-haiku-20240307":  {0.25, 1.25},
 	}
+
+	// Aliases maps friendly names to model IDs.
+	Aliases = map[string]string{
+		"opus":   "claude-opus-4-5-20251101",
+		"sonnet": "claude-sonnet-4-20250514",
+		"haiku":  "claude-haiku-3-5-20241022",
+	}
+
+	// LongContextModels maps model IDs to their context windows with the
+	// BetaContext1M beta enabled.
+	LongContextModels = map[string]int{
+		"claude-sonnet-4-20250514": 1000000,
+	}
 )
 
+// BetaContext1M is the anthropic-beta feature for the 1M-token context
+// window (see LongContextModels).
+const BetaContext1M = "context-1m-2025-08-07"
+
 const (
 	defaultBaseURL     = "https://api.anthropic.com"
 	defaultAPIVersion  = "2023-06-01"
@@ -54,10 +72,13 @@ type Backend struct {
 	apiKey     string
 	baseURL    string
 	apiVersion string
+	betas      []string
 	client     *http.Client
 
 	// Rate limiting
-	rateLimiter *rateLimiter
+	limits  backend.Limits
+	limiter *backend.Limiter
+	retry   backend.RetryPolicy
 }
 
 // Option configures the Claude backend.
@@ -77,10 +98,49 @@ func WithHTTPClient(client *http.Client) Option {
 	}
 }
 
+// WithAPIVersion sets the anthropic-version header. An empty version keeps
+// the default.
+func WithAPIVersion(version string) Option {
+	return func(b *Backend) {
+		if version != "" {
+			b.apiVersion = version
+		}
+	}
+}
+
+// WithBetaFeatures enables beta features by sending them in the
+// anthropic-beta header, e.g. BetaContext1M or
+// "prompt-caching-2024-07-31". New rejects two versions of one feature.
+func WithBetaFeatures(features ...string) Option {
+	return func(b *Backend) {
+		b.betas = append(b.betas, features...)
+	}
+}
+
 // WithRateLimit sets the rate limit (requests per minute).
 func WithRateLimit(rpm int) Option {
 	return func(b *Backend) {
-		b.rateLimiter = newRateLimiter(rpm, time.Minute)
+		b.limits.RequestsPerMinute = rpm
+	}
+}
+
+// WithModelLimits sets per-model request rates and concurrency caps. Keys
+// may be aliases; they're resolved to model IDs.
+func WithModelLimits(limits map[string]backend.ModelLimits) Option {
+	return func(b *Backend) {
+		b.limits.Models = make(map[string]backend.ModelLimits, len(limits))
+		for model, ml := range limits {
+			b.limits.Models[resolveModel(model)] = ml
+		}
+	}
+}
+
+// WithRetry sets how many attempts a request gets and the base delay for
+// exponential backoff between them (see backend.DoWithRetry).
+func WithRetry(attempts int, baseDelay time.Duration) Option {
+	return func(b *Backend) {
+		b.retry.MaxAttempts = attempts
+		b.retry.BaseDelay = baseDelay
 	}
 }
 
@@ -93,20 +153,84 @@ func New(opts ...Option) (*Backend, error) {
 	}
 
 	b := &Backend{
-		apiKey:      apiKey,
-		baseURL:     defaultBaseURL,
-		apiVersion:  defaultAPIVersion,
-		client:      &http.Client{Timeout: defaultTimeout},
-		rateLimiter: newRateLimiter(60, time.Minute), // Default 60 RPM
+		apiKey:     apiKey,
+		baseURL:    defaultBaseURL,
+		apiVersion: defaultAPIVersion,
+		client:     &http.Client{Timeout: defaultTimeout},
+		retry:      backend.DefaultRetryPolicy,
 	}
 
 	for _, opt := range opts {
 		opt(b)
 	}
+	if err := validateBetas(b.betas); err != nil {
+		return nil, err
+	}
+	b.limiter = backend.NewLimiter(b.limits)
 
 	return b, nil
 }
 
+// validateBetas rejects malformed beta names and two versions of the same
+// feature (names differing only in their date suffix), which the API
+// can't honor together.
+func validateBetas(betas []string) error {
+	seen := make(map[string]string, len(betas))
+	for _, beta := range betas {
+		if beta == "" || strings.ContainsAny(beta, ", ") {
+			return fmt.Errorf("invalid anthropic-beta feature %q", beta)
+		}
+		feature := betaFeature(beta)
+		if other, ok := seen[feature]; ok && other != beta {
+			return fmt.Errorf("anthropic-beta features %s and %s are versions of the same feature; enable one", other, beta)
+		}
+		seen[feature] = beta
+	}
+	return nil
+}
+
+// betaFeature strips a beta name's date suffix ("-2025-08-07"), leaving
+// the feature it versions.
+func betaFeature(beta string) string {
+	parts := strings.Split(beta, "-")
+	if len(parts) > 3 && isDigits(parts[len(parts)-3], 4) && isDigits(parts[len(parts)-2], 2) && isDigits(parts[len(parts)-1], 2) {
+		return strings.Join(parts[:len(parts)-3], "-")
+	}
+	return beta
+}
+
+// isDigits reports whether s is n ASCII digits.
+func isDigits(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// hasBeta reports whether the beta feature is enabled.
+func (b *Backend) hasBeta(beta string) bool {
+	for _, enabled := range b.betas {
+		if enabled == beta {
+			return true
+		}
+	}
+	return false
+}
+
+// setHeaders sets the authentication, version, and beta headers.
+func (b *Backend) setHeaders(req *http.Request) {
+	req.Header.Set("x-api-key", b.apiKey)
+	req.Header.Set("anthropic-version", b.apiVersion)
+	if len(b.betas) > 0 {
+		req.Header.Set("anthropic-beta", strings.Join(b.betas, ","))
+	}
+}
+
 // Name returns the backend identifier.
 func (b *Backend) Name() string {
 	return "claude"
@@ -114,7 +238,7 @@ func (b *Backend) Name() string {
 
 // Capabilities returns feature flags.
 func (b *Backend) Capabilities() backend.Capability {
-	return backend.CapStreaming | backend.CapTools | backend.CapVision | backend.CapLongContext
+	return backend.CapStreaming | backend.CapTools | backend.CapVision | backend.CapLongContext | backend.CapStructuredOutput
 }
 
 // AvailableModels returns supported model IDs.
@@ -133,6 +257,10 @@ func (b *Backend) DefaultModel() string {
 
 // MaxContextTokens returns the context window for a model.
 func (b *Backend) MaxContextTokens(model string) int {
+	model = resolveModel(model)
+	if ctx, ok := LongContextModels[model]; ok && b.hasBeta(BetaContext1M) {
+		return ctx
+	}
 	if ctx, ok := Models[model]; ok {
 		return ctx
 	}
@@ -141,18 +269,35 @@ func (b *Backend) MaxContextTokens(model string) int {
 
 // apiRequest is the request body for the messages API.
 type apiRequest struct {
-	Model       string       `json:"model"`
-	MaxTokens   int          `json:"max_tokens"`
-	Messages    []apiMessage `json:"messages"`
-	System      string       `json:"system,omitempty"`
-	Temperature float64      `json:"temperature,omitempty"`
-	Stream      bool         `json:"stream,omitempty"`
+	Model       string         `json:"model"`
+	MaxTokens   int            `json:"max_tokens"`
+	Messages    []apiMessage   `json:"messages"`
+	System      string         `json:"system,omitempty"`
+	Temperature float64        `json:"temperature,omitempty"`
+	Stream      bool           `json:"stream,omitempty"`
+	Tools       []apiTool      `json:"tools,omitempty"`
+	ToolChoice  *apiToolChoice `json:"tool_choice,omitempty"`
+}
+
+// apiTool defines a tool the model may call. Structured output is a
+// single forced tool call whose input schema is the requested schema.
+type apiTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// apiToolChoice forces the model to call a specific tool.
+type apiToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
 }
 
-// apiMessage is a message in the API request.
+// apiMessage is a message in the API request. Content is a string, or
+// []apiContentBlock for tool calls and tool results.
 type apiMessage struct {
 	Role    string `json:"role"`
-	Content string `json:"content"`
+	Content any    `json:"content"`
 }
 
 // apiResponse is the response from the messages API.
@@ -170,10 +315,15 @@ type apiResponse struct {
 	} `json:"usage"`
 }
 
-// apiContentBlock is a content block in the response.
+// apiContentBlock is a content block in a request or response.
 type apiContentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
 }
 
 // apiError is an error response from the API.
@@ -187,13 +337,72 @@ type apiError struct {
 
 // Invoke sends a prompt and returns the response.
 func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts backend.InvokeOptions) (*backend.InvokeResult, error) {
-	// Wait for rate limiter
-	if err := b.rateLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit: %w", err)
+	// Wait for the model's rate and concurrency limits
+	release, err := b.acquire(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	reqBody := buildRequest(messages, opts)
+	resp, err := b.post(ctx, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Read response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
 	}
 
-	// Prepare request
-	model := opts.Model
+	// Parse response
+	var apiResp apiResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	// Extract text content (or the forced tool input for structured output)
+	// and the tool calls
+	var content string
+	var toolCalls []backend.ToolCall
+	for _, block := range apiResp.Content {
+		if opts.ResponseFormat != nil {
+			if block.Type == "tool_use" && block.Name == opts.ResponseFormat.SchemaName() {
+				content = string(block.Input)
+			}
+			continue
+		}
+		switch block.Type {
+		case "text":
+			content += block.Text
+		case "tool_use":
+			toolCalls = append(toolCalls, backend.ToolCall{
+				ID:        block.ID,
+				Name:      block.Name,
+				Arguments: block.Input,
+			})
+		}
+	}
+
+	if err := backend.ValidateStructuredOutput(content, opts.ResponseFormat); err != nil {
+		return nil, err
+	}
+
+	return &backend.InvokeResult{
+		Content:      content,
+		Model:        apiResp.Model,
+		InputTokens:  apiResp.Usage.InputTokens,
+		OutputTokens: apiResp.Usage.OutputTokens,
+		FinishReason: apiResp.StopReason,
+		ToolCalls:    toolCalls,
+	}, nil
+}
+
+// buildRequest converts messages and options into a messages API request.
+func buildRequest(messages []backend.Message, opts backend.InvokeOptions) apiRequest {
+	model := resolveModel(opts.Model)
 	if model == "" {
 		model = defaultModel
 	}
@@ -209,18 +418,7 @@ func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts b
 	}
 
 	// Convert messages, extracting system message
-	var systemMsg string
-	var apiMessages []apiMessage
-	for _, msg := range messages {
-		if msg.Role == "system" {
-			systemMsg = msg.Content
-			continue
-		}
-		apiMessages = append(apiMessages, apiMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
-	}
+	systemMsg, apiMessages := toAPIMessages(messages)
 
 	// Override system if provided in options
 	if opts.SystemMsg != "" {
@@ -236,118 +434,255 @@ func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts b
 		Stream:      false,
 	}
 
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("marshaling request: %w", err)
+	for _, tool := range opts.Tools {
+		reqBody.Tools = append(reqBody.Tools, apiTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.Schema(),
+		})
 	}
 
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", b.baseURL+"/v1/messages", bytes.NewReader(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+	// Structured output: force a tool call whose input is the response
+	if f := opts.ResponseFormat; f != nil {
+		reqBody.Tools = append(reqBody.Tools, apiTool{
+			Name:        f.SchemaName(),
+			Description: "Respond with structured output matching this schema.",
+			InputSchema: f.ObjectSchema(),
+		})
+		reqBody.ToolChoice = &apiToolChoice{Type: "tool", Name: f.SchemaName()}
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", b.apiKey)
-	req.Header.Set("anthropic-version", b.apiVersion)
-
-	// Send request with retry
-	var resp *http.Response
-	var lastErr error
-	for attempt := 0; attempt < 3; attempt++ {
-		resp, err = b.client.Do(req)
-		if err != nil {
-			lastErr = err
-			time.Sleep(time.Duration(attempt+1) * time.Second)
-			continue
-		}
+	return reqBody
+}
 
-		// Check for rate limiting
-		if resp.StatusCode == http.StatusTooManyRequests {
-			resp.Body.Close()
-			retryAfter := time.Duration(attempt+1) * 10 * time.Second
-			if ra := resp.Header.Get("Retry-After"); ra != "" {
-				if d, err := time.ParseDuration(ra + "s"); err == nil {
-					retryAfter = d
+// toAPIMessages converts messages for the messages API, returning the
+// system prompt separately. Assistant tool calls become tool_use blocks, and
+// tool results become tool_result blocks in a user turn (consecutive results
+// share one turn, as the API expects).
+func toAPIMessages(messages []backend.Message) (string, []apiMessage) {
+	var systemMsg string
+	var apiMessages []apiMessage
+	for _, msg := range messages {
+		switch {
+		case msg.Role == "system":
+			systemMsg = msg.Content
+		case msg.Role == backend.RoleTool:
+			result := apiContentBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content}
+			if n := len(apiMessages); n > 0 {
+				if blocks, ok := apiMessages[n-1].Content.([]apiContentBlock); ok && apiMessages[n-1].Role == "user" && isToolResults(blocks) {
+					apiMessages[n-1].Content = append(blocks, result)
+					continue
 				}
 			}
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(retryAfter):
-				continue
+			apiMessages = append(apiMessages, apiMessage{Role: "user", Content: []apiContentBlock{result}})
+		case len(msg.ToolCalls) > 0:
+			var blocks []apiContentBlock
+			if msg.Content != "" {
+				blocks = append(blocks, apiContentBlock{Type: "text", Text: msg.Content})
 			}
+			for _, call := range msg.ToolCalls {
+				input := call.Arguments
+				if len(input) == 0 {
+					input = json.RawMessage(`{}`)
+				}
+				blocks = append(blocks, apiContentBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input})
+			}
+			apiMessages = append(apiMessages, apiMessage{Role: msg.Role, Content: blocks})
+		default:
+			apiMessages = append(apiMessages, apiMessage{Role: msg.Role, Content: msg.Content})
 		}
+	}
+	return systemMsg, apiMessages
+}
 
-		break
+// isToolResults reports whether every block is a tool_result.
+func isToolResults(blocks []apiContentBlock) bool {
+	for _, b := range blocks {
+		if b.Type != "tool_result" {
+			return false
+		}
 	}
+	return true
+}
 
-	if resp == nil {
-		return nil, fmt.Errorf("request failed after retries: %w", lastErr)
+// post sends a request to the messages API, retrying transport errors and
+// rate limits. It returns the response only for a 200; the caller must
+// close its body. Error responses are read and converted to errors.
+func (b *Backend) post(ctx context.Context, reqBody apiRequest) (*http.Response, error) {
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
 	}
-	defer resp.Body.Close()
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	// Send request, retrying transient failures
+	resp, err := backend.DoWithRetry(ctx, b.client, b.retry, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", b.baseURL+"/v1/messages", bytes.NewReader(jsonBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		b.setHeaders(req)
+		if reqBody.Stream {
+			req.Header.Set("Accept", "text/event-stream")
+		}
+		return req, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return nil, err
 	}
 
 	// Check for error response
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading response: %w", err)
+		}
 		var apiErr apiError
 		if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error.Message != "" {
+			if isModelNotFound(resp.StatusCode, apiErr) {
+				return nil, fmt.Errorf("%w: %s: %s", backend.ErrModelUnavailable, reqBody.Model, apiErr.Error.Message)
+			}
 			return nil, fmt.Errorf("API error (%s): %s", apiErr.Error.Type, apiErr.Error.Message)
 		}
 		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
-	// Parse response
-	var apiResp apiResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
-	}
+	return resp, nil
+}
 
-	// Extract text content
-	var content string
-	for _, block := range apiResp.Content {
-		if block.Type == "text" {
-			content += block.Text
-		}
+// InvokeStream streams the response using server-sent events, sending each
+// text delta as it arrives. The final chunk has Done set and carries the
+// token usage. Structured output is validated as a whole, so requests with
+// a ResponseFormat are delivered as a single chunk.
+func (b *Backend) InvokeStream(ctx context.Context, messages []backend.Message, opts backend.InvokeOptions) (<-chan backend.StreamChunk, error) {
+	if opts.ResponseFormat != nil {
+		return backend.StreamInvoke(ctx, func(ctx context.Context) (*backend.InvokeResult, error) {
+			return b.Invoke(ctx, messages, opts)
+		}), nil
 	}
 
-	return &backend.InvokeResult{
-		Content:      content,
-		Model:        apiResp.Model,
-		InputTokens:  apiResp.Usage.InputTokens,
-		OutputTokens: apiResp.Usage.OutputTokens,
-		FinishReason: apiResp.StopReason,
-	}, nil
-}
+	// Wait for the model's rate and concurrency limits; the stream
+	// goroutine holds the slot until the response is read.
+	release, err := b.acquire(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
 
-// InvokeStream returns a streaming response channel.
-func (b *Backend) InvokeStream(ctx context.Context, messages []backend.Message, opts backend.InvokeOptions) (<-chan backend.StreamChunk, error) {
-	// For now, implement as non-streaming with single chunk
-	// Full SSE streaming can be added later
-	ch := make(chan backend.StreamChunk, 1)
+	reqBody := buildRequest(messages, opts)
+	reqBody.Stream = true
+	resp, err := b.post(ctx, reqBody)
+	if err != nil {
+		release()
+		return nil, err
+	}
 
+	ch := make(chan backend.StreamChunk, 16)
 	go func() {
+		defer release()
 		defer close(ch)
+		defer resp.Body.Close()
 
-		result, err := b.Invoke(ctx, messages, opts)
-		if err != nil {
-			ch <- backend.StreamChunk{Error: err, Done: true}
-			return
+		if err := readStream(ctx, resp.Body, ch); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				err = ctxErr
+			}
+			backend.SendChunk(ctx, ch, backend.StreamChunk{Error: err, Done: true})
 		}
-
-		ch <- backend.StreamChunk{Content: result.Content, Done: true}
 	}()
 
 	return ch, nil
 }
 
+// streamEvent is a server-sent event payload from the streaming messages
+// API. Only the fields gt uses are decoded.
+type streamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// readStream parses server-sent events from body, sending text deltas on ch
+// and a final Done chunk with usage at message_stop. It returns an error for
+// malformed events, API error events, or a stream that ends early; it
+// returns nil without sending if ctx is cancelled while sending.
+func readStream(ctx context.Context, body io.Reader, ch chan<- backend.StreamChunk) error {
+	var inputTokens, outputTokens int
+	var stopReason string
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Only data lines carry payloads; event names are repeated in "type"
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" {
+			continue
+		}
+
+		var event streamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("malformed stream event %q: %w", data, err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			inputTokens = event.Message.Usage.InputTokens
+			outputTokens = event.Message.Usage.OutputTokens
+		case "content_block_delta":
+			if event.Delta.Type != "text_delta" || event.Delta.Text == "" {
+				continue
+			}
+			if !backend.SendChunk(ctx, ch, backend.StreamChunk{Content: event.Delta.Text}) {
+				return nil
+			}
+		case "message_delta":
+			if event.Usage.OutputTokens > 0 {
+				outputTokens = event.Usage.OutputTokens
+			}
+			if event.Delta.StopReason != "" {
+				stopReason = event.Delta.StopReason
+			}
+		case "message_stop":
+			backend.SendChunk(ctx, ch, backend.StreamChunk{
+				Done:         true,
+				InputTokens:  inputTokens,
+				OutputTokens: outputTokens,
+				FinishReason: stopReason,
+			})
+			return nil
+		case "error":
+			return fmt.Errorf("API error (%s): %s", event.Error.Type, event.Error.Message)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading stream: %w", err)
+	}
+	return fmt.Errorf("stream ended before message_stop")
+}
+
 // EstimateCost estimates the cost for given token counts.
 func (b *Backend) EstimateCost(inputTokens, outputTokens int, model string) backend.CostEstimate {
+	model = resolveModel(model)
 	if model == "" {
 		model = defaultModel
 	}
@@ -371,85 +706,71 @@ func (b *Backend) EstimateCost(inputTokens, outputTokens int, model string) back
 }
 
 // CountTokens estimates token count for messages.
-// Uses a simple character-based heuristic (4 chars ≈ 1 token).
+// Delegates to the shared token counter for the model's family.
 func (b *Backend) CountTokens(messages []backend.Message, model string) (int, error) {
-	var totalChars int
-	for _, msg := range messages {
-		totalChars += len(msg.Content)
-		totalChars += len(msg.Role) + 10 // Role overhead
+	model = resolveModel(model)
+	if model == "" {
+		model = defaultModel
 	}
-	// Rough estimate: 4 characters per token
-	return totalChars / 4, nil
+	return backend.CountTokens(messages, model)
 }
 
-// Healthy checks if the backend is reachable.
+// Healthy checks that the API key is well formed and, when ctx has a
+// deadline, that the API accepts it by listing models.
 func (b *Backend) Healthy(ctx context.Context) error {
-	// Simple health check - verify API key format
+	// Cheap pre-flight: verify API key format
 	if len(b.apiKey) < 10 {
 		return fmt.Errorf("invalid API key format")
 	}
+	if !backend.LiveCheckAllowed(ctx) {
+		return nil
+	}
 
-	// Could add a lightweight API call here if needed
-	return nil
+	req, err := http.NewRequestWithContext(ctx, "GET", b.baseURL+"/v1/models", nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	b.setHeaders(req)
+	return backend.CheckEndpoint(b.client, req)
 }
 
-// rateLimiter implements a simple token bucket rate limiter.
-type rateLimiter struct {
-	mu       sync.Mutex
-	tokens   int
-	maxTokens int
-	refillInterval time.Duration
-	lastRefill     time.Time
-}
-
-func newRateLimiter(maxTokens int, interval time.Duration) *rateLimiter {
-	return &rateLimiter{
-		tokens:         maxTokens,
-		maxTokens:      maxTokens,
-		refillInterval: interval,
-		lastRefill:     time.Now(),
-	}
-}
-
-func (r *rateLimiter) Wait(ctx context.Context) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	// Refill tokens based on elapsed time
-	now := time.Now()
-	elapsed := now.Sub(r.lastRefill)
-	if elapsed >= r.refillInterval {
-		r.tokens = r.maxTokens
-		r.lastRefill = now
-	} else {
-		// Partial refill
-		refillAmount := int(float64(r.maxTokens) * (float64(elapsed) / float64(r.refillInterval)))
-		r.tokens = min(r.maxTokens, r.tokens+refillAmount)
-		if refillAmount > 0 {
-			r.lastRefill = now
-		}
+// acquire waits until the limiter admits a request to the model opts
+// names. The caller must call the returned release when done.
+func (b *Backend) acquire(ctx context.Context, opts backend.InvokeOptions) (func(), error) {
+	model := resolveModel(opts.Model)
+	if model == "" {
+		model = defaultModel
 	}
-
-	if r.tokens > 0 {
-		r.tokens--
-		return nil
+	release, err := b.limiter.Acquire(ctx, model)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit: %w", err)
 	}
+	return release, nil
+}
 
-	// Wait for next token
-	waitTime := r.refillInterval - elapsed
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-time.After(waitTime):
-		r.tokens = r.maxTokens - 1
-		r.lastRefill = time.Now()
-		return nil
+// Limits returns the request limits in effect.
+func (b *Backend) Limits() backend.Limits {
+	return b.limiter.Limits()
+}
+
+// resolveModel maps a friendly name like "opus" to a full model ID.
+func resolveModel(model string) string {
+	return backend.ResolveModel("claude", model, Aliases)
+}
+
+// isModelNotFound reports whether an API error means the model is unknown
+// or retired. Anthropic returns 404 not_found_error naming the model.
+func isModelNotFound(status int, apiErr apiError) bool {
+	msg := strings.ToLower(apiErr.Error.Message)
+	if status == http.StatusNotFound && apiErr.Error.Type == "not_found_error" {
+		return strings.Contains(msg, "model")
 	}
+	return strings.Contains(msg, "model") && strings.Contains(msg, "deprecated")
 }
 
 // Register registers the Claude backend with the global registry.
-func Register() error {
-	b, err := New()
+func Register(opts ...Option) error {
+	b, err := New(opts...)
 	if err != nil {
 		return err
 	}
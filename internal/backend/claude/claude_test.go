@@ -0,0 +1,535 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/backend"
+)
+
+func TestInvokeModelNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"not_found_error","message":"model: claude-3-sonnet-20240229"}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	_, err = b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}},
+		backend.InvokeOptions{Model: "claude-3-sonnet-20240229"})
+	if !errors.Is(err, backend.ErrModelUnavailable) {
+		t.Errorf("expected ErrModelUnavailable, got %v", err)
+	}
+}
+
+func TestInvokeOtherErrorsNotModelUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: must be positive"}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	_, err = b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}}, backend.InvokeOptions{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if errors.Is(err, backend.ErrModelUnavailable) {
+		t.Errorf("unexpected ErrModelUnavailable for validation error: %v", err)
+	}
+}
+
+func TestInvokeResolvesModelAlias(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requested = body.Model
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"stop here"}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	_, _ = b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}},
+		backend.InvokeOptions{Model: "opus"})
+	if requested != "claude-opus-4-5-20251101" {
+		t.Errorf("requested model = %q, want claude-opus-4-5-20251101", requested)
+	}
+	if got := b.EstimateCost(1000, 1000, "opus").Model; got != "claude-opus-4-5-20251101" {
+		t.Errorf("EstimateCost model = %q, want claude-opus-4-5-20251101", got)
+	}
+}
+
+func TestConfiguredAliasOverridesBuiltin(t *testing.T) {
+	backend.SetModelAliases("claude", map[string]string{"opus": "claude-3-opus-20240229"})
+	t.Cleanup(backend.ResetModelAliasesForTesting)
+
+	if got := resolveModel("opus"); got != "claude-3-opus-20240229" {
+		t.Errorf("resolveModel(opus) = %q, want claude-3-opus-20240229", got)
+	}
+	if got := resolveModel("claude-opus-4-5-20251101"); got != "claude-opus-4-5-20251101" {
+		t.Errorf("full model IDs should pass through, got %q", got)
+	}
+}
+
+func TestInvokeForcesStructuredOutputTool(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"valid", `{"verdict":"approve"}`, false},
+		{"missing required", `{"reason":"looks fine"}`, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent struct {
+				Tools []struct {
+					Name        string          `json:"name"`
+					InputSchema json.RawMessage `json:"input_schema"`
+				} `json:"tools"`
+				ToolChoice struct {
+					Type string `json:"type"`
+					Name string `json:"name"`
+				} `json:"tool_choice"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(&sent)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"type":"message","role":"assistant","stop_reason":"tool_use","content":[` +
+					`{"type":"tool_use","id":"toolu_1","name":"verdict","input":` + tt.input + `}],` +
+					`"usage":{"input_tokens":10,"output_tokens":5}}`))
+			}))
+			defer server.Close()
+
+			t.Setenv("ANTHROPIC_API_KEY", "test-key-1234567890")
+			b, err := New(WithBaseURL(server.URL))
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			schema := `{"type":"object","required":["verdict"],"properties":{"verdict":{"enum":["approve","reject"]}}}`
+			result, err := b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "review"}},
+				backend.InvokeOptions{ResponseFormat: &backend.ResponseFormat{
+					Type:   backend.ResponseFormatJSONSchema,
+					Name:   "verdict",
+					Schema: json.RawMessage(schema),
+				}})
+
+			if len(sent.Tools) != 1 || sent.Tools[0].Name != "verdict" || string(sent.Tools[0].InputSchema) != schema {
+				t.Errorf("tools = %+v, want one verdict tool with the schema", sent.Tools)
+			}
+			if sent.ToolChoice.Type != "tool" || sent.ToolChoice.Name != "verdict" {
+				t.Errorf("tool_choice = %+v, want forced verdict tool", sent.ToolChoice)
+			}
+			if got := errors.Is(err, backend.ErrInvalidStructuredOutput); got != tt.wantErr {
+				t.Fatalf("err = %v, want ErrInvalidStructuredOutput: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && result.Content != tt.input {
+				t.Errorf("Content = %q, want tool input %q", result.Content, tt.input)
+			}
+		})
+	}
+}
+
+// sseServer serves the given server-sent events, flushing after each one.
+func sseServer(t *testing.T, events []string, afterEvents func(r *http.Request)) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Stream bool `json:"stream"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if !body.Stream {
+			t.Errorf("expected stream:true in request")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, e := range events {
+			_, _ = w.Write([]byte(e + "\n\n"))
+			flusher.Flush()
+		}
+		if afterEvents != nil {
+			afterEvents(r)
+		}
+	}))
+}
+
+func newStreamTestBackend(t *testing.T, url string) *Backend {
+	t.Helper()
+	t.Setenv("ANTHROPIC_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(url))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return b
+}
+
+func TestInvokeStreamSSE(t *testing.T) {
+	server := sseServer(t, []string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-haiku-3-5-20241022\",\"usage\":{\"input_tokens\":25,\"output_tokens\":1}}}",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}",
+		"event: ping\ndata: {\"type\":\"ping\"}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\", world\"}}",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}",
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":12}}",
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}",
+	}, nil)
+	defer server.Close()
+
+	b := newStreamTestBackend(t, server.URL)
+	ch, err := b.InvokeStream(context.Background(), []backend.Message{{Role: "user", Content: "hi"}}, backend.InvokeOptions{})
+	if err != nil {
+		t.Fatalf("InvokeStream: %v", err)
+	}
+
+	var deltas []string
+	var final backend.StreamChunk
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected error chunk: %v", chunk.Error)
+		}
+		if chunk.Done {
+			final = chunk
+			continue
+		}
+		deltas = append(deltas, chunk.Content)
+	}
+
+	if len(deltas) != 2 || deltas[0] != "Hello" || deltas[1] != ", world" {
+		t.Errorf("deltas = %q, want [Hello , world]", deltas)
+	}
+	if !final.Done || final.InputTokens != 25 || final.OutputTokens != 12 || final.FinishReason != "end_turn" {
+		t.Errorf("final chunk = %+v, want Done with 25 in / 12 out, end_turn", final)
+	}
+}
+
+func TestInvokeStreamMalformedEvent(t *testing.T) {
+	server := sseServer(t, []string{
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"ok\"}}",
+		"event: content_block_delta\ndata: {not json",
+	}, nil)
+	defer server.Close()
+
+	b := newStreamTestBackend(t, server.URL)
+	ch, err := b.InvokeStream(context.Background(), []backend.Message{{Role: "user", Content: "hi"}}, backend.InvokeOptions{})
+	if err != nil {
+		t.Fatalf("InvokeStream: %v", err)
+	}
+
+	var last backend.StreamChunk
+	for chunk := range ch {
+		last = chunk
+	}
+	if last.Error == nil || !strings.Contains(last.Error.Error(), "malformed stream event") {
+		t.Errorf("last chunk = %+v, want malformed event error", last)
+	}
+}
+
+func TestInvokeStreamEndsEarly(t *testing.T) {
+	server := sseServer(t, []string{
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"partial\"}}",
+	}, nil)
+	defer server.Close()
+
+	b := newStreamTestBackend(t, server.URL)
+	ch, err := b.InvokeStream(context.Background(), []backend.Message{{Role: "user", Content: "hi"}}, backend.InvokeOptions{})
+	if err != nil {
+		t.Fatalf("InvokeStream: %v", err)
+	}
+
+	var last backend.StreamChunk
+	for chunk := range ch {
+		last = chunk
+	}
+	if last.Error == nil {
+		t.Error("expected an error when the stream ends without message_stop")
+	}
+}
+
+func TestInvokeStreamCancellationClosesBody(t *testing.T) {
+	bodyClosed := make(chan struct{})
+	server := sseServer(t, []string{
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"first\"}}",
+	}, func(r *http.Request) {
+		// Hold the stream open until the client goes away
+		<-r.Context().Done()
+		close(bodyClosed)
+	})
+	defer server.Close()
+
+	b := newStreamTestBackend(t, server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := b.InvokeStream(ctx, []backend.Message{{Role: "user", Content: "hi"}}, backend.InvokeOptions{})
+	if err != nil {
+		t.Fatalf("InvokeStream: %v", err)
+	}
+
+	if chunk := <-ch; chunk.Content != "first" {
+		t.Fatalf("first chunk = %+v, want content first", chunk)
+	}
+	cancel()
+
+	closed := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream channel not closed after cancellation")
+	}
+	select {
+	case <-bodyClosed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("HTTP stream not closed after cancellation")
+	}
+}
+
+func TestInvokeStreamErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"not_found_error","message":"model: claude-3-sonnet-20240229"}}`))
+	}))
+	defer server.Close()
+
+	b := newStreamTestBackend(t, server.URL)
+	_, err := b.InvokeStream(context.Background(), []backend.Message{{Role: "user", Content: "hi"}},
+		backend.InvokeOptions{Model: "claude-3-sonnet-20240229"})
+	if !errors.Is(err, backend.ErrModelUnavailable) {
+		t.Errorf("expected ErrModelUnavailable, got %v", err)
+	}
+}
+
+func TestInvokeRetriesTransientStatus(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(529)
+			_, _ = w.Write([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"type":"message","role":"assistant","content":[{"type":"text","text":"hello"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL), WithRetry(3, time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	result, err := b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}}, backend.InvokeOptions{})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if result.Content != "hello" || calls != 2 {
+		t.Errorf("content = %q after %d calls, want hello after 2", result.Content, calls)
+	}
+}
+
+func TestInvokeRoundTripsToolCalls(t *testing.T) {
+	var sent struct {
+		Tools    []apiTool `json:"tools"`
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"type":"message","role":"assistant","stop_reason":"tool_use","content":[` +
+			`{"type":"text","text":"Checking."},` +
+			`{"type":"tool_use","id":"toolu_2","name":"get_weather","input":{"city":"Oslo"}}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	messages := []backend.Message{
+		{Role: "user", Content: "Weather in Paris and Rome?"},
+		{Role: "assistant", ToolCalls: []backend.ToolCall{
+			{ID: "toolu_0", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Paris"}`)},
+			{ID: "toolu_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Rome"}`)},
+		}},
+		backend.ToolResultMessage("toolu_0", "18C"),
+		backend.ToolResultMessage("toolu_1", "24C"),
+	}
+	result, err := b.Invoke(context.Background(), messages, backend.InvokeOptions{
+		Tools: []backend.ToolDef{{Name: "get_weather", Description: "Current weather"}},
+	})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+
+	if len(sent.Tools) != 1 || sent.Tools[0].Name != "get_weather" || string(sent.Tools[0].InputSchema) != `{"type":"object"}` {
+		t.Errorf("tools = %+v, want get_weather with default schema", sent.Tools)
+	}
+	if len(sent.Messages) != 3 {
+		t.Fatalf("sent %d messages, want 3 (tool results share one user turn)", len(sent.Messages))
+	}
+	wantAssistant := `[{"type":"tool_use","id":"toolu_0","name":"get_weather","input":{"city":"Paris"}},{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Rome"}}]`
+	if got := string(sent.Messages[1].Content); got != wantAssistant {
+		t.Errorf("assistant content = %s, want %s", got, wantAssistant)
+	}
+	wantResults := `[{"type":"tool_result","tool_use_id":"toolu_0","content":"18C"},{"type":"tool_result","tool_use_id":"toolu_1","content":"24C"}]`
+	if sent.Messages[2].Role != "user" || string(sent.Messages[2].Content) != wantResults {
+		t.Errorf("tool results = %s %s, want user %s", sent.Messages[2].Role, sent.Messages[2].Content, wantResults)
+	}
+
+	if result.Content != "Checking." || result.FinishReason != "tool_use" {
+		t.Errorf("result content=%q finish=%q", result.Content, result.FinishReason)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].ID != "toolu_2" ||
+		result.ToolCalls[0].Name != "get_weather" || string(result.ToolCalls[0].Arguments) != `{"city":"Oslo"}` {
+		t.Errorf("tool calls = %+v", result.ToolCalls)
+	}
+}
+
+func TestModelLimitsResolveAliases(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "test-key-1234567890")
+	b, err := New(WithRateLimit(30), WithModelLimits(map[string]backend.ModelLimits{
+		"opus": {RequestsPerMinute: 5, MaxConcurrent: 1},
+	}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	limits := b.Limits()
+	if limits.RequestsPerMinute != 30 {
+		t.Errorf("RequestsPerMinute = %d, want 30", limits.RequestsPerMinute)
+	}
+	want := backend.ModelLimits{RequestsPerMinute: 5, MaxConcurrent: 1}
+	if got := limits.Models["claude-opus-4-5-20251101"]; got != want {
+		t.Errorf("Models[claude-opus-4-5-20251101] = %+v, want %+v", got, want)
+	}
+}
+
+func TestHealthyChecksKeyWithAPI(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/v1/models" {
+			t.Errorf("health check path = %q, want /v1/models", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") == "revoked-key-1234567890" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// Without a deadline only the format check runs
+	if err := b.Healthy(context.Background()); err != nil || calls != 0 {
+		t.Fatalf("Healthy without deadline = %v after %d call(s), want nil with no calls", err, calls)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := b.Healthy(ctx); err != nil {
+		t.Errorf("Healthy with valid key: %v", err)
+	}
+
+	b.apiKey = "revoked-key-1234567890"
+	err = b.Healthy(ctx)
+	if err == nil || !strings.Contains(err.Error(), "API key rejected (status 401)") {
+		t.Errorf("Healthy with revoked key = %v, want key rejected", err)
+	}
+	if calls != 2 {
+		t.Errorf("API calls = %d, want 2", calls)
+	}
+}
+
+func TestBetaFeaturesAndAPIVersionHeaders(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"stop here"}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "test-key-1234567890")
+	messages := []backend.Message{{Role: "user", Content: "hi"}}
+
+	plain, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	_, _ = plain.Invoke(context.Background(), messages, backend.InvokeOptions{})
+	if got := header.Get("anthropic-beta"); got != "" {
+		t.Errorf("anthropic-beta = %q, want none by default", got)
+	}
+	if got := header.Get("anthropic-version"); got != defaultAPIVersion {
+		t.Errorf("anthropic-version = %q, want %q", got, defaultAPIVersion)
+	}
+	if got := plain.MaxContextTokens("sonnet"); got != 200000 {
+		t.Errorf("MaxContextTokens(sonnet) = %d, want 200000 without the beta", got)
+	}
+
+	beta, err := New(WithBaseURL(server.URL), WithAPIVersion("2024-01-01"),
+		WithBetaFeatures(BetaContext1M, "prompt-caching-2024-07-31"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	_, _ = beta.Invoke(context.Background(), messages, backend.InvokeOptions{})
+	if got := header.Get("anthropic-beta"); got != BetaContext1M+",prompt-caching-2024-07-31" {
+		t.Errorf("anthropic-beta = %q", got)
+	}
+	if got := header.Get("anthropic-version"); got != "2024-01-01" {
+		t.Errorf("anthropic-version = %q, want 2024-01-01", got)
+	}
+	if got := beta.MaxContextTokens("sonnet"); got != 1000000 {
+		t.Errorf("MaxContextTokens(sonnet) = %d, want 1000000 with %s", got, BetaContext1M)
+	}
+
+	// Two versions of one feature can't be combined
+	if _, err := New(WithBetaFeatures("prompt-caching-2024-07-31", "prompt-caching-2025-01-01")); err == nil {
+		t.Error("expected an error combining two prompt-caching betas")
+	}
+	if _, err := New(WithBetaFeatures("a,b")); err == nil {
+		t.Error("expected an error for a comma in a beta name")
+	}
+}
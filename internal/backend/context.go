@@ -2,7 +2,12 @@
 package backend
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
 )
 
 // TruncationStrategy defines how to handle context overflow.
@@ -17,44 +22,139 @@ const (
 
 	// TruncateLongest removes the longest messages first.
 	TruncateLongest TruncationStrategy = "truncate_longest"
+
+	// TruncateSummarize compresses the oldest messages into a summary note
+	// using ContextManager.Summarizer, falling back to TruncateOldest.
+	TruncateSummarize TruncationStrategy = "summarize"
 )
 
+const (
+	// DefaultMaxSummaryCost caps what one TruncateSummarize call may spend.
+	DefaultMaxSummaryCost = 0.01
+
+	// maxSummaryTokens caps the length of a summary note.
+	maxSummaryTokens = 1024
+
+	// summaryTimeout bounds a summarization call.
+	summaryTimeout = 60 * time.Second
+
+	// summaryPrompt instructs the summarizer.
+	summaryPrompt = "Summarize the conversation below for a model that will continue it. " +
+		"Keep facts, decisions, constraints, and open questions; drop pleasantries. Be concise."
+
+	// summaryHeading introduces the summary in the system message.
+	summaryHeading = "Summary of earlier conversation:"
+)
+
+// DefaultModelReserveTokens are per-model response reserves that differ
+// from the flat default. Reasoning models spend hidden reasoning tokens
+// out of the output budget, so they need far more headroom.
+var DefaultModelReserveTokens = map[string]int{
+	"o1":          32768,
+	"o1-mini":     32768,
+	"o1-preview":  32768,
+	"o3-mini":     32768,
+	"grok-3-mini": 16384,
+}
+
 // ContextManager handles context preparation for API backends.
 type ContextManager struct {
 	// DefaultStrategy is the default truncation strategy.
 	DefaultStrategy TruncationStrategy
 
-	// ReserveTokens is the number of tokens to reserve for the response.
+	// ReserveTokens is the number of tokens to reserve for the response
+	// when no per-model reserve applies.
 	ReserveTokens int
+
+	// ModelReserveTokens overrides ReserveTokens for specific models.
+	// Initialized from DefaultModelReserveTokens.
+	ModelReserveTokens map[string]int
+
+	// Summarizer is the backend TruncateSummarize compresses messages
+	// with, ideally a cheap one (haiku, grok-3-mini). Nil falls back to
+	// TruncateOldest.
+	Summarizer AgentBackend
+
+	// SummarizerModel is the model to summarize with; empty uses the
+	// Summarizer's default.
+	SummarizerModel string
+
+	// MaxSummaryCost is the most (USD) one summarization may cost by
+	// estimate; a larger summary falls back to TruncateOldest.
+	MaxSummaryCost float64
 }
 
 // NewContextManager creates a new context manager with defaults.
 func NewContextManager() *ContextManager {
+	reserves := make(map[string]int, len(DefaultModelReserveTokens))
+	for model, tokens := range DefaultModelReserveTokens {
+		reserves[model] = tokens
+	}
+
 	return &ContextManager{
-		DefaultStrategy: TruncateOldest,
-		ReserveTokens:   4096, // Reserve for response
+		DefaultStrategy:    TruncateOldest,
+		ReserveTokens:      4096, // Reserve for response
+		ModelReserveTokens: reserves,
+		MaxSummaryCost:     DefaultMaxSummaryCost,
 	}
 }
 
-// PrepareContext trims/summarizes context to fit model limits.
+// ReserveFor returns the response reserve for a model.
+func (cm *ContextManager) ReserveFor(model string) int {
+	if reserve, ok := cm.ModelReserveTokens[model]; ok {
+		return reserve
+	}
+	return cm.ReserveTokens
+}
+
+// PrepareContext trims/summarizes context to fit model limits,
+// using the default response reserve.
 func (cm *ContextManager) PrepareContext(
 	messages []Message,
 	maxTokens int,
 	strategy TruncationStrategy,
+) ([]Message, error) {
+	return cm.PrepareContextForModel(messages, "", maxTokens, strategy)
+}
+
+// PrepareContextForModel trims/summarizes context to fit model limits,
+// reserving the model's response headroom (see ReserveFor).
+func (cm *ContextManager) PrepareContextForModel(
+	messages []Message,
+	model string,
+	maxTokens int,
+	strategy TruncationStrategy,
+) ([]Message, error) {
+	return cm.PrepareContextForResponse(messages, model, maxTokens, 0, strategy)
+}
+
+// PrepareContextForResponse trims/summarizes context to fit model limits,
+// leaving exactly responseTokens for the reply, which should match the
+// InvokeOptions.MaxTokens the caller sends. Zero uses the model's reserve
+// (see ReserveFor).
+func (cm *ContextManager) PrepareContextForResponse(
+	messages []Message,
+	model string,
+	maxTokens int,
+	responseTokens int,
+	strategy TruncationStrategy,
 ) ([]Message, error) {
 	if len(messages) == 0 {
 		return messages, nil
 	}
 
-	// Estimate current tokens
-	currentTokens := cm.estimateTokens(messages)
-
-	// Account for response reserve
-	availableTokens := maxTokens - cm.ReserveTokens
+	reserve := responseTokens
+	if reserve <= 0 {
+		reserve = cm.ReserveFor(model)
+	}
+	availableTokens := maxTokens - reserve
 	if availableTokens <= 0 {
-		return nil, fmt.Errorf("max_tokens (%d) too small for response reserve (%d)", maxTokens, cm.ReserveTokens)
+		return nil, fmt.Errorf("response limit (%d tokens) leaves no room in the %d-token context window", reserve, maxTokens)
 	}
 
+	// Estimate current tokens with the model's tokenizer
+	currentTokens := cm.estimateTokens(messages, model)
+
 	if currentTokens <= availableTokens {
 		return messages, nil // Fits as-is
 	}
@@ -65,18 +165,20 @@ func (cm *ContextManager) PrepareContext(
 
 	switch strategy {
 	case TruncateOldest:
-		return cm.truncateOldest(messages, availableTokens)
+		return cm.truncateOldest(messages, model, availableTokens)
 	case TruncateMiddle:
-		return cm.truncateMiddle(messages, availableTokens)
+		return cm.truncateMiddle(messages, model, availableTokens)
 	case TruncateLongest:
-		return cm.truncateLongest(messages, availableTokens)
+		return cm.truncateLongest(messages, model, availableTokens)
+	case TruncateSummarize:
+		return cm.truncateSummarize(messages, model, availableTokens)
 	default:
-		return cm.truncateOldest(messages, availableTokens)
+		return cm.truncateOldest(messages, model, availableTokens)
 	}
 }
 
 // truncateOldest removes oldest messages first (keeping system + recent).
-func (cm *ContextManager) truncateOldest(messages []Message, maxTokens int) ([]Message, error) {
+func (cm *ContextManager) truncateOldest(messages []Message, model string, maxTokens int) ([]Message, error) {
 	if len(messages) < 2 {
 		return messages, nil
 	}
@@ -96,14 +198,14 @@ func (cm *ContextManager) truncateOldest(messages []Message, maxTokens int) ([]M
 	// Calculate system message tokens
 	systemTokens := 0
 	if systemMsg != nil {
-		systemTokens = cm.estimateMessageTokens(*systemMsg)
+		systemTokens = cm.estimateMessageTokens(*systemMsg, model)
 	}
 
 	availableForConversation := maxTokens - systemTokens
 	if availableForConversation <= 0 {
 		// System message alone exceeds limit - truncate it
 		if systemMsg != nil {
-			truncated := cm.truncateMessage(*systemMsg, maxTokens)
+			truncated := cm.truncateMessage(*systemMsg, model, maxTokens)
 			return []Message{truncated}, nil
 		}
 		return nil, fmt.Errorf("cannot fit any messages in %d tokens", maxTokens)
@@ -114,7 +216,7 @@ func (cm *ContextManager) truncateOldest(messages []Message, maxTokens int) ([]M
 	currentTokens := 0
 
 	for i := len(conversation) - 1; i >= 0; i-- {
-		msgTokens := cm.estimateMessageTokens(conversation[i])
+		msgTokens := cm.estimateMessageTokens(conversation[i], model)
 		if currentTokens+msgTokens > availableForConversation {
 			break
 		}
@@ -131,7 +233,7 @@ func (cm *ContextManager) truncateOldest(messages []Message, maxTokens int) ([]M
 }
 
 // truncateMiddle keeps first and last messages, removes middle.
-func (cm *ContextManager) truncateMiddle(messages []Message, maxTokens int) ([]Message, error) {
+func (cm *ContextManager) truncateMiddle(messages []Message, model string, maxTokens int) ([]Message, error) {
 	if len(messages) <= 2 {
 		return messages, nil
 	}
@@ -159,7 +261,7 @@ func (cm *ContextManager) truncateMiddle(messages []Message, maxTokens int) ([]M
 	// Calculate system message tokens
 	systemTokens := 0
 	if systemMsg != nil {
-		systemTokens = cm.estimateMessageTokens(*systemMsg)
+		systemTokens = cm.estimateMessageTokens(*systemMsg, model)
 	}
 
 	availableForConversation := maxTokens - systemTokens
@@ -167,8 +269,8 @@ func (cm *ContextManager) truncateMiddle(messages []Message, maxTokens int) ([]M
 	// Always keep first and last message
 	first := conversation[0]
 	last := conversation[len(conversation)-1]
-	firstTokens := cm.estimateMessageTokens(first)
-	lastTokens := cm.estimateMessageTokens(last)
+	firstTokens := cm.estimateMessageTokens(first, model)
+	lastTokens := cm.estimateMessageTokens(last, model)
 
 	remaining := availableForConversation - firstTokens - lastTokens
 	if remaining <= 0 {
@@ -180,44 +282,39 @@ func (cm *ContextManager) truncateMiddle(messages []Message, maxTokens int) ([]M
 		return result, nil
 	}
 
-	// Add middle messages from both ends toward center
+	// Keep middle messages from both ends toward the center, recording
+	// their original positions so the result stays in order
 	middle := conversation[1 : len(conversation)-1]
-	var kept []Message
+	var kept []int
 	left, right := 0, len(middle)-1
 	currentTokens := 0
+	fromLeft := true
 
 	for left <= right {
-		// Try to add from left
-		if left <= right {
-			leftTokens := cm.estimateMessageTokens(middle[left])
-			if currentTokens+leftTokens <= remaining {
-				kept = append(kept, middle[left])
-				currentTokens += leftTokens
-				left++
-			} else {
-				break
-			}
+		idx := right
+		if fromLeft {
+			idx = left
 		}
-
-		// Try to add from right
-		if left <= right {
-			rightTokens := cm.estimateMessageTokens(middle[right])
-			if currentTokens+rightTokens <= remaining {
-				// Insert at correct position
-				kept = append(kept, Message{}) // placeholder
-				copy(kept[left+1:], kept[left:])
-				kept[left] = middle[right]
-				currentTokens += rightTokens
-				right--
-			} else {
-				break
-			}
+		tokens := cm.estimateMessageTokens(middle[idx], model)
+		if currentTokens+tokens > remaining {
+			break
+		}
+		kept = append(kept, idx)
+		currentTokens += tokens
+		if fromLeft {
+			left++
+		} else {
+			right--
 		}
+		fromLeft = !fromLeft
 	}
+	sort.Ints(kept)
 
-	// Reconstruct: first + kept + last
+	// Reconstruct: first + kept middle (in order) + last
 	result := []Message{first}
-	result = append(result, kept...)
+	for _, idx := range kept {
+		result = append(result, middle[idx])
+	}
 	result = append(result, last)
 
 	if systemMsg != nil {
@@ -228,12 +325,12 @@ func (cm *ContextManager) truncateMiddle(messages []Message, maxTokens int) ([]M
 }
 
 // truncateLongest removes the longest messages first.
-func (cm *ContextManager) truncateLongest(messages []Message, maxTokens int) ([]Message, error) {
+func (cm *ContextManager) truncateLongest(messages []Message, model string, maxTokens int) ([]Message, error) {
 	// Make a copy to avoid modifying original
 	msgs := make([]Message, len(messages))
 	copy(msgs, messages)
 
-	for cm.estimateTokens(msgs) > maxTokens && len(msgs) > 1 {
+	for cm.estimateTokens(msgs, model) > maxTokens && len(msgs) > 1 {
 		// Find longest non-system message
 		longestIdx := -1
 		longestLen := 0
@@ -259,38 +356,171 @@ func (cm *ContextManager) truncateLongest(messages []Message, maxTokens int) ([]
 	return msgs, nil
 }
 
-// truncateMessage truncates a single message to fit token limit.
-func (cm *ContextManager) truncateMessage(msg Message, maxTokens int) Message {
-	// Rough estimate: 4 chars per token
-	maxChars := maxTokens * 4
+// truncateSummarize keeps the newest messages that fit alongside a summary
+// note and folds the older ones into that note, appended to the leading
+// system message (backends keep only one). The final message is always
+// kept, cut down if it alone is over budget. Without a Summarizer, or if
+// the summary would cost too much or fails, it falls back to truncateOldest.
+func (cm *ContextManager) truncateSummarize(messages []Message, model string, maxTokens int) ([]Message, error) {
+	if cm.Summarizer == nil {
+		return cm.truncateOldest(messages, model, maxTokens)
+	}
+
+	var system *Message
+	conversation := messages
+	if messages[0].Role == "system" {
+		system = &messages[0]
+		conversation = messages[1:]
+	}
+
+	// Keep the newest messages, leaving room for the system message and
+	// the summary note
+	budget := maxTokens - min(maxSummaryTokens, maxTokens/4)
+	if system != nil {
+		budget -= cm.estimateMessageTokens(*system, model)
+	}
+	if len(conversation) == 0 || budget <= 0 {
+		return cm.truncateOldest(messages, model, maxTokens)
+	}
+
+	// The latest turn is what the model must answer, so it is never
+	// summarized away
+	newest := len(conversation) - 1
+	last := cm.truncateMessage(conversation[newest], model, budget)
+	trimmed := make([]Message, 0, len(messages))
+	if system != nil {
+		trimmed = append(trimmed, *system)
+	}
+	trimmed = append(trimmed, conversation[:newest]...)
+	trimmed = append(trimmed, last)
+
+	split := newest
+	used := cm.estimateMessageTokens(last, model)
+	for split > 0 {
+		tokens := cm.estimateMessageTokens(conversation[split-1], model)
+		if used+tokens > budget {
+			break
+		}
+		used += tokens
+		split--
+	}
+	if split == 0 {
+		return trimmed, nil
+	}
+
+	summary, err := cm.summarize(conversation[:split])
+	if err != nil {
+		log.Printf("[context] Summarizing %d messages failed, dropping them instead: %v", split, err)
+		return cm.truncateOldest(trimmed, model, maxTokens)
+	}
+
+	note := summaryHeading + "\n" + summary
+	result := []Message{{Role: "system", Content: note}}
+	if system != nil {
+		result[0].Content = system.Content + "\n\n" + note
+	}
+	result = append(result, conversation[split:newest]...)
+	result = append(result, last)
+
+	// The summary may run long; trim it rather than overflow
+	if rest := cm.estimateTokens(result[1:], model); rest+cm.estimateMessageTokens(result[0], model) > maxTokens {
+		result[0] = cm.truncateMessage(result[0], model, maxTokens-rest)
+	}
+	return result, nil
+}
 
-	if len(msg.Content) <= maxChars {
+// summarize asks the Summarizer to compress messages into a short note,
+// refusing if the estimated cost exceeds MaxSummaryCost.
+func (cm *ContextManager) summarize(messages []Message) (string, error) {
+	b := cm.Summarizer
+	model := cm.SummarizerModel
+	if model == "" {
+		model = b.DefaultModel()
+	}
+
+	var transcript strings.Builder
+	for _, msg := range messages {
+		fmt.Fprintf(&transcript, "%s: %s\n\n", msg.Role, msg.Content)
+	}
+	request := []Message{
+		{Role: "system", Content: summaryPrompt},
+		{Role: "user", Content: transcript.String()},
+	}
+
+	// The transcript itself must fit the summarizer's context window
+	if limit := b.MaxContextTokens(model) - maxSummaryTokens - cm.estimateMessageTokens(request[0], model); cm.estimateMessageTokens(request[1], model) > limit {
+		request[1] = cm.truncateMessage(request[1], model, limit)
+	}
+
+	inputTokens, err := b.CountTokens(request, model)
+	if err != nil {
+		inputTokens = cm.estimateTokens(request, model)
+	}
+	estimate := b.EstimateCost(inputTokens, maxSummaryTokens, model)
+	if limit := cm.MaxSummaryCost; limit > 0 && estimate.TotalCost > limit {
+		return "", fmt.Errorf("estimated cost $%.4f exceeds summary cap $%.4f", estimate.TotalCost, limit)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), summaryTimeout)
+	defer cancel()
+	result, err := b.Invoke(ctx, request, InvokeOptions{
+		Model:     model,
+		MaxTokens: maxSummaryTokens,
+	})
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(result.Content)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return summary, nil
+}
+
+// truncateMessage truncates a single message to fit token limit.
+func (cm *ContextManager) truncateMessage(msg Message, model string, maxTokens int) Message {
+	if cm.estimateMessageTokens(msg, model) <= maxTokens {
 		return msg
 	}
 
-	// Truncate with ellipsis
-	truncated := msg.Content[:maxChars-3] + "..."
+	// Binary search for the longest rune prefix that fits with the ellipsis
+	runes := []rune(msg.Content)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		candidate := Message{Role: msg.Role, Content: string(runes[:mid]) + "..."}
+		if cm.estimateMessageTokens(candidate, model) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
 	return Message{
 		Role:    msg.Role,
-		Content: truncated,
+		Content: string(runes[:lo]) + "...",
 	}
 }
 
-// estimateTokens estimates total tokens for a message list.
-func (cm *ContextManager) estimateTokens(messages []Message) int {
+// estimateTokens estimates total tokens for a message list using the
+// model's token counter (see TokenCounterFor). An empty or unrecognized
+// model falls back to HeuristicTokenCounter.
+func (cm *ContextManager) estimateTokens(messages []Message, model string) int {
 	total := 0
 	for _, msg := range messages {
-		total += cm.estimateMessageTokens(msg)
+		total += cm.estimateMessageTokens(msg, model)
 	}
 	return total
 }
 
-// estimateMessageTokens estimates tokens for a single message.
-func (cm *ContextManager) estimateMessageTokens(msg Message) int {
-	// Rough estimate: 4 characters per token
-	// Add overhead for role and message structure
-	chars := len(msg.Content) + len(msg.Role) + 10
-	return chars / 4
+// estimateMessageTokens estimates tokens for a single message, including
+// its role and framing overhead.
+func (cm *ContextManager) estimateMessageTokens(msg Message, model string) int {
+	tokens, err := CountTokens([]Message{msg}, model)
+	if err != nil {
+		tokens, _ = HeuristicTokenCounter.CountTokens([]Message{msg}, model)
+	}
+	return tokens
 }
 
 // BuildMessagesFromText creates a message list from a simple prompt.
@@ -1,7 +1,12 @@
 package backend
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestContextManagerPrepareContext(t *testing.T) {
@@ -89,7 +94,7 @@ func TestContextManagerEstimateTokens(t *testing.T) {
 		{Role: "user", Content: "Hello, how are you?"},
 	}
 
-	tokens := cm.estimateTokens(messages)
+	tokens := cm.estimateTokens(messages, "")
 	if tokens <= 0 {
 		t.Errorf("estimateTokens() = %d, want > 0", tokens)
 	}
@@ -100,8 +105,325 @@ func TestContextManagerEstimateTokens(t *testing.T) {
 		{Role: "user", Content: "This is a much longer message that should produce more tokens because it contains many more characters than the shorter message above."},
 	}
 
-	longTokens := cm.estimateTokens(longMessages)
+	longTokens := cm.estimateTokens(longMessages, "")
 	if longTokens <= tokens {
 		t.Errorf("Longer message should have more tokens: %d <= %d", longTokens, tokens)
 	}
 }
+
+func TestContextManagerReserveForModel(t *testing.T) {
+	cm := NewContextManager()
+
+	if got := cm.ReserveFor("gpt-4o"); got != cm.ReserveTokens {
+		t.Errorf("ReserveFor(gpt-4o) = %d, want default %d", got, cm.ReserveTokens)
+	}
+	if got := cm.ReserveFor("o1"); got <= cm.ReserveTokens {
+		t.Errorf("ReserveFor(o1) = %d, want more than default %d", got, cm.ReserveTokens)
+	}
+
+	cm.ModelReserveTokens["haiku"] = 256
+	if got := cm.ReserveFor("haiku"); got != 256 {
+		t.Errorf("ReserveFor(haiku) = %d, want configured 256", got)
+	}
+}
+
+func TestContextManagerReasoningModelTruncatesEarlier(t *testing.T) {
+	cm := NewContextManager()
+
+	// ~1000 tokens per message, ~10k tokens total
+	var messages []Message
+	for i := 0; i < 10; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		messages = append(messages, Message{Role: role, Content: strings.Repeat("token ", 1000)})
+	}
+
+	const maxTokens = 40000
+
+	standard, err := cm.PrepareContextForModel(messages, "gpt-4o", maxTokens, TruncateOldest)
+	if err != nil {
+		t.Fatalf("PrepareContextForModel(gpt-4o) error = %v", err)
+	}
+	if len(standard) != len(messages) {
+		t.Errorf("standard model kept %d messages, want all %d", len(standard), len(messages))
+	}
+
+	reasoning, err := cm.PrepareContextForModel(messages, "o1", maxTokens, TruncateOldest)
+	if err != nil {
+		t.Fatalf("PrepareContextForModel(o1) error = %v", err)
+	}
+	if len(reasoning) >= len(standard) {
+		t.Errorf("reasoning model kept %d messages, want fewer than %d", len(reasoning), len(standard))
+	}
+}
+
+func TestContextManagerUsesModelTokenizer(t *testing.T) {
+	t.Cleanup(ResetTokenCountersForTesting)
+	cm := NewContextManager()
+
+	// 1000 ideographs: ~750 tokens by the byte heuristic, ~1000 in practice
+	messages := []Message{
+		{Role: "user", Content: strings.Repeat("字", 1000)},
+		{Role: "user", Content: strings.Repeat("字", 1000)},
+	}
+	const maxTokens = 4096 + 1800
+
+	heuristic, err := cm.PrepareContextForModel(messages, "", maxTokens, TruncateOldest)
+	if err != nil {
+		t.Fatalf("PrepareContextForModel(heuristic) error = %v", err)
+	}
+	if len(heuristic) != 2 {
+		t.Errorf("heuristic kept %d messages, want 2", len(heuristic))
+	}
+
+	claude, err := cm.PrepareContextForModel(messages, "claude-sonnet-4-20250514", maxTokens, TruncateOldest)
+	if err != nil {
+		t.Fatalf("PrepareContextForModel(claude) error = %v", err)
+	}
+	if len(claude) != 1 {
+		t.Errorf("claude kept %d messages, want 1", len(claude))
+	}
+
+	// A registered counter drives truncation too
+	RegisterTokenCounter(FamilyClaude, TokenCounterFunc(func(msgs []Message, _ string) (int, error) {
+		return 10 * len(msgs), nil
+	}))
+	registered, err := cm.PrepareContextForModel(messages, "claude-sonnet-4-20250514", maxTokens, TruncateOldest)
+	if err != nil {
+		t.Fatalf("PrepareContextForModel(registered) error = %v", err)
+	}
+	if len(registered) != 2 {
+		t.Errorf("registered counter kept %d messages, want 2", len(registered))
+	}
+}
+
+func TestContextManagerTruncateMessageFitsTokenizer(t *testing.T) {
+	cm := NewContextManager()
+	msg := Message{Role: "system", Content: strings.Repeat("日本語のテキスト", 200)}
+
+	got := cm.truncateMessage(msg, "gpt-4o", 100)
+	if tokens := cm.estimateMessageTokens(got, "gpt-4o"); tokens > 100 {
+		t.Errorf("truncated message = %d tokens, want <= 100", tokens)
+	}
+	if !strings.HasSuffix(got.Content, "...") {
+		t.Errorf("truncated content %q missing ellipsis", got.Content)
+	}
+	if !utf8.ValidString(got.Content) {
+		t.Error("truncated content split a multi-byte character")
+	}
+}
+
+// summarizingBackend is a fake summarizer recording what it was asked.
+type summarizingBackend struct {
+	mockBackend
+	cost    float64
+	err     error
+	invokes int
+	request []Message
+}
+
+func (b *summarizingBackend) EstimateCost(input, output int, model string) CostEstimate {
+	return CostEstimate{TotalCost: b.cost}
+}
+
+func (b *summarizingBackend) Invoke(_ context.Context, messages []Message, _ InvokeOptions) (*InvokeResult, error) {
+	b.invokes++
+	b.request = messages
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &InvokeResult{Content: "user asked about limits; agreed on 60 RPM"}, nil
+}
+
+// overflowingConversation returns a system prompt and ten 400-char turns.
+func overflowingConversation() []Message {
+	messages := []Message{{Role: "system", Content: "You are helpful."}}
+	for i := 0; i < 10; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		messages = append(messages, Message{Role: role, Content: strings.Repeat(string(rune('a'+i)), 400)})
+	}
+	return messages
+}
+
+func TestContextManagerTruncateSummarize(t *testing.T) {
+	summarizer := &summarizingBackend{cost: 0.001}
+	cm := NewContextManager()
+	cm.ReserveTokens = 100
+	cm.Summarizer = summarizer
+
+	messages := overflowingConversation()
+	result, err := cm.PrepareContext(messages, 600, TruncateSummarize)
+	if err != nil {
+		t.Fatalf("PrepareContext: %v", err)
+	}
+	if summarizer.invokes != 1 {
+		t.Fatalf("summarizer invoked %d times, want 1", summarizer.invokes)
+	}
+
+	// One system message carrying the original prompt and the summary
+	if result[0].Role != "system" || !strings.HasPrefix(result[0].Content, "You are helpful.") ||
+		!strings.Contains(result[0].Content, "agreed on 60 RPM") {
+		t.Errorf("system message = %q, want prompt plus summary", result[0].Content)
+	}
+	for _, msg := range result[1:] {
+		if msg.Role == "system" {
+			t.Errorf("extra system message %q", msg.Content)
+		}
+	}
+
+	// The newest message is kept verbatim; the summarized ones are in the request
+	if last := result[len(result)-1]; last.Content != messages[len(messages)-1].Content {
+		t.Errorf("last message = %q, want the newest kept", last.Content)
+	}
+	if !strings.Contains(summarizer.request[1].Content, strings.Repeat("a", 400)) {
+		t.Error("oldest message was not sent for summarization")
+	}
+	if got := cm.estimateTokens(result, ""); got > 500 {
+		t.Errorf("result is %d tokens, want at most 500", got)
+	}
+}
+
+func TestContextManagerTruncateSummarizeKeepsOversizedLastMessage(t *testing.T) {
+	summarizer := &summarizingBackend{cost: 0.001}
+	cm := NewContextManager()
+	cm.ReserveTokens = 100
+	cm.Summarizer = summarizer
+
+	messages := overflowingConversation()
+	question := "Given all that, what rate limit should we use? " + strings.Repeat("z", 4000)
+	messages = append(messages, Message{Role: "user", Content: question})
+
+	result, err := cm.PrepareContext(messages, 600, TruncateSummarize)
+	if err != nil {
+		t.Fatalf("PrepareContext: %v", err)
+	}
+	if summarizer.invokes != 1 {
+		t.Fatalf("summarizer invoked %d times, want 1", summarizer.invokes)
+	}
+	last := result[len(result)-1]
+	if last.Role != "user" || !strings.HasPrefix(last.Content, "Given all that") {
+		t.Fatalf("last message = %q, want the newest user turn kept", last.Content)
+	}
+	if !strings.HasSuffix(last.Content, "...") {
+		t.Errorf("oversized last message should be truncated, got %d chars", len(last.Content))
+	}
+	if strings.Contains(summarizer.request[1].Content, "Given all that") {
+		t.Error("the newest message was sent for summarization")
+	}
+	if got := cm.estimateTokens(result, ""); got > 500 {
+		t.Errorf("result is %d tokens, want at most 500", got)
+	}
+}
+
+func TestContextManagerTruncateSummarizeFallsBack(t *testing.T) {
+	tests := []struct {
+		name       string
+		summarizer *summarizingBackend
+		wantCalls  int
+	}{
+		{"no summarizer", nil, 0},
+		{"over cost cap", &summarizingBackend{cost: 0.5}, 0},
+		{"summarizer fails", &summarizingBackend{err: errors.New("throttled")}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := NewContextManager()
+			cm.ReserveTokens = 100
+			if tt.summarizer != nil {
+				cm.Summarizer = tt.summarizer
+			}
+
+			messages := overflowingConversation()
+			got, err := cm.PrepareContext(messages, 600, TruncateSummarize)
+			if err != nil {
+				t.Fatalf("PrepareContext: %v", err)
+			}
+			want, _ := cm.PrepareContext(messages, 600, TruncateOldest)
+			if len(got) != len(want) || got[0].Content != want[0].Content {
+				t.Errorf("got %d messages, want TruncateOldest's %d", len(got), len(want))
+			}
+			if tt.summarizer != nil && tt.summarizer.invokes != tt.wantCalls {
+				t.Errorf("summarizer invoked %d times, want %d", tt.summarizer.invokes, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestContextManagerTruncateMiddlePreservesOrder(t *testing.T) {
+	cm := NewContextManager()
+	cm.ReserveTokens = 100
+
+	messages := []Message{{Role: "system", Content: "You are helpful."}}
+	for i := 0; i < 12; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		messages = append(messages, Message{Role: role, Content: fmt.Sprintf("turn-%02d %s", i, strings.Repeat("x", 200))})
+	}
+
+	const maxTokens = 500
+	result, err := cm.PrepareContext(messages, maxTokens, TruncateMiddle)
+	if err != nil {
+		t.Fatalf("PrepareContext: %v", err)
+	}
+
+	if got, budget := cm.estimateTokens(result, ""), maxTokens-cm.ReserveTokens; got > budget {
+		t.Errorf("result is %d tokens, want at most %d", got, budget)
+	}
+	if len(result) >= len(messages) || len(result) < 4 {
+		t.Fatalf("kept %d of %d messages, want some but not all of the middle", len(result), len(messages))
+	}
+	if result[0].Role != "system" || result[1].Content != messages[1].Content || result[len(result)-1].Content != messages[len(messages)-1].Content {
+		t.Errorf("want system, first, ..., last; got first=%q last=%q", result[1].Content[:7], result[len(result)-1].Content[:7])
+	}
+
+	// Every kept message appears in its original order
+	prev := ""
+	for _, msg := range result[1:] {
+		turn := msg.Content[:7]
+		if turn <= prev {
+			t.Errorf("turn %s follows %s: order not preserved", turn, prev)
+		}
+		prev = turn
+	}
+}
+
+func TestPrepareContextForResponse(t *testing.T) {
+	cm := NewContextManager()
+	messages := []Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: strings.Repeat("a", 400)},
+		{Role: "user", Content: strings.Repeat("b", 400)},
+	}
+	tokens := cm.estimateTokens(messages, "")
+
+	// The default reserve would force trimming; the requested response
+	// size leaves room for everything
+	result, err := cm.PrepareContextForResponse(messages, "", tokens+100, 100, TruncateOldest)
+	if err != nil {
+		t.Fatalf("PrepareContextForResponse: %v", err)
+	}
+	if len(result) != len(messages) {
+		t.Errorf("kept %d messages, want all %d", len(result), len(messages))
+	}
+
+	// One more token of response trims the oldest message
+	result, err = cm.PrepareContextForResponse(messages, "", tokens+100, 101, TruncateOldest)
+	if err != nil {
+		t.Fatalf("PrepareContextForResponse: %v", err)
+	}
+	if len(result) >= len(messages) {
+		t.Errorf("kept %d messages, want the oldest trimmed", len(result))
+	}
+
+	// A response as large as the window can't fit any context
+	if _, err := cm.PrepareContextForResponse(messages, "", 1000, 1000, TruncateOldest); err == nil {
+		t.Error("expected an error for a response limit filling the window")
+	}
+}
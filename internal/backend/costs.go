@@ -2,10 +2,19 @@
 package backend
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/steveyegge/gastown/internal/util"
 )
 
 // CostTracker tracks API costs across invocations.
@@ -14,25 +23,51 @@ type CostTracker struct {
 	entries []CostEntry
 	total   float64
 
+	// daily holds totals for earlier days of the month whose entries
+	// were rolled up when the tracker was saved (keyed by YYYY-MM-DD).
+	daily map[string]float64
+
+	// path, when set, is where the tracker saves itself after each record.
+	path string
+
 	// Thresholds for warnings
 	WarnThreshold  float64 // Log warning when single invocation exceeds this
-	AlertThreshold float64 // Log alert when session total exceeds this
+	AlertThreshold float64 // Log alert when today's total exceeds this
+
+	// quiet skips threshold logging. Per-rig trackers are quiet because
+	// the global tracker already logs every invocation they record.
+	quiet bool
+
+	// teammate and teammateBudget hold an agent-team member to its own
+	// spend limit (see SetTeammateBudget).
+	teammate       string
+	teammateBudget float64
 }
 
 // CostEntry records a single API invocation cost.
 type CostEntry struct {
-	Timestamp    time.Time
-	Backend      string
-	Model        string
-	InputTokens  int
-	OutputTokens int
-	Cost         CostEstimate
+	Timestamp    time.Time    `json:"timestamp"`
+	Backend      string       `json:"backend"`
+	Model        string       `json:"model"`
+	InputTokens  int          `json:"input_tokens"`
+	OutputTokens int          `json:"output_tokens"`
+	Cost         CostEstimate `json:"cost"`
+
+	// Bead is the ID of the bead the invocation ran for, if any.
+	Bead string `json:"bead,omitempty"`
+
+	// Labels are the routed issue's labels, for per-area analytics.
+	Labels []string `json:"labels,omitempty"`
+
+	// Teammate is the agent-team member the invocation was made for.
+	Teammate string `json:"teammate,omitempty"`
 }
 
 // NewCostTracker creates a new cost tracker with default thresholds.
 func NewCostTracker() *CostTracker {
 	return &CostTracker{
 		entries:        make([]CostEntry, 0),
+		daily:          make(map[string]float64),
 		WarnThreshold:  0.10, // Warn on single invocation > $0.10
 		AlertThreshold: 5.00, // Alert when session total > $5.00
 	}
@@ -40,6 +75,12 @@ func NewCostTracker() *CostTracker {
 
 // Record records a cost entry and checks thresholds.
 func (ct *CostTracker) Record(backend, model string, result *InvokeResult, cost CostEstimate) {
+	ct.RecordForBead(backend, model, "", nil, result, cost)
+}
+
+// RecordForBead records a cost entry for the bead an invocation ran for,
+// tagged with the bead's labels, and checks thresholds.
+func (ct *CostTracker) RecordForBead(backend, model, bead string, labels []string, result *InvokeResult, cost CostEstimate) {
 	ct.mu.Lock()
 	defer ct.mu.Unlock()
 
@@ -50,10 +91,23 @@ func (ct *CostTracker) Record(backend, model string, result *InvokeResult, cost
 		InputTokens:  result.InputTokens,
 		OutputTokens: result.OutputTokens,
 		Cost:         cost,
+		Bead:         bead,
+		Labels:       labels,
+		Teammate:     ct.teammate,
 	}
 
-	ct.entries = append(ct.entries, entry)
-	ct.total += cost.TotalCost
+	if ct.path != "" {
+		if err := ct.recordSavedLocked(ct.path, entry); err != nil {
+			log.Printf("[costs] Failed to save cost tracker: %v", err)
+		}
+	} else {
+		ct.entries = append(ct.entries, entry)
+		ct.total += cost.TotalCost
+	}
+
+	if ct.quiet {
+		return
+	}
 
 	// Check thresholds
 	if cost.TotalCost > ct.WarnThreshold {
@@ -61,13 +115,14 @@ func (ct *CostTracker) Record(backend, model string, result *InvokeResult, cost
 			cost.TotalCost, ct.WarnThreshold, backend, model, result.InputTokens, result.OutputTokens)
 	}
 
-	if ct.total > ct.AlertThreshold {
-		log.Printf("[COST ALERT] Session total $%.2f exceeds threshold $%.2f",
-			ct.total, ct.AlertThreshold)
+	if today := ct.spentSinceLocked(dayKey(entry.Timestamp)); today > ct.AlertThreshold {
+		log.Printf("[COST ALERT] Today's total $%.2f exceeds threshold $%.2f",
+			today, ct.AlertThreshold)
 	}
 }
 
-// Total returns the total cost for this session.
+// Total returns the running total: this session's costs, plus earlier
+// runs' when the tracker was loaded from disk.
 func (ct *CostTracker) Total() float64 {
 	ct.mu.RLock()
 	defer ct.mu.RUnlock()
@@ -92,23 +147,52 @@ func (ct *CostTracker) Summary() map[string]BackendCostSummary {
 	summary := make(map[string]BackendCostSummary)
 
 	for _, entry := range ct.entries {
-		s := summary[entry.Backend]
-		s.Invocations++
-		s.InputTokens += entry.InputTokens
-		s.OutputTokens += entry.OutputTokens
-		s.TotalCost += entry.Cost.TotalCost
-		summary[entry.Backend] = s
+		summary[entry.Backend] = addToSummary(summary[entry.Backend], entry)
 	}
 
 	return summary
 }
 
+// SummaryByLabel returns a summary of costs by issue label, counting an
+// entry under each of its labels. Only labels with the given prefix (e.g.,
+// "area/") are included; entries with no matching label are grouped
+// under "(none)".
+func (ct *CostTracker) SummaryByLabel(prefix string) map[string]BackendCostSummary {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+
+	summary := make(map[string]BackendCostSummary)
+
+	for _, entry := range ct.entries {
+		matched := false
+		for _, label := range entry.Labels {
+			if strings.HasPrefix(label, prefix) {
+				matched = true
+				summary[label] = addToSummary(summary[label], entry)
+			}
+		}
+		if !matched {
+			summary["(none)"] = addToSummary(summary["(none)"], entry)
+		}
+	}
+
+	return summary
+}
+
+func addToSummary(s BackendCostSummary, entry CostEntry) BackendCostSummary {
+	s.Invocations++
+	s.InputTokens += entry.InputTokens
+	s.OutputTokens += entry.OutputTokens
+	s.TotalCost += entry.Cost.TotalCost
+	return s
+}
+
 // BackendCostSummary summarizes costs for a single backend.
 type BackendCostSummary struct {
-	Invocations  int
-	InputTokens  int
-	OutputTokens int
-	TotalCost    float64
+	Invocations  int     `json:"invocations"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	TotalCost    float64 `json:"total_cost"`
 }
 
 // Reset clears all cost tracking data.
@@ -116,7 +200,253 @@ func (ct *CostTracker) Reset() {
 	ct.mu.Lock()
 	defer ct.mu.Unlock()
 	ct.entries = make([]CostEntry, 0)
+	ct.daily = make(map[string]float64)
+	ct.total = 0
+}
+
+// SetTeammateBudget makes the tracker record for teammate, tagging each
+// entry with it, and has CheckTeammateBudget hold the teammate to budget
+// (USD). A budget of 0 removes the limit.
+func (ct *CostTracker) SetTeammateBudget(teammate string, budget float64) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.teammate = teammate
+	ct.teammateBudget = budget
+}
+
+// CheckTeammateBudget returns an error wrapping ErrBudgetExceeded if an
+// invocation estimated at estimate would take the teammate's spend today
+// past its budget. A tracker with a path reloads first, so spend the
+// teammate recorded from other gt processes counts.
+func (ct *CostTracker) CheckTeammateBudget(estimate float64) error {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	if ct.teammateBudget <= 0 {
+		return nil
+	}
+	if ct.path != "" {
+		if err := ct.loadLocked(ct.path); err != nil {
+			log.Printf("[costs] Could not refresh recorded spend: %v", err)
+		}
+	}
+
+	spent := ct.teammateSpentLocked(ct.teammate)
+	if spent+estimate > ct.teammateBudget {
+		return fmt.Errorf("%w: estimated cost $%.4f would exceed the $%.2f budget for teammate %s ($%.2f spent today)",
+			ErrBudgetExceeded, estimate, ct.teammateBudget, ct.teammate, spent)
+	}
+	return nil
+}
+
+// TeammateSpent returns the cost recorded today for a teammate.
+func (ct *CostTracker) TeammateSpent(teammate string) float64 {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.teammateSpentLocked(teammate)
+}
+
+// teammateSpentLocked implements TeammateSpent. Only today's entries are
+// kept per invocation, so earlier days don't count. The caller must hold ct.mu.
+func (ct *CostTracker) teammateSpentLocked(teammate string) float64 {
+	today := dayKey(time.Now())
+	var spent float64
+	for _, entry := range ct.entries {
+		if entry.Teammate == teammate && dayKey(entry.Timestamp) == today {
+			spent += entry.Cost.TotalCost
+		}
+	}
+	return spent
+}
+
+// SpentToday returns the cost recorded so far today (local time).
+func (ct *CostTracker) SpentToday() float64 {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.spentSinceLocked(dayKey(time.Now()))
+}
+
+// SpentThisMonth returns the cost recorded so far this month (local time).
+func (ct *CostTracker) SpentThisMonth() float64 {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.spentSinceLocked(dayKey(time.Now())[:len("2006-01")])
+}
+
+// spentSinceLocked sums costs for days whose key starts with prefix
+// (a YYYY-MM-DD day or a YYYY-MM month). The caller must hold ct.mu.
+func (ct *CostTracker) spentSinceLocked(prefix string) float64 {
+	var spent float64
+	for day, total := range ct.daily {
+		if strings.HasPrefix(day, prefix) {
+			spent += total
+		}
+	}
+	for _, entry := range ct.entries {
+		if strings.HasPrefix(dayKey(entry.Timestamp), prefix) {
+			spent += entry.Cost.TotalCost
+		}
+	}
+	return spent
+}
+
+// dayKey returns the local date of t as YYYY-MM-DD.
+func dayKey(t time.Time) string {
+	return t.Local().Format("2006-01-02")
+}
+
+// costTrackerFile is the persisted form of a CostTracker. Only today's
+// entries are kept; earlier days of the month are rolled up into Daily, and
+// older months are dropped (the costs ledger keeps the full history).
+type costTrackerFile struct {
+	Total   float64            `json:"total"`
+	Entries []CostEntry        `json:"entries"`
+	Daily   map[string]float64 `json:"daily,omitempty"`
+}
+
+// CostTrackerPath returns where a town persists its cost tracker.
+func CostTrackerPath(townRoot string) string {
+	return filepath.Join(townRoot, "mayor", "costs.json")
+}
+
+// Save writes the tracker to path atomically (temp file + rename),
+// applying the daily rollover. It replaces whatever is saved there, so it
+// is for rewriting the file (e.g. after Reset); recording into a tracker
+// with a path merges with the file instead.
+func (ct *CostTracker) Save(path string) error {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	fl, err := lockCostTrackerFile(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = fl.Unlock() }()
+	return ct.saveLocked(path)
+}
+
+// recordSavedLocked records entry into the tracker saved at path. Other gt
+// processes record into the same file, so under the file lock it reloads
+// the saved state, adds entry, and writes the result back; the tracker
+// ends up with everything recorded so far. If the file can't be locked or
+// read, entry is kept in memory only. The caller must hold ct.mu.
+func (ct *CostTracker) recordSavedLocked(path string, entry CostEntry) error {
+	fl, err := lockCostTrackerFile(path)
+	if err != nil {
+		ct.entries = append(ct.entries, entry)
+		ct.total += entry.Cost.TotalCost
+		return err
+	}
+	defer func() { _ = fl.Unlock() }()
+
+	if err := ct.loadLocked(path); err != nil {
+		log.Printf("[costs] Replacing unreadable cost tracker: %v", err)
+	}
+	ct.entries = append(ct.entries, entry)
+	ct.total += entry.Cost.TotalCost
+	return ct.saveLocked(path)
+}
+
+// lockCostTrackerFile takes the cross-process lock for the tracker saved
+// at path. The lock file sits beside it so readers aren't blocked.
+func lockCostTrackerFile(path string) (*flock.Flock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating cost tracker directory: %w", err)
+	}
+	fl := flock.New(path + ".lock")
+	if err := fl.Lock(); err != nil {
+		return nil, fmt.Errorf("acquiring cost tracker lock: %w", err)
+	}
+	return fl, nil
+}
+
+// saveLocked writes the tracker to path. The caller must hold ct.mu for
+// writing and the file lock.
+func (ct *CostTracker) saveLocked(path string) error {
+	ct.rollOverLocked(time.Now())
+	file := costTrackerFile{
+		Total:   ct.total,
+		Entries: ct.entries,
+		Daily:   ct.daily,
+	}
+	if err := util.EnsureDirAndWriteJSON(path, file); err != nil {
+		return fmt.Errorf("saving cost tracker: %w", err)
+	}
+	return nil
+}
+
+// Load replaces the tracker's state with what was saved at path. A missing
+// file leaves the tracker empty; a corrupt one also leaves it empty and
+// returns an error so the caller can report it.
+func (ct *CostTracker) Load(path string) error {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	return ct.loadLocked(path)
+}
+
+// Refresh reloads the tracker from the file it saves to, picking up spend
+// other gt processes recorded since it was loaded. It does nothing for a
+// tracker without a path.
+func (ct *CostTracker) Refresh() error {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	if ct.path == "" {
+		return nil
+	}
+	return ct.loadLocked(ct.path)
+}
+
+// loadLocked implements Load. Writes replace the file atomically, so no
+// file lock is needed to read it. The caller must hold ct.mu for writing.
+func (ct *CostTracker) loadLocked(path string) error {
+	ct.entries = make([]CostEntry, 0)
+	ct.daily = make(map[string]float64)
 	ct.total = 0
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("reading cost tracker: %w", err)
+	}
+
+	var file costTrackerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parsing cost tracker %s: %w", path, err)
+	}
+
+	ct.total = file.Total
+	if file.Entries != nil {
+		ct.entries = file.Entries
+	}
+	for day, total := range file.Daily {
+		ct.daily[day] = total
+	}
+	ct.rollOverLocked(time.Now())
+	return nil
+}
+
+// rollOverLocked folds entries from before today into daily totals and
+// drops totals from before this month. The caller must hold ct.mu.
+func (ct *CostTracker) rollOverLocked(now time.Time) {
+	today := dayKey(now)
+	month := today[:len("2006-01")]
+
+	kept := ct.entries[:0]
+	for _, entry := range ct.entries {
+		if day := dayKey(entry.Timestamp); day != today {
+			ct.daily[day] += entry.Cost.TotalCost
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	ct.entries = kept
+
+	for day := range ct.daily {
+		if !strings.HasPrefix(day, month) {
+			delete(ct.daily, day)
+		}
+	}
 }
 
 // FormatSummary returns a human-readable cost summary.
@@ -124,14 +454,21 @@ func (ct *CostTracker) FormatSummary() string {
 	summary := ct.Summary()
 	total := ct.Total()
 
-	if len(summary) == 0 {
+	if len(summary) == 0 && total == 0 {
 		return "No API costs recorded"
 	}
 
 	result := fmt.Sprintf("API Cost Summary (Total: $%.4f)\n", total)
 	result += "─────────────────────────────────────\n"
 
-	for backend, s := range summary {
+	backends := make([]string, 0, len(summary))
+	for backend := range summary {
+		backends = append(backends, backend)
+	}
+	sort.Strings(backends)
+
+	for _, backend := range backends {
+		s := summary[backend]
 		result += fmt.Sprintf("  %s: %d invocations, %d in / %d out tokens, $%.4f\n",
 			backend, s.Invocations, s.InputTokens, s.OutputTokens, s.TotalCost)
 	}
@@ -145,14 +482,107 @@ var (
 	globalCostTrackerOnce sync.Once
 )
 
-// GetCostTracker returns the global cost tracker.
+// costTrackerPath is where the global cost tracker persists itself.
+var (
+	costTrackerPathMu sync.Mutex
+	costTrackerPath   string
+)
+
+// SetCostTrackerPath makes the global cost tracker persist to path (see
+// CostTrackerPath), loading it on first use and saving after each record.
+// Call it before the first GetCostTracker.
+func SetCostTrackerPath(path string) {
+	costTrackerPathMu.Lock()
+	defer costTrackerPathMu.Unlock()
+	costTrackerPath = path
+}
+
+// GetCostTracker returns the global cost tracker. If SetCostTrackerPath
+// was called, the tracker starts from the saved state; a corrupt file is
+// logged and replaced on the next save.
 func GetCostTracker() *CostTracker {
 	globalCostTrackerOnce.Do(func() {
 		globalCostTracker = NewCostTracker()
+
+		costTrackerPathMu.Lock()
+		path := costTrackerPath
+		costTrackerPathMu.Unlock()
+		if path == "" {
+			return
+		}
+		if err := globalCostTracker.Load(path); err != nil {
+			log.Printf("[costs] Starting fresh: %v", err)
+		}
+		globalCostTracker.path = path
 	})
 	return globalCostTracker
 }
 
+// ResetCostTrackerForTesting discards the global cost tracker and its path.
+// This is intended for use in tests only.
+func ResetCostTrackerForTesting() {
+	globalCostTrackerOnce = sync.Once{}
+	globalCostTracker = nil
+	SetCostTrackerPath("")
+}
+
+// Per-rig cost trackers, keyed by rig path. They record alongside the
+// global tracker so one rig's report doesn't include other rigs' spend.
+var (
+	rigCostTrackersMu sync.Mutex
+	rigCostTrackers   = make(map[string]*CostTracker)
+)
+
+// RigCostTrackerPath returns where a rig persists its cost tracker.
+func RigCostTrackerPath(rigPath string) string {
+	return filepath.Join(rigPath, ".runtime", "costs.json")
+}
+
+// GetRigCostTracker returns the cost tracker for a rig, creating it on
+// first use from the state saved at RigCostTrackerPath; it saves after
+// each record, like the global tracker. An empty rigPath returns the
+// global tracker.
+func GetRigCostTracker(rigPath string) *CostTracker {
+	if rigPath == "" {
+		return GetCostTracker()
+	}
+
+	rigCostTrackersMu.Lock()
+	defer rigCostTrackersMu.Unlock()
+	ct, ok := rigCostTrackers[rigPath]
+	if !ok {
+		ct = NewCostTracker()
+		ct.quiet = true
+		path := RigCostTrackerPath(rigPath)
+		if err := ct.Load(path); err != nil {
+			log.Printf("[costs] Starting fresh for rig %s: %v", rigPath, err)
+		}
+		ct.path = path
+		rigCostTrackers[rigPath] = ct
+	}
+	return ct
+}
+
+// RigCostTrackers returns the per-rig trackers created so far, keyed by
+// rig path. The map is a copy; the trackers are shared.
+func RigCostTrackers() map[string]*CostTracker {
+	rigCostTrackersMu.Lock()
+	defer rigCostTrackersMu.Unlock()
+	trackers := make(map[string]*CostTracker, len(rigCostTrackers))
+	for path, ct := range rigCostTrackers {
+		trackers[path] = ct
+	}
+	return trackers
+}
+
+// ResetRigCostTrackersForTesting removes all per-rig trackers.
+// This is intended for use in tests only.
+func ResetRigCostTrackersForTesting() {
+	rigCostTrackersMu.Lock()
+	defer rigCostTrackersMu.Unlock()
+	rigCostTrackers = make(map[string]*CostTracker)
+}
+
 // EstimateTaskCost estimates the cost for a task based on hints.
 func EstimateTaskCost(hints *RoutingHints, backend AgentBackend) CostEstimate {
 	if hints == nil || backend == nil {
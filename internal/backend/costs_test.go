@@ -0,0 +1,283 @@
+package backend
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRigCostTrackersConcurrentRecords(t *testing.T) {
+	ResetRigCostTrackersForTesting()
+	t.Cleanup(ResetRigCostTrackersForTesting)
+	global := GetCostTracker()
+	global.Reset()
+	t.Cleanup(global.Reset)
+
+	townRoot := t.TempDir()
+	rigs := []string{filepath.Join(townRoot, "alpha"), filepath.Join(townRoot, "beta"), filepath.Join(townRoot, "gamma")}
+	const perRig = 50
+
+	var wg sync.WaitGroup
+	for i, rig := range rigs {
+		cost := float64(i+1) * 0.01 // alpha $0.01, beta $0.02, gamma $0.03
+		for n := 0; n < perRig; n++ {
+			wg.Add(1)
+			go func(rig string, cost float64) {
+				defer wg.Done()
+				result := &InvokeResult{InputTokens: 100, OutputTokens: 10}
+				est := CostEstimate{TotalCost: cost, Currency: "USD"}
+				global.Record("claude", "haiku", result, est)
+				GetRigCostTracker(rig).Record("claude", "haiku", result, est)
+			}(rig, cost)
+		}
+	}
+	wg.Wait()
+
+	trackers := RigCostTrackers()
+	if len(trackers) != len(rigs) {
+		t.Fatalf("got %d rig trackers, want %d", len(trackers), len(rigs))
+	}
+	for i, rig := range rigs {
+		want := float64(i+1) * 0.01 * perRig
+		if got := trackers[rig].Total(); math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s total = %.4f, want %.4f", rig, got, want)
+		}
+		if got := len(trackers[rig].Entries()); got != perRig {
+			t.Errorf("%s entries = %d, want %d", rig, got, perRig)
+		}
+
+		saved := NewCostTracker()
+		if err := saved.Load(RigCostTrackerPath(rig)); err != nil {
+			t.Fatalf("Load %s: %v", rig, err)
+		}
+		if got := saved.Total(); math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s saved total = %.4f, want %.4f", rig, got, want)
+		}
+	}
+	if got, want := global.Total(), 0.06*perRig; math.Abs(got-want) > 1e-9 {
+		t.Errorf("global total = %.4f, want %.4f", got, want)
+	}
+}
+
+func TestGetRigCostTracker(t *testing.T) {
+	ResetRigCostTrackersForTesting()
+	t.Cleanup(ResetRigCostTrackersForTesting)
+
+	if GetRigCostTracker("") != GetCostTracker() {
+		t.Error("empty rig path should return the global tracker")
+	}
+	townRoot := t.TempDir()
+	alpha := filepath.Join(townRoot, "alpha")
+	a := GetRigCostTracker(alpha)
+	if a == GetCostTracker() {
+		t.Error("rig tracker should be separate from the global tracker")
+	}
+	if GetRigCostTracker(alpha) != a {
+		t.Error("same rig path should return the same tracker")
+	}
+	if GetRigCostTracker(filepath.Join(townRoot, "beta")) == a {
+		t.Error("different rigs should not share a tracker")
+	}
+}
+
+func TestCostTrackerSaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mayor", "costs.json")
+
+	ct := NewCostTracker()
+	ct.RecordForBead("claude", "haiku", "gt-abc123", []string{"area/docs"}, &InvokeResult{InputTokens: 100, OutputTokens: 50}, CostEstimate{TotalCost: 0.25})
+	ct.Record("grok", "grok-3", &InvokeResult{InputTokens: 10, OutputTokens: 5}, CostEstimate{TotalCost: 0.5})
+	if err := ct.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Atomic write leaves no temp files behind, only the file and its lock
+	files, _ := os.ReadDir(filepath.Dir(path))
+	for _, f := range files {
+		if name := f.Name(); name != "costs.json" && name != "costs.json.lock" {
+			t.Errorf("mayor dir has unexpected file %s", name)
+		}
+	}
+
+	loaded := NewCostTracker()
+	if err := loaded.Load(path); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.Total() != 0.75 || loaded.SpentToday() != 0.75 || loaded.SpentThisMonth() != 0.75 {
+		t.Errorf("loaded total=%v today=%v month=%v, want 0.75 each", loaded.Total(), loaded.SpentToday(), loaded.SpentThisMonth())
+	}
+	entries := loaded.Entries()
+	if len(entries) != 2 || entries[0].Backend != "claude" || entries[0].Bead != "gt-abc123" || entries[0].Labels[0] != "area/docs" {
+		t.Errorf("loaded entries = %+v", entries)
+	}
+}
+
+func TestCostTrackerDailyRollover(t *testing.T) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.Local)
+	lastMonth := today.AddDate(0, -1, 0)
+
+	ct := NewCostTracker()
+	ct.entries = []CostEntry{
+		{Timestamp: lastMonth, Backend: "claude", Cost: CostEstimate{TotalCost: 4}},
+		{Timestamp: today, Backend: "claude", Cost: CostEstimate{TotalCost: 1}},
+	}
+	ct.total = 5
+	earlierThisMonth := ""
+	if today.Day() > 1 {
+		earlierThisMonth = dayKey(today.AddDate(0, 0, -1))
+		ct.entries = append(ct.entries, CostEntry{Timestamp: today.AddDate(0, 0, -1), Backend: "grok", Cost: CostEstimate{TotalCost: 2}})
+		ct.total += 2
+	}
+
+	path := filepath.Join(t.TempDir(), "costs.json")
+	if err := ct.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded := NewCostTracker()
+	if err := loaded.Load(path); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if got := len(loaded.Entries()); got != 1 {
+		t.Errorf("kept %d entries, want only today's", got)
+	}
+	if got := loaded.SpentToday(); got != 1 {
+		t.Errorf("SpentToday = %v, want 1", got)
+	}
+	wantMonth := 1.0
+	if earlierThisMonth != "" {
+		wantMonth = 3
+		if loaded.daily[earlierThisMonth] != 2 {
+			t.Errorf("daily[%s] = %v, want 2", earlierThisMonth, loaded.daily[earlierThisMonth])
+		}
+	}
+	if got := loaded.SpentThisMonth(); got != wantMonth {
+		t.Errorf("SpentThisMonth = %v, want %v (last month excluded)", got, wantMonth)
+	}
+	if got := loaded.Total(); got != ct.total {
+		t.Errorf("Total = %v, want running total %v", got, ct.total)
+	}
+}
+
+func TestCostTrackerLoadMissingOrCorrupt(t *testing.T) {
+	dir := t.TempDir()
+
+	ct := NewCostTracker()
+	if err := ct.Load(filepath.Join(dir, "missing.json")); err != nil {
+		t.Errorf("Load(missing) = %v, want nil", err)
+	}
+
+	corrupt := filepath.Join(dir, "costs.json")
+	if err := os.WriteFile(corrupt, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ct.Record("claude", "haiku", &InvokeResult{}, CostEstimate{TotalCost: 1})
+	if err := ct.Load(corrupt); err == nil {
+		t.Error("Load(corrupt) = nil, want error")
+	}
+	if ct.Total() != 0 || len(ct.Entries()) != 0 {
+		t.Errorf("after corrupt load total=%v entries=%d, want fresh", ct.Total(), len(ct.Entries()))
+	}
+}
+
+func TestGetCostTrackerPersists(t *testing.T) {
+	t.Cleanup(ResetCostTrackerForTesting)
+	path := CostTrackerPath(t.TempDir())
+
+	ResetCostTrackerForTesting()
+	SetCostTrackerPath(path)
+	GetCostTracker().Record("claude", "haiku", &InvokeResult{}, CostEstimate{TotalCost: 0.5})
+
+	// A later run starts from the saved total
+	ResetCostTrackerForTesting()
+	SetCostTrackerPath(path)
+	ct := GetCostTracker()
+	ct.Record("claude", "haiku", &InvokeResult{}, CostEstimate{TotalCost: 0.25})
+	if ct.Total() != 0.75 {
+		t.Errorf("Total = %v, want 0.75 across runs", ct.Total())
+	}
+
+	// A corrupt file is tolerated
+	if err := os.WriteFile(path, []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ResetCostTrackerForTesting()
+	SetCostTrackerPath(path)
+	if got := GetCostTracker().Total(); got != 0 {
+		t.Errorf("Total after corrupt file = %v, want 0", got)
+	}
+}
+
+func TestCostTrackerMergesConcurrentProcesses(t *testing.T) {
+	path := CostTrackerPath(t.TempDir())
+	seed := NewCostTracker()
+	seed.Record("claude", "haiku", &InvokeResult{}, CostEstimate{TotalCost: 1})
+	if err := seed.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Each tracker stands in for a gt process that loaded the file at startup
+	const processes, perProcess = 4, 25
+	trackers := make([]*CostTracker, processes)
+	for i := range trackers {
+		trackers[i] = NewCostTracker()
+		if err := trackers[i].Load(path); err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		trackers[i].path = path
+	}
+
+	var wg sync.WaitGroup
+	for _, ct := range trackers {
+		for n := 0; n < perProcess; n++ {
+			wg.Add(1)
+			go func(ct *CostTracker) {
+				defer wg.Done()
+				ct.Record("grok", "grok-3", &InvokeResult{}, CostEstimate{TotalCost: 0.01})
+			}(ct)
+		}
+	}
+	wg.Wait()
+
+	want := 1 + 0.01*processes*perProcess
+	saved := NewCostTracker()
+	if err := saved.Load(path); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := saved.Total(); math.Abs(got-want) > 1e-9 {
+		t.Errorf("saved total = %.4f, want %.4f (no records lost)", got, want)
+	}
+	if got := len(saved.Entries()); got != 1+processes*perProcess {
+		t.Errorf("saved entries = %d, want %d", got, 1+processes*perProcess)
+	}
+
+	// A tracker sees other processes' spend after Refresh
+	stale := NewCostTracker()
+	stale.path = path
+	if err := stale.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if got := stale.Total(); math.Abs(got-want) > 1e-9 {
+		t.Errorf("refreshed total = %.4f, want %.4f", got, want)
+	}
+}
+
+func TestGetRigCostTrackerPersists(t *testing.T) {
+	ResetRigCostTrackersForTesting()
+	t.Cleanup(ResetRigCostTrackersForTesting)
+	rigPath := filepath.Join(t.TempDir(), "alpha")
+
+	GetRigCostTracker(rigPath).Record("grok", "grok-3", &InvokeResult{}, CostEstimate{TotalCost: 0.5})
+
+	// A later run starts from the rig's saved total
+	ResetRigCostTrackersForTesting()
+	ct := GetRigCostTracker(rigPath)
+	ct.Record("grok", "grok-3", &InvokeResult{}, CostEstimate{TotalCost: 0.25})
+	if ct.Total() != 0.75 {
+		t.Errorf("Total = %v, want 0.75 across runs", ct.Total())
+	}
+}
@@ -0,0 +1,92 @@
+// Package backend provides typed errors shared by API backends.
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/steveyegge/gastown/internal/util"
+)
+
+// ErrModelUnavailable indicates the provider rejected a model as unknown,
+// retired, or deprecated. Retrying with the same model will not succeed.
+// Backends wrap it so callers can test with errors.Is.
+var ErrModelUnavailable = errors.New("model unavailable")
+
+// ErrCapabilityUnsupported indicates a request needs a feature the backend
+// doesn't advertise in Capabilities (see CheckInvokeOptions).
+var ErrCapabilityUnsupported = errors.New("capability not supported")
+
+// ErrBudgetExceeded indicates a task would push API spend past a
+// configured budget: the session budget or a teammate's budget.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// UnavailableModel records a model a provider reported as unavailable.
+type UnavailableModel struct {
+	Backend    string    `json:"backend"`
+	Model      string    `json:"model"`
+	Reason     string    `json:"reason,omitempty"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// UnavailableModelsPath returns where a town records unavailable models.
+func UnavailableModelsPath(townRoot string) string {
+	return filepath.Join(townRoot, ".runtime", "unavailable-models.json")
+}
+
+// LoadUnavailableModels reads the models recorded as unavailable for a town.
+// Returns an empty list (not an error) if nothing has been recorded.
+func LoadUnavailableModels(townRoot string) ([]UnavailableModel, error) {
+	data, err := os.ReadFile(UnavailableModelsPath(townRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading unavailable models: %w", err)
+	}
+
+	var models []UnavailableModel
+	if err := json.Unmarshal(data, &models); err != nil {
+		return nil, fmt.Errorf("parsing unavailable models: %w", err)
+	}
+	return models, nil
+}
+
+// RecordUnavailableModel adds a model to the town's unavailable list,
+// replacing any earlier record for the same backend/model. The list is
+// re-read and rewritten under a file lock, so records from concurrent
+// polecats aren't lost.
+func RecordUnavailableModel(townRoot string, m UnavailableModel) error {
+	path := UnavailableModelsPath(townRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating runtime dir: %w", err)
+	}
+	fl := flock.New(path + ".lock")
+	if err := fl.Lock(); err != nil {
+		return fmt.Errorf("acquiring unavailable models lock: %w", err)
+	}
+	defer func() { _ = fl.Unlock() }()
+
+	models, err := LoadUnavailableModels(townRoot)
+	if err != nil {
+		return err
+	}
+
+	kept := models[:0]
+	for _, existing := range models {
+		if existing.Backend != m.Backend || existing.Model != m.Model {
+			kept = append(kept, existing)
+		}
+	}
+	kept = append(kept, m)
+
+	if err := util.EnsureDirAndWriteJSON(path, kept); err != nil {
+		return fmt.Errorf("writing unavailable models: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package backend
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRecordUnavailableModel(t *testing.T) {
+	townRoot := t.TempDir()
+
+	models, err := LoadUnavailableModels(townRoot)
+	if err != nil || len(models) != 0 {
+		t.Fatalf("LoadUnavailableModels on empty town = %v, %v", models, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := RecordUnavailableModel(townRoot, UnavailableModel{Backend: "grok", Model: "grok-beta", Reason: "retired"}); err != nil {
+			t.Fatalf("RecordUnavailableModel: %v", err)
+		}
+	}
+	if err := RecordUnavailableModel(townRoot, UnavailableModel{Backend: "openai", Model: "gpt-4"}); err != nil {
+		t.Fatalf("RecordUnavailableModel: %v", err)
+	}
+
+	models, err = LoadUnavailableModels(townRoot)
+	if err != nil {
+		t.Fatalf("LoadUnavailableModels: %v", err)
+	}
+	if len(models) != 2 {
+		t.Errorf("expected 2 deduplicated records, got %d: %+v", len(models), models)
+	}
+}
+
+func TestRecordUnavailableModelConcurrent(t *testing.T) {
+	townRoot := t.TempDir()
+
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- RecordUnavailableModel(townRoot, UnavailableModel{Backend: "grok", Model: fmt.Sprintf("grok-%d", i)})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("RecordUnavailableModel: %v", err)
+		}
+	}
+
+	models, err := LoadUnavailableModels(townRoot)
+	if err != nil {
+		t.Fatalf("LoadUnavailableModels: %v", err)
+	}
+	if len(models) != writers {
+		t.Errorf("recorded %d models, want %d (concurrent records lost)", len(models), writers)
+	}
+}
@@ -10,10 +10,11 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/steveyegge/gastown/internal/backend"
+	"github.com/steveyegge/gastown/internal/backend/openaicompat"
 )
 
 // Model definitions with context windows and pricing.
@@ -43,6 +44,15 @@ var (
 		"grok-2-vision-1212": {2.00, 10.00},
 		"grok-beta":          {5.00, 15.00},
 	}
+
+	// Aliases maps friendly names (including gt tiers) to model IDs.
+	Aliases = map[string]string{
+		"grok-fast": "grok-3-mini",
+		"grok":      "grok-3",
+		"haiku":     "grok-3-mini",
+		"sonnet":    "grok-3",
+		"opus":      "grok-4",
+	}
 )
 
 const (
@@ -55,10 +65,16 @@ const (
 
 // Backend implements backend.AgentBackend for xAI's Grok API.
 type Backend struct {
-	apiKey      string
-	baseURL     string
-	client      *http.Client
-	rateLimiter *rateLimiter
+	apiKey  string
+	baseURL string
+	client  *http.Client
+	limits  backend.Limits
+	limiter *backend.Limiter
+	retry   backend.RetryPolicy
+
+	// search is the default search_parameters, sent when an invocation
+	// doesn't set its own (nil disables live search).
+	search json.RawMessage
 }
 
 // Option configures the Grok backend.
@@ -81,7 +97,41 @@ func WithHTTPClient(client *http.Client) Option {
 // WithRateLimit sets the rate limit (requests per minute).
 func WithRateLimit(rpm int) Option {
 	return func(b *Backend) {
-		b.rateLimiter = newRateLimiter(rpm, time.Minute)
+		b.limits.RequestsPerMinute = rpm
+	}
+}
+
+// WithModelLimits sets per-model request rates and concurrency caps. Keys
+// may be aliases; they're resolved to model IDs.
+func WithModelLimits(limits map[string]backend.ModelLimits) Option {
+	return func(b *Backend) {
+		b.limits.Models = make(map[string]backend.ModelLimits, len(limits))
+		for model, ml := range limits {
+			b.limits.Models[resolveModel(model)] = ml
+		}
+	}
+}
+
+// WithLiveSearch turns on xAI live search for every invocation that doesn't
+// set InvokeOptions.SearchParameters. mode is "auto" (the model decides) or
+// "on"; "" or "off" leaves live search off. Source URLs are returned in
+// InvokeResult.Citations.
+func WithLiveSearch(mode string) Option {
+	return func(b *Backend) {
+		if mode == "" || mode == "off" {
+			b.search = nil
+			return
+		}
+		b.search, _ = json.Marshal(apiSearchParameters{Mode: mode, ReturnCitations: true})
+	}
+}
+
+// WithRetry sets how many attempts a request gets and the base delay for
+// exponential backoff between them (see backend.DoWithRetry).
+func WithRetry(attempts int, baseDelay time.Duration) Option {
+	return func(b *Backend) {
+		b.retry.MaxAttempts = attempts
+		b.retry.BaseDelay = baseDelay
 	}
 }
 
@@ -94,15 +144,16 @@ func New(opts ...Option) (*Backend, error) {
 	}
 
 	b := &Backend{
-		apiKey:      apiKey,
-		baseURL:     defaultBaseURL,
-		client:      &http.Client{Timeout: defaultTimeout},
-		rateLimiter: newRateLimiter(60, time.Minute), // Default 60 RPM
+		apiKey:  apiKey,
+		baseURL: defaultBaseURL,
+		client:  &http.Client{Timeout: defaultTimeout},
+		retry:   backend.DefaultRetryPolicy,
 	}
 
 	for _, opt := range opts {
 		opt(b)
 	}
+	b.limiter = backend.NewLimiter(b.limits)
 
 	return b, nil
 }
@@ -114,7 +165,7 @@ func (b *Backend) Name() string {
 
 // Capabilities returns feature flags.
 func (b *Backend) Capabilities() backend.Capability {
-	return backend.CapStreaming | backend.CapTools | backend.CapLongContext
+	return backend.CapStreaming | backend.CapTools | backend.CapLongContext | backend.CapStructuredOutput
 }
 
 // AvailableModels returns supported model IDs.
@@ -133,7 +184,7 @@ func (b *Backend) DefaultModel() string {
 
 // MaxContextTokens returns the context window for a model.
 func (b *Backend) MaxContextTokens(model string) int {
-	if ctx, ok := Models[model]; ok {
+	if ctx, ok := Models[resolveModel(model)]; ok {
 		return ctx
 	}
 	return 131072 // Default for unknown models
@@ -142,17 +193,21 @@ func (b *Backend) MaxContextTokens(model string) int {
 // apiRequest is the request body for the chat completions API.
 // xAI uses OpenAI-compatible format.
 type apiRequest struct {
-	Model       string       `json:"model"`
-	Messages    []apiMessage `json:"messages"`
-	MaxTokens   int          `json:"max_tokens,omitempty"`
-	Temperature float64      `json:"temperature,omitempty"`
-	Stream      bool         `json:"stream,omitempty"`
+	Model          string                       `json:"model"`
+	Messages       []openaicompat.Message       `json:"messages"`
+	MaxTokens      int                          `json:"max_tokens,omitempty"`
+	Temperature    float64                      `json:"temperature,omitempty"`
+	Stream         bool                         `json:"stream,omitempty"`
+	ResponseFormat *openaicompat.ResponseFormat `json:"response_format,omitempty"`
+	Tools          []openaicompat.Tool          `json:"tools,omitempty"`
+
+	SearchParameters json.RawMessage `json:"search_parameters,omitempty"`
 }
 
-// apiMessage is a message in the API request.
-type apiMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+// apiSearchParameters configures live search.
+type apiSearchParameters struct {
+	Mode            string `json:"mode"`
+	ReturnCitations bool   `json:"return_citations"`
 }
 
 // apiResponse is the response from the chat completions API.
@@ -162,15 +217,16 @@ type apiResponse struct {
 	Created int64  `json:"created"`
 	Model   string `json:"model"`
 	Choices []struct {
-		Index        int        `json:"index"`
-		Message      apiMessage `json:"message"`
-		FinishReason string     `json:"finish_reason"`
+		Index        int                  `json:"index"`
+		Message      openaicompat.Message `json:"message"`
+		FinishReason string               `json:"finish_reason"`
 	} `json:"choices"`
 	Usage struct {
 		PromptTokens     int `json:"prompt_tokens"`
 		CompletionTokens int `json:"completion_tokens"`
 		TotalTokens      int `json:"total_tokens"`
 	} `json:"usage"`
+	Citations []string `json:"citations"`
 }
 
 // apiError is an error response from the API.
@@ -184,13 +240,15 @@ type apiError struct {
 
 // Invoke sends a prompt and returns the response.
 func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts backend.InvokeOptions) (*backend.InvokeResult, error) {
-	// Wait for rate limiter
-	if err := b.rateLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit: %w", err)
+	// Wait for the model's rate and concurrency limits
+	release, err := b.acquire(ctx, opts)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
 	// Prepare request
-	model := opts.Model
+	model := resolveModel(opts.Model)
 	if model == "" {
 		model = defaultModel
 	}
@@ -205,21 +263,20 @@ func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts b
 		temp = defaultTemperature
 	}
 
-	// Convert messages
-	var apiMessages []apiMessage
-	for _, msg := range messages {
-		apiMessages = append(apiMessages, apiMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
-	}
-
 	reqBody := apiRequest{
 		Model:       model,
-		Messages:    apiMessages,
+		Messages:    openaicompat.ToMessages(messages, opts.SystemMsg),
 		MaxTokens:   maxTokens,
 		Temperature: temp,
 		Stream:      false,
+
+		ResponseFormat: openaicompat.ToResponseFormat(opts.ResponseFormat),
+		Tools:          openaicompat.ToTools(opts.Tools),
+
+		SearchParameters: b.search,
+	}
+	if len(opts.SearchParameters) > 0 {
+		reqBody.SearchParameters = opts.SearchParameters
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -227,48 +284,18 @@ func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts b
 		return nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	// Create HTTP request - xAI uses /v1/chat/completions endpoint
-	req, err := http.NewRequestWithContext(ctx, "POST", b.baseURL+"/v1/chat/completions", bytes.NewReader(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+b.apiKey)
-
-	// Send request with retry
-	var resp *http.Response
-	var lastErr error
-	for attempt := 0; attempt < 3; attempt++ {
-		resp, err = b.client.Do(req)
+	// Send request to xAI's /v1/chat/completions endpoint, retrying transient failures
+	resp, err := backend.DoWithRetry(ctx, b.client, b.retry, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", b.baseURL+"/v1/chat/completions", bytes.NewReader(jsonBody))
 		if err != nil {
-			lastErr = err
-			time.Sleep(time.Duration(attempt+1) * time.Second)
-			continue
-		}
-
-		// Check for rate limiting
-		if resp.StatusCode == http.StatusTooManyRequests {
-			resp.Body.Close()
-			retryAfter := time.Duration(attempt+1) * 10 * time.Second
-			if ra := resp.Header.Get("Retry-After"); ra != "" {
-				if d, err := time.ParseDuration(ra + "s"); err == nil {
-					retryAfter = d
-				}
-			}
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(retryAfter):
-				continue
-			}
+			return nil, err
 		}
-
-		break
-	}
-
-	if resp == nil {
-		return nil, fmt.Errorf("request failed after retries: %w", lastErr)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+b.apiKey)
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -282,6 +309,9 @@ func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts b
 	if resp.StatusCode != http.StatusOK {
 		var apiErr apiError
 		if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error.Message != "" {
+			if isModelNotFound(resp.StatusCode, apiErr) {
+				return nil, fmt.Errorf("%w: %s: %s", backend.ErrModelUnavailable, model, apiErr.Error.Message)
+			}
 			return nil, fmt.Errorf("API error (%s): %s", apiErr.Error.Type, apiErr.Error.Message)
 		}
 		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
@@ -300,8 +330,14 @@ func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts b
 	}
 
 	finishReason := ""
+	var toolCalls []backend.ToolCall
 	if len(apiResp.Choices) > 0 {
 		finishReason = apiResp.Choices[0].FinishReason
+		toolCalls = openaicompat.FromToolCalls(apiResp.Choices[0].Message.ToolCalls)
+	}
+
+	if err := backend.ValidateStructuredOutput(content, opts.ResponseFormat); err != nil {
+		return nil, err
 	}
 
 	return &backend.InvokeResult{
@@ -310,31 +346,22 @@ func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts b
 		InputTokens:  apiResp.Usage.PromptTokens,
 		OutputTokens: apiResp.Usage.CompletionTokens,
 		FinishReason: finishReason,
+		ToolCalls:    toolCalls,
+		Citations:    apiResp.Citations,
 	}, nil
 }
 
 // InvokeStream returns a streaming response channel.
 func (b *Backend) InvokeStream(ctx context.Context, messages []backend.Message, opts backend.InvokeOptions) (<-chan backend.StreamChunk, error) {
 	// For now, implement as non-streaming with single chunk
-	ch := make(chan backend.StreamChunk, 1)
-
-	go func() {
-		defer close(ch)
-
-		result, err := b.Invoke(ctx, messages, opts)
-		if err != nil {
-			ch <- backend.StreamChunk{Error: err, Done: true}
-			return
-		}
-
-		ch <- backend.StreamChunk{Content: result.Content, Done: true}
-	}()
-
-	return ch, nil
+	return backend.StreamInvoke(ctx, func(ctx context.Context) (*backend.InvokeResult, error) {
+		return b.Invoke(ctx, messages, opts)
+	}), nil
 }
 
 // EstimateCost estimates the cost for given token counts.
 func (b *Backend) EstimateCost(inputTokens, outputTokens int, model string) backend.CostEstimate {
+	model = resolveModel(model)
 	if model == "" {
 		model = defaultModel
 	}
@@ -358,83 +385,78 @@ func (b *Backend) EstimateCost(inputTokens, outputTokens int, model string) back
 }
 
 // CountTokens estimates token count for messages.
-// Uses a simple character-based heuristic (4 chars ≈ 1 token).
+// Delegates to the shared token counter for the model's family.
 func (b *Backend) CountTokens(messages []backend.Message, model string) (int, error) {
-	var totalChars int
-	for _, msg := range messages {
-		totalChars += len(msg.Content)
-		totalChars += len(msg.Role) + 10 // Role overhead
+	model = resolveModel(model)
+	if model == "" {
+		model = defaultModel
 	}
-	// Rough estimate: 4 characters per token
-	return totalChars / 4, nil
+	return backend.CountTokens(messages, model)
 }
 
-// Healthy checks if the backend is reachable.
+// Healthy checks that the API key is well formed and, when ctx has a
+// deadline, that the API accepts it by listing models.
 func (b *Backend) Healthy(ctx context.Context) error {
-	// Simple health check - verify API key format
+	// Cheap pre-flight: verify API key format
 	if len(b.apiKey) < 10 {
 		return fmt.Errorf("invalid API key format")
 	}
-	return nil
-}
+	if !backend.LiveCheckAllowed(ctx) {
+		return nil
+	}
 
-// rateLimiter implements a simple token bucket rate limiter.
-type rateLimiter struct {
-	mu             sync.Mutex
-	tokens         int
-	maxTokens      int
-	refillInterval time.Duration
-	lastRefill     time.Time
+	req, err := http.NewRequestWithContext(ctx, "GET", b.baseURL+"/v1/models", nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	return backend.CheckEndpoint(b.client, req)
 }
 
-func newRateLimiter(maxTokens int, interval time.Duration) *rateLimiter {
-	return &rateLimiter{
-		tokens:         maxTokens,
-		maxTokens:      maxTokens,
-		refillInterval: interval,
-		lastRefill:     time.Now(),
+// acquire waits until the limiter admits a request to the model opts
+// names. The caller must call the returned release when done.
+func (b *Backend) acquire(ctx context.Context, opts backend.InvokeOptions) (func(), error) {
+	model := resolveModel(opts.Model)
+	if model == "" {
+		model = defaultModel
+	}
+	release, err := b.limiter.Acquire(ctx, model)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit: %w", err)
 	}
+	return release, nil
 }
 
-func (r *rateLimiter) Wait(ctx context.Context) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	// Refill tokens based on elapsed time
-	now := time.Now()
-	elapsed := now.Sub(r.lastRefill)
-	if elapsed >= r.refillInterval {
-		r.tokens = r.maxTokens
-		r.lastRefill = now
-	} else {
-		// Partial refill
-		refillAmount := int(float64(r.maxTokens) * (float64(elapsed) / float64(r.refillInterval)))
-		r.tokens = min(r.maxTokens, r.tokens+refillAmount)
-		if refillAmount > 0 {
-			r.lastRefill = now
-		}
-	}
+// Limits returns the request limits in effect.
+func (b *Backend) Limits() backend.Limits {
+	return b.limiter.Limits()
+}
 
-	if r.tokens > 0 {
-		r.tokens--
-		return nil
-	}
+// resolveModel maps a friendly name like "opus" to a full model ID.
+func resolveModel(model string) string {
+	return backend.ResolveModel("grok", model, Aliases)
+}
 
-	// Wait for next token
-	waitTime := r.refillInterval - elapsed
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-time.After(waitTime):
-		r.tokens = r.maxTokens - 1
-		r.lastRefill = time.Now()
-		return nil
-	}
+// isModelNotFound reports whether an API error means the model is unknown
+// or retired. xAI follows OpenAI's "model_not_found" code, but some
+// responses only describe the problem in the message.
+func isModelNotFound(status int, apiErr apiError) bool {
+	if apiErr.Error.Code == "model_not_found" {
+		return true
+	}
+	msg := strings.ToLower(apiErr.Error.Message)
+	if !strings.Contains(msg, "model") {
+		return false
+	}
+	return status == http.StatusNotFound ||
+		strings.Contains(msg, "does not exist") ||
+		strings.Contains(msg, "not found") ||
+		strings.Contains(msg, "deprecated")
 }
 
 // Register registers the Grok backend with the global registry.
-func Register() error {
-	b, err := New()
+func Register(opts ...Option) error {
+	b, err := New(opts...)
 	if err != nil {
 		return err
 	}
@@ -2,10 +2,16 @@ package grok
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/steveyegge/gastown/internal/backend"
+	"github.com/steveyegge/gastown/internal/backend/openaicompat"
 )
 
 func TestGrokAPI(t *testing.T) {
@@ -40,3 +46,288 @@ func TestGrokAPI(t *testing.T) {
 		t.Error("Expected non-empty response")
 	}
 }
+
+func TestInvokeModelNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"The model grok-beta does not exist or your team does not have access to it.","type":"invalid_request_error","code":""}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("XAI_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	_, err = b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}},
+		backend.InvokeOptions{Model: "grok-beta"})
+	if !errors.Is(err, backend.ErrModelUnavailable) {
+		t.Errorf("expected ErrModelUnavailable, got %v", err)
+	}
+}
+
+func TestInvokeResolvesModelAlias(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requested = body.Model
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"stop here"}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("XAI_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	_, _ = b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}},
+		backend.InvokeOptions{Model: "grok-fast"})
+	if requested != "grok-3-mini" {
+		t.Errorf("requested model = %q, want grok-3-mini", requested)
+	}
+	if got := b.EstimateCost(1000, 1000, "grok-fast").Model; got != "grok-3-mini" {
+		t.Errorf("EstimateCost model = %q, want grok-3-mini", got)
+	}
+}
+
+func TestConfiguredAliasOverridesBuiltin(t *testing.T) {
+	backend.SetModelAliases("grok", map[string]string{"grok-fast": "grok-2-mini"})
+	t.Cleanup(backend.ResetModelAliasesForTesting)
+
+	if got := resolveModel("grok-fast"); got != "grok-2-mini" {
+		t.Errorf("resolveModel(grok-fast) = %q, want grok-2-mini", got)
+	}
+	if got := resolveModel("grok-3-mini"); got != "grok-3-mini" {
+		t.Errorf("full model IDs should pass through, got %q", got)
+	}
+}
+
+func TestInvokeSendsResponseFormat(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"valid", `{"verdict":"approve"}`, false},
+		{"schema mismatch", `{"verdict":"maybe"}`, true},
+		{"not JSON", "Sure! Here is the JSON you asked for", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent struct {
+				ResponseFormat struct {
+					Type       string `json:"type"`
+					JSONSchema struct {
+						Name   string          `json:"name"`
+						Schema json.RawMessage `json:"schema"`
+					} `json:"json_schema"`
+				} `json:"response_format"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(&sent)
+				content, _ := json.Marshal(tt.content)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":` + string(content) + `},"finish_reason":"stop"}]}`))
+			}))
+			defer server.Close()
+
+			t.Setenv("XAI_API_KEY", "test-key-1234567890")
+			b, err := New(WithBaseURL(server.URL))
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			schema := `{"type":"object","required":["verdict"],"properties":{"verdict":{"enum":["approve","reject"]}}}`
+			_, err = b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "review"}},
+				backend.InvokeOptions{ResponseFormat: &backend.ResponseFormat{
+					Type:   backend.ResponseFormatJSONSchema,
+					Name:   "verdict",
+					Schema: json.RawMessage(schema),
+				}})
+
+			if sent.ResponseFormat.Type != "json_schema" || sent.ResponseFormat.JSONSchema.Name != "verdict" {
+				t.Errorf("response_format = %+v, want json_schema named verdict", sent.ResponseFormat)
+			}
+			if string(sent.ResponseFormat.JSONSchema.Schema) != schema {
+				t.Errorf("schema = %s, want %s", sent.ResponseFormat.JSONSchema.Schema, schema)
+			}
+			if got := errors.Is(err, backend.ErrInvalidStructuredOutput); got != tt.wantErr {
+				t.Errorf("err = %v, want ErrInvalidStructuredOutput: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInvokeRetriesTransientStatus(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error":{"message":"try again"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("XAI_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL), WithRetry(3, time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	result, err := b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}}, backend.InvokeOptions{})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if result.Content != "hello" || calls != 2 {
+		t.Errorf("content = %q after %d calls, want hello after 2", result.Content, calls)
+	}
+}
+
+func TestInvokeRoundTripsToolCalls(t *testing.T) {
+	var sent apiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"m","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":null,` +
+			`"tool_calls":[{"id":"call_2","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Oslo\"}"}}]}}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("XAI_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	messages := []backend.Message{
+		{Role: "user", Content: "Weather in Paris?"},
+		{Role: "assistant", ToolCalls: []backend.ToolCall{
+			{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Paris"}`)},
+		}},
+		backend.ToolResultMessage("call_1", "18C"),
+	}
+	schema := `{"type":"object","properties":{"city":{"type":"string"}}}`
+	result, err := b.Invoke(context.Background(), messages, backend.InvokeOptions{
+		Tools: []backend.ToolDef{{Name: "get_weather", Description: "Current weather", InputSchema: json.RawMessage(schema)}},
+	})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+
+	if len(sent.Tools) != 1 || sent.Tools[0].Type != "function" || sent.Tools[0].Function.Name != "get_weather" ||
+		string(sent.Tools[0].Function.Parameters) != schema {
+		t.Errorf("tools = %+v", sent.Tools)
+	}
+	if len(sent.Messages) != 3 {
+		t.Fatalf("sent %d messages, want 3", len(sent.Messages))
+	}
+	if calls := sent.Messages[1].ToolCalls; len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("assistant tool calls = %+v", calls)
+	}
+	if tool := sent.Messages[2]; tool.Role != "tool" || tool.ToolCallID != "call_1" || tool.Content != "18C" {
+		t.Errorf("tool result = %+v", tool)
+	}
+
+	if result.FinishReason != "tool_calls" || result.Content != "" {
+		t.Errorf("result finish=%q content=%q", result.FinishReason, result.Content)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].ID != "call_2" ||
+		result.ToolCalls[0].Name != "get_weather" || string(result.ToolCalls[0].Arguments) != `{"city":"Oslo"}` {
+		t.Errorf("tool calls = %+v", result.ToolCalls)
+	}
+}
+
+func TestInvokeSendsSystemMsg(t *testing.T) {
+	var sent []openaicompat.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []openaicompat.Message `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		sent = body.Messages
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"stop here"}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("XAI_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	messages := []backend.Message{
+		{Role: "system", Content: "Be verbose."},
+		{Role: "user", Content: "hi"},
+	}
+	_, _ = b.Invoke(context.Background(), messages, backend.InvokeOptions{SystemMsg: "Be brief."})
+	if len(sent) != 2 || sent[0].Role != "system" || sent[0].Content != "Be brief." || sent[1].Role != "user" {
+		t.Errorf("sent messages = %+v, want the SystemMsg replacing the system message", sent)
+	}
+}
+
+func TestInvokeLiveSearchCitations(t *testing.T) {
+	var sent []json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			SearchParameters json.RawMessage `json:"search_parameters"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		sent = append(sent, body.SearchParameters)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"Go 1.24 adds generic type aliases."},"finish_reason":"stop"}],` +
+			`"citations":["https://go.dev/doc/go1.24","https://go.dev/blog/go1.24"]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("XAI_API_KEY", "test-key-1234567890")
+	messages := []backend.Message{{Role: "user", Content: "what's new in Go 1.24?"}}
+
+	// Existing calls send no search parameters
+	plain, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := plain.Invoke(context.Background(), messages, backend.InvokeOptions{}); err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+
+	searching, err := New(WithBaseURL(server.URL), WithLiveSearch("auto"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	result, err := searching.Invoke(context.Background(), messages, backend.InvokeOptions{})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if len(result.Citations) != 2 || result.Citations[0] != "https://go.dev/doc/go1.24" {
+		t.Errorf("Citations = %v, want the two go.dev sources", result.Citations)
+	}
+
+	// Per-call parameters replace the backend default
+	if _, err := searching.Invoke(context.Background(), messages, backend.InvokeOptions{
+		SearchParameters: json.RawMessage(`{"mode":"on","max_search_results":3}`),
+	}); err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+
+	want := []string{"", `{"mode":"auto","return_citations":true}`, `{"mode":"on","max_search_results":3}`}
+	if len(sent) != len(want) {
+		t.Fatalf("sent %d requests, want %d", len(sent), len(want))
+	}
+	for i := range want {
+		if string(sent[i]) != want[i] {
+			t.Errorf("request %d search_parameters = %s, want %s", i, sent[i], want[i])
+		}
+	}
+}
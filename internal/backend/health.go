@@ -0,0 +1,46 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxHealthErrorBody caps how much of an error response a health check
+// quotes back.
+const maxHealthErrorBody = 200
+
+// LiveCheckAllowed reports whether a health check may make a network call.
+// Cheap format checks always run; the live call only happens when the
+// caller has bounded how long it's willing to wait.
+func LiveCheckAllowed(ctx context.Context) bool {
+	_, ok := ctx.Deadline()
+	return ok
+}
+
+// CheckEndpoint sends a lightweight request (such as listing models) and
+// reports whether the API accepted it, so a revoked key or an unreachable
+// API shows up as unhealthy.
+func CheckEndpoint(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("API unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxHealthErrorBody))
+	detail := strings.TrimSpace(string(body))
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("API key rejected (status %d): %s", resp.StatusCode, detail)
+	default:
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, detail)
+	}
+}
@@ -0,0 +1,118 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultRequestsPerMinute is the request rate a backend allows when none
+// is configured.
+const DefaultRequestsPerMinute = 60
+
+// ModelLimits caps the requests a backend sends to one model. Zero values
+// mean "not set": RequestsPerMinute falls back to the backend's rate and
+// MaxConcurrent leaves in-flight requests uncapped.
+type ModelLimits struct {
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"`
+	MaxConcurrent     int `json:"max_concurrent,omitempty"`
+}
+
+// Limits are the effective request limits of a backend.
+type Limits struct {
+	// RequestsPerMinute is the shared rate for models without their own.
+	RequestsPerMinute int `json:"requests_per_minute"`
+
+	// Models holds the per-model overrides, keyed by model ID.
+	Models map[string]ModelLimits `json:"models,omitempty"`
+}
+
+// LimitedBackend is implemented by backends that throttle their requests,
+// so callers can show the limits in effect.
+type LimitedBackend interface {
+	Limits() Limits
+}
+
+// Limiter throttles a backend's requests. Models with their own
+// RequestsPerMinute get their own token bucket; all other models share the
+// backend-wide bucket. Models with MaxConcurrent also hold a slot for as
+// long as a request is in flight.
+type Limiter struct {
+	limits  Limits
+	shared  *RateLimiter
+	buckets map[string]*RateLimiter
+	slots   map[string]chan struct{}
+}
+
+// NewLimiter creates a limiter enforcing limits. A RequestsPerMinute of zero
+// means DefaultRequestsPerMinute. The limits are fixed once created.
+func NewLimiter(limits Limits) *Limiter {
+	if limits.RequestsPerMinute <= 0 {
+		limits.RequestsPerMinute = DefaultRequestsPerMinute
+	}
+
+	l := &Limiter{
+		limits:  Limits{RequestsPerMinute: limits.RequestsPerMinute},
+		shared:  NewRateLimiter(limits.RequestsPerMinute, time.Minute),
+		buckets: make(map[string]*RateLimiter),
+		slots:   make(map[string]chan struct{}),
+	}
+	for model, ml := range limits.Models {
+		if ml.RequestsPerMinute <= 0 && ml.MaxConcurrent <= 0 {
+			continue
+		}
+		if l.limits.Models == nil {
+			l.limits.Models = make(map[string]ModelLimits)
+		}
+		l.limits.Models[model] = ml
+		if ml.RequestsPerMinute > 0 {
+			l.buckets[model] = NewRateLimiter(ml.RequestsPerMinute, time.Minute)
+		}
+		if ml.MaxConcurrent > 0 {
+			l.slots[model] = make(chan struct{}, ml.MaxConcurrent)
+		}
+	}
+	return l
+}
+
+// Acquire blocks until a request to model may be sent or ctx is done. A
+// concurrency slot is taken before a rate token, so callers queued behind
+// the cap don't burn tokens. The returned release must be called once the
+// response has been read in full.
+func (l *Limiter) Acquire(ctx context.Context, model string) (release func(), err error) {
+	bucket, ok := l.buckets[model]
+	if !ok {
+		bucket = l.shared
+	}
+	slots := l.slots[model]
+
+	release = func() {}
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var once sync.Once
+		release = func() { once.Do(func() { <-slots }) }
+	}
+
+	if err := bucket.Wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// Limits returns the limits in effect, with per-model overrides in a copy
+// the caller may modify.
+func (l *Limiter) Limits() Limits {
+	limits := Limits{RequestsPerMinute: l.limits.RequestsPerMinute}
+	if len(l.limits.Models) > 0 {
+		limits.Models = make(map[string]ModelLimits, len(l.limits.Models))
+		for model, ml := range l.limits.Models {
+			limits.Models[model] = ml
+		}
+	}
+	return limits
+}
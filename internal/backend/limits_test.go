@@ -0,0 +1,122 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterPerModelBucketsAreIndependent(t *testing.T) {
+	limiter := NewLimiter(Limits{
+		RequestsPerMinute: 1,
+		Models:            map[string]ModelLimits{"fast": {RequestsPerMinute: 600}},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Drain the shared bucket; the model with its own bucket is unaffected
+	release, err := limiter.Acquire(ctx, "slow")
+	if err != nil {
+		t.Fatalf("Acquire slow: %v", err)
+	}
+	release()
+	for i := 0; i < 5; i++ {
+		release, err := limiter.Acquire(ctx, "fast")
+		if err != nil {
+			t.Fatalf("Acquire fast #%d: %v", i, err)
+		}
+		release()
+	}
+
+	// Other models share the drained backend-wide bucket
+	if _, err := limiter.Acquire(ctx, "other"); err != context.DeadlineExceeded {
+		t.Errorf("Acquire on drained shared bucket = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestLimiterCapsConcurrency(t *testing.T) {
+	limiter := NewLimiter(Limits{
+		RequestsPerMinute: 6000,
+		Models:            map[string]ModelLimits{"m": {MaxConcurrent: 2}},
+	})
+
+	var inFlight, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := limiter.Acquire(context.Background(), "m")
+			if err != nil {
+				t.Errorf("Acquire: %v", err)
+				return
+			}
+			defer release()
+
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+		}()
+	}
+	wg.Wait()
+
+	if peak != 2 {
+		t.Errorf("peak in-flight requests = %d, want 2", peak)
+	}
+}
+
+func TestLimiterCancelWhileWaitingForSlot(t *testing.T) {
+	limiter := NewLimiter(Limits{Models: map[string]ModelLimits{"m": {MaxConcurrent: 1}}})
+	release, err := limiter.Acquire(context.Background(), "m")
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, "m"); err != context.DeadlineExceeded {
+		t.Fatalf("Acquire with slot held = %v, want DeadlineExceeded", err)
+	}
+
+	// Releasing twice must not free a slot that isn't held
+	release()
+	release()
+	if release, err = limiter.Acquire(context.Background(), "m"); err != nil {
+		t.Fatalf("Acquire after release: %v", err)
+	}
+	if len(limiter.slots["m"]) != 1 {
+		t.Errorf("slots held = %d, want 1", len(limiter.slots["m"]))
+	}
+	release()
+}
+
+func TestLimiterLimits(t *testing.T) {
+	limiter := NewLimiter(Limits{Models: map[string]ModelLimits{
+		"m":     {RequestsPerMinute: 10, MaxConcurrent: 3},
+		"unset": {},
+	}})
+
+	limits := limiter.Limits()
+	if limits.RequestsPerMinute != DefaultRequestsPerMinute {
+		t.Errorf("RequestsPerMinute = %d, want default %d", limits.RequestsPerMinute, DefaultRequestsPerMinute)
+	}
+	if got := limits.Models["m"]; got != (ModelLimits{RequestsPerMinute: 10, MaxConcurrent: 3}) {
+		t.Errorf("Models[m] = %+v", got)
+	}
+	if _, ok := limits.Models["unset"]; ok {
+		t.Error("model without limits should not be listed")
+	}
+
+	limits.Models["m"] = ModelLimits{}
+	if limiter.Limits().Models["m"].MaxConcurrent != 3 {
+		t.Error("Limits should return a copy")
+	}
+}
@@ -0,0 +1,357 @@
+// Package ollama implements the AgentBackend interface for a local Ollama
+// daemon. Requests never leave the machine, so this backend costs nothing
+// and works air-gapped, which makes it a good home for simple tasks.
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/backend"
+)
+
+// Model definitions with the context windows gt requests.
+// Ollama allocates memory for the full window (num_ctx), so these stay
+// well below each model's trained maximum to fit on a workstation.
+var (
+	// Models maps model IDs to the context window sent as num_ctx.
+	Models = map[string]int{
+		"llama3.2": 8192,
+		"llama3.1": 8192,
+		"qwen2.5":  8192,
+		"mistral":  8192,
+		"gemma2":   8192,
+		"phi3":     4096,
+	}
+
+	// Aliases maps friendly names (including gt tiers) to model IDs.
+	// Every tier maps to the default model; configure model_aliases to use
+	// larger local models for sonnet/opus.
+	Aliases = map[string]string{
+		"local":  "llama3.2",
+		"haiku":  "llama3.2",
+		"sonnet": "llama3.2",
+		"opus":   "llama3.2",
+	}
+)
+
+const (
+	defaultBaseURL       = "http://localhost:11434"
+	defaultModel         = "llama3.2"
+	defaultMaxTokens     = 4096
+	defaultContextTokens = 8192
+	defaultTimeout       = 10 * time.Minute // Local models can be slow on CPU
+	healthCheckTimeout   = 2 * time.Second
+)
+
+// Backend implements backend.AgentBackend for a local Ollama daemon.
+type Backend struct {
+	baseURL string
+	client  *http.Client
+}
+
+// Option configures the Ollama backend.
+type Option func(*Backend)
+
+// WithBaseURL sets the daemon URL (default http://localhost:11434).
+// An empty URL keeps the current one.
+func WithBaseURL(url string) Option {
+	return func(b *Backend) {
+		if url != "" {
+			b.baseURL = strings.TrimSuffix(url, "/")
+		}
+	}
+}
+
+// WithHTTPClient sets a custom HTTP client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(b *Backend) {
+		b.client = client
+	}
+}
+
+// New creates a new Ollama backend.
+// The daemon URL defaults to OLLAMA_HOST when set, as with the ollama CLI.
+func New(opts ...Option) *Backend {
+	b := &Backend{
+		baseURL: defaultBaseURL,
+		client:  &http.Client{Timeout: defaultTimeout},
+	}
+
+	if host := os.Getenv("OLLAMA_HOST"); host != "" {
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+		WithBaseURL(host)(b)
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Name returns the backend identifier.
+func (b *Backend) Name() string {
+	return "ollama"
+}
+
+// Capabilities returns feature flags.
+func (b *Backend) Capabilities() backend.Capability {
+	return backend.CapStreaming | backend.CapStructuredOutput
+}
+
+// AvailableModels returns supported model IDs.
+func (b *Backend) AvailableModels() []string {
+	models := make([]string, 0, len(Models))
+	for model := range Models {
+		models = append(models, model)
+	}
+	return models
+}
+
+// DefaultModel returns the default model.
+func (b *Backend) DefaultModel() string {
+	return defaultModel
+}
+
+// MaxContextTokens returns the context window for a model.
+func (b *Backend) MaxContextTokens(model string) int {
+	if ctx, ok := Models[baseModel(resolveModel(model))]; ok {
+		return ctx
+	}
+	return defaultContextTokens
+}
+
+// apiRequest is the request body for the /api/chat endpoint.
+type apiRequest struct {
+	Model    string          `json:"model"`
+	Messages []apiMessage    `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   json.RawMessage `json:"format,omitempty"`
+	Options  apiOptions      `json:"options"`
+}
+
+// apiOptions are the model parameters for a request.
+type apiOptions struct {
+	NumPredict  int     `json:"num_predict,omitempty"`
+	NumCtx      int     `json:"num_ctx,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+}
+
+// apiMessage is a message in the API request and response.
+type apiMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// apiResponse is the response from the /api/chat endpoint.
+type apiResponse struct {
+	Model           string     `json:"model"`
+	Message         apiMessage `json:"message"`
+	Done            bool       `json:"done"`
+	DoneReason      string     `json:"done_reason"`
+	PromptEvalCount int        `json:"prompt_eval_count"`
+	EvalCount       int        `json:"eval_count"`
+}
+
+// apiError is an error response from the daemon.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// toAPIFormat converts a backend.ResponseFormat for the request: "json"
+// for json_object, or the schema itself for json_schema.
+func toAPIFormat(f *backend.ResponseFormat) json.RawMessage {
+	if f == nil {
+		return nil
+	}
+	if f.Type == backend.ResponseFormatJSONSchema {
+		return f.ObjectSchema()
+	}
+	return json.RawMessage(`"json"`)
+}
+
+// Invoke sends a prompt and returns the response.
+func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts backend.InvokeOptions) (*backend.InvokeResult, error) {
+	model := resolveModel(opts.Model)
+	if model == "" {
+		model = defaultModel
+	}
+
+	maxTokens := opts.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	// Convert messages
+	var apiMessages []apiMessage
+	if opts.SystemMsg != "" {
+		apiMessages = append(apiMessages, apiMessage{Role: "system", Content: opts.SystemMsg})
+	}
+	for _, msg := range messages {
+		apiMessages = append(apiMessages, apiMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
+	}
+
+	reqBody := apiRequest{
+		Model:    model,
+		Messages: apiMessages,
+		Stream:   false,
+		Format:   toAPIFormat(opts.ResponseFormat),
+		Options: apiOptions{
+			NumPredict:  maxTokens,
+			NumCtx:      b.MaxContextTokens(model),
+			Temperature: opts.Temperature,
+		},
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", b.baseURL+"/api/chat", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ollama daemon unreachable at %s: %w", b.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	// Check for error response
+	if resp.StatusCode != http.StatusOK {
+		var apiErr apiError
+		if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error != "" {
+			if isModelNotFound(resp.StatusCode, apiErr) {
+				return nil, fmt.Errorf("%w: %s: %s (run 'ollama pull %s')", backend.ErrModelUnavailable, model, apiErr.Error, model)
+			}
+			return nil, fmt.Errorf("API error: %s", apiErr.Error)
+		}
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var apiResp apiResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	content := apiResp.Message.Content
+	if err := backend.ValidateStructuredOutput(content, opts.ResponseFormat); err != nil {
+		return nil, err
+	}
+
+	return &backend.InvokeResult{
+		Content:      content,
+		Model:        apiResp.Model,
+		InputTokens:  apiResp.PromptEvalCount,
+		OutputTokens: apiResp.EvalCount,
+		FinishReason: apiResp.DoneReason,
+	}, nil
+}
+
+// InvokeStream returns a streaming response channel.
+func (b *Backend) InvokeStream(ctx context.Context, messages []backend.Message, opts backend.InvokeOptions) (<-chan backend.StreamChunk, error) {
+	// For now, implement as non-streaming with single chunk
+	return backend.StreamInvoke(ctx, func(ctx context.Context) (*backend.InvokeResult, error) {
+		return b.Invoke(ctx, messages, opts)
+	}), nil
+}
+
+// EstimateCost estimates the cost for given token counts.
+// Local inference is free, so every estimate is zero.
+func (b *Backend) EstimateCost(inputTokens, outputTokens int, model string) backend.CostEstimate {
+	model = resolveModel(model)
+	if model == "" {
+		model = defaultModel
+	}
+
+	return backend.CostEstimate{
+		Currency: "USD",
+		Model:    model,
+	}
+}
+
+// CountTokens estimates token count for messages.
+// Delegates to the shared token counter for the model's family.
+func (b *Backend) CountTokens(messages []backend.Message, model string) (int, error) {
+	model = resolveModel(model)
+	if model == "" {
+		model = defaultModel
+	}
+	return backend.CountTokens(messages, model)
+}
+
+// Healthy checks that the daemon is up by listing its local models.
+func (b *Backend) Healthy(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", b.baseURL+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ollama daemon unreachable at %s: %w", b.baseURL, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ollama daemon at %s returned status %d", b.baseURL, resp.StatusCode)
+	}
+	return nil
+}
+
+// resolveModel maps a friendly name like "haiku" to a local model ID.
+func resolveModel(model string) string {
+	return backend.ResolveModel("ollama", model, Aliases)
+}
+
+// baseModel strips an Ollama tag (e.g., "llama3.2:3b" -> "llama3.2").
+func baseModel(model string) string {
+	if i := strings.Index(model, ":"); i >= 0 {
+		return model[:i]
+	}
+	return model
+}
+
+// isModelNotFound reports whether an error means the model hasn't been
+// pulled. Ollama answers 404 with "model \"x\" not found, try pulling it first".
+func isModelNotFound(status int, apiErr apiError) bool {
+	msg := strings.ToLower(apiErr.Error)
+	return status == http.StatusNotFound || (strings.Contains(msg, "model") && strings.Contains(msg, "not found"))
+}
+
+// Register registers the Ollama backend with the global registry if the
+// daemon is reachable, so the router never prefers a backend that's down.
+func Register(opts ...Option) error {
+	b := New(opts...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	if err := b.Healthy(ctx); err != nil {
+		return err
+	}
+
+	backend.GetRegistry().Register(b)
+	return nil
+}
@@ -0,0 +1,131 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/backend"
+)
+
+func TestInvoke(t *testing.T) {
+	var sent apiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("path = %s, want /api/chat", r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"llama3.2","message":{"role":"assistant","content":"hello"},"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":3}`))
+	}))
+	defer server.Close()
+
+	b := New(WithBaseURL(server.URL))
+	result, err := b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}},
+		backend.InvokeOptions{Model: "haiku", MaxTokens: 100})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+
+	if sent.Model != "llama3.2" || sent.Stream {
+		t.Errorf("request model=%q stream=%v, want llama3.2 non-streaming", sent.Model, sent.Stream)
+	}
+	if sent.Options.NumPredict != 100 || sent.Options.NumCtx != 8192 {
+		t.Errorf("options = %+v, want num_predict 100 and num_ctx 8192", sent.Options)
+	}
+	if result.Content != "hello" || result.InputTokens != 12 || result.OutputTokens != 3 || result.FinishReason != "stop" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestInvokeModelNotPulled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"model \"qwen2.5\" not found, try pulling it first"}`))
+	}))
+	defer server.Close()
+
+	b := New(WithBaseURL(server.URL))
+	_, err := b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}},
+		backend.InvokeOptions{Model: "qwen2.5"})
+	if !errors.Is(err, backend.ErrModelUnavailable) {
+		t.Errorf("expected ErrModelUnavailable, got %v", err)
+	}
+}
+
+func TestInvokeSendsFormat(t *testing.T) {
+	var sent struct {
+		Format json.RawMessage `json:"format"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		_, _ = w.Write([]byte(`{"model":"llama3.2","message":{"role":"assistant","content":"{\"verdict\":\"maybe\"}"},"done":true}`))
+	}))
+	defer server.Close()
+
+	schema := `{"type":"object","required":["verdict"],"properties":{"verdict":{"enum":["approve","reject"]}}}`
+	b := New(WithBaseURL(server.URL))
+	_, err := b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "review"}},
+		backend.InvokeOptions{ResponseFormat: &backend.ResponseFormat{
+			Type:   backend.ResponseFormatJSONSchema,
+			Schema: json.RawMessage(schema),
+		}})
+
+	if string(sent.Format) != schema {
+		t.Errorf("format = %s, want %s", sent.Format, schema)
+	}
+	if !errors.Is(err, backend.ErrInvalidStructuredOutput) {
+		t.Errorf("expected ErrInvalidStructuredOutput, got %v", err)
+	}
+}
+
+func TestEstimateCostIsFree(t *testing.T) {
+	b := New()
+	cost := b.EstimateCost(100000, 100000, "")
+	if cost.TotalCost != 0 || cost.Model != defaultModel {
+		t.Errorf("EstimateCost = %+v, want zero cost for %s", cost, defaultModel)
+	}
+}
+
+func TestMaxContextTokensIgnoresTag(t *testing.T) {
+	b := New()
+	if got := b.MaxContextTokens("phi3:mini"); got != 4096 {
+		t.Errorf("MaxContextTokens(phi3:mini) = %d, want 4096", got)
+	}
+	if got := b.MaxContextTokens("some-custom-model"); got != defaultContextTokens {
+		t.Errorf("MaxContextTokens(unknown) = %d, want %d", got, defaultContextTokens)
+	}
+}
+
+func TestHealthy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"models":[]}`))
+	}))
+
+	b := New(WithBaseURL(server.URL))
+	if err := b.Healthy(context.Background()); err != nil {
+		t.Errorf("Healthy with daemon up: %v", err)
+	}
+
+	server.Close()
+	if err := b.Healthy(context.Background()); err == nil {
+		t.Error("Healthy with daemon down: want error")
+	}
+}
+
+func TestNewUsesOllamaHost(t *testing.T) {
+	t.Setenv("OLLAMA_HOST", "gpu-box:11434")
+	if got := New().baseURL; got != "http://gpu-box:11434" {
+		t.Errorf("baseURL = %q, want http://gpu-box:11434", got)
+	}
+	if got := New(WithBaseURL("http://other:1/")).baseURL; got != "http://other:1" {
+		t.Errorf("WithBaseURL should win over OLLAMA_HOST, got %q", got)
+	}
+}
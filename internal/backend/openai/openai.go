@@ -2,17 +2,20 @@
 package openai
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/steveyegge/gastown/internal/backend"
+	"github.com/steveyegge/gastown/internal/backend/openaicompat"
 )
 
 // Model definitions with context windows and pricing.
@@ -43,6 +46,15 @@ var (
 		"o1-preview":    {15.00, 60.00},
 		"o3-mini":       {1.10, 4.40},
 	}
+
+	// Aliases maps friendly names (including gt tiers) to model IDs.
+	Aliases = map[string]string{
+		"gpt4":      "gpt-4o",
+		"gpt4-mini": "gpt-4o-mini",
+		"haiku":     "gpt-4o-mini",
+		"sonnet":    "gpt-4o",
+		"opus":      "o1",
+	}
 )
 
 const (
@@ -55,19 +67,36 @@ const (
 
 // Backend implements backend.AgentBackend for OpenAI's API.
 type Backend struct {
-	apiKey     string
-	baseURL    string
-	client     *http.Client
-	rateLimiter *rateLimiter
+	apiKey  string
+	baseURL string
+	client  *http.Client
+	limits  backend.Limits
+	limiter *backend.Limiter
+	retry   backend.RetryPolicy
+
+	// compat sends only the legacy max_tokens field, for OpenAI-compatible
+	// servers (vLLM, LiteLLM) that don't know max_completion_tokens.
+	compat bool
 }
 
 // Option configures the OpenAI backend.
 type Option func(*Backend)
 
-// WithBaseURL sets a custom base URL (for testing or proxies).
+// WithBaseURL sets a custom base URL (for testing or proxies). An empty
+// URL keeps the default.
 func WithBaseURL(url string) Option {
 	return func(b *Backend) {
-		b.baseURL = url
+		if url != "" {
+			b.baseURL = strings.TrimSuffix(url, "/")
+		}
+	}
+}
+
+// WithCompatibilityMode sends the response limit as the legacy max_tokens
+// field only, for OpenAI-compatible servers such as vLLM or LiteLLM.
+func WithCompatibilityMode(enabled bool) Option {
+	return func(b *Backend) {
+		b.compat = enabled
 	}
 }
 
@@ -81,7 +110,27 @@ func WithHTTPClient(client *http.Client) Option {
 // WithRateLimit sets the rate limit (requests per minute).
 func WithRateLimit(rpm int) Option {
 	return func(b *Backend) {
-		b.rateLimiter = newRateLimiter(rpm, time.Minute)
+		b.limits.RequestsPerMinute = rpm
+	}
+}
+
+// WithModelLimits sets per-model request rates and concurrency caps. Keys
+// may be aliases; they're resolved to model IDs.
+func WithModelLimits(limits map[string]backend.ModelLimits) Option {
+	return func(b *Backend) {
+		b.limits.Models = make(map[string]backend.ModelLimits, len(limits))
+		for model, ml := range limits {
+			b.limits.Models[resolveModel(model)] = ml
+		}
+	}
+}
+
+// WithRetry sets how many attempts a request gets and the base delay for
+// exponential backoff between them (see backend.DoWithRetry).
+func WithRetry(attempts int, baseDelay time.Duration) Option {
+	return func(b *Backend) {
+		b.retry.MaxAttempts = attempts
+		b.retry.BaseDelay = baseDelay
 	}
 }
 
@@ -94,15 +143,16 @@ func New(opts ...Option) (*Backend, error) {
 	}
 
 	b := &Backend{
-		apiKey:      apiKey,
-		baseURL:     defaultBaseURL,
-		client:      &http.Client{Timeout: defaultTimeout},
-		rateLimiter: newRateLimiter(60, time.Minute), // Default 60 RPM
+		apiKey:  apiKey,
+		baseURL: defaultBaseURL,
+		client:  &http.Client{Timeout: defaultTimeout},
+		retry:   backend.DefaultRetryPolicy,
 	}
 
 	for _, opt := range opts {
 		opt(b)
 	}
+	b.limiter = backend.NewLimiter(b.limits)
 
 	return b, nil
 }
@@ -114,7 +164,7 @@ func (b *Backend) Name() string {
 
 // Capabilities returns feature flags.
 func (b *Backend) Capabilities() backend.Capability {
-	return backend.CapStreaming | backend.CapTools | backend.CapVision | backend.CapLongContext
+	return backend.CapStreaming | backend.CapTools | backend.CapVision | backend.CapLongContext | backend.CapStructuredOutput
 }
 
 // AvailableModels returns supported model IDs.
@@ -133,7 +183,7 @@ func (b *Backend) DefaultModel() string {
 
 // MaxContextTokens returns the context window for a model.
 func (b *Backend) MaxContextTokens(model string) int {
-	if ctx, ok := Models[model]; ok {
+	if ctx, ok := Models[resolveModel(model)]; ok {
 		return ctx
 	}
 	return 128000 // Default for unknown models
@@ -141,17 +191,20 @@ func (b *Backend) MaxContextTokens(model string) int {
 
 // apiRequest is the request body for the chat completions API.
 type apiRequest struct {
-	Model       string       `json:"model"`
-	Messages    []apiMessage `json:"messages"`
-	MaxTokens   int          `json:"max_completion_tokens,omitempty"`
-	Temperature float64      `json:"temperature,omitempty"`
-	Stream      bool         `json:"stream,omitempty"`
+	Model          string                       `json:"model"`
+	Messages       []openaicompat.Message       `json:"messages"`
+	MaxTokens      int                          `json:"max_tokens,omitempty"`
+	MaxCompletion  int                          `json:"max_completion_tokens,omitempty"`
+	Temperature    float64                      `json:"temperature,omitempty"`
+	Stream         bool                         `json:"stream,omitempty"`
+	StreamOptions  *apiStreamOptions            `json:"stream_options,omitempty"`
+	ResponseFormat *openaicompat.ResponseFormat `json:"response_format,omitempty"`
+	Tools          []openaicompat.Tool          `json:"tools,omitempty"`
 }
 
-// apiMessage is a message in the API request.
-type apiMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+// apiStreamOptions asks for a final usage chunk on streamed responses.
+type apiStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // apiResponse is the response from the chat completions API.
@@ -161,9 +214,9 @@ type apiResponse struct {
 	Created int64  `json:"created"`
 	Model   string `json:"model"`
 	Choices []struct {
-		Index        int `json:"index"`
-		Message      apiMessage `json:"message"`
-		FinishReason string `json:"finish_reason"`
+		Index        int                  `json:"index"`
+		Message      openaicompat.Message `json:"message"`
+		FinishReason string               `json:"finish_reason"`
 	} `json:"choices"`
 	Usage struct {
 		PromptTokens     int `json:"prompt_tokens"`
@@ -177,19 +230,73 @@ type apiError struct {
 	Error struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
+		Param   string `json:"param"`
 		Code    string `json:"code"`
 	} `json:"error"`
 }
 
+// errStreamingUnsupported means the model rejected stream: true (some
+// reasoning models only answer whole).
+var errStreamingUnsupported = errors.New("streaming not supported for model")
+
 // Invoke sends a prompt and returns the response.
 func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts backend.InvokeOptions) (*backend.InvokeResult, error) {
-	// Wait for rate limiter
-	if err := b.rateLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit: %w", err)
+	// Wait for the model's rate and concurrency limits
+	release, err := b.acquire(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	reqBody := b.buildRequest(messages, opts)
+	resp, err := b.post(ctx, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Read response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	// Parse response
+	var apiResp apiResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+
+	// Extract content from first choice
+	var content string
+	if len(apiResp.Choices) > 0 {
+		content = apiResp.Choices[0].Message.Content
+	}
+
+	finishReason := ""
+	var toolCalls []backend.ToolCall
+	if len(apiResp.Choices) > 0 {
+		finishReason = apiResp.Choices[0].FinishReason
+		toolCalls = openaicompat.FromToolCalls(apiResp.Choices[0].Message.ToolCalls)
 	}
 
-	// Prepare request
-	model := opts.Model
+	if err := backend.ValidateStructuredOutput(content, opts.ResponseFormat); err != nil {
+		return nil, err
+	}
+
+	return &backend.InvokeResult{
+		Content:      content,
+		Model:        apiResp.Model,
+		InputTokens:  apiResp.Usage.PromptTokens,
+		OutputTokens: apiResp.Usage.CompletionTokens,
+		FinishReason: finishReason,
+		ToolCalls:    toolCalls,
+	}, nil
+}
+
+// buildRequest converts messages and options into a chat completions request.
+func (b *Backend) buildRequest(messages []backend.Message, opts backend.InvokeOptions) apiRequest {
+	model := resolveModel(opts.Model)
 	if model == "" {
 		model = defaultModel
 	}
@@ -204,141 +311,262 @@ func (b *Backend) Invoke(ctx context.Context, messages []backend.Message, opts b
 		temp = defaultTemperature
 	}
 
-	// Convert messages
-	var apiMessages []apiMessage
-	for _, msg := range messages {
-		apiMessages = append(apiMessages, apiMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
-	}
-
 	reqBody := apiRequest{
 		Model:       model,
-		Messages:    apiMessages,
-		MaxTokens:   maxTokens,
+		Messages:    openaicompat.ToMessages(messages, opts.SystemMsg),
 		Temperature: temp,
 		Stream:      false,
+
+		ResponseFormat: openaicompat.ToResponseFormat(opts.ResponseFormat),
+		Tools:          openaicompat.ToTools(opts.Tools),
 	}
+	b.setMaxTokens(&reqBody, maxTokens)
 
 	// O1/O3 models don't support temperature
 	if isReasoningModel(model) {
 		reqBody.Temperature = 0
 	}
 
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("marshaling request: %w", err)
+	return reqBody
+}
+
+// setMaxTokens sets the response limit in the field the server expects.
+// OpenAI's reasoning models only accept max_completion_tokens and its
+// other chat models still take max_tokens. Custom base URLs get both,
+// since gateways differ in which one they read, unless compatibility mode
+// asks for max_tokens alone.
+func (b *Backend) setMaxTokens(req *apiRequest, maxTokens int) {
+	switch {
+	case b.compat:
+		req.MaxTokens = maxTokens
+	case isReasoningModel(req.Model):
+		req.MaxCompletion = maxTokens
+	case b.baseURL != defaultBaseURL:
+		req.MaxTokens = maxTokens
+		req.MaxCompletion = maxTokens
+	default:
+		req.MaxTokens = maxTokens
 	}
+}
 
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", b.baseURL+"/v1/chat/completions", bytes.NewReader(jsonBody))
+// post sends a request to the chat completions API, retrying transport
+// errors and rate limits. It returns the response only for a 200; the
+// caller must close its body. Error responses are read and converted to
+// errors.
+func (b *Backend) post(ctx context.Context, reqBody apiRequest) (*http.Response, error) {
+	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+b.apiKey)
-
-	// Send request with retry
-	var resp *http.Response
-	var lastErr error
-	for attempt := 0; attempt < 3; attempt++ {
-		resp, err = b.client.Do(req)
+	// Send request, retrying transient failures
+	resp, err := backend.DoWithRetry(ctx, b.client, b.retry, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", b.baseURL+"/v1/chat/completions", bytes.NewReader(jsonBody))
 		if err != nil {
-			lastErr = err
-			time.Sleep(time.Duration(attempt+1) * time.Second)
-			continue
-		}
-
-		// Check for rate limiting
-		if resp.StatusCode == http.StatusTooManyRequests {
-			resp.Body.Close()
-			retryAfter := time.Duration(attempt+1) * 10 * time.Second
-			if ra := resp.Header.Get("Retry-After"); ra != "" {
-				if d, err := time.ParseDuration(ra + "s"); err == nil {
-					retryAfter = d
-				}
-			}
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(retryAfter):
-				continue
-			}
+			return nil, err
 		}
-
-		break
-	}
-
-	if resp == nil {
-		return nil, fmt.Errorf("request failed after retries: %w", lastErr)
-	}
-	defer resp.Body.Close()
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+b.apiKey)
+		return req, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return nil, err
 	}
 
 	// Check for error response
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading response: %w", err)
+		}
 		var apiErr apiError
 		if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error.Message != "" {
+			if isModelNotFound(resp.StatusCode, apiErr) {
+				return nil, fmt.Errorf("%w: %s: %s", backend.ErrModelUnavailable, reqBody.Model, apiErr.Error.Message)
+			}
+			if reqBody.Stream && apiErr.Error.Param == "stream" {
+				return nil, fmt.Errorf("%w: %s: %s", errStreamingUnsupported, reqBody.Model, apiErr.Error.Message)
+			}
 			return nil, fmt.Errorf("API error (%s): %s", apiErr.Error.Type, apiErr.Error.Message)
 		}
 		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
-	// Parse response
-	var apiResp apiResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
-	}
+	return resp, nil
+}
 
-	// Extract content from first choice
-	var content string
-	if len(apiResp.Choices) > 0 {
-		content = apiResp.Choices[0].Message.Content
+// InvokeStream streams the response, sending each content delta as it
+// arrives. The final chunk has Done set and carries the token usage.
+// Structured output, and models that reject streaming, are delivered as a
+// single chunk instead.
+func (b *Backend) InvokeStream(ctx context.Context, messages []backend.Message, opts backend.InvokeOptions) (<-chan backend.StreamChunk, error) {
+	invokeWhole := func() <-chan backend.StreamChunk {
+		return backend.StreamInvoke(ctx, func(ctx context.Context) (*backend.InvokeResult, error) {
+			return b.Invoke(ctx, messages, opts)
+		})
 	}
-
-	finishReason := ""
-	if len(apiResp.Choices) > 0 {
-		finishReason = apiResp.Choices[0].FinishReason
+	if opts.ResponseFormat != nil {
+		return invokeWhole(), nil
 	}
 
-	return &backend.InvokeResult{
-		Content:      content,
-		Model:        apiResp.Model,
-		InputTokens:  apiResp.Usage.PromptTokens,
-		OutputTokens: apiResp.Usage.CompletionTokens,
-		FinishReason: finishReason,
-	}, nil
-}
+	// Wait for the model's rate and concurrency limits; the stream
+	// goroutine holds the slot until the response is read.
+	release, err := b.acquire(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
 
-// InvokeStream returns a streaming response channel.
-func (b *Backend) InvokeStream(ctx context.Context, messages []backend.Message, opts backend.InvokeOptions) (<-chan backend.StreamChunk, error) {
-	// For now, implement as non-streaming with single chunk
-	ch := make(chan backend.StreamChunk, 1)
+	reqBody := b.buildRequest(messages, opts)
+	reqBody.Stream = true
+	reqBody.StreamOptions = &apiStreamOptions{IncludeUsage: true}
+	resp, err := b.post(ctx, reqBody)
+	if errors.Is(err, errStreamingUnsupported) {
+		release()
+		return invokeWhole(), nil
+	}
+	if err != nil {
+		release()
+		return nil, err
+	}
 
+	ch := make(chan backend.StreamChunk, 16)
 	go func() {
+		defer release()
 		defer close(ch)
+		defer resp.Body.Close()
 
-		result, err := b.Invoke(ctx, messages, opts)
-		if err != nil {
-			ch <- backend.StreamChunk{Error: err, Done: true}
-			return
+		if err := readStream(ctx, resp.Body, ch); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				err = ctxErr
+			}
+			backend.SendChunk(ctx, ch, backend.StreamChunk{Error: err, Done: true})
 		}
-
-		ch <- backend.StreamChunk{Content: result.Content, Done: true}
 	}()
 
 	return ch, nil
 }
 
+// streamChunk is one data payload of a streamed chat completion. With
+// include_usage, the last payload before [DONE] has no choices and carries
+// the usage for the whole response.
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string          `json:"content"`
+			ToolCalls []toolCallDelta `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+// toolCallDelta is a piece of a streamed tool call. The first piece for an
+// index carries the call's ID and function name; each piece appends to its
+// arguments.
+type toolCallDelta struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// readStream parses server-sent events from body, sending content deltas
+// on ch and a final Done chunk with usage and the assembled tool calls at
+// the [DONE] sentinel. Deltas without content (role headers, tool call
+// pieces, reasoning models thinking) are not sent.
+// It returns an error for malformed or error payloads or a stream that ends
+// early; it returns nil without sending if ctx is cancelled while sending.
+func readStream(ctx context.Context, body io.Reader, ch chan<- backend.StreamChunk) error {
+	var inputTokens, outputTokens int
+	var finishReason string
+	var toolCalls []openaicompat.ToolCall
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" {
+			continue
+		}
+		if data == "[DONE]" {
+			backend.SendChunk(ctx, ch, backend.StreamChunk{
+				Done:         true,
+				InputTokens:  inputTokens,
+				OutputTokens: outputTokens,
+				FinishReason: finishReason,
+				ToolCalls:    openaicompat.FromToolCalls(toolCalls),
+			})
+			return nil
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("malformed stream chunk %q: %w", data, err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("API error (%s): %s", chunk.Error.Type, chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			inputTokens = chunk.Usage.PromptTokens
+			outputTokens = chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != "" {
+			finishReason = chunk.Choices[0].FinishReason
+		}
+		if len(chunk.Choices) > 0 {
+			toolCalls = addToolCallDeltas(toolCalls, chunk.Choices[0].Delta.ToolCalls)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		if !backend.SendChunk(ctx, ch, backend.StreamChunk{Content: chunk.Choices[0].Delta.Content}) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading stream: %w", err)
+	}
+	return fmt.Errorf("stream ended before [DONE]")
+}
+
+// addToolCallDeltas merges streamed tool call pieces into calls by index.
+func addToolCallDeltas(calls []openaicompat.ToolCall, deltas []toolCallDelta) []openaicompat.ToolCall {
+	for _, d := range deltas {
+		if d.Index < 0 {
+			continue
+		}
+		for len(calls) <= d.Index {
+			calls = append(calls, openaicompat.ToolCall{Type: "function"})
+		}
+		call := &calls[d.Index]
+		if d.ID != "" {
+			call.ID = d.ID
+		}
+		if d.Function.Name != "" {
+			call.Function.Name = d.Function.Name
+		}
+		call.Function.Arguments += d.Function.Arguments
+	}
+	return calls
+}
+
 // EstimateCost estimates the cost for given token counts.
 func (b *Backend) EstimateCost(inputTokens, outputTokens int, model string) backend.CostEstimate {
+	model = resolveModel(model)
 	if model == "" {
 		model = defaultModel
 	}
@@ -362,24 +590,32 @@ func (b *Backend) EstimateCost(inputTokens, outputTokens int, model string) back
 }
 
 // CountTokens estimates token count for messages.
-// Uses a simple character-based heuristic (4 chars ≈ 1 token).
+// Delegates to the shared token counter for the model's family.
 func (b *Backend) CountTokens(messages []backend.Message, model string) (int, error) {
-	var totalChars int
-	for _, msg := range messages {
-		totalChars += len(msg.Content)
-		totalChars += len(msg.Role) + 10 // Role overhead
+	model = resolveModel(model)
+	if model == "" {
+		model = defaultModel
 	}
-	// Rough estimate: 4 characters per token
-	return totalChars / 4, nil
+	return backend.CountTokens(messages, model)
 }
 
-// Healthy checks if the backend is reachable.
+// Healthy checks that the API key is well formed and, when ctx has a
+// deadline, that the API accepts it by listing models.
 func (b *Backend) Healthy(ctx context.Context) error {
-	// Simple health check - verify API key format
+	// Cheap pre-flight: verify API key format
 	if len(b.apiKey) < 10 {
 		return fmt.Errorf("invalid API key format")
 	}
-	return nil
+	if !backend.LiveCheckAllowed(ctx) {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", b.baseURL+"/v1/models", nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	return backend.CheckEndpoint(b.client, req)
 }
 
 // isReasoningModel checks if a model is an O1/O3 reasoning model.
@@ -387,63 +623,49 @@ func isReasoningModel(model string) bool {
 	return model == "o1" || model == "o1-mini" || model == "o1-preview" || model == "o3-mini"
 }
 
-// rateLimiter implements a simple token bucket rate limiter.
-type rateLimiter struct {
-	mu             sync.Mutex
-	tokens         int
-	maxTokens      int
-	refillInterval time.Duration
-	lastRefill     time.Time
-}
-
-func newRateLimiter(maxTokens int, interval time.Duration) *rateLimiter {
-	return &rateLimiter{
-		tokens:         maxTokens,
-		maxTokens:      maxTokens,
-		refillInterval: interval,
-		lastRefill:     time.Now(),
-	}
-}
-
-func (r *rateLimiter) Wait(ctx context.Context) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	// Refill tokens based on elapsed time
-	now := time.Now()
-	elapsed := now.Sub(r.lastRefill)
-	if elapsed >= r.refillInterval {
-		r.tokens = r.maxTokens
-		r.lastRefill = now
-	} else {
-		// Partial refill
-		refillAmount := int(float64(r.maxTokens) * (float64(elapsed) / float64(r.refillInterval)))
-		r.tokens = min(r.maxTokens, r.tokens+refillAmount)
-		if refillAmount > 0 {
-			r.lastRefill = now
-		}
+// acquire waits until the limiter admits a request to the model opts
+// names. The caller must call the returned release when done.
+func (b *Backend) acquire(ctx context.Context, opts backend.InvokeOptions) (func(), error) {
+	model := resolveModel(opts.Model)
+	if model == "" {
+		model = defaultModel
 	}
-
-	if r.tokens > 0 {
-		r.tokens--
-		return nil
+	release, err := b.limiter.Acquire(ctx, model)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit: %w", err)
 	}
+	return release, nil
+}
 
-	// Wait for next token
-	waitTime := r.refillInterval - elapsed
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-time.After(waitTime):
-		r.tokens = r.maxTokens - 1
-		r.lastRefill = time.Now()
-		return nil
+// Limits returns the request limits in effect.
+func (b *Backend) Limits() backend.Limits {
+	return b.limiter.Limits()
+}
+
+// resolveModel maps a friendly name like "opus" to a full model ID.
+func resolveModel(model string) string {
+	return backend.ResolveModel("openai", model, Aliases)
+}
+
+// isModelNotFound reports whether an API error means the model is unknown
+// or retired. OpenAI returns 404 with code "model_not_found".
+func isModelNotFound(status int, apiErr apiError) bool {
+	if apiErr.Error.Code == "model_not_found" {
+		return true
+	}
+	msg := strings.ToLower(apiErr.Error.Message)
+	if !strings.Contains(msg, "model") {
+		return false
 	}
+	return status == http.StatusNotFound ||
+		strings.Contains(msg, "does not exist") ||
+		strings.Contains(msg, "not found") ||
+		strings.Contains(msg, "deprecated")
 }
 
 // Register registers the OpenAI backend with the global registry.
-func Register() error {
-	b, err := New()
+func Register(opts ...Option) error {
+	b, err := New(opts...)
 	if err != nil {
 		return err
 	}
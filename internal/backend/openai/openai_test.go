@@ -0,0 +1,516 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/backend"
+)
+
+func TestInvokeModelNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"message":"The model 'gpt-4-0314' does not exist or you do not have access to it.","type":"invalid_request_error","code":"model_not_found"}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENAI_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	_, err = b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}},
+		backend.InvokeOptions{Model: "gpt-4-0314"})
+	if !errors.Is(err, backend.ErrModelUnavailable) {
+		t.Errorf("expected ErrModelUnavailable, got %v", err)
+	}
+}
+
+func TestInvokeOtherErrorsNotModelUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENAI_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	_, err = b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}}, backend.InvokeOptions{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if errors.Is(err, backend.ErrModelUnavailable) {
+		t.Errorf("unexpected ErrModelUnavailable for auth error: %v", err)
+	}
+}
+
+func TestInvokeResolvesModelAlias(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requested = body.Model
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"stop here"}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENAI_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	_, _ = b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}},
+		backend.InvokeOptions{Model: "gpt4"})
+	if requested != "gpt-4o" {
+		t.Errorf("requested model = %q, want gpt-4o", requested)
+	}
+	if got := b.EstimateCost(1000, 1000, "gpt4").Model; got != "gpt-4o" {
+		t.Errorf("EstimateCost model = %q, want gpt-4o", got)
+	}
+}
+
+func TestConfiguredAliasOverridesBuiltin(t *testing.T) {
+	backend.SetModelAliases("openai", map[string]string{"gpt4": "gpt-4-turbo"})
+	t.Cleanup(backend.ResetModelAliasesForTesting)
+
+	if got := resolveModel("gpt4"); got != "gpt-4-turbo" {
+		t.Errorf("resolveModel(gpt4) = %q, want gpt-4-turbo", got)
+	}
+	if got := resolveModel("gpt-4o"); got != "gpt-4o" {
+		t.Errorf("full model IDs should pass through, got %q", got)
+	}
+}
+
+func TestInvokeSendsResponseFormat(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"valid", `{"verdict":"approve"}`, false},
+		{"schema mismatch", `{"verdict":"maybe"}`, true},
+		{"not JSON", "Sure! Here is the JSON you asked for", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent struct {
+				ResponseFormat struct {
+					Type       string `json:"type"`
+					JSONSchema struct {
+						Name   string          `json:"name"`
+						Schema json.RawMessage `json:"schema"`
+					} `json:"json_schema"`
+				} `json:"response_format"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(&sent)
+				content, _ := json.Marshal(tt.content)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":` + string(content) + `},"finish_reason":"stop"}]}`))
+			}))
+			defer server.Close()
+
+			t.Setenv("OPENAI_API_KEY", "test-key-1234567890")
+			b, err := New(WithBaseURL(server.URL))
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			schema := `{"type":"object","required":["verdict"],"properties":{"verdict":{"enum":["approve","reject"]}}}`
+			_, err = b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "review"}},
+				backend.InvokeOptions{ResponseFormat: &backend.ResponseFormat{
+					Type:   backend.ResponseFormatJSONSchema,
+					Name:   "verdict",
+					Schema: json.RawMessage(schema),
+				}})
+
+			if sent.ResponseFormat.Type != "json_schema" || sent.ResponseFormat.JSONSchema.Name != "verdict" {
+				t.Errorf("response_format = %+v, want json_schema named verdict", sent.ResponseFormat)
+			}
+			if string(sent.ResponseFormat.JSONSchema.Schema) != schema {
+				t.Errorf("schema = %s, want %s", sent.ResponseFormat.JSONSchema.Schema, schema)
+			}
+			if got := errors.Is(err, backend.ErrInvalidStructuredOutput); got != tt.wantErr {
+				t.Errorf("err = %v, want ErrInvalidStructuredOutput: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// sseServer serves the given data payloads as server-sent events, flushing
+// after each one, then calls afterEvents (if set) before ending the stream.
+func sseServer(t *testing.T, payloads []string, afterEvents func(r *http.Request)) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Stream        bool `json:"stream"`
+			StreamOptions struct {
+				IncludeUsage bool `json:"include_usage"`
+			} `json:"stream_options"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if !body.Stream || !body.StreamOptions.IncludeUsage {
+			t.Errorf("expected stream:true with include_usage, got %+v", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, p := range payloads {
+			_, _ = w.Write([]byte("data: " + p + "\n\n"))
+			flusher.Flush()
+		}
+		if afterEvents != nil {
+			afterEvents(r)
+		}
+	}))
+}
+
+func newStreamTestBackend(t *testing.T, url string) *Backend {
+	t.Helper()
+	t.Setenv("OPENAI_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(url))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return b
+}
+
+func TestInvokeStreamDeltasAndUsage(t *testing.T) {
+	server := sseServer(t, []string{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"Hello"}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":", world"}}]}`,
+		`{"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":9,"completion_tokens":4,"total_tokens":13}}`,
+		`[DONE]`,
+	}, nil)
+	defer server.Close()
+
+	b := newStreamTestBackend(t, server.URL)
+	ch, err := b.InvokeStream(context.Background(), []backend.Message{{Role: "user", Content: "hi"}}, backend.InvokeOptions{})
+	if err != nil {
+		t.Fatalf("InvokeStream: %v", err)
+	}
+
+	var deltas []string
+	var final backend.StreamChunk
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected error chunk: %v", chunk.Error)
+		}
+		if chunk.Done {
+			final = chunk
+			continue
+		}
+		deltas = append(deltas, chunk.Content)
+	}
+
+	if len(deltas) != 2 || deltas[0] != "Hello" || deltas[1] != ", world" {
+		t.Errorf("deltas = %q, want [Hello , world]", deltas)
+	}
+	if !final.Done || final.InputTokens != 9 || final.OutputTokens != 4 || final.FinishReason != "stop" {
+		t.Errorf("final chunk = %+v, want Done with 9 in / 4 out, stop", final)
+	}
+}
+
+func TestInvokeStreamAssemblesToolCalls(t *testing.T) {
+	server := sseServer(t, []string{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","content":null,"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"get_time","arguments":""}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`[DONE]`,
+	}, nil)
+	defer server.Close()
+
+	b := newStreamTestBackend(t, server.URL)
+	ch, err := b.InvokeStream(context.Background(), []backend.Message{{Role: "user", Content: "weather in Paris?"}}, backend.InvokeOptions{})
+	if err != nil {
+		t.Fatalf("InvokeStream: %v", err)
+	}
+
+	var final backend.StreamChunk
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected error chunk: %v", chunk.Error)
+		}
+		if !chunk.Done {
+			t.Errorf("unexpected content chunk %q", chunk.Content)
+			continue
+		}
+		final = chunk
+	}
+
+	if final.FinishReason != "tool_calls" || len(final.ToolCalls) != 2 {
+		t.Fatalf("final chunk = %+v, want two tool calls", final)
+	}
+	if call := final.ToolCalls[0]; call.ID != "call_1" || call.Name != "get_weather" || string(call.Arguments) != `{"city":"Paris"}` {
+		t.Errorf("first tool call = %+v", call)
+	}
+	if call := final.ToolCalls[1]; call.ID != "call_2" || call.Name != "get_time" || string(call.Arguments) != `{}` {
+		t.Errorf("second tool call = %+v", call)
+	}
+}
+
+func TestInvokeStreamReasoningModelFallsBack(t *testing.T) {
+	var streamed, whole int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Stream bool `json:"stream"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		if body.Stream {
+			streamed++
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"Unsupported value: 'stream' does not support true with this model.","type":"invalid_request_error","param":"stream","code":"unsupported_value"}}`))
+			return
+		}
+		whole++
+		_, _ = w.Write([]byte(`{"model":"o1","choices":[{"index":0,"message":{"role":"assistant","content":"42"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":300}}`))
+	}))
+	defer server.Close()
+
+	b := newStreamTestBackend(t, server.URL)
+	ch, err := b.InvokeStream(context.Background(), []backend.Message{{Role: "user", Content: "hi"}}, backend.InvokeOptions{Model: "o1"})
+	if err != nil {
+		t.Fatalf("InvokeStream: %v", err)
+	}
+
+	var content string
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected error chunk: %v", chunk.Error)
+		}
+		content += chunk.Content
+	}
+	if content != "42" {
+		t.Errorf("content = %q, want 42", content)
+	}
+	if streamed != 1 || whole != 1 {
+		t.Errorf("requests: streamed=%d whole=%d, want 1 each", streamed, whole)
+	}
+}
+
+func TestInvokeStreamMalformedChunk(t *testing.T) {
+	server := sseServer(t, []string{
+		`{"choices":[{"index":0,"delta":{"content":"ok"}}]}`,
+		`{not json`,
+	}, nil)
+	defer server.Close()
+
+	b := newStreamTestBackend(t, server.URL)
+	ch, err := b.InvokeStream(context.Background(), []backend.Message{{Role: "user", Content: "hi"}}, backend.InvokeOptions{})
+	if err != nil {
+		t.Fatalf("InvokeStream: %v", err)
+	}
+
+	var last backend.StreamChunk
+	for chunk := range ch {
+		last = chunk
+	}
+	if last.Error == nil || !strings.Contains(last.Error.Error(), "malformed stream chunk") {
+		t.Errorf("last chunk = %+v, want malformed chunk error", last)
+	}
+}
+
+func TestInvokeStreamCancellationClosesConnection(t *testing.T) {
+	connClosed := make(chan struct{})
+	server := sseServer(t, []string{
+		`{"choices":[{"index":0,"delta":{"content":"first"}}]}`,
+	}, func(r *http.Request) {
+		// Hold the stream open until the client goes away
+		<-r.Context().Done()
+		close(connClosed)
+	})
+	defer server.Close()
+
+	b := newStreamTestBackend(t, server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := b.InvokeStream(ctx, []backend.Message{{Role: "user", Content: "hi"}}, backend.InvokeOptions{})
+	if err != nil {
+		t.Fatalf("InvokeStream: %v", err)
+	}
+
+	if chunk := <-ch; chunk.Content != "first" {
+		t.Fatalf("first chunk = %+v, want content first", chunk)
+	}
+	cancel()
+
+	closed := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream channel not closed after cancellation")
+	}
+	select {
+	case <-connClosed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("HTTP stream not closed after cancellation")
+	}
+}
+
+func TestInvokeRetriesTransientStatus(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`bad gateway`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENAI_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL), WithRetry(3, time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	result, err := b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}}, backend.InvokeOptions{})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if result.Content != "hello" || calls != 2 {
+		t.Errorf("content = %q after %d calls, want hello after 2", result.Content, calls)
+	}
+}
+
+func TestInvokeRoundTripsToolCalls(t *testing.T) {
+	var sent apiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"m","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":null,` +
+			`"tool_calls":[{"id":"call_2","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Oslo\"}"}}]}}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENAI_API_KEY", "test-key-1234567890")
+	b, err := New(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	messages := []backend.Message{
+		{Role: "user", Content: "Weather in Paris?"},
+		{Role: "assistant", ToolCalls: []backend.ToolCall{
+			{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Paris"}`)},
+		}},
+		backend.ToolResultMessage("call_1", "18C"),
+	}
+	schema := `{"type":"object","properties":{"city":{"type":"string"}}}`
+	result, err := b.Invoke(context.Background(), messages, backend.InvokeOptions{
+		Tools: []backend.ToolDef{{Name: "get_weather", Description: "Current weather", InputSchema: json.RawMessage(schema)}},
+	})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+
+	if len(sent.Tools) != 1 || sent.Tools[0].Type != "function" || sent.Tools[0].Function.Name != "get_weather" ||
+		string(sent.Tools[0].Function.Parameters) != schema {
+		t.Errorf("tools = %+v", sent.Tools)
+	}
+	if len(sent.Messages) != 3 {
+		t.Fatalf("sent %d messages, want 3", len(sent.Messages))
+	}
+	if calls := sent.Messages[1].ToolCalls; len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("assistant tool calls = %+v", calls)
+	}
+	if tool := sent.Messages[2]; tool.Role != "tool" || tool.ToolCallID != "call_1" || tool.Content != "18C" {
+		t.Errorf("tool result = %+v", tool)
+	}
+
+	if result.FinishReason != "tool_calls" || result.Content != "" {
+		t.Errorf("result finish=%q content=%q", result.FinishReason, result.Content)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].ID != "call_2" ||
+		result.ToolCalls[0].Name != "get_weather" || string(result.ToolCalls[0].Arguments) != `{"city":"Oslo"}` {
+		t.Errorf("tool calls = %+v", result.ToolCalls)
+	}
+}
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestInvokeSendsMaxTokensField(t *testing.T) {
+	var sent map[string]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = nil
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"stop here"}}`))
+	}))
+	defer server.Close()
+
+	// Sends requests for the real API to the stub server
+	serverURL, _ := url.Parse(server.URL)
+	redirect := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		r.URL.Scheme, r.URL.Host = serverURL.Scheme, serverURL.Host
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+
+	tests := []struct {
+		name           string
+		opts           []Option
+		model          string
+		wantMax        bool
+		wantCompletion bool
+	}{
+		{"openai chat model", []Option{WithHTTPClient(redirect)}, "gpt-4o", true, false},
+		{"openai reasoning model", []Option{WithHTTPClient(redirect)}, "o1", false, true},
+		{"custom base URL", []Option{WithBaseURL(server.URL)}, "gpt-4o", true, true},
+		{"custom base URL reasoning model", []Option{WithBaseURL(server.URL)}, "o3-mini", false, true},
+		{"compatibility mode", []Option{WithBaseURL(server.URL), WithCompatibilityMode(true)}, "gpt-4o", true, false},
+	}
+
+	t.Setenv("OPENAI_API_KEY", "test-key-1234567890")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := New(append(tt.opts, WithRetry(1, 0))...)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			_, _ = b.Invoke(context.Background(), []backend.Message{{Role: "user", Content: "hi"}},
+				backend.InvokeOptions{Model: tt.model, MaxTokens: 512})
+
+			if sent == nil {
+				t.Fatal("no request reached the stub server")
+			}
+			for field, want := range map[string]bool{"max_tokens": tt.wantMax, "max_completion_tokens": tt.wantCompletion} {
+				got, ok := sent[field]
+				if ok != want {
+					t.Errorf("%s sent = %v, want %v", field, ok, want)
+				} else if ok && string(got) != "512" {
+					t.Errorf("%s = %s, want 512", field, got)
+				}
+			}
+		})
+	}
+}
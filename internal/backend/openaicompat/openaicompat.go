@@ -0,0 +1,135 @@
+// Package openaicompat holds the request and response shapes shared by
+// backends that speak the OpenAI chat completions API.
+package openaicompat
+
+import (
+	"encoding/json"
+
+	"github.com/steveyegge/gastown/internal/backend"
+)
+
+// Message is a message in the API request and response.
+type Message struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// Tool defines a function the model may call.
+type Tool struct {
+	Type     string   `json:"type"` // always "function"
+	Function Function `json:"function"`
+}
+
+// Function describes a callable function.
+type Function struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// ToolCall is a function call made by an assistant message.
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall names the function and its JSON-encoded arguments.
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ToMessages converts messages for the request, including the tool calls
+// an assistant made and the tool results answering them. A non-empty
+// systemMsg replaces any system messages, as with the other backends.
+func ToMessages(messages []backend.Message, systemMsg string) []Message {
+	var apiMessages []Message
+	if systemMsg != "" {
+		apiMessages = append(apiMessages, Message{Role: "system", Content: systemMsg})
+	}
+	for _, msg := range messages {
+		if systemMsg != "" && msg.Role == "system" {
+			continue
+		}
+		apiMsg := Message{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCallID: msg.ToolCallID,
+		}
+		for _, call := range msg.ToolCalls {
+			args := string(call.Arguments)
+			if args == "" {
+				args = "{}"
+			}
+			apiMsg.ToolCalls = append(apiMsg.ToolCalls, ToolCall{
+				ID:       call.ID,
+				Type:     "function",
+				Function: FunctionCall{Name: call.Name, Arguments: args},
+			})
+		}
+		apiMessages = append(apiMessages, apiMsg)
+	}
+	return apiMessages
+}
+
+// ToTools converts tool definitions to function tools.
+func ToTools(tools []backend.ToolDef) []Tool {
+	var apiTools []Tool
+	for _, tool := range tools {
+		apiTools = append(apiTools, Tool{
+			Type: "function",
+			Function: Function{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Schema(),
+			},
+		})
+	}
+	return apiTools
+}
+
+// FromToolCalls converts the response's function calls.
+func FromToolCalls(calls []ToolCall) []backend.ToolCall {
+	var toolCalls []backend.ToolCall
+	for _, call := range calls {
+		args := json.RawMessage(call.Function.Arguments)
+		if len(args) == 0 {
+			args = json.RawMessage(`{}`)
+		}
+		toolCalls = append(toolCalls, backend.ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: args,
+		})
+	}
+	return toolCalls
+}
+
+// ResponseFormat constrains the response to JSON.
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema names the schema for a json_schema response format.
+type JSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// ToResponseFormat converts a backend.ResponseFormat for the request.
+func ToResponseFormat(f *backend.ResponseFormat) *ResponseFormat {
+	if f == nil {
+		return nil
+	}
+	if f.Type == backend.ResponseFormatJSONSchema {
+		return &ResponseFormat{
+			Type:       backend.ResponseFormatJSONSchema,
+			JSONSchema: &JSONSchema{Name: f.SchemaName(), Schema: f.ObjectSchema()},
+		}
+	}
+	return &ResponseFormat{Type: backend.ResponseFormatJSONObject}
+}
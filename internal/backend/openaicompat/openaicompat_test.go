@@ -0,0 +1,52 @@
+package openaicompat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/backend"
+)
+
+func TestToMessagesReplacesSystemAndCarriesToolCalls(t *testing.T) {
+	messages := []backend.Message{
+		{Role: "system", Content: "ignored"},
+		{Role: "user", Content: "weather?"},
+		{Role: "assistant", ToolCalls: []backend.ToolCall{{ID: "call_1", Name: "get_weather"}}},
+		{Role: "tool", Content: "sunny", ToolCallID: "call_1"},
+	}
+
+	got := ToMessages(messages, "be brief")
+	if len(got) != 4 {
+		t.Fatalf("ToMessages returned %d messages, want 4: %+v", len(got), got)
+	}
+	if got[0].Role != "system" || got[0].Content != "be brief" {
+		t.Errorf("system message = %+v, want the replacement", got[0])
+	}
+	call := got[2].ToolCalls
+	if len(call) != 1 || call[0].Type != "function" || call[0].Function.Name != "get_weather" || call[0].Function.Arguments != "{}" {
+		t.Errorf("assistant tool calls = %+v", call)
+	}
+	if got[3].ToolCallID != "call_1" {
+		t.Errorf("tool result ToolCallID = %q, want call_1", got[3].ToolCallID)
+	}
+}
+
+func TestFromToolCallsDefaultsEmptyArguments(t *testing.T) {
+	got := FromToolCalls([]ToolCall{
+		{ID: "a", Function: FunctionCall{Name: "f", Arguments: `{"x":1}`}},
+		{ID: "b", Function: FunctionCall{Name: "g"}},
+	})
+	if len(got) != 2 {
+		t.Fatalf("FromToolCalls returned %d calls, want 2", len(got))
+	}
+	if string(got[0].Arguments) != `{"x":1}` || string(got[1].Arguments) != `{}` {
+		t.Errorf("arguments = %s, %s", got[0].Arguments, got[1].Arguments)
+	}
+}
+
+func TestToToolsUsesFunctionType(t *testing.T) {
+	tools := ToTools([]backend.ToolDef{{Name: "f", Description: "does f", InputSchema: json.RawMessage(`{"type":"object"}`)}})
+	if len(tools) != 1 || tools[0].Type != "function" || tools[0].Function.Name != "f" {
+		t.Errorf("ToTools = %+v", tools)
+	}
+}
@@ -0,0 +1,74 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket shared by every request a backend makes.
+// The bucket starts full, so up to maxTokens requests go out at once, then
+// refills continuously at maxTokens per interval: a 60-per-minute limiter
+// settles at one request per second.
+type RateLimiter struct {
+	mu         sync.Mutex
+	tokens     float64 // may go negative: callers waiting on a reserved token
+	maxTokens  float64
+	perToken   time.Duration
+	lastRefill time.Time
+}
+
+// NewRateLimiter creates a limiter allowing maxTokens requests per interval.
+func NewRateLimiter(maxTokens int, interval time.Duration) *RateLimiter {
+	if maxTokens < 1 {
+		maxTokens = 1
+	}
+	return &RateLimiter{
+		tokens:     float64(maxTokens),
+		maxTokens:  float64(maxTokens),
+		perToken:   interval / time.Duration(maxTokens),
+		lastRefill: time.Now(),
+	}
+}
+
+// Wait blocks until the caller may send a request or ctx is done.
+// Waiting callers each reserve a token up front, so they're released one
+// refill apart in arrival order instead of racing for the next token.
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	r.mu.Lock()
+	r.refillLocked(time.Now())
+	r.tokens--
+	deficit := -r.tokens
+	r.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(deficit * float64(r.perToken)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		// Hand the reserved token back
+		r.mu.Lock()
+		r.tokens++
+		r.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// refillLocked adds the fractional tokens earned since the last refill.
+// The caller must hold r.mu.
+func (r *RateLimiter) refillLocked(now time.Time) {
+	elapsed := now.Sub(r.lastRefill)
+	if elapsed <= 0 {
+		return
+	}
+	r.tokens += float64(elapsed) / float64(r.perToken)
+	if r.tokens > r.maxTokens {
+		r.tokens = r.maxTokens
+	}
+	r.lastRefill = now
+}
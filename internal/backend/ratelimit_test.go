@@ -0,0 +1,96 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterConcurrentTimingEnvelope(t *testing.T) {
+	// 10 per 100ms is 60 RPM scaled down 600x: a full bucket of 10, then
+	// one request every 10ms.
+	const burst, interval, callers = 10, 100 * time.Millisecond, 120
+	limiter := NewRateLimiter(burst, interval)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	released := make([]time.Duration, 0, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Wait(context.Background()); err != nil {
+				t.Errorf("Wait: %v", err)
+				return
+			}
+			mu.Lock()
+			released = append(released, time.Since(start))
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// The 110 callers beyond the burst need 110 refills of 10ms each
+	minElapsed := time.Duration(callers-burst) * interval / burst
+	if elapsed < minElapsed-5*time.Millisecond {
+		t.Errorf("%d callers finished in %v, faster than the %v the limit allows", callers, elapsed, minElapsed)
+	}
+	if elapsed > minElapsed+500*time.Millisecond {
+		t.Errorf("%d callers took %v, want about %v (bucket starved?)", callers, elapsed, minElapsed)
+	}
+
+	// Only the burst goes out immediately
+	immediate := 0
+	for _, d := range released {
+		if d < interval/burst/2 {
+			immediate++
+		}
+	}
+	if immediate > burst {
+		t.Errorf("%d callers released immediately, want at most %d", immediate, burst)
+	}
+}
+
+func TestRateLimiterSteadyState(t *testing.T) {
+	limiter := NewRateLimiter(10, 100*time.Millisecond)
+	ctx := context.Background()
+
+	// Drain the burst, then calls 5ms apart must never starve: each waits
+	// at most one refill period (10ms) plus scheduling slack.
+	for i := 0; i < 10; i++ {
+		_ = limiter.Wait(ctx)
+	}
+	for i := 0; i < 20; i++ {
+		time.Sleep(5 * time.Millisecond)
+		start := time.Now()
+		if err := limiter.Wait(ctx); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+		if waited := time.Since(start); waited > 30*time.Millisecond {
+			t.Fatalf("call %d waited %v, want at most ~10ms", i, waited)
+		}
+	}
+}
+
+func TestRateLimiterCancelReturnsToken(t *testing.T) {
+	limiter := NewRateLimiter(1, time.Hour)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Wait on empty bucket = %v, want DeadlineExceeded", err)
+	}
+
+	limiter.mu.Lock()
+	tokens := limiter.tokens
+	limiter.mu.Unlock()
+	if tokens < -0.01 {
+		t.Errorf("tokens = %v after cancel, want the reservation returned", tokens)
+	}
+}
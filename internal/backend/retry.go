@@ -0,0 +1,218 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how HTTP backends retry transient failures.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+
+	// BaseDelay is the backoff before the first retry; each later retry
+	// doubles it. The actual wait is drawn uniformly from [0, backoff)
+	// ("full jitter") so concurrent callers don't retry in lockstep.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the backoff. A server's Retry-After is honored up to
+	// MaxRetryAfter instead.
+	MaxDelay time.Duration
+}
+
+// MaxRetryAfter caps how long a Retry-After header can make a request wait,
+// so a misbehaving server can't stall a task indefinitely.
+const MaxRetryAfter = 5 * time.Minute
+
+// DefaultRetryPolicy is used by HTTP backends unless overridden.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// IsRetryableStatus reports whether an HTTP status is worth retrying:
+// rate limiting (429), transient server errors (500, 502, 503), and
+// Anthropic's overloaded status (529).
+func IsRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		529:
+		return true
+	}
+	return false
+}
+
+// DoWithRetry sends the request built by newRequest, retrying network
+// errors and retryable statuses with exponential backoff. A Retry-After
+// header, when present, replaces the backoff for that retry. newRequest is
+// called per attempt so the body can be re-read.
+//
+// When attempts run out on a retryable status, the last response is
+// returned so the caller can report the API's error body.
+func DoWithRetry(ctx context.Context, client *http.Client, policy RetryPolicy, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := retrySleep(ctx, retryDelay(policy, attempt, lastErr)); err != nil {
+				return nil, err
+			}
+		}
+
+		req, err := newRequest()
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+
+		if !IsRetryableStatus(resp.StatusCode) || attempt == attempts-1 {
+			return resp, nil
+		}
+
+		// Drain and close so the connection can be reused
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		lastErr = &retryableStatusError{
+			status:     resp.StatusCode,
+			retryAfter: retryAfterDelay(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	return nil, fmt.Errorf("request failed after %d attempts: %w", attempts, lastErr)
+}
+
+// Retry calls attempt until it succeeds, fails with an error retryable
+// rejects, or policy.MaxAttempts run out, backing off between attempts as
+// DoWithRetry does. It serves SDK clients that don't send their own HTTP
+// requests. The last attempt's error is returned unwrapped.
+func Retry(ctx context.Context, policy RetryPolicy, retryable func(error) bool, attempt func() error) error {
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			if sleepErr := retrySleep(ctx, backoff(policy, i)); sleepErr != nil {
+				return sleepErr
+			}
+		}
+		if err = attempt(); err == nil || !retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// retryableStatusError records a retryable response between attempts.
+type retryableStatusError struct {
+	status     int
+	retryAfter time.Duration // 0 if the server didn't say
+}
+
+func (e *retryableStatusError) Error() string {
+	return fmt.Sprintf("status %d", e.status)
+}
+
+// retryDelay returns how long to wait before the given retry attempt.
+func retryDelay(policy RetryPolicy, attempt int, lastErr error) time.Duration {
+	if se, ok := lastErr.(*retryableStatusError); ok && se.retryAfter > 0 {
+		return se.retryAfter
+	}
+	return backoff(policy, attempt)
+}
+
+// backoff returns a full-jitter delay for the given retry attempt (1-based):
+// uniform in [0, min(MaxDelay, BaseDelay*2^(attempt-1))).
+func backoff(policy RetryPolicy, attempt int) time.Duration {
+	if policy.BaseDelay <= 0 {
+		return 0
+	}
+	ceiling := policy.BaseDelay
+	for i := 1; i < attempt; i++ {
+		ceiling *= 2
+		if policy.MaxDelay > 0 && ceiling >= policy.MaxDelay {
+			break
+		}
+	}
+	if policy.MaxDelay > 0 && ceiling > policy.MaxDelay {
+		ceiling = policy.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// retryAfterDelay returns the wait a Retry-After header asks for, clamped
+// to MaxRetryAfter, or 0 to fall back to backoff.
+func retryAfterDelay(value string) time.Duration {
+	d := parseRetryAfter(value)
+	if d <= 0 {
+		return 0
+	}
+	if d > MaxRetryAfter {
+		log.Printf("[retry] Retry-After %q asks for %s; clamping to %s", value, d.Round(time.Second), MaxRetryAfter)
+		return MaxRetryAfter
+	}
+	log.Printf("[retry] Retry-After %q: waiting %s", value, d.Round(time.Millisecond))
+	return d
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date. It returns 0 when the header is missing or unparseable.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// retrySleep waits between attempts; tests replace it to observe delays.
+var retrySleep = sleepContext
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
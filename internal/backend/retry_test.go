@@ -0,0 +1,184 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fastRetry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+func retryServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		status := statuses[len(statuses)-1]
+		if n <= len(statuses) {
+			status = statuses[n-1]
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("body"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func doRetry(ctx context.Context, server *httptest.Server, policy RetryPolicy) (*http.Response, error) {
+	return DoWithRetry(ctx, server.Client(), policy, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "POST", server.URL, strings.NewReader("payload"))
+	})
+}
+
+func TestDoWithRetryRetriesTransientStatuses(t *testing.T) {
+	for _, status := range []int{429, 500, 502, 503, 529} {
+		server, calls := retryServer(t, status, http.StatusOK)
+		resp, err := doRetry(context.Background(), server, fastRetry)
+		if err != nil {
+			t.Fatalf("status %d: %v", status, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || calls.Load() != 2 {
+			t.Errorf("status %d: got %d after %d calls, want 200 after 2", status, resp.StatusCode, calls.Load())
+		}
+	}
+}
+
+func TestDoWithRetryDoesNotRetryClientErrors(t *testing.T) {
+	server, calls := retryServer(t, http.StatusBadRequest)
+	resp, err := doRetry(context.Background(), server, fastRetry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || calls.Load() != 1 {
+		t.Errorf("got %d after %d calls, want 400 after 1", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestDoWithRetryReturnsLastResponseWhenExhausted(t *testing.T) {
+	server, calls := retryServer(t, http.StatusServiceUnavailable)
+	resp, err := doRetry(context.Background(), server, fastRetry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 3 {
+		t.Errorf("got %d after %d calls, want 503 after 3", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestDoWithRetryNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	_, err := doRetry(context.Background(), server, fastRetry)
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("err = %v, want failure after 3 attempts", err)
+	}
+}
+
+func TestDoWithRetryStopsOnCancel(t *testing.T) {
+	server, calls := retryServer(t, http.StatusServiceUnavailable)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := doRetry(ctx, server, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if calls.Load() > 1 {
+		t.Errorf("made %d calls after cancel, want at most 1", calls.Load())
+	}
+}
+
+func TestRetryDelayHonorsRetryAfter(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	got := retryDelay(policy, 1, &retryableStatusError{status: 429, retryAfter: 7 * time.Second})
+	if got != 7*time.Second {
+		t.Errorf("retryDelay = %v, want Retry-After 7s", got)
+	}
+
+	if got := parseRetryAfter("12"); got != 12*time.Second {
+		t.Errorf("parseRetryAfter(12) = %v, want 12s", got)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got <= 0 || got > time.Minute {
+		t.Errorf("parseRetryAfter(date) = %v, want (0, 1m]", got)
+	}
+	if got := parseRetryAfter("soon"); got != 0 {
+		t.Errorf("parseRetryAfter(soon) = %v, want 0", got)
+	}
+}
+
+func TestBackoffIsCappedFullJitter(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 1; attempt <= 10; attempt++ {
+		ceiling := min(policy.BaseDelay<<(attempt-1), policy.MaxDelay)
+		for i := 0; i < 50; i++ {
+			if d := backoff(policy, attempt); d < 0 || d >= ceiling {
+				t.Fatalf("backoff(attempt %d) = %v, want [0, %v)", attempt, d, ceiling)
+			}
+		}
+	}
+}
+
+func TestDoWithRetryHonorsRetryAfterFormats(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter func() string
+		wantMin    time.Duration
+		wantMax    time.Duration
+	}{
+		{"seconds", func() string { return "3" }, 3 * time.Second, 3 * time.Second},
+		{"http date", func() string {
+			return time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat)
+		}, 88 * time.Second, 90 * time.Second},
+		{"absurd seconds", func() string { return "86400" }, MaxRetryAfter, MaxRetryAfter},
+		{"absurd date", func() string {
+			return time.Now().Add(48 * time.Hour).UTC().Format(http.TimeFormat)
+		}, MaxRetryAfter, MaxRetryAfter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var waits []time.Duration
+			orig := retrySleep
+			retrySleep = func(ctx context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+			t.Cleanup(func() { retrySleep = orig })
+
+			var calls int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls == 1 {
+					w.Header().Set("Retry-After", tt.retryAfter())
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			resp, err := doRetry(context.Background(), server, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if len(waits) != 1 {
+				t.Fatalf("waited %d times, want 1", len(waits))
+			}
+			if waits[0] < tt.wantMin || waits[0] > tt.wantMax {
+				t.Errorf("waited %v, want [%v, %v]", waits[0], tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
@@ -2,8 +2,10 @@
 package backend
 
 import (
+	"fmt"
 	"log"
 	"strings"
+	"sync"
 )
 
 // RoutingConfig contains user-configurable routing rules.
@@ -32,6 +34,39 @@ type RoutingConfig struct {
 
 	// Rules are custom routing rules applied in order.
 	Rules []RoutingRule `json:"rules,omitempty"`
+
+	// Models are merged over the built-in ModelCapabilities table (see
+	// MergeModelCapabilities): an entry for an existing backend/model
+	// replaces it, and new entries are appended. Invalid entries are
+	// logged and ignored.
+	Models []ModelCapability `json:"models,omitempty"`
+
+	// ModelOverrides adjusts ModelCapabilities speed/cost values,
+	// keyed by "backend/model". It applies after Models.
+	ModelOverrides map[string]ModelOverride `json:"model_overrides,omitempty"`
+
+	// BalancedCostWeight is how much cost (vs. speed) counts for
+	// IntentBalanced, from 0 (speed only) to 1 (cost only). Nil uses
+	// DefaultBalancedCostWeight.
+	BalancedCostWeight *float64 `json:"balanced_cost_weight,omitempty"`
+
+	// ForceCLILabels is a safety list, checked before any other routing:
+	// a task carrying any of these labels (case-insensitive) always routes
+	// to CLI and is never sent to a third-party API.
+	ForceCLILabels []string `json:"force_cli_labels,omitempty"`
+
+	// Patterns add to or adjust the task analyzer's built-in keywords
+	// (see MergeAnalyzerPatterns).
+	Patterns *AnalyzerPatterns `json:"patterns,omitempty"`
+}
+
+// balancedCostWeight returns the IntentBalanced cost weight, or
+// DefaultBalancedCostWeight when none is configured.
+func (c *RoutingConfig) balancedCostWeight() float64 {
+	if c.BalancedCostWeight == nil {
+		return DefaultBalancedCostWeight
+	}
+	return *c.BalancedCostWeight
 }
 
 // RoutingRule defines a custom routing condition.
@@ -48,6 +83,9 @@ type RoutingRule struct {
 	Route   RoutingDecision `json:"route"`
 	Backend string          `json:"backend,omitempty"`
 	Model   string          `json:"model,omitempty"`
+
+	// WriteBack records API results on the bead (opt-in per rule).
+	WriteBack bool `json:"write_back,omitempty"`
 }
 
 // RoutingHints contains hints extracted from task metadata.
@@ -82,6 +120,10 @@ type Router struct {
 	config   *RoutingConfig
 	registry *Registry
 	analyzer *TaskAnalyzer
+
+	mu           sync.RWMutex
+	capabilities []ModelCapability
+	excluded     map[string]bool // "backend/model" keys excluded for this session
 }
 
 // NewRouter creates a new router with the given config.
@@ -90,21 +132,102 @@ func NewRouter(config *RoutingConfig) *Router {
 		config = DefaultRoutingConfig()
 	}
 	return &Router{
-		config:   config,
-		registry: GetRegistry(),
-		analyzer: NewTaskAnalyzer(),
+		config:       config,
+		registry:     GetRegistry(),
+		analyzer:     NewTaskAnalyzer(config.Patterns),
+		capabilities: ApplyModelOverrides(MergeModelCapabilities(ModelCapabilities, validModels(config.Models)), config.ModelOverrides),
+		excluded:     make(map[string]bool),
+	}
+}
+
+// Analyzer returns the task analyzer the router scores tasks with.
+func (r *Router) Analyzer() *TaskAnalyzer {
+	return r.analyzer
+}
+
+// validModels returns the configured models that pass Validate, logging
+// the rest.
+func validModels(models []ModelCapability) []ModelCapability {
+	valid := make([]ModelCapability, 0, len(models))
+	for _, m := range models {
+		if err := m.Validate(); err != nil {
+			log.Printf("[router] Ignoring model %s/%s: %v", m.Backend, m.Model, err)
+			continue
+		}
+		valid = append(valid, m)
+	}
+	return valid
+}
+
+// ExcludeModel stops the router from selecting a model for the rest of
+// the session, e.g. after the provider reports it retired.
+func (r *Router) ExcludeModel(backendName, model string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := backendName + "/" + model
+	if r.excluded[key] {
+		return
+	}
+	r.excluded[key] = true
+
+	kept := make([]ModelCapability, 0, len(r.capabilities))
+	for _, c := range r.capabilities {
+		if c.Backend+"/"+c.Model != key {
+			kept = append(kept, c)
+		}
+	}
+	r.capabilities = kept
+	log.Printf("[router] Excluding %s for this session", key)
+}
+
+// isExcluded reports whether a model was excluded via ExcludeModel.
+func (r *Router) isExcluded(backendName, model string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.excluded[backendName+"/"+model]
+}
+
+// forcedCLILabel returns the first label matching ForceCLILabels, or "".
+func (r *Router) forcedCLILabel(labels []string) string {
+	for _, label := range labels {
+		for _, forced := range r.config.ForceCLILabels {
+			if strings.EqualFold(label, forced) {
+				return label
+			}
+		}
 	}
+	return ""
 }
 
-// DefaultRoutingConfig returns sensible defaults.
+// selectModel runs model selection over the router's capability table.
+func (r *Router) selectModel(complexity *TaskComplexity, intent Intent, availableBackends []string) *ModelCapability {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return selectModelWeighted(r.capabilities, complexity, intent, availableBackends, r.config.balancedCostWeight())
+}
+
+// Routing defaults. These are the single source for both
+// DefaultRoutingConfig and config.NewBackendConfig, so a town without
+// settings/backend.json resolves to the same routing as the built-in default.
+const (
+	DefaultBackendName    = "claude"
+	DefaultModelName      = "claude-haiku-3-5-20241022"
+	DefaultCostThreshold  = 0.50  // $0.50 max per API task
+	DefaultTokenThreshold = 50000 // 50k tokens before CLI
+)
+
+// DefaultRoutingConfig returns sensible defaults: hybrid routing is opt-in,
+// and API tasks are capped by DefaultCostThreshold and DefaultTokenThreshold.
+// Users override these in settings/backend.json.
 func DefaultRoutingConfig() *RoutingConfig {
 	return &RoutingConfig{
 		Enabled:        false, // Opt-in
 		DefaultRoute:   RouteCLI,
-		DefaultBackend: "bedrock",
-		DefaultModel:   "haiku",
-		CostThreshold:  0.50,  // $0.50 max per API task
-		TokenThreshold: 50000, // 50k tokens before CLI
+		DefaultBackend: DefaultBackendName,
+		DefaultModel:   DefaultModelName,
+		CostThreshold:  DefaultCostThreshold,
+		TokenThreshold: DefaultTokenThreshold,
 		FallbackToCLI:  true,
 	}
 }
@@ -123,6 +246,76 @@ func (r *Router) Route(hints *RoutingHints) *RouteResult {
 		hints = &RoutingHints{}
 	}
 
+	// Safety: sensitive labels never leave for an API, whatever the rules say
+	if label := r.forcedCLILabel(hints.Labels); label != "" {
+		return &RouteResult{
+			Decision: RouteCLI,
+			Reason:   fmt.Sprintf("label %q forces CLI", label),
+		}
+	}
+
+	// Custom rules are matched in order; the matched rule rides along on
+	// the result so callers can honor its options (e.g. WriteBack)
+	rule := r.matchRule(hints)
+	result := r.routeByRule(rule)
+	if result == nil {
+		result = r.routeTask(hints)
+	}
+	result.Rule = rule
+	return result
+}
+
+// matchRule returns the first rule whose match conditions all hold for the
+// hints, or nil. An empty condition matches any task.
+func (r *Router) matchRule(hints *RoutingHints) *RoutingRule {
+	for i := range r.config.Rules {
+		rule := &r.config.Rules[i]
+		if len(rule.TypeMatch) > 0 && !contains(rule.TypeMatch, hints.Type) {
+			continue
+		}
+		if len(rule.ModelTagMatch) > 0 && !contains(rule.ModelTagMatch, hints.ModelTag) {
+			continue
+		}
+		if len(rule.TierMatch) > 0 && !contains(rule.TierMatch, hints.Tier) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// routeByRule applies a matched rule's action. It returns nil when the rule
+// leaves model selection to the router: an API rule without a backend, or
+// one whose backend or model isn't available.
+func (r *Router) routeByRule(rule *RoutingRule) *RouteResult {
+	if rule == nil {
+		return nil
+	}
+	if rule.Route != RouteAPI {
+		return &RouteResult{
+			Decision: RouteCLI,
+			Reason:   fmt.Sprintf("rule %q routes to CLI", rule.Name),
+		}
+	}
+	if rule.Backend == "" {
+		return nil
+	}
+	if !r.registry.Has(rule.Backend) || (rule.Model != "" && r.isExcluded(rule.Backend, rule.Model)) {
+		log.Printf("[router] Backend %s not available for rule %q, selecting a model instead", rule.Backend, rule.Name)
+		return nil
+	}
+	return &RouteResult{
+		Decision:      RouteAPI,
+		Backend:       rule.Backend,
+		Model:         rule.Model,
+		Reason:        fmt.Sprintf("rule %q", rule.Name),
+		FallbackToCLI: r.config.FallbackToCLI,
+	}
+}
+
+// routeTask routes a task no rule decided, from its legacy hints or its
+// analyzed complexity.
+func (r *Router) routeTask(hints *RoutingHints) *RouteResult {
 	// 2. Extract intent from labels
 	intent := ExtractIntent(hints.Labels)
 	if intent == IntentAuto && hints.Intent != "" {
@@ -177,7 +370,7 @@ func (r *Router) Route(hints *RoutingHints) *RouteResult {
 	}
 
 	// 9. Select best model based on complexity, intent, and availability
-	selected := SelectModel(complexity, intent, availableBackends)
+	selected := r.selectModel(complexity, intent, availableBackends)
 	if selected == nil {
 		return &RouteResult{
 			Decision:      RouteCLI,
@@ -186,6 +379,20 @@ func (r *Router) Route(hints *RoutingHints) *RouteResult {
 		}
 	}
 
+	// 10. Keep the estimated cost under the threshold, stepping down to a
+	// cheaper model (or tier) before giving up on the API
+	var rejected string
+	if r.overCostThreshold(selected, hints.EstimatedTokens) {
+		rejected = r.describeRejection(selected, hints.EstimatedTokens)
+		selected = r.selectAffordableModel(complexity, intent, availableBackends, hints.EstimatedTokens)
+		if selected == nil {
+			return &RouteResult{
+				Decision: RouteCLI,
+				Reason:   "estimated cost exceeds threshold: rejected " + rejected,
+			}
+		}
+	}
+
 	log.Printf("[router] Selected model: %s/%s (tier=%s, cost=%.4f/1K)",
 		selected.Backend, selected.Model, selected.Tier, selected.CostPer1K)
 
@@ -193,13 +400,66 @@ func (r *Router) Route(hints *RoutingHints) *RouteResult {
 		Decision:      RouteAPI,
 		Backend:       selected.Backend,
 		Model:         selected.Model,
-		Reason:        r.buildReason(complexity, intent, selected),
+		Reason:        r.buildReason(complexity, intent, selected, rejected),
 		FallbackToCLI: r.config.FallbackToCLI,
 	}
 }
 
+// estimatedRouteCost estimates a task's cost on a model from its input
+// tokens, assuming output is 25% of input (as EstimateTaskCost does).
+func estimatedRouteCost(c *ModelCapability, inputTokens int) float64 {
+	totalTokens := inputTokens + inputTokens/4
+	return float64(totalTokens) / 1000 * c.CostPer1K
+}
+
+// overCostThreshold reports whether a task's estimated cost on a model
+// exceeds CostThreshold. Without a token estimate or threshold it never does.
+func (r *Router) overCostThreshold(c *ModelCapability, inputTokens int) bool {
+	if inputTokens <= 0 || r.config.CostThreshold <= 0 {
+		return false
+	}
+	return estimatedRouteCost(c, inputTokens) > r.config.CostThreshold
+}
+
+// describeRejection records a model rejected for cost, for the route reason.
+func (r *Router) describeRejection(c *ModelCapability, inputTokens int) string {
+	return fmt.Sprintf("%s/%s (~$%.4f > $%.2f)",
+		c.Backend, c.Model, estimatedRouteCost(c, inputTokens), r.config.CostThreshold)
+}
+
+// selectAffordableModel selects among models whose estimated cost fits
+// CostThreshold: first at the task's tier, then one tier lower.
+// Returns nil if none fit.
+func (r *Router) selectAffordableModel(complexity *TaskComplexity, intent Intent, availableBackends []string, inputTokens int) *ModelCapability {
+	r.mu.RLock()
+	affordable := make([]ModelCapability, 0, len(r.capabilities))
+	for _, c := range r.capabilities {
+		if !r.overCostThreshold(&c, inputTokens) {
+			affordable = append(affordable, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	costWeight := r.config.balancedCostWeight()
+	if selected := selectModelWeighted(affordable, complexity, intent, availableBackends, costWeight); selected != nil {
+		return selected
+	}
+	if complexity.MinTier == TierSimple {
+		return nil
+	}
+
+	lowered := *complexity
+	lowered.MinTier--
+	selected := selectModelWeighted(affordable, &lowered, intent, availableBackends, costWeight)
+	if selected != nil {
+		log.Printf("[router] Dropping to tier %s to stay under the $%.2f cost threshold", selected.Tier, r.config.CostThreshold)
+	}
+	return selected
+}
+
 // buildReason constructs a human-readable reason for the routing decision.
-func (r *Router) buildReason(complexity *TaskComplexity, intent Intent, selected *ModelCapability) string {
+// A model rejected for cost is included so decisions are auditable.
+func (r *Router) buildReason(complexity *TaskComplexity, intent Intent, selected *ModelCapability, rejected string) string {
 	parts := []string{}
 
 	// Complexity info
@@ -213,6 +473,10 @@ func (r *Router) buildReason(complexity *TaskComplexity, intent Intent, selected
 	// Model selection
 	parts = append(parts, "selected="+selected.Backend+"/"+selected.Model)
 
+	if rejected != "" {
+		parts = append(parts, "rejected="+rejected)
+	}
+
 	return strings.Join(parts, ", ")
 }
 
@@ -220,8 +484,8 @@ func (r *Router) buildReason(complexity *TaskComplexity, intent Intent, selected
 func (r *Router) routeByModelTag(tag string) *RouteResult {
 	// Check TierToBackend mapping for legacy tags
 	if mapping, ok := TierToBackend[tag]; ok {
-		// Verify backend is available
-		if r.registry.Has(mapping.Backend) {
+		// Verify backend is available and the model hasn't been retired
+		if r.registry.Has(mapping.Backend) && !r.isExcluded(mapping.Backend, mapping.Model) {
 			return &RouteResult{
 				Decision:      RouteAPI,
 				Backend:       mapping.Backend,
@@ -273,7 +537,7 @@ func (r *Router) findFallbackForTag(tag string) *RouteResult {
 		complexity.MinTier = TierModerate
 	}
 
-	selected := SelectModel(complexity, intent, availableBackends)
+	selected := r.selectModel(complexity, intent, availableBackends)
 	if selected == nil {
 		return nil
 	}
@@ -313,7 +577,7 @@ func (r *Router) routeByLegacyTier(tier string) *RouteResult {
 	availableBackends := r.registry.List()
 	complexity := &TaskComplexity{MinTier: minTier}
 
-	selected := SelectModel(complexity, intent, availableBackends)
+	selected := r.selectModel(complexity, intent, availableBackends)
 	if selected == nil {
 		// No API model available, fall back to CLI
 		return &RouteResult{
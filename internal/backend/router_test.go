@@ -2,6 +2,7 @@ package backend
 
 import (
 	"context"
+	"strings"
 	"testing"
 )
 
@@ -262,3 +263,245 @@ func (m *mockBackend) Invoke(_ context.Context, _ []Message, _ InvokeOptions) (*
 func (m *mockBackend) InvokeStream(_ context.Context, _ []Message, _ InvokeOptions) (<-chan StreamChunk, error) {
 	return nil, nil
 }
+
+func TestRouterAppliesModelOverrides(t *testing.T) {
+	ResetRegistryForTesting()
+	GetRegistry().Register(&mockBackend{name: "bedrock"})
+	GetRegistry().Register(&mockBackend{name: "grok"})
+
+	router := NewRouter(&RoutingConfig{
+		Enabled: true,
+		ModelOverrides: map[string]ModelOverride{
+			"bedrock/haiku": {CostPer1K: floatPtr(0.00001)},
+		},
+	})
+
+	result := router.Route(&RoutingHints{
+		Title:  "Summarize",
+		Labels: []string{"tier:cheap"},
+	})
+	if result.Decision != RouteAPI {
+		t.Fatalf("Decision = %s, want api (reason: %s)", result.Decision, result.Reason)
+	}
+	if result.Backend != "bedrock" || result.Model != "haiku" {
+		t.Errorf("selected %s/%s, want bedrock/haiku", result.Backend, result.Model)
+	}
+}
+
+func TestRoutingConfigBalancedCostWeight(t *testing.T) {
+	if got := (&RoutingConfig{}).balancedCostWeight(); got != DefaultBalancedCostWeight {
+		t.Errorf("unset weight = %v, want default %v", got, DefaultBalancedCostWeight)
+	}
+	if got := (&RoutingConfig{BalancedCostWeight: floatPtr(0)}).balancedCostWeight(); got != 0 {
+		t.Errorf("explicit 0 weight = %v, want 0 (speed only)", got)
+	}
+}
+
+func TestRouterExcludeModel(t *testing.T) {
+	ResetRegistryForTesting()
+	GetRegistry().Register(&mockBackend{name: "bedrock"})
+	GetRegistry().Register(&mockBackend{name: "grok"})
+
+	router := NewRouter(&RoutingConfig{Enabled: true})
+	hints := &RoutingHints{Title: "Summarize", Labels: []string{"tier:cheap"}}
+
+	first := router.Route(hints)
+	if first.Decision != RouteAPI {
+		t.Fatalf("Decision = %s, want api", first.Decision)
+	}
+
+	router.ExcludeModel(first.Backend, first.Model)
+
+	second := router.Route(hints)
+	if second.Decision != RouteAPI {
+		t.Fatalf("Decision = %s, want api after exclusion", second.Decision)
+	}
+	if second.Backend == first.Backend && second.Model == first.Model {
+		t.Errorf("excluded model %s/%s was selected again", first.Backend, first.Model)
+	}
+
+	// Legacy tags for an excluded model fall back to an alternative
+	router.ExcludeModel("bedrock", "haiku")
+	legacy := router.Route(&RoutingHints{ModelTag: "haiku"})
+	if legacy.Backend == "bedrock" && legacy.Model == "haiku" {
+		t.Errorf("legacy tag routed to excluded model bedrock/haiku")
+	}
+}
+
+func TestRouterAppliesRules(t *testing.T) {
+	ResetRegistryForTesting()
+	GetRegistry().Register(&mockBackend{name: "grok"})
+
+	router := NewRouter(&RoutingConfig{
+		Enabled:       true,
+		FallbackToCLI: true,
+		Rules: []RoutingRule{
+			{Name: "docs", TypeMatch: []string{"docs"}, Route: RouteAPI, Backend: "grok", Model: "grok-3-mini", WriteBack: true},
+			{Name: "epics", TypeMatch: []string{"epic"}, Route: RouteCLI},
+			{Name: "offline", TypeMatch: []string{"chore"}, Route: RouteAPI, Backend: "bedrock"},
+		},
+	})
+
+	tests := []struct {
+		name        string
+		issueType   string
+		wantDec     RoutingDecision
+		wantBackend string
+		wantRule    string
+	}{
+		{"API rule with backend", "docs", RouteAPI, "grok", "docs"},
+		{"CLI rule", "EPIC", RouteCLI, "", "epics"},
+		{"unavailable backend falls through", "chore", RouteAPI, "grok", "offline"},
+		{"no matching rule", "bug", RouteAPI, "grok", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := router.Route(&RoutingHints{Title: "Summarize", Type: tt.issueType})
+			if result.Decision != tt.wantDec {
+				t.Errorf("Decision = %s, want %s (reason: %s)", result.Decision, tt.wantDec, result.Reason)
+			}
+			if result.Backend != tt.wantBackend {
+				t.Errorf("Backend = %q, want %q", result.Backend, tt.wantBackend)
+			}
+			gotRule := ""
+			if result.Rule != nil {
+				gotRule = result.Rule.Name
+			}
+			if gotRule != tt.wantRule {
+				t.Errorf("Rule = %q, want %q", gotRule, tt.wantRule)
+			}
+		})
+	}
+
+	if result := router.Route(&RoutingHints{Type: "docs"}); result.Model != "grok-3-mini" || !result.Rule.WriteBack {
+		t.Errorf("docs rule: Model = %q, WriteBack = %v; want the rule's model and write-back", result.Model, result.Rule.WriteBack)
+	}
+}
+
+func TestRouterForceCLILabels(t *testing.T) {
+	ResetRegistryForTesting()
+	GetRegistry().Register(&mockBackend{name: "bedrock"})
+
+	router := NewRouter(&RoutingConfig{
+		Enabled:        true,
+		ForceCLILabels: []string{"security", "secrets"},
+	})
+
+	// The same simple task is API-eligible without the label
+	eligible := router.Route(&RoutingHints{Title: "Summarize", Labels: []string{"tier:cheap"}})
+	if eligible.Decision != RouteAPI {
+		t.Fatalf("Decision = %s, want api for unlabeled task (reason: %s)", eligible.Decision, eligible.Reason)
+	}
+
+	for _, labels := range [][]string{
+		{"tier:cheap", "security"},
+		{"SECRETS"},
+		{"model:grok-fast", "security"}, // explicit model tags don't bypass it
+	} {
+		result := router.Route(&RoutingHints{Title: "Summarize", Labels: labels, ModelTag: ExtractModelTag(labels)})
+		if result.Decision != RouteCLI {
+			t.Errorf("labels %v: Decision = %s, want cli", labels, result.Decision)
+		}
+		if !strings.Contains(result.Reason, "forces CLI") {
+			t.Errorf("labels %v: Reason = %q, want forced-CLI reason", labels, result.Reason)
+		}
+	}
+}
+
+func TestRouterUsesConfiguredPatterns(t *testing.T) {
+	ResetRegistryForTesting()
+	GetRegistry().Register(&mockBackend{name: "bedrock"})
+
+	hints := &RoutingHints{Title: "Nightly ETL", Description: "Backfill the orders pipeline"}
+	if result := NewRouter(&RoutingConfig{Enabled: true}).Route(hints); result.Model != "haiku" {
+		t.Fatalf("with built-in patterns selected %s/%s, want bedrock/haiku", result.Backend, result.Model)
+	}
+
+	router := NewRouter(&RoutingConfig{
+		Enabled:  true,
+		Patterns: &AnalyzerPatterns{Complex: map[string]int{"etl": 20, "backfill": 20, "pipeline": 15}},
+	})
+	result := router.Route(hints)
+	if result.Decision != RouteAPI || result.Model != "opus" {
+		t.Errorf("with data patterns got %s %s/%s (%s), want api bedrock/opus", result.Decision, result.Backend, result.Model, result.Reason)
+	}
+}
+
+func TestRouterCostThresholdStepsDown(t *testing.T) {
+	ResetRegistryForTesting()
+	GetRegistry().Register(&mockBackend{name: "bedrock"})
+
+	router := NewRouter(&RoutingConfig{
+		Enabled:        true,
+		CostThreshold:  0.02,
+		TokenThreshold: 50000,
+		FallbackToCLI:  true,
+	})
+
+	// A moderate task; 4000 input tokens + 25% output: sonnet ~$0.045, haiku ~$0.005
+	hints := &RoutingHints{
+		Title:           "Implement authentication",
+		Description:     "Implement a comprehensive OAuth authentication system with refresh tokens",
+		EstimatedTokens: 4000,
+	}
+	result := router.Route(hints)
+	if result.Decision != RouteAPI {
+		t.Fatalf("Decision = %s, want api (reason: %s)", result.Decision, result.Reason)
+	}
+	if result.Model != "haiku" {
+		t.Errorf("selected %s/%s, want bedrock/haiku one tier down", result.Backend, result.Model)
+	}
+	if !strings.Contains(result.Reason, "rejected=bedrock/sonnet (~$0.0450 > $0.02)") {
+		t.Errorf("Reason = %q, want the rejected sonnet estimate", result.Reason)
+	}
+
+	// Without a token estimate the threshold can't apply
+	hints.EstimatedTokens = 0
+	if result := router.Route(hints); result.Model != "sonnet" {
+		t.Errorf("without an estimate selected %s, want sonnet", result.Model)
+	}
+}
+
+func TestRouterCostThresholdRoutesToCLI(t *testing.T) {
+	ResetRegistryForTesting()
+	GetRegistry().Register(&mockBackend{name: "bedrock"})
+
+	router := NewRouter(&RoutingConfig{
+		Enabled:        true,
+		CostThreshold:  0.01,
+		TokenThreshold: 50000,
+	})
+
+	// haiku: 50000 tokens at $0.001/1K = $0.05, and there's no cheaper tier
+	result := router.Route(&RoutingHints{
+		Title:           "Summarize",
+		Description:     "Summarize this document",
+		EstimatedTokens: 40000,
+	})
+	if result.Decision != RouteCLI {
+		t.Fatalf("Decision = %s, want cli (reason: %s)", result.Decision, result.Reason)
+	}
+	if !strings.Contains(result.Reason, "bedrock/haiku") {
+		t.Errorf("Reason = %q, want the rejected model", result.Reason)
+	}
+}
+
+func TestRouterUsesConfiguredModels(t *testing.T) {
+	ResetRegistryForTesting()
+	GetRegistry().Register(&mockBackend{name: "bedrock"})
+	GetRegistry().Register(&mockBackend{name: "openai"})
+
+	router := NewRouter(&RoutingConfig{
+		Enabled: true,
+		Models: []ModelCapability{
+			{Backend: "openai", Model: "gpt-4o-mini", Tier: TierSimple, CostPer1K: 0.0002, SpeedScore: 8},
+			{Backend: "made-up", Model: "free", Tier: TierSimple, CostPer1K: 0, SpeedScore: 10},
+		},
+	})
+
+	result := router.Route(&RoutingHints{Title: "Summarize", Labels: []string{"tier:cheap"}})
+	if result.Backend != "openai" || result.Model != "gpt-4o-mini" {
+		t.Errorf("selected %s/%s, want the configured openai/gpt-4o-mini (reason: %s)", result.Backend, result.Model, result.Reason)
+	}
+}
@@ -0,0 +1,63 @@
+package backend
+
+import "context"
+
+// SendChunk sends chunk on ch unless ctx is cancelled first. It reports
+// whether the chunk was sent; a producer should stop when it returns false,
+// since a cancelled caller may no longer be reading.
+func SendChunk(ctx context.Context, ch chan<- StreamChunk, chunk StreamChunk) bool {
+	select {
+	case ch <- chunk:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// StreamInvoke adapts a blocking invocation into a stream that delivers the
+// whole response as a single chunk. The producer goroutine exits and closes
+// the channel as soon as ctx is cancelled, even if invoke is still blocked
+// or nobody is reading.
+func StreamInvoke(ctx context.Context, invoke func(context.Context) (*InvokeResult, error)) <-chan StreamChunk {
+	ch := make(chan StreamChunk, 1)
+
+	go func() {
+		defer close(ch)
+
+		type outcome struct {
+			result *InvokeResult
+			err    error
+		}
+		done := make(chan outcome, 1)
+		go func() {
+			result, err := invoke(ctx)
+			done <- outcome{result, err}
+		}()
+
+		var o outcome
+		select {
+		case o = <-done:
+		case <-ctx.Done():
+			// The buffer is still empty, so this never blocks; invoke's
+			// goroutine exits on its own once it observes ctx.
+			ch <- StreamChunk{Error: ctx.Err(), Done: true}
+			return
+		}
+
+		if o.err != nil {
+			SendChunk(ctx, ch, StreamChunk{Error: o.err, Done: true})
+			return
+		}
+		SendChunk(ctx, ch, StreamChunk{
+			Content:      o.result.Content,
+			Done:         true,
+			InputTokens:  o.result.InputTokens,
+			OutputTokens: o.result.OutputTokens,
+			FinishReason: o.result.FinishReason,
+			Citations:    o.result.Citations,
+			ToolCalls:    o.result.ToolCalls,
+		})
+	}()
+
+	return ch
+}
@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStreamInvokeCancelledMidStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	invokeDone := make(chan struct{})
+
+	ch := StreamInvoke(ctx, func(ctx context.Context) (*InvokeResult, error) {
+		defer close(invokeDone)
+		close(started)
+		<-ctx.Done() // block like a slow API call
+		return nil, ctx.Err()
+	})
+
+	<-started
+	cancel()
+
+	select {
+	case chunk, ok := <-ch:
+		if ok && !errors.Is(chunk.Error, context.Canceled) {
+			t.Errorf("chunk = %+v, want context.Canceled", chunk)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream did not deliver after cancellation")
+	}
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("expected channel to be closed after cancellation")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel not closed after cancellation")
+	}
+
+	select {
+	case <-invokeDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("invoke goroutine did not terminate")
+	}
+}
+
+func TestStreamInvokeProducerExitsWhenCallerStopsReading(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+
+	ch := StreamInvoke(ctx, func(ctx context.Context) (*InvokeResult, error) {
+		<-release // ignores ctx, like a call that can't be interrupted
+		return &InvokeResult{Content: "late"}, nil
+	})
+
+	// The caller gives up without reading
+	cancel()
+	defer close(release)
+
+	select {
+	case <-drained(ch):
+	case <-time.After(2 * time.Second):
+		t.Fatal("producer did not close the channel after cancellation")
+	}
+}
+
+func TestStreamInvokeDeliversResult(t *testing.T) {
+	ch := StreamInvoke(context.Background(), func(context.Context) (*InvokeResult, error) {
+		return &InvokeResult{Content: "hello", InputTokens: 7, OutputTokens: 2}, nil
+	})
+
+	chunk := <-ch
+	if chunk.Content != "hello" || !chunk.Done || chunk.Error != nil {
+		t.Errorf("chunk = %+v, want done chunk with content", chunk)
+	}
+	if chunk.InputTokens != 7 || chunk.OutputTokens != 2 {
+		t.Errorf("usage = %d in / %d out, want 7 / 2", chunk.InputTokens, chunk.OutputTokens)
+	}
+	if _, ok := <-ch; ok {
+		t.Error("expected channel to be closed after the final chunk")
+	}
+}
+
+func TestSendChunkCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ch := make(chan StreamChunk) // unbuffered and never read
+	if SendChunk(ctx, ch, StreamChunk{Content: "x"}) {
+		t.Error("SendChunk reported success with no reader and a cancelled context")
+	}
+}
+
+// drained returns a channel that closes once ch is closed.
+func drained(ch <-chan StreamChunk) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	return done
+}
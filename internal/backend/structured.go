@@ -0,0 +1,202 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Response format types for InvokeOptions.ResponseFormat.
+const (
+	// ResponseFormatJSONObject requests any valid JSON object.
+	ResponseFormatJSONObject = "json_object"
+
+	// ResponseFormatJSONSchema requests JSON matching ResponseFormat.Schema.
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ErrInvalidStructuredOutput indicates a response requested with a
+// ResponseFormat was not valid JSON or did not match the schema.
+var ErrInvalidStructuredOutput = errors.New("invalid structured output")
+
+// ResponseFormat constrains a response to machine-parseable JSON.
+// OpenAI-compatible backends send it as response_format; Anthropic backends
+// force a single tool call whose input schema is the requested schema.
+type ResponseFormat struct {
+	// Type is ResponseFormatJSONObject or ResponseFormatJSONSchema.
+	Type string `json:"type"`
+
+	// Name identifies the schema to the provider (defaults to "response").
+	Name string `json:"name,omitempty"`
+
+	// Schema is the JSON Schema the response must match (json_schema only).
+	Schema json.RawMessage `json:"schema,omitempty"`
+}
+
+// SchemaName returns the provider-facing schema name.
+func (f *ResponseFormat) SchemaName() string {
+	if f.Name == "" {
+		return "response"
+	}
+	return f.Name
+}
+
+// ObjectSchema returns the schema to enforce: Schema for json_schema, or a
+// bare object schema for json_object.
+func (f *ResponseFormat) ObjectSchema() json.RawMessage {
+	if f.Type == ResponseFormatJSONSchema && len(f.Schema) > 0 {
+		return f.Schema
+	}
+	return json.RawMessage(`{"type":"object"}`)
+}
+
+// ValidateStructuredOutput checks content against the requested format.
+// It supports the common JSON Schema keywords: type, properties, required,
+// additionalProperties (false), items, and enum. Errors wrap
+// ErrInvalidStructuredOutput.
+func ValidateStructuredOutput(content string, format *ResponseFormat) error {
+	if format == nil {
+		return nil
+	}
+
+	var value any
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("%w: not valid JSON: %v", ErrInvalidStructuredOutput, err)
+	}
+	if dec.More() {
+		return fmt.Errorf("%w: trailing data after JSON value", ErrInvalidStructuredOutput)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(format.ObjectSchema(), &schema); err != nil {
+		return fmt.Errorf("parsing schema: %w", err)
+	}
+	if err := validateSchemaValue(value, schema, "$"); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStructuredOutput, err)
+	}
+	return nil
+}
+
+// validateSchemaValue checks a decoded JSON value against a schema node.
+func validateSchemaValue(value any, schema map[string]any, path string) error {
+	if t, ok := schema["type"]; ok && !matchesSchemaType(value, t) {
+		return fmt.Errorf("%s: expected %v, got %s", path, t, jsonTypeName(value))
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !inEnum(value, enum) {
+		return fmt.Errorf("%s: value not in enum %v", path, enum)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, r := range required {
+				name, _ := r.(string)
+				if _, present := v[name]; !present {
+					return fmt.Errorf("%s: missing required property %q", path, name)
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			propSchema, known := props[k].(map[string]any)
+			if !known {
+				if ap, ok := schema["additionalProperties"].(bool); ok && !ap {
+					return fmt.Errorf("%s: unexpected property %q", path, k)
+				}
+				continue
+			}
+			if err := validateSchemaValue(v[k], propSchema, path+"."+k); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateSchemaValue(item, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// matchesSchemaType reports whether value matches a schema "type", which
+// may be a single type name or a list of them.
+func matchesSchemaType(value any, t any) bool {
+	switch tt := t.(type) {
+	case string:
+		return matchesTypeName(value, tt)
+	case []any:
+		for _, name := range tt {
+			if s, ok := name.(string); ok && matchesTypeName(value, s) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesTypeName(value any, name string) bool {
+	switch name {
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	default:
+		return jsonTypeName(value) == name
+	}
+}
+
+// jsonTypeName returns the JSON Schema type name of a decoded value.
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+// inEnum reports whether value equals one of the enum entries.
+func inEnum(value any, enum []any) bool {
+	got, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	for _, e := range enum {
+		want, err := json.Marshal(e)
+		if err == nil && bytes.Equal(got, want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestValidateStructuredOutput(t *testing.T) {
+	schema := json.RawMessage(`{
+		"type": "object",
+		"required": ["verdict", "issues"],
+		"additionalProperties": false,
+		"properties": {
+			"verdict": {"enum": ["approve", "reject"]},
+			"score": {"type": "integer"},
+			"issues": {"type": "array", "items": {"type": "string"}}
+		}
+	}`)
+	format := &ResponseFormat{Type: ResponseFormatJSONSchema, Schema: schema}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"valid", `{"verdict":"approve","score":3,"issues":[]}`, false},
+		{"not JSON", `verdict: approve`, true},
+		{"trailing data", `{"verdict":"approve","issues":[]} extra`, true},
+		{"missing required", `{"verdict":"approve"}`, true},
+		{"enum mismatch", `{"verdict":"maybe","issues":[]}`, true},
+		{"non-integer", `{"verdict":"approve","score":2.5,"issues":[]}`, true},
+		{"bad array item", `{"verdict":"reject","issues":["ok",3]}`, true},
+		{"unexpected property", `{"verdict":"approve","issues":[],"extra":true}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStructuredOutput(tt.content, format)
+			if tt.wantErr && !errors.Is(err, ErrInvalidStructuredOutput) {
+				t.Errorf("expected ErrInvalidStructuredOutput, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateStructuredOutputJSONObject(t *testing.T) {
+	format := &ResponseFormat{Type: ResponseFormatJSONObject}
+
+	if err := ValidateStructuredOutput(`{"anything":1}`, format); err != nil {
+		t.Errorf("object rejected: %v", err)
+	}
+	if err := ValidateStructuredOutput(`[1,2]`, format); !errors.Is(err, ErrInvalidStructuredOutput) {
+		t.Errorf("array accepted as json_object: %v", err)
+	}
+	if err := ValidateStructuredOutput("plain text", nil); err != nil {
+		t.Errorf("nil format should skip validation: %v", err)
+	}
+}
+
+type capsBackend struct {
+	mockBackend
+	caps Capability
+}
+
+func (b *capsBackend) Capabilities() Capability { return b.caps }
+
+func TestCheckInvokeOptions(t *testing.T) {
+	format := &ResponseFormat{Type: ResponseFormatJSONObject}
+	tools := []ToolDef{{Name: "lookup"}}
+
+	plain := &capsBackend{mockBackend: mockBackend{name: "plain"}}
+	for _, opts := range []InvokeOptions{{ResponseFormat: format}, {Tools: tools}} {
+		err := CheckInvokeOptions(plain, opts)
+		if !errors.Is(err, ErrCapabilityUnsupported) {
+			t.Errorf("CheckInvokeOptions(%+v) = %v, want ErrCapabilityUnsupported", opts, err)
+		}
+	}
+
+	full := &capsBackend{mockBackend: mockBackend{name: "full"}, caps: CapStructuredOutput | CapTools}
+	if err := CheckInvokeOptions(full, InvokeOptions{ResponseFormat: format, Tools: tools}); err != nil {
+		t.Errorf("CheckInvokeOptions with capabilities: %v", err)
+	}
+	if err := CheckInvokeOptions(plain, InvokeOptions{Model: "haiku"}); err != nil {
+		t.Errorf("CheckInvokeOptions without features: %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// TokenCounter estimates how many tokens messages use for a model.
+// Backends delegate CountTokens to the counter registered for the model's
+// family, so a more accurate tokenizer only has to be plugged in once.
+type TokenCounter interface {
+	CountTokens(messages []Message, model string) (int, error)
+}
+
+// TokenCounterFunc adapts a function to the TokenCounter interface.
+type TokenCounterFunc func(messages []Message, model string) (int, error)
+
+// CountTokens implements TokenCounter.
+func (f TokenCounterFunc) CountTokens(messages []Message, model string) (int, error) {
+	return f(messages, model)
+}
+
+// Model families with built-in token counters.
+const (
+	FamilyOpenAI = "openai"
+	FamilyClaude = "claude"
+	FamilyGrok   = "grok"
+)
+
+// HeuristicTokenCounter is the fallback for models without a registered
+// counter (no encoding is bundled for them): roughly 4 characters per token
+// plus a little per-message overhead. It badly underestimates code and
+// non-English text, so prefer registering a family counter.
+var HeuristicTokenCounter TokenCounter = TokenCounterFunc(countTokensHeuristic)
+
+var (
+	tokenCountersMu sync.RWMutex
+	tokenCounters   = defaultTokenCounters()
+)
+
+func defaultTokenCounters() map[string]TokenCounter {
+	return map[string]TokenCounter{
+		FamilyOpenAI: TokenCounterFunc(countTokensOpenAI),
+		FamilyClaude: TokenCounterFunc(countTokensClaude),
+		FamilyGrok:   TokenCounterFunc(countTokensGrok),
+	}
+}
+
+// RegisterTokenCounter sets the counter for a model family (e.g., to plug
+// in an exact tokenizer), replacing any existing one.
+func RegisterTokenCounter(family string, counter TokenCounter) {
+	tokenCountersMu.Lock()
+	defer tokenCountersMu.Unlock()
+	tokenCounters[family] = counter
+}
+
+// ResetTokenCountersForTesting restores the built-in token counters.
+// This is intended for use in tests only.
+func ResetTokenCountersForTesting() {
+	tokenCountersMu.Lock()
+	defer tokenCountersMu.Unlock()
+	tokenCounters = defaultTokenCounters()
+}
+
+// ModelFamily returns the tokenizer family for a model ID or tier name,
+// or "" if it isn't recognized.
+func ModelFamily(model string) string {
+	m := strings.ToLower(model)
+	switch {
+	case strings.Contains(m, "claude"), m == "haiku", m == "sonnet", m == "opus":
+		return FamilyClaude
+	case strings.HasPrefix(m, "gpt"), strings.HasPrefix(m, "chatgpt"),
+		strings.HasPrefix(m, "o1"), strings.HasPrefix(m, "o3"), strings.HasPrefix(m, "o4"):
+		return FamilyOpenAI
+	case strings.HasPrefix(m, "grok"):
+		return FamilyGrok
+	}
+	return ""
+}
+
+// TokenCounterFor returns the counter registered for the model's family,
+// falling back to HeuristicTokenCounter.
+func TokenCounterFor(model string) TokenCounter {
+	tokenCountersMu.RLock()
+	defer tokenCountersMu.RUnlock()
+	if c, ok := tokenCounters[ModelFamily(model)]; ok {
+		return c
+	}
+	return HeuristicTokenCounter
+}
+
+// CountTokens estimates the tokens messages use with the counter for model.
+func CountTokens(messages []Message, model string) (int, error) {
+	return TokenCounterFor(model).CountTokens(messages, model)
+}
+
+// countTokensHeuristic estimates 4 characters per token.
+func countTokensHeuristic(messages []Message, _ string) (int, error) {
+	var totalChars int
+	for _, msg := range messages {
+		totalChars += len(msg.Content)
+		totalChars += len(msg.Role) + 10 // Role overhead
+	}
+	return totalChars / 4, nil
+}
+
+// countTokensClaude approximates Claude's tokenizer, which averages about
+// 3.5 characters per token on English text and code and roughly one token
+// per CJK character.
+func countTokensClaude(messages []Message, _ string) (int, error) {
+	var totalChars, ideographs int
+	for _, msg := range messages {
+		for _, r := range msg.Content {
+			if isIdeograph(r) {
+				ideographs++
+			} else {
+				totalChars++
+			}
+		}
+		totalChars += len(msg.Role) + 10 // Role overhead
+	}
+	return ideographs + (totalChars*2+6)/7, nil
+}
+
+// countTokensOpenAI counts tokens exactly with the model's tiktoken BPE
+// encoding (see openAIEncoding). Each message costs 3 framing tokens, and
+// every reply is primed with 3 more.
+func countTokensOpenAI(messages []Message, model string) (int, error) {
+	enc, err := bpeEncoding(openAIEncoding(model))
+	if err != nil {
+		return 0, err
+	}
+	tokens := 3
+	for _, msg := range messages {
+		tokens += 3 + len(enc.Encode(msg.Role, nil, nil)) + len(enc.Encode(msg.Content, nil, nil))
+	}
+	return tokens, nil
+}
+
+// countTokensGrok counts Grok tokens with o200k_base. xAI doesn't publish
+// its vocabulary in tiktoken form, but its BPE lands close to o200k's on
+// English text and code, far closer than characters/4.
+func countTokensGrok(messages []Message, _ string) (int, error) {
+	return countTokensOpenAI(messages, "gpt-4o")
+}
+
+// openAIEncoding returns the tiktoken encoding an OpenAI model uses:
+// cl100k_base for GPT-4 and GPT-3.5, o200k_base for GPT-4o and later
+// (including the o-series reasoning models and unrecognized new models).
+func openAIEncoding(model string) string {
+	m := strings.ToLower(model)
+	switch {
+	case strings.HasPrefix(m, "gpt-4o"), strings.HasPrefix(m, "gpt-4.1"), strings.HasPrefix(m, "gpt-4.5"):
+		return tiktoken.MODEL_O200K_BASE
+	case strings.HasPrefix(m, "gpt-4"), strings.HasPrefix(m, "gpt-3.5"):
+		return tiktoken.MODEL_CL100K_BASE
+	}
+	return tiktoken.MODEL_O200K_BASE
+}
+
+// BPE encodings are loaded from the vocabularies bundled in the binary
+// (never downloaded) on first use, then cached.
+var (
+	bpeLoaderOnce sync.Once
+	bpeEncodingMu sync.Mutex
+	bpeEncodings  = make(map[string]*tiktoken.Tiktoken)
+)
+
+// bpeEncoding returns the named tiktoken encoding.
+func bpeEncoding(name string) (*tiktoken.Tiktoken, error) {
+	bpeLoaderOnce.Do(func() { tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader()) })
+
+	bpeEncodingMu.Lock()
+	defer bpeEncodingMu.Unlock()
+	if enc, ok := bpeEncodings[name]; ok {
+		return enc, nil
+	}
+	enc, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s encoding: %w", name, err)
+	}
+	bpeEncodings[name] = enc
+	return enc, nil
+}
+
+// isIdeograph reports whether r is a CJK character, which BPE vocabularies
+// trained mostly on English encode at about one token per character.
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,126 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestModelFamily(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"claude-sonnet-4-20250514", FamilyClaude},
+		{"anthropic.claude-3-5-haiku-20241022-v1:0", FamilyClaude},
+		{"opus", FamilyClaude},
+		{"gpt-4o-mini", FamilyOpenAI},
+		{"o3-mini", FamilyOpenAI},
+		{"grok-3-mini", FamilyGrok},
+		{"llama-3-70b", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ModelFamily(tt.model); got != tt.want {
+			t.Errorf("ModelFamily(%q) = %q, want %q", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestTokenCounterSelection(t *testing.T) {
+	t.Cleanup(ResetTokenCountersForTesting)
+
+	calls := map[string]int{}
+	counter := func(family string) TokenCounter {
+		return TokenCounterFunc(func([]Message, string) (int, error) {
+			calls[family]++
+			return 1, nil
+		})
+	}
+	RegisterTokenCounter(FamilyOpenAI, counter(FamilyOpenAI))
+	RegisterTokenCounter(FamilyClaude, counter(FamilyClaude))
+
+	msgs := []Message{{Role: "user", Content: "hello"}}
+	for _, model := range []string{"gpt-4o", "claude-opus-4-5-20251101", "haiku"} {
+		if _, err := CountTokens(msgs, model); err != nil {
+			t.Fatalf("CountTokens(%s): %v", model, err)
+		}
+	}
+	if calls[FamilyOpenAI] != 1 || calls[FamilyClaude] != 2 {
+		t.Errorf("counter calls = %v, want openai:1 claude:2", calls)
+	}
+}
+
+func TestTokenCounterFallsBackToHeuristic(t *testing.T) {
+	msgs := []Message{{Role: "user", Content: "0123456789012345678901234567890123456789"}}
+	want, _ := HeuristicTokenCounter.CountTokens(msgs, "")
+
+	// Models outside every registered family
+	for _, model := range []string{"llama-3-70b", "mistral-large", ""} {
+		got, err := CountTokens(msgs, model)
+		if err != nil {
+			t.Fatalf("CountTokens(%q): %v", model, err)
+		}
+		if got != want {
+			t.Errorf("CountTokens(%q) = %d, want heuristic %d", model, got, want)
+		}
+	}
+	if want != (40+4+10)/4 {
+		t.Errorf("heuristic = %d, want %d", want, (40+4+10)/4)
+	}
+}
+
+func TestBuiltinTokenCounters(t *testing.T) {
+	msgs := []Message{{Role: "user", Content: "The quick brown fox jumps over the lazy dog."}}
+
+	// tiktoken encodes this sentence as 10 tokens, plus 3 framing + 1 role + 3 priming
+	for _, model := range []string{"gpt-4o", "gpt-4-turbo", "o3-mini", "grok-3"} {
+		got, err := CountTokens(msgs, model)
+		if err != nil {
+			t.Fatalf("CountTokens(%s): %v", model, err)
+		}
+		if got != 17 {
+			t.Errorf("CountTokens(%s) = %d, want 17", model, got)
+		}
+	}
+
+	// Claude: ~3.5 chars per token over content plus role overhead
+	got, _ := CountTokens(msgs, "claude-sonnet-4-20250514")
+	if got != ((44+4+10)*2+6)/7 {
+		t.Errorf("claude count = %d, want %d", got, ((44+4+10)*2+6)/7)
+	}
+}
+
+func TestOpenAIEncodingByModel(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"gpt-4o-mini", "o200k_base"},
+		{"gpt-4.1", "o200k_base"},
+		{"o3-mini", "o200k_base"},
+		{"gpt-5", "o200k_base"},
+		{"gpt-4-turbo", "cl100k_base"},
+		{"GPT-3.5-turbo", "cl100k_base"},
+	}
+	for _, tt := range tests {
+		if got := openAIEncoding(tt.model); got != tt.want {
+			t.Errorf("openAIEncoding(%q) = %q, want %q", tt.model, got, tt.want)
+		}
+	}
+
+	// o200k's larger vocabulary merges CJK text that cl100k splits
+	msgs := []Message{{Role: "user", Content: strings.Repeat("漢字", 10)}}
+	o200k, _ := CountTokens(msgs, "gpt-4o")
+	cl100k, _ := CountTokens(msgs, "gpt-4")
+	if o200k != 27 || cl100k != 37 {
+		t.Errorf("CJK counts = %d (o200k), %d (cl100k), want 27 and 37", o200k, cl100k)
+	}
+}
+
+func TestClaudeCounterChargesIdeographs(t *testing.T) {
+	msgs := []Message{{Role: "user", Content: strings.Repeat("漢字", 50)}}
+	got, _ := CountTokens(msgs, "claude-sonnet-4-20250514")
+	if got < 100 {
+		t.Errorf("claude count = %d, want at least one token per ideograph", got)
+	}
+}
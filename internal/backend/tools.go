@@ -0,0 +1,45 @@
+package backend
+
+import "encoding/json"
+
+// ToolDef describes a tool (function) the model may call.
+// Backends advertising CapTools translate it to their provider's shape.
+type ToolDef struct {
+	// Name identifies the tool in the model's calls.
+	Name string `json:"name"`
+
+	// Description tells the model when and how to use the tool.
+	Description string `json:"description,omitempty"`
+
+	// InputSchema is the JSON Schema for the tool's arguments
+	// (defaults to an object with no declared properties).
+	InputSchema json.RawMessage `json:"input_schema,omitempty"`
+}
+
+// Schema returns the tool's argument schema, defaulting to a bare object.
+func (t ToolDef) Schema() json.RawMessage {
+	if len(t.InputSchema) > 0 {
+		return t.InputSchema
+	}
+	return json.RawMessage(`{"type":"object"}`)
+}
+
+// ToolCall is a tool invocation requested by the model.
+type ToolCall struct {
+	// ID identifies the call; tool results refer back to it.
+	ID string `json:"id"`
+
+	// Name is the ToolDef.Name being called.
+	Name string `json:"name"`
+
+	// Arguments is the JSON object of arguments the model supplied.
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// RoleTool is the role of a message carrying a tool result.
+const RoleTool = "tool"
+
+// ToolResultMessage builds the message that answers a tool call.
+func ToolResultMessage(callID, content string) Message {
+	return Message{Role: RoleTool, ToolCallID: callID, Content: content}
+}
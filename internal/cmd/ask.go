@@ -2,13 +2,20 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/steveyegge/gastown/internal/backend"
 	"github.com/steveyegge/gastown/internal/backend/bedrock"
+	"github.com/steveyegge/gastown/internal/backend/grok"
+	"github.com/steveyegge/gastown/internal/config"
 	"github.com/steveyegge/gastown/internal/style"
 	"github.com/steveyegge/gastown/internal/workspace"
 )
@@ -30,28 +37,151 @@ that don't require file operations or tool use. Perfect for:
 Cost-Effective:
   Uses haiku by default (cheapest Claude model). Override with --tier flag.
 
+Streaming:
+  Responses stream by default, with the token usage and cost printed once the
+  stream ends. To turn streaming off, set "ask_stream": false in
+  settings/backend.json. Precedence: --stream flag, then town config, then the
+  built-in default (stream).
+
+Cost Preview:
+  --estimate (or --dry-run) prints the input token count and estimated cost,
+  assuming a response a quarter the size of the prompt, without calling the API.
+
+Piped Input:
+  Input piped on stdin is added to the question as a fenced block, or is the
+  whole question when no arguments are given. Piped input over
+  --stdin-max-bytes (100KB by default) is refused rather than sent.
+
+Files:
+  --file includes a file's contents in the question, fenced and labeled with
+  its path; repeat it for several files. Binary files are rejected, and the
+  files together may not exceed --file-max-bytes (100KB by default).
+  --files-glob includes every text file matching a pattern, truncating
+  instead once the files outgrow the context window.
+
+System Prompt:
+  Questions are sent with a short default system prompt asking for direct,
+  concise answers. --system replaces it, e.g. with project conventions, and
+  --system-file reads the replacement from a file.
+
+Conversations:
+  Each question starts fresh unless --session names a conversation. The
+  question and answer are saved to mayor/ask-sessions/<name>.json and replayed
+  on the next --session call, dropping the oldest turns once the history
+  outgrows the model's context window.
+
+Response Limit:
+  --max-tokens caps the response (4096 tokens by default). A reply that stops
+  at the limit is flagged with a warning; --continue-on-truncate asks once for
+  the rest and appends it.
+
+Usage for Scripts:
+  --json-usage prints one JSON object on stderr after the answer, with the
+  backend, model, input_tokens, output_tokens, and cost_usd, so wrappers can
+  read usage without parsing the Cost line. The answer on stdout is unchanged.
+  With --compare, one object is printed per model that answered.
+
+Structured Output:
+  --json returns a JSON object and --json-schema a response matching a JSON
+  Schema file. Replies that don't parse are re-asked once before failing.
+
 Examples:
   gt ask "what does the --force flag do in git push?"
   gt ask "explain this Go error: undefined: foo"
   gt ask --tier sonnet "design a REST API for user management"
   gt ask --backend grok "what's new in Go 1.22?"
+  gt ask --backend grok --live-search on "what's new in Go 1.24?"
+  cat error.log | gt ask "summarize these errors"
+  gt ask --file main.go "what's wrong here?"
+  gt ask --file a.go --file b.go "why do these disagree about the timeout?"
+  gt ask --files-glob "internal/backend/*.go" "review this package for races"
+  gt ask --output answer.md "write a design doc for the cache layer"
+  gt ask --json "is this a bug or a feature request? reply as {\"kind\": ...}"
+  gt ask --json-schema verdict.json "is this diff safe to merge? <diff>"
+  gt ask --json-usage "summarize this log" 2>usage.json
+  gt ask --system-file CONVENTIONS.md "how should I name this package?"
+  gt ask --compare haiku,opus "when should I use a sync.Pool?"
+  gt ask --tier opus --estimate "<long prompt>"
+  gt ask --session cache "how should the cache layer evict entries?"
+  gt ask --session cache "what about under memory pressure?"
+  gt ask --list-sessions
+  gt ask --clear-session cache
 
 Note: This is for quick questions only. For work that requires file operations,
 code changes, or multi-step reasoning, use gt sling instead.`,
-	Args: cobra.MinimumNArgs(1),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if askListSessions || askClearSession != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		// The question may come entirely from stdin
+		return nil
+	},
 	RunE: runAsk,
 }
 
 var (
-	askTier    string // --tier: model tier (haiku, sonnet, opus)
-	askBackend string // --backend: API backend (bedrock, grok)
-	askStream  bool   // --stream: stream response as it's generated
+	askTier       string   // --tier: model tier (haiku, sonnet, opus)
+	askBackend    string   // --backend: API backend (bedrock, grok)
+	askStream     bool     // --stream: stream response as it's generated
+	askFilesGlob  string   // --files-glob: include matching files as context
+	askFiles      []string // --file: include these files as context (repeatable)
+	askFileMax    int      // --file-max-bytes: total size cap for --file contents
+	askOutput     string   // --output: also write the response to this file
+	askAppend     bool     // --append: append to --output instead of overwriting
+	askOutputCost bool     // --output-cost: add the cost as a trailing comment in --output
+	askJSONUsage  bool     // --json-usage: print usage as a JSON object on stderr
+	askMaxTokens  int      // --max-tokens: response token limit, enforced on streams
+	askJSON       bool     // --json: constrain the response to a JSON object
+	askJSONSchema string   // --json-schema: constrain the response to this JSON Schema file
+	askCompare    string   // --compare: comma-separated tiers/models to answer side by side
+	askMaxCost    float64  // --max-cost: estimated spend limit for --compare (USD)
+	askEstimate   bool     // --estimate/--dry-run: print the token count and cost, don't call the API
+	askSystem     string   // --system: system prompt replacing the default
+	askSystemFile string   // --system-file: read the system prompt from this file
+	askStdinMax   int      // --stdin-max-bytes: size cap for piped input
+	askSearch     string   // --live-search: xAI live search mode (auto, on)
+
+	askContinueOnTruncate bool // --continue-on-truncate: ask once for the rest of a reply cut at --max-tokens
+
+	askSessionName  string // --session: replay and extend this named transcript
+	askListSessions bool   // --list-sessions: list saved transcripts and exit
+	askClearSession string // --clear-session: delete this transcript and exit
 )
 
+// askDefaultCompareMaxCost caps the estimated spend of one --compare run,
+// since every listed model answers the full question.
+const askDefaultCompareMaxCost = 1.00
+
+// askEstimateOutputRatio is the share of input tokens --estimate assumes the
+// response will use.
+const askEstimateOutputRatio = 0.25
+
 func init() {
 	askCmd.Flags().StringVar(&askTier, "tier", "haiku", "Model tier: haiku (default, cheapest), sonnet, opus")
 	askCmd.Flags().StringVar(&askBackend, "backend", "bedrock", "API backend: bedrock (default), grok")
 	askCmd.Flags().BoolVar(&askStream, "stream", true, "Stream response as it's generated")
+	askCmd.Flags().StringVar(&askFilesGlob, "files-glob", "", "Include files matching this glob as context (binary files skipped)")
+	askCmd.Flags().StringArrayVar(&askFiles, "file", nil, "Include this file as context (repeatable)")
+	askCmd.Flags().IntVar(&askFileMax, "file-max-bytes", askFileDefaultMaxBytes, "Maximum total size of --file contents")
+	askCmd.Flags().StringVarP(&askOutput, "output", "o", "", "Also write the response to this file")
+	askCmd.Flags().BoolVar(&askAppend, "append", false, "Append to the --output file instead of overwriting it")
+	askCmd.Flags().BoolVar(&askOutputCost, "output-cost", false, "Add the token usage and cost as a trailing comment in the --output file")
+	askCmd.Flags().BoolVar(&askJSONUsage, "json-usage", false, "After the answer, print token usage and cost as a JSON object on stderr")
+	askCmd.Flags().IntVar(&askMaxTokens, "max-tokens", askDefaultMaxTokens, "Maximum response tokens; streams are cut off once exceeded")
+	askCmd.Flags().BoolVar(&askJSON, "json", false, "Return a JSON object (disables streaming)")
+	askCmd.Flags().StringVar(&askJSONSchema, "json-schema", "", "Return JSON matching this JSON Schema file (disables streaming)")
+	askCmd.Flags().StringVar(&askCompare, "compare", "", "Answer with each of these comma-separated tiers/models side by side (e.g. sonnet,opus)")
+	askCmd.Flags().Float64Var(&askMaxCost, "max-cost", askDefaultCompareMaxCost, "Refuse --compare runs whose estimated cost exceeds this (USD)")
+	askCmd.Flags().BoolVar(&askEstimate, "estimate", false, "Print the token count and estimated cost without calling the API")
+	askCmd.Flags().BoolVar(&askEstimate, "dry-run", false, "Alias for --estimate")
+	askCmd.Flags().BoolVar(&askContinueOnTruncate, "continue-on-truncate", false, "If the response stops at --max-tokens, ask once for the rest")
+	askCmd.Flags().StringVar(&askSearch, "live-search", "", "Grok live search mode: auto or on (prints source citations)")
+	askCmd.Flags().IntVar(&askStdinMax, "stdin-max-bytes", askFileDefaultMaxBytes, "Maximum size of input piped on stdin")
+	askCmd.Flags().StringVar(&askSystem, "system", "", "System prompt to use instead of the default")
+	askCmd.Flags().StringVar(&askSystemFile, "system-file", "", "Read the system prompt from this file")
+	askCmd.Flags().StringVar(&askSessionName, "session", "", "Continue a named conversation, saved under mayor/ask-sessions/")
+	askCmd.Flags().BoolVar(&askListSessions, "list-sessions", false, "List saved conversations and exit")
+	askCmd.Flags().StringVar(&askClearSession, "clear-session", "", "Delete a saved conversation and exit")
 
 	rootCmd.AddCommand(askCmd)
 }
@@ -61,28 +191,72 @@ func runAsk(cmd *cobra.Command, args []string) error {
 
 	// Get town root for config (may be empty if outside a town)
 	townRoot, _ := workspace.FindFromCwd()
-	_ = townRoot // May use later for config
 
-	// Register bedrock backend
-	bedrockBackend, err := bedrock.New()
+	if askListSessions || askClearSession != "" || askSessionName != "" {
+		if townRoot == "" {
+			return fmt.Errorf("not in a Gas Town workspace (ask sessions are saved per town)")
+		}
+		if askListSessions {
+			return printAskSessions(townRoot)
+		}
+		if askClearSession != "" {
+			if err := clearAskSession(townRoot, askClearSession); err != nil {
+				return err
+			}
+			fmt.Printf("%s Cleared session %s\n", style.Success.Render("✓"), askClearSession)
+			return nil
+		}
+		if askCompare != "" {
+			return fmt.Errorf("--session can't be combined with --compare")
+		}
+	}
+
+	piped, err := readAskStdin(askStdin, askStdinMax)
 	if err != nil {
-		return fmt.Errorf("initializing bedrock backend: %w", err)
+		return err
+	}
+	if question == "" && piped == "" {
+		return cmd.Usage()
 	}
-	backend.GetRegistry().Register(bedrockBackend)
+	question = appendAskStdin(question, piped)
 
-	// Select the backend
-	var selectedBackend backend.AgentBackend
-	switch strings.ToLower(askBackend) {
-	case "bedrock":
-		selectedBackend = bedrockBackend
-	case "grok":
-		grokBackend, err := backend.GetRegistry().Get("grok")
+	stream := resolveAskStream(cmd, townRoot)
+
+	systemPrompt, err := resolveAskSystemPrompt(askSystem, askSystemFile)
+	if err != nil {
+		return err
+	}
+	searchParams, err := askSearchParameters(askSearch, askBackend)
+	if err != nil {
+		return err
+	}
+
+	// Structured output is validated as a whole, so it can't stream
+	var responseFormat *backend.ResponseFormat
+	switch {
+	case askJSONSchema != "":
+		responseFormat, err = loadAskResponseFormat(askJSONSchema)
 		if err != nil {
-			return fmt.Errorf("grok backend not available (check XAI_API_KEY): %w", err)
+			return err
 		}
-		selectedBackend = grokBackend
-	default:
-		return fmt.Errorf("unknown backend '%s': must be bedrock or grok", askBackend)
+		stream = false
+	case askJSON:
+		responseFormat = &backend.ResponseFormat{Type: backend.ResponseFormatJSONObject}
+		stream = false
+	}
+	if townRoot != "" {
+		applyModelAliases(config.ResolveBackendConfig(townRoot, ""))
+		backend.SetCostTrackerPath(backend.CostTrackerPath(townRoot))
+	}
+
+	// Initialize only the requested backend, so e.g. --backend grok
+	// doesn't require AWS credentials
+	selectedBackend, err := newAskBackend(askBackend)
+	if err != nil {
+		return err
+	}
+	if err := backend.CheckInvokeOptions(selectedBackend, backend.InvokeOptions{ResponseFormat: responseFormat}); err != nil {
+		return err
 	}
 
 	// Map tier to model
@@ -98,58 +272,653 @@ func runAsk(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unknown tier '%s': must be haiku, sonnet, or opus", askTier)
 	}
 
-	// Build messages
+	if askMaxTokens <= 0 {
+		return fmt.Errorf("--max-tokens must be positive")
+	}
+
+	// Include matching files as context, capped to fit the context window
+	if askFilesGlob != "" {
+		maxBytes, err := askFilesGlobMaxBytes(selectedBackend.MaxContextTokens(model), askMaxTokens)
+		if err != nil {
+			return err
+		}
+
+		files, err := expandAskFilesGlob(askFilesGlob, maxBytes)
+		if err != nil {
+			return err
+		}
+		if len(files.Files) == 0 {
+			return fmt.Errorf("no text files match %q", askFilesGlob)
+		}
+
+		fmt.Printf("%s Including %d file(s) matching %s\n", style.Dim.Render("→"), len(files.Files), askFilesGlob)
+		if len(files.Binary) > 0 {
+			fmt.Printf("%s Skipped %d binary file(s)\n", style.Dim.Render("○"), len(files.Binary))
+		}
+		if files.Truncated() {
+			fmt.Printf("%s Files truncated to fit the %d-byte context cap (%d omitted)\n",
+				style.WarningPrefix, maxBytes, len(files.Omitted))
+		}
+
+		question = formatAskFiles(files.Files) + question
+	}
+
+	if len(askFiles) > 0 {
+		files, err := readAskFiles(askFiles, askFileMax)
+		if err != nil {
+			return err
+		}
+		question = formatAskFiles(files) + question
+	}
+
+	// Build messages, replaying the session's history if there is one
 	messages := []backend.Message{
 		{
 			Role:    "user",
 			Content: question,
 		},
 	}
+	var session *askSession
+	if askSessionName != "" {
+		session, err = loadAskSession(townRoot, askSessionName)
+		if err != nil {
+			return err
+		}
+		messages, err = askSessionMessages(session, question, selectedBackend, model, askMaxTokens)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(askFiles) > 0 || piped != "" {
+		warnAskContextOverflow(selectedBackend, messages, model, askMaxTokens)
+	}
+
+	if askEstimate {
+		models := []string{model}
+		if askCompare != "" {
+			if models, err = parseAskCompare(askCompare); err != nil {
+				return err
+			}
+		}
+		printAskEstimate(os.Stdout, selectedBackend, messages, models)
+		return nil
+	}
 
-	// Display what we're doing
-	fmt.Printf("%s Asking %s (%s)...\n\n", style.Dim.Render("→"), model, selectedBackend.Name())
+	// Display what we're doing (--compare announces its own models)
+	if askCompare == "" {
+		fmt.Printf("%s Asking %s (%s)...\n\n", style.Dim.Render("→"), model, selectedBackend.Name())
+	}
+
+	// Open the output file before invoking so a bad path doesn't waste a call
+	var out io.Writer = os.Stdout
+	var outFile *os.File
+	if askOutput != "" {
+		outFile, err = openAskOutput(askOutput, askAppend)
+		if err != nil {
+			return err
+		}
+		defer outFile.Close()
+		out = io.MultiWriter(os.Stdout, outFile)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	if askStream {
+	if askCompare != "" {
+		models, err := parseAskCompare(askCompare)
+		if err != nil {
+			return err
+		}
+		if err := runAskCompare(ctx, selectedBackend, messages, systemPrompt, responseFormat, models, out); err != nil {
+			return err
+		}
+		if outFile != nil {
+			fmt.Printf("%s Responses written to %s\n", style.Dim.Render("✓"), askOutput)
+		}
+		return nil
+	}
+
+	if stream {
 		// Stream the response
-		streamCh, err := selectedBackend.InvokeStream(ctx, messages, backend.InvokeOptions{
+		opts := backend.InvokeOptions{
 			Model:     model,
-			MaxTokens: 4096,
-		})
+			MaxTokens: askMaxTokens,
+			SystemMsg: systemPrompt,
+
+			SearchParameters: searchParams,
+		}
+		streamCh, err := selectedBackend.InvokeStream(ctx, messages, opts)
 		if err != nil {
 			return fmt.Errorf("invoking API: %w", err)
 		}
 
-		for chunk := range streamCh {
-			if chunk.Error != nil {
-				return fmt.Errorf("streaming error: %w", chunk.Error)
+		res, err := streamAskResponse(streamCh, out, askMaxTokens*4, cancel)
+		if err != nil {
+			return err
+		}
+		content, citations := res.Content, res.Citations
+		inputTokens, outputTokens := askStreamUsage(selectedBackend, messages, model, res)
+
+		continued := false
+		if backend.TruncatedByLength(res.FinishReason) && askContinueOnTruncate {
+			continued = true
+			contMessages := continueAskMessages(messages, content)
+			streamCh, err := selectedBackend.InvokeStream(ctx, contMessages, opts)
+			if err != nil {
+				return fmt.Errorf("continuing truncated response: %w", err)
+			}
+			res, err = streamAskResponse(streamCh, out, askMaxTokens*4, cancel)
+			if err != nil {
+				return err
 			}
-			fmt.Print(chunk.Content)
+			content += res.Content
+			citations = append(citations, res.Citations...)
+			contIn, contOut := askStreamUsage(selectedBackend, contMessages, model, res)
+			inputTokens += contIn
+			outputTokens += contOut
+		}
+
+		_, _ = fmt.Fprintln(out)
+		if res.CutOff {
+			fmt.Printf("\n%s Response cut off at ~%d tokens (raise with --max-tokens)\n", style.WarningPrefix, askMaxTokens)
+		} else if backend.TruncatedByLength(res.FinishReason) {
+			warnAskTruncated(askMaxTokens, continued)
 		}
-		fmt.Println()
+		printAskCitations(out, citations)
 
-		// Note: Cost estimate not available for streaming (would need token counting)
-		fmt.Printf("\n%s Response complete (streaming mode - use --stream=false for cost estimate)\n", style.Dim.Render("✓"))
+		if err := saveAskSessionTurn(session, townRoot, question, content); err != nil {
+			return err
+		}
+
+		if outFile != nil && askOutputCost {
+			writeAskCostComment(outFile, selectedBackend, model, inputTokens, outputTokens)
+		}
+
+		cost := recordAskCost(selectedBackend, model, &backend.InvokeResult{InputTokens: inputTokens, OutputTokens: outputTokens})
+		printAskCost(inputTokens, outputTokens, cost)
+		if askJSONUsage {
+			writeAskUsage(os.Stderr, selectedBackend.Name(), model, inputTokens, outputTokens, cost)
+		}
 	} else {
 		// Non-streaming response
-		result, err := selectedBackend.Invoke(ctx, messages, backend.InvokeOptions{
-			Model:     model,
-			MaxTokens: 4096,
-		})
+		opts := backend.InvokeOptions{
+			Model:          model,
+			MaxTokens:      askMaxTokens,
+			SystemMsg:      systemPrompt,
+			ResponseFormat: responseFormat,
+
+			SearchParameters: searchParams,
+		}
+		result, err := invokeAsk(ctx, selectedBackend, messages, opts)
+		if errors.Is(err, backend.ErrInvalidStructuredOutput) {
+			want := "a JSON object"
+			if askJSONSchema != "" {
+				want = askJSONSchema
+			}
+			return fmt.Errorf("response does not match %s: %w", want, err)
+		}
 		if err != nil {
 			return fmt.Errorf("invoking API: %w", err)
 		}
 
-		fmt.Println(result.Content)
+		// A structured reply can't be stitched together, so only prose is continued
+		continued := false
+		if backend.TruncatedByLength(result.FinishReason) && askContinueOnTruncate && responseFormat == nil {
+			continued = true
+			if result, err = continueAsk(ctx, selectedBackend, messages, opts, result); err != nil {
+				return err
+			}
+		}
+
+		_, _ = fmt.Fprintln(out, result.Content)
+		if backend.TruncatedByLength(result.FinishReason) {
+			warnAskTruncated(askMaxTokens, continued)
+		}
+		printAskCitations(out, result.Citations)
+		if err := saveAskSessionTurn(session, townRoot, question, result.Content); err != nil {
+			return err
+		}
+
+		if outFile != nil && askOutputCost {
+			writeAskCostComment(outFile, selectedBackend, model, result.InputTokens, result.OutputTokens)
+		}
+
+		cost := recordAskCost(selectedBackend, model, result)
+		printAskCost(result.InputTokens, result.OutputTokens, cost)
+		if askJSONUsage {
+			writeAskUsage(os.Stderr, selectedBackend.Name(), model, result.InputTokens, result.OutputTokens, cost)
+		}
+	}
 
-		// Show cost estimate
-		cost := selectedBackend.EstimateCost(result.InputTokens, result.OutputTokens, model)
-		fmt.Printf("\n%s %d input + %d output tokens, ~$%.4f\n",
-			style.Dim.Render("Cost:"),
-			result.InputTokens, result.OutputTokens, cost.TotalCost)
+	if outFile != nil {
+		fmt.Printf("%s Response written to %s\n", style.Dim.Render("✓"), askOutput)
 	}
 
 	return nil
 }
+
+// warnAskContextOverflow warns when messages plus the response limit
+// outgrow the model's context window, so the API is likely to reject them.
+func warnAskContextOverflow(b backend.AgentBackend, messages []backend.Message, model string, maxResponseTokens int) {
+	tokens, err := b.CountTokens(messages, model)
+	if err != nil {
+		return
+	}
+	if window := b.MaxContextTokens(model); tokens+maxResponseTokens > window {
+		fmt.Printf("%s Prompt is ~%d tokens; with a %d-token response it exceeds the %d-token %s context window\n",
+			style.WarningPrefix, tokens, maxResponseTokens, window, model)
+	}
+}
+
+// printAskEstimate prints the input token count and estimated cost of
+// asking each model, assuming a response of askEstimateOutputRatio of the
+// input. Nothing is sent to the API.
+func printAskEstimate(w io.Writer, b backend.AgentBackend, messages []backend.Message, models []string) {
+	var total float64
+	for _, model := range models {
+		inputTokens, _ := b.CountTokens(messages, model)
+		outputTokens := int(float64(inputTokens) * askEstimateOutputRatio)
+		cost := b.EstimateCost(inputTokens, outputTokens, model)
+		total += cost.TotalCost
+
+		fmt.Fprintf(w, "%s %s (%s), not sent:\n", style.Dim.Render("Estimate:"), model, b.Name())
+		fmt.Fprintf(w, "  Input:  %d tokens\n", inputTokens)
+		fmt.Fprintf(w, "  Output: ~%d tokens (%.0f%% of input)\n", outputTokens, askEstimateOutputRatio*100)
+		fmt.Fprintf(w, "  Cost:   ~$%.4f\n", cost.TotalCost)
+	}
+	if len(models) > 1 {
+		fmt.Fprintf(w, "%s ~$%.4f\n", style.Dim.Render("Combined:"), total)
+	}
+}
+
+// parseAskCompare splits a --compare list into the tiers/models to ask.
+func parseAskCompare(list string) ([]string, error) {
+	var models []string
+	seen := make(map[string]bool)
+	for _, m := range strings.Split(list, ",") {
+		m = strings.ToLower(strings.TrimSpace(m))
+		if m == "" || seen[m] {
+			continue
+		}
+		seen[m] = true
+		models = append(models, m)
+	}
+	if len(models) < 2 {
+		return nil, fmt.Errorf("--compare needs at least two tiers or models, got %q", list)
+	}
+	return models, nil
+}
+
+// runAskCompare asks each model the same question concurrently and prints
+// the answers in labeled sections with per-answer and combined costs.
+// Each model gets the same --json/--json-schema response format. The run
+// is refused up front if its worst-case estimate exceeds --max-cost.
+func runAskCompare(ctx context.Context, b backend.AgentBackend, messages []backend.Message, systemPrompt string, responseFormat *backend.ResponseFormat, models []string, out io.Writer) error {
+	var estimate float64
+	for _, model := range models {
+		inputTokens, _ := b.CountTokens(messages, model)
+		estimate += b.EstimateCost(inputTokens, askMaxTokens, model).TotalCost
+	}
+	if askMaxCost > 0 && estimate > askMaxCost {
+		return fmt.Errorf("comparing %s could cost up to ~$%.4f, over the $%.2f budget (raise with --max-cost)",
+			strings.Join(models, ", "), estimate, askMaxCost)
+	}
+
+	fmt.Printf("%s Comparing %s (%s)...\n\n", style.Dim.Render("→"), strings.Join(models, " vs "), b.Name())
+
+	reqs := make([]backend.BatchRequest, len(models))
+	for i, model := range models {
+		reqs[i] = backend.BatchRequest{
+			Messages: messages,
+			Options: backend.InvokeOptions{
+				Model:          model,
+				MaxTokens:      askMaxTokens,
+				SystemMsg:      systemPrompt,
+				ResponseFormat: responseFormat,
+			},
+		}
+	}
+	resp, err := backend.InvokeBatch(ctx, b, reqs, len(reqs))
+	if err != nil {
+		return fmt.Errorf("invoking API: %w", err)
+	}
+
+	for i, res := range resp.Results {
+		_, _ = fmt.Fprintf(out, "━━━ %s ━━━\n", models[i])
+		if res.Err != nil {
+			_, _ = fmt.Fprintf(out, "%s %v\n\n", style.ErrorPrefix, res.Err)
+			continue
+		}
+		_, _ = fmt.Fprintln(out, res.Result.Content)
+		_, _ = fmt.Fprintf(out, "\n%s %d input + %d output tokens, ~$%.4f\n\n",
+			style.Dim.Render("Cost:"), res.Result.InputTokens, res.Result.OutputTokens, res.Cost.TotalCost)
+		backend.GetCostTracker().Record(b.Name(), models[i], res.Result, res.Cost)
+		if askJSONUsage {
+			writeAskUsage(os.Stderr, b.Name(), models[i], res.Result.InputTokens, res.Result.OutputTokens, res.Cost)
+		}
+	}
+
+	_, _ = fmt.Fprintf(out, "%s ~$%.4f across %d answers\n",
+		style.Bold.Render("Combined cost:"), resp.TotalCost, resp.Succeeded())
+	printAskSpend()
+
+	if resp.Succeeded() == 0 {
+		return fmt.Errorf("all %d models failed", len(models))
+	}
+	return nil
+}
+
+// recordAskCost adds an answer's cost to the cost tracker, which persists
+// across runs inside a town, and returns the cost.
+func recordAskCost(b backend.AgentBackend, model string, result *backend.InvokeResult) backend.CostEstimate {
+	cost := b.EstimateCost(result.InputTokens, result.OutputTokens, model)
+	backend.GetCostTracker().Record(b.Name(), model, result, cost)
+	return cost
+}
+
+// printAskCost prints the token usage and cost of a response, followed by
+// the running spend.
+func printAskCost(inputTokens, outputTokens int, cost backend.CostEstimate) {
+	fmt.Printf("\n%s %d input + %d output tokens, ~$%.4f\n",
+		style.Dim.Render("Cost:"), inputTokens, outputTokens, cost.TotalCost)
+	printAskSpend()
+}
+
+// askUsage is the --json-usage summary of an answer.
+type askUsage struct {
+	Backend      string  `json:"backend"`
+	Model        string  `json:"model"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// writeAskUsage writes an answer's usage to w as a single-line JSON object.
+func writeAskUsage(w io.Writer, backendName, model string, inputTokens, outputTokens int, cost backend.CostEstimate) {
+	_ = json.NewEncoder(w).Encode(askUsage{
+		Backend:      backendName,
+		Model:        model,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+		CostUSD:      cost.TotalCost,
+	})
+}
+
+// askStreamUsage returns the usage a stream reported, counting tokens in
+// the prompt and the streamed text for backends that don't report it.
+func askStreamUsage(b backend.AgentBackend, messages []backend.Message, model string, res askStreamResult) (inputTokens, outputTokens int) {
+	inputTokens, outputTokens = res.InputTokens, res.OutputTokens
+	if inputTokens == 0 {
+		inputTokens, _ = b.CountTokens(messages, model)
+	}
+	if outputTokens == 0 {
+		outputTokens, _ = b.CountTokens([]backend.Message{{Role: "assistant", Content: res.Content}}, model)
+	}
+	return inputTokens, outputTokens
+}
+
+// printAskSpend shows the running API spend for today and this month.
+func printAskSpend() {
+	tracker := backend.GetCostTracker()
+	fmt.Printf("%s ~$%.4f today, ~$%.4f this month\n",
+		style.Dim.Render("Spent:"), tracker.SpentToday(), tracker.SpentThisMonth())
+}
+
+// askStructuredRetryPrompt re-asks after a reply that didn't match the
+// requested format; %v is the validation error.
+const askStructuredRetryPrompt = "Your previous reply could not be used: %v. Reply again with only JSON that matches the requested format."
+
+// invokeAsk invokes the backend, re-prompting once if a structured response
+// doesn't match the requested format.
+func invokeAsk(ctx context.Context, b backend.AgentBackend, messages []backend.Message, opts backend.InvokeOptions) (*backend.InvokeResult, error) {
+	result, err := b.Invoke(ctx, messages, opts)
+	if opts.ResponseFormat == nil || !errors.Is(err, backend.ErrInvalidStructuredOutput) {
+		return result, err
+	}
+
+	fmt.Printf("%s %v; asking again\n", style.WarningPrefix, err)
+	retry := append(messages[:len(messages):len(messages)], backend.Message{
+		Role:    "user",
+		Content: fmt.Sprintf(askStructuredRetryPrompt, err),
+	})
+	return b.Invoke(ctx, retry, opts)
+}
+
+// loadAskResponseFormat reads a JSON Schema file for --json-schema. The
+// schema is named after the file, reduced to the characters providers
+// accept in schema and tool names.
+func loadAskResponseFormat(path string) (*backend.ResponseFormat, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: path is user-provided via --json-schema
+	if err != nil {
+		return nil, fmt.Errorf("reading JSON schema: %w", err)
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("JSON schema %s is not valid JSON", path)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	name = strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+
+	return &backend.ResponseFormat{
+		Type:   backend.ResponseFormatJSONSchema,
+		Name:   name,
+		Schema: json.RawMessage(data),
+	}, nil
+}
+
+// newAskBackend returns the named backend, creating and registering it
+// if it isn't already in the registry.
+func newAskBackend(name string) (backend.AgentBackend, error) {
+	name = strings.ToLower(name)
+	if b, err := backend.GetRegistry().Get(name); err == nil {
+		return b, nil
+	}
+
+	var b backend.AgentBackend
+	switch name {
+	case "bedrock":
+		bedrockBackend, err := bedrock.New()
+		if err != nil {
+			return nil, fmt.Errorf("initializing bedrock backend: %w", err)
+		}
+		b = bedrockBackend
+	case "grok":
+		grokBackend, err := grok.New()
+		if err != nil {
+			return nil, fmt.Errorf("grok backend not available (check XAI_API_KEY): %w", err)
+		}
+		b = grokBackend
+	default:
+		return nil, fmt.Errorf("unknown backend '%s': must be bedrock or grok", name)
+	}
+
+	backend.GetRegistry().Register(b)
+	return b, nil
+}
+
+// resolveAskStream decides whether gt ask streams: an explicit --stream flag
+// wins, then the town's ask_stream setting, then the flag default.
+func resolveAskStream(cmd *cobra.Command, townRoot string) bool {
+	if cmd.Flags().Changed("stream") || townRoot == "" {
+		return askStream
+	}
+	if cfg := config.ResolveBackendConfig(townRoot, ""); cfg.AskStream != nil {
+		return *cfg.AskStream
+	}
+	return askStream
+}
+
+// askDefaultSystemPrompt is the system prompt gt ask sends unless --system
+// or --system-file replaces it.
+const askDefaultSystemPrompt = "You are answering a quick question from a software developer. " +
+	"Answer directly and concisely, without restating the question. " +
+	"Put code in fenced Markdown code blocks, and say so when you are unsure."
+
+// resolveAskSystemPrompt returns the system prompt from --system or
+// --system-file, or the default when neither is set.
+func resolveAskSystemPrompt(text, path string) (string, error) {
+	if text != "" && path != "" {
+		return "", fmt.Errorf("--system and --system-file can't be combined")
+	}
+	if path != "" {
+		data, err := os.ReadFile(path) //nolint:gosec // G304: path is user-provided via --system-file
+		if err != nil {
+			return "", fmt.Errorf("reading system prompt: %w", err)
+		}
+		text = strings.TrimSpace(string(data))
+		if text == "" {
+			return "", fmt.Errorf("system prompt file %s is empty", path)
+		}
+	}
+	if text == "" {
+		return askDefaultSystemPrompt, nil
+	}
+	return text, nil
+}
+
+// askSearchParameters builds the search_parameters for --live-search, or
+// nil when it isn't set. Live search is an xAI feature, so it needs
+// --backend grok.
+func askSearchParameters(mode, backendName string) (json.RawMessage, error) {
+	switch mode {
+	case "", "off":
+		return nil, nil
+	case "auto", "on":
+	default:
+		return nil, fmt.Errorf("unknown --live-search mode '%s': must be auto or on", mode)
+	}
+	if !strings.EqualFold(backendName, "grok") {
+		return nil, fmt.Errorf("--live-search requires --backend grok")
+	}
+	return json.Marshal(map[string]any{"mode": mode, "return_citations": true})
+}
+
+// askDefaultMaxTokens is the default response token limit for gt ask.
+const askDefaultMaxTokens = 4096
+
+// askStreamResult is what streamAskResponse collected from a stream.
+type askStreamResult struct {
+	Content      string
+	Citations    []string // from the final chunk
+	FinishReason string   // from the final chunk
+	InputTokens  int      // from the final chunk; zero if not reported
+	OutputTokens int      // from the final chunk; zero if not reported
+	CutOff       bool     // stream canceled at the byte limit
+}
+
+// streamAskResponse copies streamed chunks to w as they arrive and returns
+// the complete response text along with the citations, finish reason, and
+// usage on the final chunk. If the response exceeds maxBytes (a runaway stream
+// repeating tokens), the stream is canceled and drained, the content is cut
+// at the limit, and CutOff is reported.
+func streamAskResponse(streamCh <-chan backend.StreamChunk, w io.Writer, maxBytes int, cancel context.CancelFunc) (askStreamResult, error) {
+	var sb strings.Builder
+	var res askStreamResult
+	for chunk := range streamCh {
+		if chunk.Error != nil {
+			return askStreamResult{Content: sb.String()}, fmt.Errorf("streaming error: %w", chunk.Error)
+		}
+		if chunk.Done {
+			res.Citations = chunk.Citations
+			res.FinishReason = chunk.FinishReason
+			res.InputTokens = chunk.InputTokens
+			res.OutputTokens = chunk.OutputTokens
+		}
+
+		text := chunk.Content
+		if maxBytes > 0 && sb.Len()+len(text) > maxBytes {
+			text = truncateUTF8(text, maxBytes-sb.Len())
+			res.CutOff = true
+		}
+		sb.WriteString(text)
+		if _, err := io.WriteString(w, text); err != nil {
+			return askStreamResult{Content: sb.String()}, fmt.Errorf("writing response: %w", err)
+		}
+
+		if res.CutOff {
+			cancel()
+			// Unblock the producer until it notices the cancellation
+			go func() {
+				for range streamCh {
+				}
+			}()
+			return askStreamResult{Content: sb.String(), CutOff: true}, nil
+		}
+	}
+	res.Content = sb.String()
+	return res, nil
+}
+
+// askContinuePrompt asks the model to pick up a reply that stopped at the
+// token limit.
+const askContinuePrompt = "Your reply was cut off at the token limit. Continue exactly where you left off, without repeating anything."
+
+// continueAskMessages extends messages with a truncated reply and a request
+// to continue it.
+func continueAskMessages(messages []backend.Message, partial string) []backend.Message {
+	return append(messages[:len(messages):len(messages)],
+		backend.Message{Role: "assistant", Content: partial},
+		backend.Message{Role: "user", Content: askContinuePrompt},
+	)
+}
+
+// continueAsk asks once for the rest of a reply that stopped at the token
+// limit and merges the continuation into result.
+func continueAsk(ctx context.Context, b backend.AgentBackend, messages []backend.Message, opts backend.InvokeOptions, result *backend.InvokeResult) (*backend.InvokeResult, error) {
+	more, err := b.Invoke(ctx, continueAskMessages(messages, result.Content), opts)
+	if err != nil {
+		return nil, fmt.Errorf("continuing truncated response: %w", err)
+	}
+	merged := *more
+	merged.Content = result.Content + more.Content
+	merged.InputTokens += result.InputTokens
+	merged.OutputTokens += result.OutputTokens
+	merged.Citations = append(result.Citations[:len(result.Citations):len(result.Citations)], more.Citations...)
+	return &merged, nil
+}
+
+// warnAskTruncated warns that a reply stopped at the response token limit.
+func warnAskTruncated(maxTokens int, continued bool) {
+	hint := "raise with --max-tokens or pass --continue-on-truncate"
+	if continued {
+		hint = "even after continuing; raise with --max-tokens"
+	}
+	fmt.Printf("\n%s Response stopped at the %d-token limit (%s)\n", style.WarningPrefix, maxTokens, hint)
+}
+
+// printAskCitations lists the sources a live search drew on, if any.
+func printAskCitations(w io.Writer, citations []string) {
+	if len(citations) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "\nSources:\n")
+	for i, url := range citations {
+		_, _ = fmt.Fprintf(w, "  [%d] %s\n", i+1, url)
+	}
+}
+
+// openAskOutput opens the --output file, truncating it unless appendMode is set.
+func openAskOutput(path string, appendMode bool) (*os.File, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0644) //nolint:gosec // G302: user-requested output file
+	if err != nil {
+		return nil, fmt.Errorf("opening output file: %w", err)
+	}
+	return f, nil
+}
+
+// writeAskCostComment appends the token usage and cost as a trailing comment.
+func writeAskCostComment(w io.Writer, b backend.AgentBackend, model string, inputTokens, outputTokens int) {
+	cost := b.EstimateCost(inputTokens, outputTokens, model)
+	_, _ = fmt.Fprintf(w, "\n<!-- gt ask: %s/%s, %d input + %d output tokens, ~$%.4f -->\n",
+		b.Name(), model, inputTokens, outputTokens, cost.TotalCost)
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// askFilesMaxBytes caps the total size of files included as context by
+// gt ask (~50k tokens), independent of the model's context window.
+const askFilesMaxBytes = 200_000
+
+// askFileDefaultMaxBytes is the default cap on the total size of --file
+// contents (~25k tokens).
+const askFileDefaultMaxBytes = 100_000
+
+// askFile is a file included as context for gt ask.
+type askFile struct {
+	Path      string
+	Content   string
+	Truncated bool
+}
+
+// askFilesResult is the outcome of expanding a --files-glob pattern.
+type askFilesResult struct {
+	// Files are the included files, in path order.
+	Files []askFile
+
+	// Binary lists matched files skipped because they aren't text.
+	Binary []string
+
+	// Omitted lists matched files dropped entirely by the size cap.
+	Omitted []string
+}
+
+// Truncated reports whether the size cap cut any file content.
+func (r *askFilesResult) Truncated() bool {
+	if len(r.Omitted) > 0 {
+		return true
+	}
+	for _, f := range r.Files {
+		if f.Truncated {
+			return true
+		}
+	}
+	return false
+}
+
+// expandAskFilesGlob reads the files matching pattern, skipping directories
+// and binary files, until maxBytes of content has been collected.
+// The file that crosses the cap is truncated; later files are omitted.
+func expandAskFilesGlob(pattern string, maxBytes int) (*askFilesResult, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no files match %q", pattern)
+	}
+	sort.Strings(matches)
+
+	result := &askFilesResult{}
+	remaining := maxBytes
+
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		if info.IsDir() {
+			continue
+		}
+
+		data, err := os.ReadFile(path) //nolint:gosec // G304: user-specified context file
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		if isBinaryContent(data) {
+			result.Binary = append(result.Binary, path)
+			continue
+		}
+
+		if remaining <= 0 {
+			result.Omitted = append(result.Omitted, path)
+			continue
+		}
+
+		file := askFile{Path: path, Content: string(data)}
+		if len(data) > remaining {
+			file.Content = truncateUTF8(string(data), remaining)
+			file.Truncated = true
+		}
+		remaining -= len(file.Content)
+		result.Files = append(result.Files, file)
+	}
+
+	return result, nil
+}
+
+// askFilesGlobMaxBytes is the --files-glob size cap for a model with a
+// contextTokens window: askFilesMaxBytes, or less if the window minus the
+// response tokens can't hold that much (~4 bytes per token). It's an error
+// when the response leaves no room for file content at all.
+func askFilesGlobMaxBytes(contextTokens, responseTokens int) (int, error) {
+	ctxBytes := (contextTokens - responseTokens) * 4
+	if ctxBytes <= 0 {
+		return 0, fmt.Errorf("no room for --files-glob content: the %d tokens reserved for the response fill the model's %d-token context window", responseTokens, contextTokens)
+	}
+	return min(askFilesMaxBytes, ctxBytes), nil
+}
+
+// readAskFiles reads the files named by --file, in order. Unlike
+// --files-glob, the files were asked for by name, so binary files,
+// directories, and a total over maxBytes are errors rather than skipped or
+// truncated.
+func readAskFiles(paths []string, maxBytes int) ([]askFile, error) {
+	var files []askFile
+	total := 0
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("%s is a directory (use --files-glob to include several files)", path)
+		}
+
+		data, err := os.ReadFile(path) //nolint:gosec // G304: user-specified context file
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		if isBinaryContent(data) {
+			return nil, fmt.Errorf("%s is a binary file; only text files can be included", path)
+		}
+
+		total += len(data)
+		if total > maxBytes {
+			return nil, fmt.Errorf("files total over %d bytes (raise with --file-max-bytes)", maxBytes)
+		}
+		files = append(files, askFile{Path: path, Content: string(data)})
+	}
+	return files, nil
+}
+
+// askStdin is where gt ask reads piped input. Tests replace it.
+var askStdin = os.Stdin
+
+// readAskStdin reads input piped to gt ask. It returns "" without reading
+// when f is a terminal, so an interactive gt ask never waits on stdin.
+// Input over maxBytes or that isn't text is an error, so a large log or a
+// binary isn't sent to the API by accident.
+func readAskStdin(f *os.File, maxBytes int) (string, error) {
+	if f == nil {
+		return "", nil
+	}
+	stat, err := f.Stat()
+	if err != nil || stat.Mode()&os.ModeCharDevice != 0 {
+		return "", nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(f, int64(maxBytes)+1))
+	if err != nil {
+		return "", fmt.Errorf("reading stdin: %w", err)
+	}
+	if len(data) > maxBytes {
+		return "", fmt.Errorf("stdin is over %d bytes; not sending it (raise with --stdin-max-bytes)", maxBytes)
+	}
+	if isBinaryContent(data) {
+		return "", fmt.Errorf("stdin is binary; only text can be piped to gt ask")
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// appendAskStdin adds piped input to the question as a fenced block. With
+// no question, the piped input is the question.
+func appendAskStdin(question, piped string) string {
+	if piped == "" {
+		return question
+	}
+	if question == "" {
+		return piped
+	}
+	return question + "\n\n```\n" + piped + "\n```\n"
+}
+
+// isBinaryContent reports whether data looks like a binary file:
+// a NUL byte in the first 8KB, or invalid UTF-8.
+func isBinaryContent(data []byte) bool {
+	head := data
+	if len(head) > 8192 {
+		head = head[:8192]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	return !utf8.Valid(data)
+}
+
+// truncateUTF8 cuts s to at most maxBytes without splitting a rune.
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	for maxBytes > 0 && !utf8.RuneStart(s[maxBytes]) {
+		maxBytes--
+	}
+	return s[:maxBytes]
+}
+
+// formatAskFiles renders included files as a context block for the prompt.
+func formatAskFiles(files []askFile) string {
+	var sb strings.Builder
+	for _, f := range files {
+		fmt.Fprintf(&sb, "File: %s\n```\n%s", f.Path, f.Content)
+		if !strings.HasSuffix(f.Content, "\n") {
+			sb.WriteString("\n")
+		}
+		if f.Truncated {
+			sb.WriteString("... [truncated]\n")
+		}
+		sb.WriteString("```\n\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/backend"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/util"
+)
+
+// askSessionNamePattern restricts session names to safe file names.
+var askSessionNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// askSession is a gt ask transcript replayed into later questions.
+type askSession struct {
+	Name      string            `json:"name"`
+	Messages  []backend.Message `json:"messages"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Turns returns the number of completed question/answer exchanges.
+func (s *askSession) Turns() int {
+	return len(s.Messages) / 2
+}
+
+// askSessionDir returns where a town keeps gt ask transcripts.
+func askSessionDir(townRoot string) string {
+	return filepath.Join(townRoot, "mayor", "ask-sessions")
+}
+
+// askSessionPath returns the transcript file for a session, rejecting
+// names that aren't plain file names.
+func askSessionPath(townRoot, name string) (string, error) {
+	if !askSessionNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid session name %q: use letters, digits, '.', '_' or '-'", name)
+	}
+	return filepath.Join(askSessionDir(townRoot), name+".json"), nil
+}
+
+// loadAskSession reads a session transcript. A session that doesn't exist
+// yet is returned empty.
+func loadAskSession(townRoot, name string) (*askSession, error) {
+	path, err := askSessionPath(townRoot, name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &askSession{Name: name}, nil
+		}
+		return nil, fmt.Errorf("reading session %s: %w", name, err)
+	}
+
+	var session askSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("parsing session %s: %w", name, err)
+	}
+	session.Name = name
+	return &session, nil
+}
+
+// Append records one exchange and saves the transcript.
+func (s *askSession) Append(townRoot, question, answer string) error {
+	path, err := askSessionPath(townRoot, s.Name)
+	if err != nil {
+		return err
+	}
+
+	s.Messages = append(s.Messages,
+		backend.Message{Role: "user", Content: question},
+		backend.Message{Role: "assistant", Content: answer},
+	)
+	s.UpdatedAt = time.Now()
+
+	if err := util.EnsureDirAndWriteJSON(path, s); err != nil {
+		return fmt.Errorf("saving session %s: %w", s.Name, err)
+	}
+	return nil
+}
+
+// listAskSessions returns a town's sessions, most recently used first.
+func listAskSessions(townRoot string) ([]*askSession, error) {
+	paths, err := filepath.Glob(filepath.Join(askSessionDir(townRoot), "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("listing sessions: %w", err)
+	}
+
+	var sessions []*askSession
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		session, err := loadAskSession(townRoot, name)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
+	return sessions, nil
+}
+
+// clearAskSession deletes a session transcript.
+func clearAskSession(townRoot, name string) error {
+	path, err := askSessionPath(townRoot, name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no session named %q", name)
+		}
+		return fmt.Errorf("removing session %s: %w", name, err)
+	}
+	return nil
+}
+
+// askSessionMessages returns the session history followed by the new
+// question, trimmed oldest-first to fit the model's context window. A
+// trimmed history never starts with an assistant reply.
+func askSessionMessages(session *askSession, question string, b backend.AgentBackend, model string, maxResponseTokens int) ([]backend.Message, error) {
+	messages := append(append([]backend.Message{}, session.Messages...), backend.Message{Role: "user", Content: question})
+	if len(messages) == 1 {
+		return messages, nil
+	}
+
+	cm := backend.NewContextManager()
+	trimmed, err := cm.PrepareContextForResponse(messages, model, b.MaxContextTokens(model), maxResponseTokens, backend.TruncateOldest)
+	if err != nil {
+		return nil, fmt.Errorf("fitting session %s into context: %w", session.Name, err)
+	}
+	for len(trimmed) > 0 && trimmed[0].Role != "user" {
+		trimmed = trimmed[1:]
+	}
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("question doesn't fit the %s context window", model)
+	}
+	if dropped := len(messages) - len(trimmed); dropped > 0 {
+		fmt.Printf("%s Dropped %d oldest session message(s) to fit the context window\n", style.Dim.Render("○"), dropped)
+	}
+	return trimmed, nil
+}
+
+// saveAskSessionTurn appends an exchange to session, if there is one.
+func saveAskSessionTurn(session *askSession, townRoot, question, answer string) error {
+	if session == nil {
+		return nil
+	}
+	return session.Append(townRoot, question, answer)
+}
+
+// printAskSessions lists a town's saved conversations.
+func printAskSessions(townRoot string) error {
+	sessions, err := listAskSessions(townRoot)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Println(style.Dim.Render("No ask sessions. Start one with: gt ask --session <name> \"<question>\""))
+		return nil
+	}
+	for _, s := range sessions {
+		fmt.Printf("  %-20s %3d turn(s)  last used %s\n", s.Name, s.Turns(), s.UpdatedAt.Local().Format("2006-01-02 15:04"))
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/backend"
+)
+
+// smallContextBackend is a fake backend with a tiny context window.
+type smallContextBackend struct {
+	countingBackend
+	window int
+}
+
+func (b *smallContextBackend) MaxContextTokens(string) int { return b.window }
+
+func TestAskSessionReplaysHistory(t *testing.T) {
+	townRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(townRoot, "mayor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(townRoot); err != nil {
+		t.Fatal(err)
+	}
+
+	backend.ResetRegistryForTesting()
+	t.Cleanup(backend.ResetRegistryForTesting)
+	backend.ResetCostTrackerForTesting()
+	t.Cleanup(backend.ResetCostTrackerForTesting)
+	fake := &jsonBackend{countingBackend: countingBackend{name: "bedrock"}, replies: []string{"LRU", "Shrink it"}}
+	backend.GetRegistry().Register(fake)
+
+	savedBackend, savedStream, savedTier, savedSession := askBackend, askStream, askTier, askSessionName
+	savedList, savedClear := askListSessions, askClearSession
+	t.Cleanup(func() {
+		askBackend, askStream, askTier, askSessionName = savedBackend, savedStream, savedTier, savedSession
+		askListSessions, askClearSession = savedList, savedClear
+	})
+	askBackend, askStream, askTier, askSessionName = "bedrock", false, "haiku", "cache"
+
+	for _, q := range []string{"how should the cache evict?", "and under memory pressure?"} {
+		captureStdout(t, func() {
+			if err := runAsk(&cobra.Command{}, []string{q}); err != nil {
+				t.Fatalf("runAsk --session: %v", err)
+			}
+		})
+	}
+
+	if len(fake.received) != 2 {
+		t.Fatalf("expected 2 invocations, got %d", len(fake.received))
+	}
+	second := fake.received[1]
+	if len(second) != 3 || second[0].Content != "how should the cache evict?" || second[1].Content != "LRU" {
+		t.Errorf("second call messages = %+v, want replayed first turn", second)
+	}
+
+	askSessionName, askListSessions = "", true
+	output := captureStdout(t, func() {
+		if err := runAsk(&cobra.Command{}, nil); err != nil {
+			t.Errorf("runAsk --list-sessions: %v", err)
+		}
+	})
+	if !strings.Contains(output, "cache") || !strings.Contains(output, "2 turn(s)") {
+		t.Errorf("expected cache session with 2 turns, got:\n%s", output)
+	}
+
+	askListSessions, askClearSession = false, "cache"
+	captureStdout(t, func() {
+		if err := runAsk(&cobra.Command{}, nil); err != nil {
+			t.Errorf("runAsk --clear-session: %v", err)
+		}
+	})
+	if _, err := os.Stat(filepath.Join(askSessionDir(townRoot), "cache.json")); !os.IsNotExist(err) {
+		t.Errorf("expected transcript removed, stat err = %v", err)
+	}
+	if err := clearAskSession(townRoot, "cache"); err == nil {
+		t.Error("clearing a missing session should fail")
+	}
+}
+
+func TestAskSessionMessagesTrimsOldestTurns(t *testing.T) {
+	session := &askSession{Name: "long"}
+	for i := 0; i < 10; i++ {
+		session.Messages = append(session.Messages,
+			backend.Message{Role: "user", Content: strings.Repeat("question ", 100)},
+			backend.Message{Role: "assistant", Content: strings.Repeat("answer ", 150)},
+		)
+	}
+	b := &smallContextBackend{window: 2000}
+
+	var messages []backend.Message
+	var err error
+	captureStdout(t, func() {
+		messages, err = askSessionMessages(session, "latest?", b, "haiku", 500)
+	})
+	if err != nil {
+		t.Fatalf("askSessionMessages: %v", err)
+	}
+	if len(messages) >= 21 {
+		t.Fatalf("expected history to be trimmed, got all %d messages", len(messages))
+	}
+	if messages[0].Role != "user" {
+		t.Errorf("trimmed history starts with %s, want user", messages[0].Role)
+	}
+	if last := messages[len(messages)-1]; last.Content != "latest?" {
+		t.Errorf("last message = %q, want the new question", last.Content)
+	}
+}
+
+func TestAskSessionPathRejectsUnsafeNames(t *testing.T) {
+	for _, name := range []string{"", "../escape", "a/b", ".hidden"} {
+		if _, err := askSessionPath(t.TempDir(), name); err == nil {
+			t.Errorf("askSessionPath(%q) should fail", name)
+		}
+	}
+}
@@ -0,0 +1,845 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/backend"
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+func TestExpandAskFilesGlob(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name string, data []byte) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	writeFile("a.go", []byte("package a\n"))
+	writeFile("b.go", []byte("package b\n"))
+	writeFile("c.go", []byte{0x7f, 'E', 'L', 'F', 0x00, 0x01})
+	writeFile("notes.txt", []byte("not matched\n"))
+	if err := os.Mkdir(filepath.Join(dir, "sub.go"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	result, err := expandAskFilesGlob(filepath.Join(dir, "*.go"), askFilesMaxBytes)
+	if err != nil {
+		t.Fatalf("expandAskFilesGlob: %v", err)
+	}
+
+	if len(result.Files) != 2 {
+		t.Fatalf("expected 2 text files, got %d: %+v", len(result.Files), result.Files)
+	}
+	if filepath.Base(result.Files[0].Path) != "a.go" || filepath.Base(result.Files[1].Path) != "b.go" {
+		t.Errorf("unexpected files: %s, %s", result.Files[0].Path, result.Files[1].Path)
+	}
+	if len(result.Binary) != 1 || filepath.Base(result.Binary[0]) != "c.go" {
+		t.Errorf("expected c.go skipped as binary, got %v", result.Binary)
+	}
+	if result.Truncated() {
+		t.Error("expected no truncation under the cap")
+	}
+}
+
+func TestExpandAskFilesGlobSizeCap(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"1.txt", "2.txt", "3.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(strings.Repeat("x", 60)), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	result, err := expandAskFilesGlob(filepath.Join(dir, "*.txt"), 100)
+	if err != nil {
+		t.Fatalf("expandAskFilesGlob: %v", err)
+	}
+
+	if !result.Truncated() {
+		t.Fatal("expected truncation over the cap")
+	}
+	if len(result.Files) != 2 {
+		t.Fatalf("expected 2 included files, got %d", len(result.Files))
+	}
+	if result.Files[0].Truncated || len(result.Files[0].Content) != 60 {
+		t.Errorf("first file should be complete, got %d bytes", len(result.Files[0].Content))
+	}
+	if !result.Files[1].Truncated || len(result.Files[1].Content) != 40 {
+		t.Errorf("second file should be truncated to 40 bytes, got %d", len(result.Files[1].Content))
+	}
+	if len(result.Omitted) != 1 || filepath.Base(result.Omitted[0]) != "3.txt" {
+		t.Errorf("expected 3.txt omitted, got %v", result.Omitted)
+	}
+
+	total := 0
+	for _, f := range result.Files {
+		total += len(f.Content)
+	}
+	if total > 100 {
+		t.Errorf("total content %d exceeds cap 100", total)
+	}
+
+	formatted := formatAskFiles(result.Files)
+	if !strings.Contains(formatted, "[truncated]") {
+		t.Errorf("formatted context should mark truncation:\n%s", formatted)
+	}
+}
+
+func TestAskFilesGlobMaxBytes(t *testing.T) {
+	tests := []struct {
+		contextTokens, responseTokens int
+		want                          int
+		wantErr                       bool
+	}{
+		{contextTokens: 200000, responseTokens: 4096, want: askFilesMaxBytes},
+		{contextTokens: 8192, responseTokens: 4096, want: 4096 * 4},
+		{contextTokens: 8192, responseTokens: 8192, wantErr: true},
+		{contextTokens: 8192, responseTokens: 16000, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := askFilesGlobMaxBytes(tt.contextTokens, tt.responseTokens)
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "no room") {
+				t.Errorf("askFilesGlobMaxBytes(%d, %d) = %d, %v; want no-room error", tt.contextTokens, tt.responseTokens, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("askFilesGlobMaxBytes(%d, %d) = %d, %v; want %d", tt.contextTokens, tt.responseTokens, got, err, tt.want)
+		}
+	}
+}
+
+func TestExpandAskFilesGlobNoMatches(t *testing.T) {
+	if _, err := expandAskFilesGlob(filepath.Join(t.TempDir(), "*.go"), askFilesMaxBytes); err == nil {
+		t.Error("expected error when no files match")
+	}
+}
+
+func TestReadAskFiles(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.go")
+	util := filepath.Join(dir, "util.go")
+	bin := filepath.Join(dir, "gt")
+	if err := os.WriteFile(main, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(util, []byte("package main\n\nfunc util() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bin, []byte{0x7f, 'E', 'L', 'F', 0x00}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := readAskFiles([]string{util, main}, askFileDefaultMaxBytes)
+	if err != nil {
+		t.Fatalf("readAskFiles: %v", err)
+	}
+	if len(files) != 2 || files[0].Path != util || files[1].Path != main {
+		t.Fatalf("files = %+v, want util.go then main.go", files)
+	}
+	if formatted := formatAskFiles(files); !strings.Contains(formatted, "File: "+main+"\n```\npackage main\n```") {
+		t.Errorf("formatted files missing labeled fence for main.go:\n%s", formatted)
+	}
+
+	if _, err := readAskFiles([]string{main, bin}, askFileDefaultMaxBytes); err == nil || !strings.Contains(err.Error(), "binary") {
+		t.Errorf("binary file error = %v, want a binary file error", err)
+	}
+	if _, err := readAskFiles([]string{dir}, askFileDefaultMaxBytes); err == nil {
+		t.Error("expected an error for a directory")
+	}
+	if _, err := readAskFiles([]string{main, util}, 20); err == nil || !strings.Contains(err.Error(), "--file-max-bytes") {
+		t.Errorf("over-cap error = %v, want a --file-max-bytes error", err)
+	}
+}
+
+func TestReadAskStdin(t *testing.T) {
+	pipe := func(data []byte) *os.File {
+		t.Helper()
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { r.Close() })
+		go func() {
+			_, _ = w.Write(data)
+			w.Close()
+		}()
+		return r
+	}
+
+	got, err := readAskStdin(pipe([]byte("panic: nil map\n\tat main.go:12\n")), 100)
+	if err != nil || got != "panic: nil map\n\tat main.go:12" {
+		t.Errorf("readAskStdin = %q, %v", got, err)
+	}
+	if q := appendAskStdin("summarize these errors", got); q != "summarize these errors\n\n```\n"+got+"\n```\n" {
+		t.Errorf("appendAskStdin = %q, want the input fenced after the question", q)
+	}
+	if q := appendAskStdin("", got); q != got {
+		t.Errorf("appendAskStdin with no question = %q, want the input alone", q)
+	}
+
+	if _, err := readAskStdin(pipe([]byte(strings.Repeat("x", 101))), 100); err == nil || !strings.Contains(err.Error(), "--stdin-max-bytes") {
+		t.Errorf("oversized stdin error = %v, want a --stdin-max-bytes error", err)
+	}
+	if _, err := readAskStdin(pipe([]byte{0x7f, 'E', 'L', 'F', 0x00}), 100); err == nil {
+		t.Error("expected an error for binary stdin")
+	}
+
+	// A terminal (character device) is never read
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	if got, err := readAskStdin(devNull, 100); err != nil || got != "" {
+		t.Errorf("readAskStdin(%s) = %q, %v; want nothing read", os.DevNull, got, err)
+	}
+}
+
+func TestResolveAskSystemPrompt(t *testing.T) {
+	if got, err := resolveAskSystemPrompt("", ""); err != nil || got != askDefaultSystemPrompt {
+		t.Errorf("no flags = %q, %v; want the default prompt", got, err)
+	}
+	if got, err := resolveAskSystemPrompt("Follow the Go style guide.", ""); err != nil || got != "Follow the Go style guide." {
+		t.Errorf("--system = %q, %v", got, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "system.md")
+	if err := os.WriteFile(path, []byte("Use gt conventions.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := resolveAskSystemPrompt("", path); err != nil || got != "Use gt conventions." {
+		t.Errorf("--system-file = %q, %v", got, err)
+	}
+	if _, err := resolveAskSystemPrompt("inline", path); err == nil {
+		t.Error("expected an error combining --system and --system-file")
+	}
+	if _, err := resolveAskSystemPrompt("", filepath.Join(t.TempDir(), "missing.md")); err == nil {
+		t.Error("expected an error for a missing --system-file")
+	}
+}
+
+func TestStreamAskResponseWritesCompleteOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answer.md")
+	f, err := openAskOutput(path, false)
+	if err != nil {
+		t.Fatalf("openAskOutput: %v", err)
+	}
+
+	chunks := []string{"The cache ", "layer should ", "use LRU eviction."}
+	streamCh := make(chan backend.StreamChunk, len(chunks))
+	for _, c := range chunks {
+		streamCh <- backend.StreamChunk{Content: c}
+	}
+	close(streamCh)
+
+	var terminal bytes.Buffer
+	res, err := streamAskResponse(streamCh, io.MultiWriter(&terminal, f), 0, func() {})
+	if err != nil {
+		t.Fatalf("streamAskResponse: %v", err)
+	}
+	writeAskCostComment(f, &countingBackend{name: "bedrock"}, "haiku", 10, 5)
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	want := strings.Join(chunks, "")
+	if res.Content != want {
+		t.Errorf("content = %q, want %q", res.Content, want)
+	}
+	if terminal.String() != want {
+		t.Errorf("terminal = %q, want %q", terminal.String(), want)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if !strings.HasPrefix(string(data), want) {
+		t.Errorf("output file missing complete response:\n%s", data)
+	}
+	if !strings.Contains(string(data), "<!-- gt ask: bedrock/haiku, 10 input + 5 output tokens, ~$0.0123 -->") {
+		t.Errorf("output file missing cost comment:\n%s", data)
+	}
+}
+
+func TestStreamAskResponseError(t *testing.T) {
+	streamCh := make(chan backend.StreamChunk, 2)
+	streamCh <- backend.StreamChunk{Content: "partial"}
+	streamCh <- backend.StreamChunk{Error: errors.New("connection reset")}
+	close(streamCh)
+
+	var buf bytes.Buffer
+	res, err := streamAskResponse(streamCh, &buf, 0, func() {})
+	if err == nil {
+		t.Fatal("expected streaming error")
+	}
+	if res.Content != "partial" || buf.String() != "partial" {
+		t.Errorf("expected partial content preserved, got %q / %q", res.Content, buf.String())
+	}
+}
+
+func TestStreamAskResponseCitations(t *testing.T) {
+	streamCh := make(chan backend.StreamChunk, 1)
+	streamCh <- backend.StreamChunk{Content: "Go 1.24 adds generic type aliases.", Done: true, Citations: []string{"https://go.dev/doc/go1.24"}}
+	close(streamCh)
+
+	var buf bytes.Buffer
+	res, err := streamAskResponse(streamCh, &buf, 0, func() {})
+	if err != nil {
+		t.Fatalf("streamAskResponse: %v", err)
+	}
+	printAskCitations(&buf, res.Citations)
+	if !strings.Contains(buf.String(), "Sources:\n  [1] https://go.dev/doc/go1.24\n") {
+		t.Errorf("output missing citations:\n%s", buf.String())
+	}
+}
+
+func TestContinueAskMergesContinuation(t *testing.T) {
+	fake := &countingBackend{name: "bedrock", finishReason: "end_turn"}
+	messages := []backend.Message{{Role: "user", Content: "write a haiku"}}
+	partial := &backend.InvokeResult{Content: "Autumn moonlight, ", InputTokens: 3, OutputTokens: 4, FinishReason: "max_tokens"}
+
+	result, err := continueAsk(context.Background(), fake, messages, backend.InvokeOptions{Model: "haiku"}, partial)
+	if err != nil {
+		t.Fatalf("continueAsk: %v", err)
+	}
+	if result.Content != "Autumn moonlight, done" {
+		t.Errorf("Content = %q", result.Content)
+	}
+	if result.InputTokens != 13 || result.OutputTokens != 9 {
+		t.Errorf("tokens = %d/%d, want 13/9", result.InputTokens, result.OutputTokens)
+	}
+	if backend.TruncatedByLength(result.FinishReason) {
+		t.Errorf("FinishReason = %q, want the continuation's", result.FinishReason)
+	}
+
+	cont := continueAskMessages(messages, partial.Content)
+	if len(cont) != 3 || cont[1].Role != "assistant" || cont[2].Content != askContinuePrompt {
+		t.Errorf("continuation messages = %+v", cont)
+	}
+	if len(messages) != 1 {
+		t.Error("continueAskMessages modified the original messages")
+	}
+}
+
+func TestAskStreamUsage(t *testing.T) {
+	fake := &countingBackend{name: "bedrock"}
+	messages := []backend.Message{{Role: "user", Content: "hi"}}
+
+	streamCh := make(chan backend.StreamChunk, 2)
+	streamCh <- backend.StreamChunk{Content: "hello"}
+	streamCh <- backend.StreamChunk{Done: true, InputTokens: 25, OutputTokens: 12}
+	close(streamCh)
+	res, err := streamAskResponse(streamCh, io.Discard, 0, func() {})
+	if err != nil {
+		t.Fatalf("streamAskResponse: %v", err)
+	}
+	if in, out := askStreamUsage(fake, messages, "haiku", res); in != 25 || out != 12 {
+		t.Errorf("reported usage = %d/%d, want 25/12", in, out)
+	}
+
+	// Without reported usage, tokens are counted locally
+	if in, out := askStreamUsage(fake, messages, "haiku", askStreamResult{Content: "hello"}); in != 1000 || out != 1000 {
+		t.Errorf("counted usage = %d/%d, want 1000/1000", in, out)
+	}
+}
+
+func TestAskSearchParameters(t *testing.T) {
+	if params, err := askSearchParameters("", "bedrock"); err != nil || params != nil {
+		t.Errorf("no --live-search = %s, %v; want nil", params, err)
+	}
+	params, err := askSearchParameters("on", "grok")
+	if err != nil || string(params) != `{"mode":"on","return_citations":true}` {
+		t.Errorf("--live-search on = %s, %v", params, err)
+	}
+	if _, err := askSearchParameters("on", "bedrock"); err == nil {
+		t.Error("expected an error for --live-search without grok")
+	}
+	if _, err := askSearchParameters("always", "grok"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestStreamAskResponseCutsOffRunawayStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// An endpoint stuck repeating tokens until canceled
+	streamCh := make(chan backend.StreamChunk)
+	producerDone := make(chan struct{})
+	go func() {
+		defer close(producerDone)
+		defer close(streamCh)
+		for {
+			select {
+			case streamCh <- backend.StreamChunk{Content: "again "}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var buf bytes.Buffer
+	res, err := streamAskResponse(streamCh, &buf, 100, cancel)
+	if err != nil {
+		t.Fatalf("streamAskResponse: %v", err)
+	}
+	if !res.CutOff {
+		t.Error("expected truncation to be reported")
+	}
+	if len(res.Content) != 100 || buf.Len() != 100 {
+		t.Errorf("expected output cut at 100 bytes, got content=%d written=%d", len(res.Content), buf.Len())
+	}
+
+	select {
+	case <-producerDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream was not canceled after hitting the limit")
+	}
+}
+
+func TestOpenAskOutputAppendAndOverwrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answer.md")
+	if err := os.WriteFile(path, []byte("first\n"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	write := func(appendMode bool, text string) {
+		t.Helper()
+		f, err := openAskOutput(path, appendMode)
+		if err != nil {
+			t.Fatalf("openAskOutput: %v", err)
+		}
+		if _, err := f.WriteString(text); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+	}
+	read := func() string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		return string(data)
+	}
+
+	write(true, "second\n")
+	if got := read(); got != "first\nsecond\n" {
+		t.Errorf("append: got %q", got)
+	}
+
+	write(false, "third\n")
+	if got := read(); got != "third\n" {
+		t.Errorf("overwrite: got %q", got)
+	}
+}
+
+func TestResolveAskStreamConfigDefault(t *testing.T) {
+	saved := askStream
+	t.Cleanup(func() { askStream = saved })
+
+	townRoot := t.TempDir()
+	cfg := config.NewBackendConfig()
+	noStream := false
+	cfg.AskStream = &noStream
+	if err := config.SaveBackendConfig(config.BackendConfigPath(townRoot), cfg); err != nil {
+		t.Fatalf("save backend config: %v", err)
+	}
+
+	newCmd := func() *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().BoolVar(&askStream, "stream", true, "")
+		return cmd
+	}
+
+	// Flag unspecified: config default disables streaming
+	if resolveAskStream(newCmd(), townRoot) {
+		t.Error("expected ask_stream=false in config to disable streaming")
+	}
+
+	// Explicit flag overrides config
+	cmd := newCmd()
+	if err := cmd.Flags().Set("stream", "true"); err != nil {
+		t.Fatalf("set flag: %v", err)
+	}
+	if !resolveAskStream(cmd, townRoot) {
+		t.Error("expected --stream=true to override config")
+	}
+
+	// No config: built-in default streams
+	if !resolveAskStream(newCmd(), t.TempDir()) {
+		t.Error("expected streaming by default without config")
+	}
+}
+
+func TestAskGrokWithoutAWSCredentials(t *testing.T) {
+	// Make any AWS config load fail, and hide real credentials
+	t.Setenv("AWS_PROFILE", "gt-test-nonexistent-profile")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "missing-config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "missing-credentials"))
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("XAI_API_KEY", "xai-test")
+
+	backend.ResetRegistryForTesting()
+	t.Cleanup(backend.ResetRegistryForTesting)
+
+	b, err := newAskBackend("grok")
+	if err != nil {
+		t.Fatalf("newAskBackend(grok) without AWS credentials: %v", err)
+	}
+	if b.Name() != "grok" {
+		t.Errorf("backend = %s, want grok", b.Name())
+	}
+	if _, err := backend.GetRegistry().Get("bedrock"); err == nil {
+		t.Error("bedrock should not be initialized when grok is selected")
+	}
+
+	// The whole command runs against the selected backend
+	backend.ResetRegistryForTesting()
+	fake := &countingBackend{name: "grok"}
+	backend.GetRegistry().Register(fake)
+
+	savedBackend, savedStream, savedTier := askBackend, askStream, askTier
+	t.Cleanup(func() { askBackend, askStream, askTier = savedBackend, savedStream, savedTier })
+	askBackend, askStream, askTier = "grok", false, "haiku"
+
+	// Run inside a scratch town so spend is saved there
+	townRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(townRoot, "mayor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(townRoot); err != nil {
+		t.Fatal(err)
+	}
+	backend.ResetCostTrackerForTesting()
+	t.Cleanup(backend.ResetCostTrackerForTesting)
+
+	var runErr error
+	output := captureStdout(t, func() {
+		runErr = runAsk(&cobra.Command{}, []string{"what is a mutex?"})
+	})
+	if runErr != nil {
+		t.Fatalf("runAsk --backend grok: %v", runErr)
+	}
+	if fake.invokes.Load() != 1 {
+		t.Errorf("expected grok to be invoked once, got %d", fake.invokes.Load())
+	}
+	if !strings.Contains(output, "done") {
+		t.Errorf("expected response in output, got:\n%s", output)
+	}
+	if _, err := os.Stat(backend.CostTrackerPath(townRoot)); err != nil {
+		t.Errorf("expected spend to be saved in the town: %v", err)
+	}
+}
+
+func TestLoadAskResponseFormat(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "review verdict.schema.json")
+	schema := `{"type":"object","required":["verdict"]}`
+	if err := os.WriteFile(path, []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	format, err := loadAskResponseFormat(path)
+	if err != nil {
+		t.Fatalf("loadAskResponseFormat: %v", err)
+	}
+	if format.Type != backend.ResponseFormatJSONSchema {
+		t.Errorf("Type = %q, want json_schema", format.Type)
+	}
+	if format.Name != "review_verdict_schema" {
+		t.Errorf("Name = %q, want review_verdict_schema", format.Name)
+	}
+	if string(format.Schema) != schema {
+		t.Errorf("Schema = %s, want %s", format.Schema, schema)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"type":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadAskResponseFormat(bad); err == nil {
+		t.Error("expected error for invalid JSON schema file")
+	}
+}
+
+// tierBackend answers with the model it was asked, at a per-model cost.
+type tierBackend struct {
+	countingBackend
+	costs      map[string]float64
+	structured atomic.Int32 // invocations asked for a response format
+}
+
+func (b *tierBackend) EstimateCost(in, out int, model string) backend.CostEstimate {
+	return backend.CostEstimate{TotalCost: b.costs[model], Currency: "USD", Model: model}
+}
+
+func (b *tierBackend) Invoke(_ context.Context, _ []backend.Message, opts backend.InvokeOptions) (*backend.InvokeResult, error) {
+	b.invokes.Add(1)
+	if opts.ResponseFormat != nil {
+		b.structured.Add(1)
+	}
+	return &backend.InvokeResult{Content: "answer from " + opts.Model, Model: opts.Model, InputTokens: 10, OutputTokens: 5}, nil
+}
+
+func TestRunAskCompare(t *testing.T) {
+	b := &tierBackend{
+		countingBackend: countingBackend{name: "fake"},
+		costs:           map[string]float64{"sonnet": 0.01, "opus": 0.05},
+	}
+	messages := []backend.Message{{Role: "user", Content: "what is a mutex?"}}
+
+	savedMaxCost, savedMaxTokens := askMaxCost, askMaxTokens
+	t.Cleanup(func() { askMaxCost, askMaxTokens = savedMaxCost, savedMaxTokens })
+	askMaxCost, askMaxTokens = askDefaultCompareMaxCost, askDefaultMaxTokens
+
+	var out bytes.Buffer
+	var runErr error
+	captureStdout(t, func() {
+		runErr = runAskCompare(context.Background(), b, messages, askDefaultSystemPrompt, nil, []string{"sonnet", "opus"}, &out)
+	})
+	if runErr != nil {
+		t.Fatalf("runAskCompare: %v", runErr)
+	}
+
+	got := out.String()
+	for _, want := range []string{"━━━ sonnet ━━━", "answer from sonnet", "━━━ opus ━━━", "answer from opus", "~$0.0100", "~$0.0500", "~$0.0600 across 2 answers"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "answer from sonnet") > strings.Index(got, "answer from opus") {
+		t.Errorf("sections out of order:\n%s", got)
+	}
+
+	// --json-usage: one usage object per model on stderr
+	savedUsage := askJSONUsage
+	t.Cleanup(func() { askJSONUsage = savedUsage })
+	askJSONUsage = true
+	oldStderr := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("create pipe: %v", err)
+	}
+	os.Stderr = w
+	captureStdout(t, func() {
+		runErr = runAskCompare(context.Background(), b, messages, askDefaultSystemPrompt, nil, []string{"sonnet", "opus"}, io.Discard)
+	})
+	_ = w.Close()
+	os.Stderr = oldStderr
+	stderr, _ := io.ReadAll(r)
+	_ = r.Close()
+	askJSONUsage = false
+	if runErr != nil {
+		t.Fatalf("runAskCompare with --json-usage: %v", runErr)
+	}
+	var usages []askUsage
+	dec := json.NewDecoder(bytes.NewReader(stderr))
+	for dec.More() {
+		var u askUsage
+		if err := dec.Decode(&u); err != nil {
+			t.Fatalf("stderr %q is not usage objects: %v", stderr, err)
+		}
+		usages = append(usages, u)
+	}
+	if len(usages) != 2 || usages[0].Model != "sonnet" || usages[0].CostUSD != 0.01 || usages[1].Model != "opus" || usages[1].CostUSD != 0.05 {
+		t.Errorf("usage objects = %+v, want sonnet $0.01 then opus $0.05", usages)
+	}
+
+	// --json: every model is asked for the response format
+	captureStdout(t, func() {
+		runErr = runAskCompare(context.Background(), b, messages, askDefaultSystemPrompt,
+			&backend.ResponseFormat{Type: backend.ResponseFormatJSONObject}, []string{"sonnet", "opus"}, io.Discard)
+	})
+	if runErr != nil {
+		t.Fatalf("runAskCompare with --json: %v", runErr)
+	}
+	if got := b.structured.Load(); got != 2 {
+		t.Errorf("%d of 2 compare invocations carried the response format", got)
+	}
+
+	// Over budget: refused before invoking anything
+	b.invokes.Store(0)
+	askMaxCost = 0.05
+	err = runAskCompare(context.Background(), b, messages, askDefaultSystemPrompt, nil, []string{"sonnet", "opus"}, &out)
+	if err == nil || !strings.Contains(err.Error(), "budget") {
+		t.Errorf("expected budget error, got %v", err)
+	}
+	if b.invokes.Load() != 0 {
+		t.Errorf("over-budget compare invoked the backend %d times", b.invokes.Load())
+	}
+}
+
+func TestParseAskCompare(t *testing.T) {
+	models, err := parseAskCompare(" Sonnet, opus ,sonnet")
+	if err != nil {
+		t.Fatalf("parseAskCompare: %v", err)
+	}
+	if len(models) != 2 || models[0] != "sonnet" || models[1] != "opus" {
+		t.Errorf("models = %v, want [sonnet opus]", models)
+	}
+	if _, err := parseAskCompare("opus"); err == nil {
+		t.Error("expected error for a single model")
+	}
+}
+
+// jsonBackend returns its replies in order, validating each against the
+// requested format like a real backend.
+type jsonBackend struct {
+	countingBackend
+	replies  []string
+	received [][]backend.Message
+}
+
+func (b *jsonBackend) Invoke(_ context.Context, messages []backend.Message, opts backend.InvokeOptions) (*backend.InvokeResult, error) {
+	reply := b.replies[len(b.received)]
+	b.received = append(b.received, messages)
+	if err := backend.ValidateStructuredOutput(reply, opts.ResponseFormat); err != nil {
+		return nil, err
+	}
+	return &backend.InvokeResult{Content: reply}, nil
+}
+
+func TestInvokeAskRepromptsOnceOnInvalidJSON(t *testing.T) {
+	opts := backend.InvokeOptions{ResponseFormat: &backend.ResponseFormat{Type: backend.ResponseFormatJSONObject}}
+	messages := []backend.Message{{Role: "user", Content: "bug or feature?"}}
+
+	b := &jsonBackend{replies: []string{"It's a bug.", `{"kind":"bug"}`}}
+	var result *backend.InvokeResult
+	var err error
+	captureStdout(t, func() {
+		result, err = invokeAsk(context.Background(), b, messages, opts)
+	})
+	if err != nil {
+		t.Fatalf("invokeAsk: %v", err)
+	}
+	if result.Content != `{"kind":"bug"}` {
+		t.Errorf("content = %q, want the re-prompted JSON", result.Content)
+	}
+	if len(b.received) != 2 || len(b.received[1]) != 2 || !strings.Contains(b.received[1][1].Content, "Reply again with only JSON") {
+		t.Errorf("re-prompt messages = %+v", b.received)
+	}
+	if len(messages) != 1 {
+		t.Errorf("caller's messages modified: %+v", messages)
+	}
+
+	// A second bad reply fails rather than looping
+	b = &jsonBackend{replies: []string{"bug", "still not JSON"}}
+	captureStdout(t, func() {
+		_, err = invokeAsk(context.Background(), b, messages, opts)
+	})
+	if !errors.Is(err, backend.ErrInvalidStructuredOutput) || len(b.received) != 2 {
+		t.Errorf("err = %v after %d calls, want ErrInvalidStructuredOutput after 2", err, len(b.received))
+	}
+}
+
+func TestAskEstimateDoesNotInvoke(t *testing.T) {
+	backend.ResetRegistryForTesting()
+	t.Cleanup(backend.ResetRegistryForTesting)
+	fake := &countingBackend{name: "bedrock"}
+	backend.GetRegistry().Register(fake)
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	savedBackend, savedTier, savedStream, savedEstimate, savedCompare := askBackend, askTier, askStream, askEstimate, askCompare
+	t.Cleanup(func() {
+		askBackend, askTier, askStream, askEstimate, askCompare = savedBackend, savedTier, savedStream, savedEstimate, savedCompare
+	})
+	askBackend, askTier, askEstimate, askCompare = "bedrock", "opus", true, ""
+
+	for _, stream := range []bool{true, false} {
+		askStream = stream
+		var runErr error
+		output := captureStdout(t, func() {
+			runErr = runAsk(&cobra.Command{}, []string{"a long prompt"})
+		})
+		if runErr != nil {
+			t.Fatalf("runAsk --estimate (stream=%v): %v", stream, runErr)
+		}
+		for _, want := range []string{"opus (bedrock), not sent", "Input:  1000 tokens", "Output: ~250 tokens (25% of input)", "Cost:   ~$0.0123"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("stream=%v: expected %q in output, got:\n%s", stream, want, output)
+			}
+		}
+	}
+	if n := fake.invokes.Load(); n != 0 {
+		t.Errorf("expected no API calls with --estimate, got %d", n)
+	}
+}
+
+func TestAskJSONUsage(t *testing.T) {
+	backend.ResetRegistryForTesting()
+	t.Cleanup(backend.ResetRegistryForTesting)
+	backend.ResetCostTrackerForTesting()
+	t.Cleanup(backend.ResetCostTrackerForTesting)
+	backend.GetRegistry().Register(&countingBackend{name: "bedrock"})
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	savedBackend, savedTier, savedStream, savedUsage := askBackend, askTier, askStream, askJSONUsage
+	t.Cleanup(func() {
+		askBackend, askTier, askStream, askJSONUsage = savedBackend, savedTier, savedStream, savedUsage
+	})
+	askBackend, askTier, askJSONUsage = "bedrock", "haiku", true
+
+	for _, stream := range []bool{true, false} {
+		askStream = stream
+
+		oldStderr := os.Stderr
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("create pipe: %v", err)
+		}
+		os.Stderr = w
+		var runErr error
+		stdout := captureStdout(t, func() {
+			runErr = runAsk(&cobra.Command{}, []string{"what is a mutex?"})
+		})
+		_ = w.Close()
+		os.Stderr = oldStderr
+		stderr, _ := io.ReadAll(r)
+		_ = r.Close()
+
+		if runErr != nil {
+			t.Fatalf("runAsk (stream=%v): %v", stream, runErr)
+		}
+		if !strings.Contains(stdout, "done") || strings.Contains(stdout, "cost_usd") {
+			t.Errorf("stream=%v: stdout = %q, want the answer without the usage JSON", stream, stdout)
+		}
+
+		var usage askUsage
+		if err := json.Unmarshal(stderr, &usage); err != nil {
+			t.Fatalf("stream=%v: stderr %q is not a usage object: %v", stream, stderr, err)
+		}
+		if usage.Backend != "bedrock" || usage.Model == "" || usage.InputTokens == 0 || usage.OutputTokens == 0 || usage.CostUSD != 0.0123 {
+			t.Errorf("stream=%v: usage = %+v", stream, usage)
+		}
+	}
+}
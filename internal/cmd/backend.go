@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/backend"
+	"github.com/steveyegge/gastown/internal/config"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+// noBackendsMessage explains an empty backend registry.
+const noBackendsMessage = "No API backends available (enable them in settings/backend.json and set their API keys)"
+
+var (
+	backendBudgetJSON    bool
+	backendHealthJSON    bool
+	backendListJSON      bool
+	backendHealthTimeout time.Duration
+)
+
+var backendCmd = &cobra.Command{
+	Use:     "backend",
+	GroupID: GroupConfig,
+	Short:   "Inspect API backend routing and spend",
+	Long: `Inspect the API backends gt routes simple tasks to.
+
+API backends are configured in settings/backend.json.
+
+Subcommands:
+  gt backend budget     # Show API spend against the session budget
+  gt backend health     # Check each enabled backend against its API
+  gt backend list       # Show registered backends and their models`,
+	RunE: requireSubcommand,
+}
+
+var backendBudgetCmd = &cobra.Command{
+	Use:   "budget",
+	Short: "Show API spend against the session budget",
+	Long: `Show how much API spend the town has recorded and how much of the
+session budget remains.
+
+Set session_budget (USD) in settings/backend.json to cap API spend. Once a
+task's estimated cost would exceed what's left, gt refuses to invoke the API
+and falls back to a CLI agent (or fails when fallback_to_cli is off).
+
+The budget counts all spend recorded since mayor/costs.json was last
+cleared; it does not reset per process or per day. Run 'gt cost --reset'
+to start a new budget.
+
+Examples:
+  gt backend budget
+  gt backend budget --json`,
+	RunE: runBackendBudget,
+}
+
+var backendHealthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Check each enabled backend against its API",
+	Long: `Check that each enabled API backend can actually be used.
+
+Every backend enabled in settings/backend.json whose credentials are present
+is checked in turn: hosted APIs are asked to list models, so a revoked key
+or an unreachable endpoint reports an error instead of looking healthy.
+Each check is bounded by --timeout.
+
+Exits non-zero if any backend is unhealthy.
+
+Examples:
+  gt backend health
+  gt backend health --timeout 5s --json`,
+	Args: cobra.NoArgs,
+	RunE: runBackendHealth,
+}
+
+var backendListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show registered backends and their models",
+	Long: `Show the API backends that initialized successfully, with their
+capabilities, models, and default model.
+
+A backend is listed only if it's enabled in settings/backend.json and its
+credentials are present (or, for ollama, its daemon is reachable). If a task
+didn't route to a backend you expected, check that it appears here.
+
+Examples:
+  gt backend list
+  gt backend list --json`,
+	Args: cobra.NoArgs,
+	RunE: runBackendList,
+}
+
+func init() {
+	backendBudgetCmd.Flags().BoolVar(&backendBudgetJSON, "json", false, "Output as JSON")
+	backendHealthCmd.Flags().BoolVar(&backendHealthJSON, "json", false, "Output as JSON")
+	backendListCmd.Flags().BoolVar(&backendListJSON, "json", false, "Output as JSON")
+	backendHealthCmd.Flags().DurationVar(&backendHealthTimeout, "timeout", 10*time.Second, "Time limit for each backend's check")
+
+	backendCmd.AddCommand(backendBudgetCmd)
+	backendCmd.AddCommand(backendHealthCmd)
+	backendCmd.AddCommand(backendListCmd)
+	rootCmd.AddCommand(backendCmd)
+}
+
+// backendBudgetStatus is the JSON output of 'gt backend budget'.
+type backendBudgetStatus struct {
+	Spent     float64 `json:"spent"`
+	Today     float64 `json:"today"`
+	ThisMonth float64 `json:"this_month"`
+	Budget    float64 `json:"budget"`              // 0 when no budget is set
+	Remaining float64 `json:"remaining,omitempty"` // omitted when no budget is set
+}
+
+func runBackendBudget(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return err
+	}
+
+	cfg := config.ResolveBackendConfig(townRoot, "")
+	backend.SetCostTrackerPath(backend.CostTrackerPath(townRoot))
+	status := budgetStatus(cfg, backend.GetCostTracker())
+
+	if backendBudgetJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
+	}
+	printBudgetStatus(os.Stdout, status)
+	return nil
+}
+
+// budgetStatus summarizes a cost tracker against cfg's session budget.
+func budgetStatus(cfg *config.BackendConfig, ct *backend.CostTracker) backendBudgetStatus {
+	status := backendBudgetStatus{
+		Spent:     ct.Total(),
+		Today:     ct.SpentToday(),
+		ThisMonth: ct.SpentThisMonth(),
+		Budget:    cfg.SessionBudget,
+	}
+	if status.Budget > 0 {
+		status.Remaining = sessionBudgetRemaining(status.Budget, status.Spent)
+	}
+	return status
+}
+
+// printBudgetStatus writes the human-readable budget report.
+func printBudgetStatus(w io.Writer, status backendBudgetStatus) {
+	fmt.Fprintf(w, "%s $%.4f (today $%.4f, this month $%.4f)\n",
+		style.Bold.Render("Spent:"), status.Spent, status.Today, status.ThisMonth)
+	if status.Budget <= 0 {
+		fmt.Fprintf(w, "%s none %s\n", style.Bold.Render("Budget:"),
+			style.Dim.Render("(set session_budget in settings/backend.json)"))
+		return
+	}
+	fmt.Fprintf(w, "%s $%.2f %s\n", style.Bold.Render("Budget:"), status.Budget,
+		style.Dim.Render("(for all spend since the last 'gt cost --reset')"))
+	fmt.Fprintf(w, "%s $%.4f\n", style.Bold.Render("Remaining:"), status.Remaining)
+}
+
+// backendHealth is one backend's entry in 'gt backend health' output.
+type backendHealth struct {
+	Backend   string `json:"backend"`
+	OK        bool   `json:"ok"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+func runBackendHealth(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return err
+	}
+
+	dispatcher := NewBackendDispatcher(config.ResolveBackendConfig(townRoot, ""))
+	if err := dispatcher.Initialize(); err != nil {
+		return fmt.Errorf("initializing backends: %w", err)
+	}
+	results := checkBackendHealth(backend.GetRegistry(), backendHealthTimeout)
+
+	if backendHealthJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		printBackendHealth(os.Stdout, results)
+	}
+
+	unhealthy := 0
+	for _, r := range results {
+		if !r.OK {
+			unhealthy++
+		}
+	}
+	if unhealthy > 0 {
+		return fmt.Errorf("%d backend(s) unhealthy", unhealthy)
+	}
+	return nil
+}
+
+// checkBackendHealth runs each registered backend's health check, in name
+// order, giving each up to timeout.
+func checkBackendHealth(registry *backend.Registry, timeout time.Duration) []backendHealth {
+	names := registry.List()
+	sort.Strings(names)
+
+	results := make([]backendHealth, 0, len(names))
+	for _, name := range names {
+		b, err := registry.Get(name)
+		if err != nil {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		start := time.Now()
+		err = b.Healthy(ctx)
+		latency := time.Since(start)
+		cancel()
+
+		result := backendHealth{Backend: name, OK: err == nil, LatencyMS: latency.Milliseconds()}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// printBackendHealth writes one line per backend check.
+func printBackendHealth(w io.Writer, results []backendHealth) {
+	if len(results) == 0 {
+		fmt.Fprintln(w, style.Dim.Render(noBackendsMessage))
+		return
+	}
+	for _, r := range results {
+		if r.OK {
+			fmt.Fprintf(w, "%s %-10s OK %s\n", style.Success.Render("✓"), r.Backend, style.Dim.Render(fmt.Sprintf("(%dms)", r.LatencyMS)))
+			continue
+		}
+		fmt.Fprintf(w, "%s %-10s %s %s\n", style.Error.Render("✗"), r.Backend, r.Error, style.Dim.Render(fmt.Sprintf("(%dms)", r.LatencyMS)))
+	}
+}
+
+// backendInfo is one backend's entry in 'gt backend list' output.
+type backendInfo struct {
+	Name         string   `json:"name"`
+	Capabilities []string `json:"capabilities"`
+	Models       []string `json:"models"`
+	DefaultModel string   `json:"default_model"`
+}
+
+func runBackendList(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return err
+	}
+
+	dispatcher := NewBackendDispatcher(config.ResolveBackendConfig(townRoot, ""))
+	if err := dispatcher.Initialize(); err != nil {
+		return fmt.Errorf("initializing backends: %w", err)
+	}
+	infos := listBackends(backend.GetRegistry())
+
+	if backendListJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	}
+	printBackendList(os.Stdout, infos)
+	return nil
+}
+
+// listBackends describes each registered backend, in name order.
+func listBackends(registry *backend.Registry) []backendInfo {
+	names := registry.List()
+	sort.Strings(names)
+
+	infos := make([]backendInfo, 0, len(names))
+	for _, name := range names {
+		b, err := registry.Get(name)
+		if err != nil {
+			continue
+		}
+		caps := b.Capabilities().Names()
+		if caps == nil {
+			caps = []string{}
+		}
+		// Backends may build their model list from a map, so sort a copy
+		// to keep the output stable between runs
+		models := append([]string(nil), b.AvailableModels()...)
+		sort.Strings(models)
+		infos = append(infos, backendInfo{
+			Name:         name,
+			Capabilities: caps,
+			Models:       models,
+			DefaultModel: b.DefaultModel(),
+		})
+	}
+	return infos
+}
+
+// printBackendList writes each backend with its capabilities and models.
+func printBackendList(w io.Writer, infos []backendInfo) {
+	if len(infos) == 0 {
+		fmt.Fprintln(w, style.Dim.Render(noBackendsMessage))
+		return
+	}
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s\n", style.Bold.Render(info.Name))
+		caps := "none"
+		if len(info.Capabilities) > 0 {
+			caps = strings.Join(info.Capabilities, ", ")
+		}
+		fmt.Fprintf(w, "  Capabilities: %s\n", caps)
+		fmt.Fprintf(w, "  Default:      %s\n", info.DefaultModel)
+		fmt.Fprintf(w, "  Models:       %s\n", strings.Join(info.Models, ", "))
+	}
+}
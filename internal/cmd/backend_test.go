@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/backend"
+)
+
+// unhealthyBackend is a fake AgentBackend whose health check fails only
+// when it's allowed to make a live call.
+type unhealthyBackend struct {
+	countingBackend
+}
+
+func (b *unhealthyBackend) Healthy(ctx context.Context) error {
+	if !backend.LiveCheckAllowed(ctx) {
+		return nil
+	}
+	return errors.New("API key rejected (status 401)")
+}
+
+func TestCheckBackendHealth(t *testing.T) {
+	backend.ResetRegistryForTesting()
+	t.Cleanup(backend.ResetRegistryForTesting)
+	backend.GetRegistry().Register(&unhealthyBackend{countingBackend{name: "grok"}})
+	backend.GetRegistry().Register(&countingBackend{name: "claude"})
+
+	results := checkBackendHealth(backend.GetRegistry(), time.Second)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2: %+v", len(results), results)
+	}
+	if results[0].Backend != "claude" || !results[0].OK || results[0].Error != "" {
+		t.Errorf("claude result = %+v, want OK", results[0])
+	}
+	if results[1].Backend != "grok" || results[1].OK || results[1].Error != "API key rejected (status 401)" {
+		t.Errorf("grok result = %+v, want the live check's error", results[1])
+	}
+}
+
+// capableBackend is a fake AgentBackend advertising streaming and tools.
+type capableBackend struct {
+	countingBackend
+}
+
+func (b *capableBackend) Capabilities() backend.Capability {
+	return backend.CapStreaming | backend.CapTools
+}
+
+func TestListBackends(t *testing.T) {
+	backend.ResetRegistryForTesting()
+	t.Cleanup(backend.ResetRegistryForTesting)
+	backend.GetRegistry().Register(&capableBackend{countingBackend{name: "grok"}})
+	backend.GetRegistry().Register(&countingBackend{name: "claude"})
+
+	infos := listBackends(backend.GetRegistry())
+	if len(infos) != 2 || infos[0].Name != "claude" || infos[1].Name != "grok" {
+		t.Fatalf("infos = %+v, want claude then grok", infos)
+	}
+	if len(infos[0].Capabilities) != 0 {
+		t.Errorf("claude capabilities = %v, want none", infos[0].Capabilities)
+	}
+	if got := strings.Join(infos[1].Capabilities, ","); got != "streaming,tools" {
+		t.Errorf("grok capabilities = %q, want streaming,tools", got)
+	}
+	if infos[1].DefaultModel != "haiku" || len(infos[1].Models) != 1 {
+		t.Errorf("grok models = %v default %q", infos[1].Models, infos[1].DefaultModel)
+	}
+
+	var buf bytes.Buffer
+	printBackendList(&buf, infos)
+	for _, want := range []string{"Capabilities: none", "Capabilities: streaming, tools", "Default:      haiku"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestListBackendsSortsModels(t *testing.T) {
+	backend.ResetRegistryForTesting()
+	t.Cleanup(backend.ResetRegistryForTesting)
+	models := []string{"qwen2.5", "llama3.2", "mistral"}
+	backend.GetRegistry().Register(&modelsBackend{countingBackend: countingBackend{name: "ollama"}, models: models})
+
+	infos := listBackends(backend.GetRegistry())
+	if len(infos) != 1 {
+		t.Fatalf("infos = %+v, want one backend", infos)
+	}
+	if got := strings.Join(infos[0].Models, ","); got != "llama3.2,mistral,qwen2.5" {
+		t.Errorf("models = %q, want sorted", got)
+	}
+	if models[0] != "qwen2.5" {
+		t.Errorf("listBackends reordered the backend's own model list: %v", models)
+	}
+}
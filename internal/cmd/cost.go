@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/backend"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var (
+	costJSON        bool
+	costReset       bool
+	costRig         string
+	costBy          string
+	costLabelPrefix string
+)
+
+var costCmd = &cobra.Command{
+	Use:     "cost",
+	GroupID: GroupDiag,
+	Short:   "Show API backend spend",
+	Long: `Show what API backend routing (gt sling, gt ask) has spent in this town.
+
+Spend is read from mayor/costs.json. The total covers every recorded
+invocation; per-backend lines cover today's invocations, since earlier days
+are kept only as daily totals. For Claude Code session costs, see 'gt costs'.
+
+To see which beads, models, or rigs spent the most today, use 'gt cost top'.
+
+With --rig, only that rig's spend is shown, read from <rig>/.runtime/costs.json.
+The town total still includes it.
+
+With --by label, today's invocations are grouped by the labels of the beads
+they ran for. An invocation counts toward each of its labels, so the label
+totals can add up to more than the overall spend.
+
+Examples:
+  gt cost                    # Per-backend summary and totals
+  gt cost --rig gastown      # Spend for one rig
+  gt cost --by label --label-prefix area/   # Costliest code areas today
+  gt cost --json             # Output as JSON
+  gt cost --reset            # Clear the recorded spend
+  gt cost --rig gastown --reset  # Clear one rig's recorded spend`,
+	Args: cobra.NoArgs,
+	RunE: runCost,
+}
+
+func init() {
+	costCmd.Flags().BoolVar(&costJSON, "json", false, "Output as JSON")
+	costCmd.Flags().BoolVar(&costReset, "reset", false, "Clear the recorded API spend")
+	costCmd.Flags().StringVar(&costRig, "rig", "", "Show spend recorded for this rig only")
+	costCmd.Flags().StringVar(&costBy, "by", "", "Also group today's spend by: label")
+	costCmd.Flags().StringVar(&costLabelPrefix, "label-prefix", "", "With --by label, only count labels with this prefix (e.g. area/)")
+
+	rootCmd.AddCommand(costCmd)
+}
+
+// costReport is the JSON output of 'gt cost'.
+type costReport struct {
+	Total     float64                               `json:"total"`
+	Today     float64                               `json:"today"`
+	ThisMonth float64                               `json:"this_month"`
+	Backends  map[string]backend.BackendCostSummary `json:"backends"`
+	Labels    map[string]backend.BackendCostSummary `json:"labels,omitempty"`
+}
+
+func runCost(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwd()
+	if err != nil {
+		return fmt.Errorf("finding town: %w", err)
+	}
+	if townRoot == "" {
+		return fmt.Errorf("not in a Gas Town workspace (API spend is recorded per town)")
+	}
+	if costBy != "" && costBy != "label" {
+		return fmt.Errorf("invalid --by %q: must be label (for beads, models, or rigs, see 'gt cost top')", costBy)
+	}
+
+	path := backend.CostTrackerPath(townRoot)
+	if costRig != "" {
+		rigPath := filepath.Join(townRoot, costRig)
+		if info, err := os.Stat(rigPath); err != nil || !info.IsDir() {
+			return fmt.Errorf("rig '%s' not found", costRig)
+		}
+		path = backend.RigCostTrackerPath(rigPath)
+	}
+
+	ct := backend.NewCostTracker()
+	if err := ct.Load(path); err != nil {
+		return err
+	}
+
+	if costReset {
+		ct.Reset()
+		if err := ct.Save(path); err != nil {
+			return err
+		}
+		if costRig != "" {
+			fmt.Printf("%s Cleared recorded API spend for rig %s\n", style.Success.Render("✓"), costRig)
+			return nil
+		}
+		fmt.Printf("%s Cleared recorded API spend\n", style.Success.Render("✓"))
+		return nil
+	}
+
+	if costJSON {
+		report := buildCostReport(ct)
+		if costBy == "label" {
+			report.Labels = ct.SummaryByLabel(costLabelPrefix)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printCostReport(os.Stdout, ct)
+	if costBy == "label" {
+		printLabelCosts(os.Stdout, ct.SummaryByLabel(costLabelPrefix))
+	}
+	return nil
+}
+
+// buildCostReport summarizes a cost tracker for JSON output.
+func buildCostReport(ct *backend.CostTracker) costReport {
+	return costReport{
+		Total:     ct.Total(),
+		Today:     ct.SpentToday(),
+		ThisMonth: ct.SpentThisMonth(),
+		Backends:  ct.Summary(),
+	}
+}
+
+// printCostReport writes the per-backend summary followed by the totals.
+func printCostReport(w io.Writer, ct *backend.CostTracker) {
+	fmt.Fprint(w, ct.FormatSummary())
+	if ct.Total() == 0 {
+		fmt.Fprintln(w)
+		return
+	}
+	fmt.Fprintf(w, "\n%s $%.4f today, $%.4f this month\n",
+		style.Bold.Render("Spent:"), ct.SpentToday(), ct.SpentThisMonth())
+}
+
+// printLabelCosts writes the per-label summary, costliest label first.
+func printLabelCosts(w io.Writer, summary map[string]backend.BackendCostSummary) {
+	if len(summary) == 0 {
+		return
+	}
+	labels := make([]string, 0, len(summary))
+	for label := range summary {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if summary[labels[i]].TotalCost != summary[labels[j]].TotalCost {
+			return summary[labels[i]].TotalCost > summary[labels[j]].TotalCost
+		}
+		return labels[i] < labels[j]
+	})
+
+	fmt.Fprintf(w, "\n%s\n", style.Bold.Render("By label (today):"))
+	for _, label := range labels {
+		s := summary[label]
+		fmt.Fprintf(w, "  %s: %d invocations, $%.4f\n", label, s.Invocations, s.TotalCost)
+	}
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/backend"
+)
+
+func TestRunCostReportsAndResets(t *testing.T) {
+	townRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(townRoot, "mayor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := backend.CostTrackerPath(townRoot)
+
+	ct := backend.NewCostTracker()
+	ct.Record("grok", "grok-3-mini", &backend.InvokeResult{InputTokens: 100, OutputTokens: 20}, backend.CostEstimate{TotalCost: 0.02})
+	ct.Record("claude", "haiku", &backend.InvokeResult{InputTokens: 200, OutputTokens: 40}, backend.CostEstimate{TotalCost: 0.03})
+	if err := ct.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(townRoot); err != nil {
+		t.Fatal(err)
+	}
+	savedJSON, savedReset, savedRig := costJSON, costReset, costRig
+	t.Cleanup(func() { costJSON, costReset, costRig = savedJSON, savedReset, savedRig })
+	costRig = ""
+
+	costJSON, costReset = false, false
+	output := captureStdout(t, func() {
+		if err := runCost(costCmd, nil); err != nil {
+			t.Errorf("runCost: %v", err)
+		}
+	})
+	if !strings.Contains(output, "Total: $0.0500") {
+		t.Errorf("expected total in output, got:\n%s", output)
+	}
+	if strings.Index(output, "claude:") > strings.Index(output, "grok:") {
+		t.Errorf("expected backends in sorted order, got:\n%s", output)
+	}
+
+	costJSON = true
+	output = captureStdout(t, func() {
+		if err := runCost(costCmd, nil); err != nil {
+			t.Errorf("runCost --json: %v", err)
+		}
+	})
+	var report costReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("parsing --json output: %v\n%s", err, output)
+	}
+	if report.Total != 0.05 || report.Backends["grok"].Invocations != 1 || report.Backends["claude"].InputTokens != 200 {
+		t.Errorf("report = %+v", report)
+	}
+
+	costJSON, costReset = false, true
+	captureStdout(t, func() {
+		if err := runCost(costCmd, nil); err != nil {
+			t.Errorf("runCost --reset: %v", err)
+		}
+	})
+	reloaded := backend.NewCostTracker()
+	if err := reloaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Total() != 0 || len(reloaded.Entries()) != 0 {
+		t.Errorf("after --reset total=%v entries=%d, want cleared", reloaded.Total(), len(reloaded.Entries()))
+	}
+}
+
+// Run with -race: dispatchers for different rigs record concurrently, and
+// 'gt cost --rig' must report each rig's own spend.
+func TestRunCostRigReportsConcurrentSpend(t *testing.T) {
+	backend.ResetRigCostTrackersForTesting()
+	t.Cleanup(backend.ResetRigCostTrackersForTesting)
+
+	townRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(townRoot, "mayor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rigCosts := map[string]float64{"alpha": 0.01, "beta": 0.02, "gamma": 0.05}
+	const perRig = 40
+
+	var wg sync.WaitGroup
+	for name, cost := range rigCosts {
+		rigPath := filepath.Join(townRoot, name)
+		if err := os.MkdirAll(rigPath, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < perRig; i++ {
+			wg.Add(1)
+			go func(rigPath string, cost float64) {
+				defer wg.Done()
+				backend.GetRigCostTracker(rigPath).Record("grok", "grok-3-mini", &backend.InvokeResult{}, backend.CostEstimate{TotalCost: cost})
+			}(rigPath, cost)
+		}
+	}
+	wg.Wait()
+
+	// Reports must come from disk, not the in-memory trackers
+	backend.ResetRigCostTrackersForTesting()
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(townRoot); err != nil {
+		t.Fatal(err)
+	}
+	savedJSON, savedReset, savedRig := costJSON, costReset, costRig
+	t.Cleanup(func() { costJSON, costReset, costRig = savedJSON, savedReset, savedRig })
+
+	costJSON, costReset = true, false
+	for name, cost := range rigCosts {
+		costRig = name
+		output := captureStdout(t, func() {
+			if err := runCost(costCmd, nil); err != nil {
+				t.Errorf("runCost --rig %s: %v", name, err)
+			}
+		})
+		var report costReport
+		if err := json.Unmarshal([]byte(output), &report); err != nil {
+			t.Fatalf("parsing --rig %s output: %v\n%s", name, err, output)
+		}
+		if want := cost * perRig; math.Abs(report.Total-want) > 1e-9 {
+			t.Errorf("rig %s total = %.4f, want %.4f", name, report.Total, want)
+		}
+		if got := report.Backends["grok"].Invocations; got != perRig {
+			t.Errorf("rig %s invocations = %d, want %d", name, got, perRig)
+		}
+	}
+
+	costRig = "missing"
+	if err := runCost(costCmd, nil); err == nil || !strings.Contains(err.Error(), fmt.Sprintf("rig '%s' not found", costRig)) {
+		t.Errorf("runCost --rig missing = %v, want rig not found", err)
+	}
+}
+
+func TestRunCostByLabel(t *testing.T) {
+	townRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(townRoot, "mayor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ct := backend.NewCostTracker()
+	ct.RecordForBead("grok", "grok-3", "", []string{"area/cli", "bug"}, &backend.InvokeResult{}, backend.CostEstimate{TotalCost: 0.01})
+	ct.RecordForBead("grok", "grok-3", "", []string{"area/backend"}, &backend.InvokeResult{}, backend.CostEstimate{TotalCost: 0.04})
+	ct.RecordForBead("openai", "gpt-4o", "", []string{"area/backend", "area/cli"}, &backend.InvokeResult{}, backend.CostEstimate{TotalCost: 0.02})
+	ct.Record("openai", "gpt-4o", &backend.InvokeResult{}, backend.CostEstimate{TotalCost: 0.005})
+	if err := ct.Save(backend.CostTrackerPath(townRoot)); err != nil {
+		t.Fatal(err)
+	}
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(townRoot); err != nil {
+		t.Fatal(err)
+	}
+	savedJSON, savedReset, savedRig := costJSON, costReset, costRig
+	savedBy, savedPrefix := costBy, costLabelPrefix
+	t.Cleanup(func() {
+		costJSON, costReset, costRig = savedJSON, savedReset, savedRig
+		costBy, costLabelPrefix = savedBy, savedPrefix
+	})
+
+	costJSON, costReset, costRig = false, false, ""
+	costBy, costLabelPrefix = "label", "area/"
+	output := captureStdout(t, func() {
+		if err := runCost(costCmd, nil); err != nil {
+			t.Errorf("runCost --by label: %v", err)
+		}
+	})
+	for _, want := range []string{"area/backend: 2 invocations, $0.0600", "area/cli: 2 invocations, $0.0300", "(none): 1 invocations, $0.0050"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, output)
+		}
+	}
+	if strings.Index(output, "area/backend:") > strings.Index(output, "area/cli:") {
+		t.Errorf("expected costliest label first, got:\n%s", output)
+	}
+	if strings.Contains(output, "bug:") {
+		t.Errorf("labels outside --label-prefix should not be listed, got:\n%s", output)
+	}
+
+	costJSON = true
+	output = captureStdout(t, func() {
+		if err := runCost(costCmd, nil); err != nil {
+			t.Errorf("runCost --by label --json: %v", err)
+		}
+	})
+	var report costReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("parsing --json output: %v\n%s", err, output)
+	}
+	if len(report.Labels) != 3 || report.Labels["area/backend"].Invocations != 2 {
+		t.Errorf("report.Labels = %+v", report.Labels)
+	}
+
+	costBy = "role"
+	if err := runCost(costCmd, nil); err == nil || !strings.Contains(err.Error(), "invalid --by") {
+		t.Errorf("runCost --by role = %v, want invalid --by", err)
+	}
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/backend"
+	"github.com/steveyegge/gastown/internal/config"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var (
+	costTopBy    string
+	costTopLimit int
+)
+
+var costTopCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Show the beads, models, or rigs with the most API spend",
+	Long: `Rank today's API backend spend by bead, model, or rig.
+
+Bead and model rankings read the town's spend from mayor/costs.json. Rig
+rankings read each rig's <rig>/.runtime/costs.json; spend recorded outside
+any rig (e.g. gt ask from the town root) is shown as "(town)".
+
+Only today's invocations are ranked, since earlier days are kept only as
+daily totals (see 'gt cost').
+
+Examples:
+  gt cost top                  # Top 10 beads by spend
+  gt cost top --by model       # Which models cost the most
+  gt cost top --by rig -n 5    # Top 5 rigs`,
+	Args: cobra.NoArgs,
+	RunE: runCostTop,
+}
+
+func init() {
+	costTopCmd.Flags().StringVar(&costTopBy, "by", "bead", "Group by: bead, model, or rig")
+	costTopCmd.Flags().IntVarP(&costTopLimit, "limit", "n", 10, "Number of entries to show")
+
+	costCmd.AddCommand(costTopCmd)
+}
+
+// costRank is one row of gt cost top.
+type costRank struct {
+	Key         string
+	Total       float64
+	Invocations int
+}
+
+func runCostTop(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwd()
+	if err != nil {
+		return fmt.Errorf("finding town: %w", err)
+	}
+	if townRoot == "" {
+		return fmt.Errorf("not in a Gas Town workspace (API spend is recorded per town)")
+	}
+
+	ranks, err := loadCostRanks(townRoot, costTopBy)
+	if err != nil {
+		return err
+	}
+	printCostRanks(os.Stdout, topCostRanks(ranks, costTopLimit), costTopBy)
+	return nil
+}
+
+// loadCostRanks totals today's recorded API spend in a town by the given
+// dimension.
+func loadCostRanks(townRoot, by string) ([]costRank, error) {
+	switch by {
+	case "bead", "model":
+		ct := backend.NewCostTracker()
+		if err := ct.Load(backend.CostTrackerPath(townRoot)); err != nil {
+			return nil, err
+		}
+		return rankCostEntries(ct.Entries(), by), nil
+	case "rig":
+		return rankRigCosts(townRoot)
+	}
+	return nil, fmt.Errorf("invalid --by %q: must be bead, model, or rig", by)
+}
+
+// rankCostEntries totals entries by bead or model. Entries without a bead
+// (e.g. from gt ask) are grouped as "(none)".
+func rankCostEntries(entries []backend.CostEntry, by string) []costRank {
+	groups := make(map[string]*costRank)
+	for _, e := range entries {
+		key := e.Model
+		if by == "bead" {
+			key = e.Bead
+		}
+		if key == "" {
+			key = "(none)"
+		}
+		addCostRank(groups, key, e.Cost.TotalCost, 1)
+	}
+	return costRankList(groups)
+}
+
+// rankRigCosts totals today's spend from each registered rig's cost
+// tracker. The town tracker records every rig's spend too, so what it
+// holds beyond the rigs' totals was recorded outside any rig.
+func rankRigCosts(townRoot string) ([]costRank, error) {
+	town := backend.NewCostTracker()
+	if err := town.Load(backend.CostTrackerPath(townRoot)); err != nil {
+		return nil, err
+	}
+	townSpent, townInvocations := todaysSpend(town)
+
+	rigsConfig, err := config.LoadRigsConfig(filepath.Join(townRoot, "mayor", "rigs.json"))
+	if err != nil {
+		rigsConfig = &config.RigsConfig{Rigs: make(map[string]config.RigEntry)}
+	}
+
+	groups := make(map[string]*costRank)
+	for name := range rigsConfig.Rigs {
+		ct := backend.NewCostTracker()
+		if err := ct.Load(backend.RigCostTrackerPath(filepath.Join(townRoot, name))); err != nil {
+			return nil, fmt.Errorf("rig %s: %w", name, err)
+		}
+		spent, invocations := todaysSpend(ct)
+		if invocations == 0 {
+			continue
+		}
+		addCostRank(groups, name, spent, invocations)
+		townSpent -= spent
+		townInvocations -= invocations
+	}
+	if townInvocations > 0 && townSpent > 0 {
+		addCostRank(groups, "(town)", townSpent, townInvocations)
+	}
+	return costRankList(groups), nil
+}
+
+// todaysSpend returns the cost and number of a tracker's invocations
+// today (its entries are today's, after the load-time rollover).
+func todaysSpend(ct *backend.CostTracker) (float64, int) {
+	entries := ct.Entries()
+	var spent float64
+	for _, e := range entries {
+		spent += e.Cost.TotalCost
+	}
+	return spent, len(entries)
+}
+
+// addCostRank adds spend to the group for key, creating it if needed.
+func addCostRank(groups map[string]*costRank, key string, cost float64, invocations int) {
+	r, ok := groups[key]
+	if !ok {
+		r = &costRank{Key: key}
+		groups[key] = r
+	}
+	r.Total += cost
+	r.Invocations += invocations
+}
+
+// costRankList returns the groups most expensive first, ties by key.
+func costRankList(groups map[string]*costRank) []costRank {
+	ranks := make([]costRank, 0, len(groups))
+	for _, r := range groups {
+		ranks = append(ranks, *r)
+	}
+	sort.Slice(ranks, func(i, j int) bool {
+		if ranks[i].Total != ranks[j].Total {
+			return ranks[i].Total > ranks[j].Total
+		}
+		return ranks[i].Key < ranks[j].Key
+	})
+	return ranks
+}
+
+// topCostRanks returns the first limit ranks (all of them if limit <= 0).
+func topCostRanks(ranks []costRank, limit int) []costRank {
+	if limit > 0 && len(ranks) > limit {
+		return ranks[:limit]
+	}
+	return ranks
+}
+
+// printCostRanks writes the ranking table.
+func printCostRanks(w io.Writer, ranks []costRank, by string) {
+	if len(ranks) == 0 {
+		fmt.Fprintln(w, style.Dim.Render("No API spend recorded today"))
+		return
+	}
+
+	fmt.Fprintf(w, "\n%s Top API spend today by %s\n\n", style.Bold.Render("💰"), by)
+	fmt.Fprintf(w, "%4s  %-40s %10s %11s\n", "#", strings.ToUpper(by[:1])+by[1:], "Cost", "Invocations")
+	fmt.Fprintln(w, strings.Repeat("─", 69))
+	for i, r := range ranks {
+		fmt.Fprintf(w, "%4d  %-40s %10s %11d\n", i+1, truncate(r.Key, 40), fmt.Sprintf("$%.4f", r.Total), r.Invocations)
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/backend"
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+// seedCostTop records API spend the way the dispatcher does: every
+// invocation in the town tracker, rig invocations in the rig's too.
+func seedCostTop(t *testing.T) string {
+	t.Helper()
+	townRoot := t.TempDir()
+	rigs := &config.RigsConfig{Version: 1, Rigs: map[string]config.RigEntry{"gastown": {}, "beads": {}, "idle": {}}}
+	if err := config.SaveRigsConfig(filepath.Join(townRoot, "mayor", "rigs.json"), rigs); err != nil {
+		t.Fatal(err)
+	}
+
+	town := backend.NewCostTracker()
+	rigTrackers := map[string]*backend.CostTracker{"gastown": backend.NewCostTracker(), "beads": backend.NewCostTracker()}
+	for _, e := range []struct {
+		rig, bead, model string
+		cost             float64
+	}{
+		{"gastown", "gt-aaa", "grok-3", 0.15},
+		{"gastown", "gt-bbb", "gpt-4o", 0.40},
+		{"beads", "gt-aaa", "grok-3", 0.30},
+		{"beads", "gt-ccc", "claude-haiku-3-5-20241022", 0.025},
+		{"", "", "grok-3", 0.05}, // gt ask from the town root
+	} {
+		result := &backend.InvokeResult{}
+		cost := backend.CostEstimate{TotalCost: e.cost}
+		town.RecordForBead("test", e.model, e.bead, nil, result, cost)
+		if ct := rigTrackers[e.rig]; ct != nil {
+			ct.RecordForBead("test", e.model, e.bead, nil, result, cost)
+		}
+	}
+	if err := town.Save(backend.CostTrackerPath(townRoot)); err != nil {
+		t.Fatal(err)
+	}
+	for name, ct := range rigTrackers {
+		if err := ct.Save(backend.RigCostTrackerPath(filepath.Join(townRoot, name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return townRoot
+}
+
+func TestLoadCostRanks(t *testing.T) {
+	townRoot := seedCostTop(t)
+
+	tests := []struct {
+		by   string
+		want []costRank
+	}{
+		{"bead", []costRank{
+			{"gt-aaa", 0.45, 2},
+			{"gt-bbb", 0.40, 1},
+			{"(none)", 0.05, 1},
+			{"gt-ccc", 0.025, 1},
+		}},
+		{"model", []costRank{
+			{"grok-3", 0.50, 3},
+			{"gpt-4o", 0.40, 1},
+			{"claude-haiku-3-5-20241022", 0.025, 1},
+		}},
+		{"rig", []costRank{
+			{"gastown", 0.55, 2},
+			{"beads", 0.325, 2},
+			{"(town)", 0.05, 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		got, err := loadCostRanks(townRoot, tt.by)
+		if err != nil {
+			t.Fatalf("loadCostRanks(%s): %v", tt.by, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("loadCostRanks(%s) = %+v, want %+v", tt.by, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i].Key != tt.want[i].Key || got[i].Invocations != tt.want[i].Invocations || math.Abs(got[i].Total-tt.want[i].Total) > 1e-9 {
+				t.Errorf("loadCostRanks(%s)[%d] = %+v, want %+v", tt.by, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	if _, err := loadCostRanks(townRoot, "role"); err == nil {
+		t.Error("expected error for unsupported --by")
+	}
+
+	if top := topCostRanks([]costRank{{Key: "a"}, {Key: "b"}, {Key: "c"}}, 2); len(top) != 2 || top[1].Key != "b" {
+		t.Errorf("topCostRanks(2) = %+v", top)
+	}
+}
+
+func TestPrintCostRanks(t *testing.T) {
+	var buf bytes.Buffer
+	printCostRanks(&buf, nil, "bead")
+	if !strings.Contains(buf.String(), "No API spend recorded today") {
+		t.Errorf("empty ranking output = %q", buf.String())
+	}
+
+	buf.Reset()
+	printCostRanks(&buf, []costRank{{"gt-aaa", 0.45, 2}}, "bead")
+	if out := buf.String(); !strings.Contains(out, "Bead") || !strings.Contains(out, "gt-aaa") || !strings.Contains(out, "$0.4500") {
+		t.Errorf("ranking output = %q", out)
+	}
+}
@@ -28,6 +28,7 @@ var (
 	costsWeek    bool
 	costsByRole  bool
 	costsByRig   bool
+	costsRig     string
 	costsVerbose bool
 
 	// Record subcommand flags
@@ -58,6 +59,7 @@ Examples:
   gt costs --week       # This week's costs from digest beads + today's log
   gt costs --by-role    # Breakdown by role (polecat, witness, etc.)
   gt costs --by-rig     # Breakdown by rig
+  gt costs --rig gastown # Only gastown's sessions
   gt costs --json       # Output as JSON
   gt costs -v           # Show debug output for failures
 
@@ -134,6 +136,7 @@ func init() {
 	costsCmd.Flags().BoolVar(&costsWeek, "week", false, "Show this week's total from session events")
 	costsCmd.Flags().BoolVar(&costsByRole, "by-role", false, "Show breakdown by role")
 	costsCmd.Flags().BoolVar(&costsByRig, "by-rig", false, "Show breakdown by rig")
+	costsCmd.Flags().StringVar(&costsRig, "rig", "", "Only show costs from this rig")
 	costsCmd.Flags().BoolVarP(&costsVerbose, "verbose", "v", false, "Show debug output for failures")
 
 	// Add record subcommand
@@ -266,6 +269,9 @@ func runLiveCosts() error {
 
 		// Parse session name to get role/rig/worker
 		role, rig, worker := parseSessionName(session)
+		if costsRig != "" && rig != costsRig {
+			continue
+		}
 
 		// Get working directory of the session
 		workDir, err := getTmuxSessionWorkDir(session)
@@ -351,6 +357,8 @@ func runCostsFromLedger() error {
 		entries = querySessionEvents()
 	}
 
+	entries = filterCostEntriesByRig(entries, costsRig)
+
 	if len(entries) == 0 {
 		fmt.Println(style.Dim.Render("No cost data found. Costs are recorded when sessions end."))
 		return nil
@@ -395,6 +403,20 @@ func runCostsFromLedger() error {
 	return outputLedgerHuman(output, entries)
 }
 
+// filterCostEntriesByRig keeps only entries from rig (all entries if rig is empty).
+func filterCostEntriesByRig(entries []CostEntry, rig string) []CostEntry {
+	if rig == "" {
+		return entries
+	}
+	var filtered []CostEntry
+	for _, e := range entries {
+		if e.Rig == rig {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
 // SessionEvent represents a session.ended event from beads.
 type SessionEvent struct {
 	ID        string    `json:"id"`
@@ -952,6 +974,36 @@ type CostLogEntry struct {
 	WorkItem  string    `json:"work_item,omitempty"`
 }
 
+// appendCostLogEntry appends one entry to the costs log at logPath.
+func appendCostLogEntry(logPath string, entry CostLogEntry) error {
+	// Marshal to JSON
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshaling cost entry: %w", err)
+	}
+
+	// Ensure directory exists
+	logDir := filepath.Dir(logPath)
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return fmt.Errorf("creating log directory: %w", err)
+	}
+
+	// Open file for append (create if doesn't exist).
+	// O_APPEND writes are atomic on POSIX for writes < PIPE_BUF (~4KB).
+	// A JSON log entry is ~200 bytes, so concurrent appends are safe.
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening costs log: %w", err)
+	}
+	defer f.Close()
+
+	// Write entry with newline
+	if _, err := f.Write(append(entryJSON, '\n')); err != nil {
+		return fmt.Errorf("writing to costs log: %w", err)
+	}
+	return nil
+}
+
 // getCostsLogPath returns the path to the costs log file (~/.gt/costs.jsonl).
 func getCostsLogPath() string {
 	home, err := os.UserHomeDir()
@@ -1022,33 +1074,8 @@ func runCostsRecord(cmd *cobra.Command, args []string) error {
 		WorkItem:  recordWorkItem,
 	}
 
-	// Marshal to JSON
-	entryJSON, err := json.Marshal(entry)
-	if err != nil {
-		return fmt.Errorf("marshaling cost entry: %w", err)
-	}
-
-	// Append to log file
-	logPath := getCostsLogPath()
-
-	// Ensure directory exists
-	logDir := filepath.Dir(logPath)
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		return fmt.Errorf("creating log directory: %w", err)
-	}
-
-	// Open file for append (create if doesn't exist).
-	// O_APPEND writes are atomic on POSIX for writes < PIPE_BUF (~4KB).
-	// A JSON log entry is ~200 bytes, so concurrent appends are safe.
-	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("opening costs log: %w", err)
-	}
-	defer f.Close()
-
-	// Write entry with newline
-	if _, err := f.Write(append(entryJSON, '\n')); err != nil {
-		return fmt.Errorf("writing to costs log: %w", err)
+	if err := appendCostLogEntry(getCostsLogPath(), entry); err != nil {
+		return err
 	}
 
 	// Output confirmation (silent if cost is zero and no work item)
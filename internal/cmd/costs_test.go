@@ -112,3 +112,19 @@ func TestDeriveSessionName(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterCostEntriesByRig(t *testing.T) {
+	entries := []CostEntry{
+		{SessionID: "a", Rig: "gastown", CostUSD: 1},
+		{SessionID: "b", Rig: "beads", CostUSD: 2},
+		{SessionID: "c", Role: "mayor", CostUSD: 3},
+	}
+
+	got := filterCostEntriesByRig(entries, "gastown")
+	if len(got) != 1 || got[0].SessionID != "a" {
+		t.Errorf("filter gastown = %+v, want only session a", got)
+	}
+	if got := filterCostEntriesByRig(entries, ""); len(got) != 3 {
+		t.Errorf("empty rig should keep all entries, got %d", len(got))
+	}
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
@@ -12,6 +13,7 @@ import (
 
 var (
 	doctorFix             bool
+	doctorJSON            bool
 	doctorVerbose         bool
 	doctorRig             string
 	doctorRestartSessions bool
@@ -80,6 +82,11 @@ Session hook checks:
   - session-hooks            Check settings.local.json use session-start.sh
   - claude-settings          Check Claude settings.local.json match templates (fixable)
 
+Notification and API checks:
+  - api-models               Detect API models reported retired or not found (fixable)
+  - backend-config           Check enabled API backends have credentials
+  - slack-config             Validate settings/slack.json (--fix posts a test notification)
+
 Patrol checks:
   - patrol-molecules-exist   Verify patrol molecules exist
   - patrol-hooks-wired       Verify daemon triggers patrols
@@ -89,12 +96,14 @@ Patrol checks:
 
 Use --fix to attempt automatic fixes for issues that support it.
 Use --rig to check a specific rig instead of the entire workspace.
+Use --json for machine-readable results; statuses are "ok", "warning", or "error".
 Use --slow to highlight slow checks (default threshold: 1s, e.g. --slow=500ms).`,
 	RunE: runDoctor,
 }
 
 func init() {
 	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Attempt to automatically fix issues")
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Output results as JSON (exits 1 if any check errors)")
 	doctorCmd.Flags().BoolVarP(&doctorVerbose, "verbose", "v", false, "Show detailed output")
 	doctorCmd.Flags().StringVar(&doctorRig, "rig", "", "Check specific rig only")
 	doctorCmd.Flags().BoolVar(&doctorRestartSessions, "restart-sessions", false, "Restart patrol sessions when fixing stale settings (use with --fix)")
@@ -117,6 +126,7 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 		RigName:         doctorRig,
 		Verbose:         doctorVerbose,
 		RestartSessions: doctorRestartSessions,
+		Fixing:          doctorFix,
 	}
 
 	// Create doctor and register checks
@@ -162,6 +172,9 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 	d.Register(doctor.NewThemeCheck())
 	d.Register(doctor.NewCrashReportCheck())
 	d.Register(doctor.NewEnvVarsCheck())
+	d.Register(doctor.NewUnavailableModelsCheck())
+	d.Register(doctor.NewBackendConfigCheck())
+	d.Register(doctor.NewSlackConfigCheck())
 
 	// Patrol system checks
 	d.Register(doctor.NewPatrolMoleculesExistCheck())
@@ -225,6 +238,24 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if doctorJSON {
+		var report *doctor.Report
+		if doctorFix {
+			report = d.Fix(ctx)
+		} else {
+			report = d.Run(ctx)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+		if report.HasErrors() {
+			return NewSilentExit(1)
+		}
+		return nil
+	}
+
 	// Run checks with streaming output
 	fmt.Println() // Initial blank line
 	var report *doctor.Report
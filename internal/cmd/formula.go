@@ -50,6 +50,8 @@ Commands:
   show    Display formula details (steps, variables, composition)
   run     Execute a formula (pour and dispatch)
   create  Create a new formula template
+  diff    Compare the steps and variables of two formulas
+  lint    Check authored formulas for errors
 
 Search paths (in order):
   1. .beads/formulas/ (project)
@@ -60,7 +62,9 @@ Examples:
   gt formula list                    # List all formulas
   gt formula show shiny              # Show formula details
   gt formula run shiny --pr=123      # Run formula on PR #123
-  gt formula create my-workflow      # Create new formula template`,
+  gt formula create my-workflow      # Create new formula template
+  gt formula diff a b                # Compare two formulas
+  gt formula lint ./my.formula.toml  # Check a formula before using it`,
 }
 
 var formulaListCmd = &cobra.Command{
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/formula"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+var formulaDiffCmd = &cobra.Command{
+	Use:   "diff <a> <b>",
+	Short: "Compare the steps and variables of two formulas",
+	Long: `Compare two formulas and report how b differs from a.
+
+Steps are matched by ID. Reports added and removed steps, steps whose
+title, instructions, dependencies, parallel flag, or tier changed, and
+added, removed, or changed variables.
+
+Each argument is a formula name (looked up like 'gt formula run') or a
+path to a formula file.
+
+Examples:
+  gt formula diff mol-polecat-work mol-polecat-work-team
+  gt formula diff ./old.formula.toml ./new.formula.toml`,
+	Args: cobra.ExactArgs(2),
+	RunE: runFormulaDiff,
+}
+
+func init() {
+	formulaCmd.AddCommand(formulaDiffCmd)
+}
+
+func runFormulaDiff(cmd *cobra.Command, args []string) error {
+	a, err := loadFormulaForDiff(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := loadFormulaForDiff(args[1])
+	if err != nil {
+		return err
+	}
+
+	printFormulaDiff(os.Stdout, args[0], args[1], formula.Compare(a, b))
+	return nil
+}
+
+// loadFormulaForDiff parses a formula given a file path or a formula name.
+func loadFormulaForDiff(nameOrPath string) (*formula.Formula, error) {
+	path, err := formulaPathFor(nameOrPath)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := parseFormulaFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("parsing formula %s: %w", nameOrPath, err)
+	}
+	return f, nil
+}
+
+// formulaPathFor returns nameOrPath if it is a file, or else the path of
+// the formula with that name.
+func formulaPathFor(nameOrPath string) (string, error) {
+	if info, err := os.Stat(nameOrPath); err == nil && !info.IsDir() {
+		return nameOrPath, nil
+	}
+	return findFormulaFile(nameOrPath)
+}
+
+// printFormulaDiff writes a human-readable formula diff.
+func printFormulaDiff(w io.Writer, aName, bName string, d *formula.Diff) {
+	fmt.Fprintf(w, "%s %s → %s\n\n", style.Bold.Render("Formula diff:"), aName, bName)
+	if d.Empty() {
+		fmt.Fprintln(w, style.Dim.Render("No step or variable differences"))
+		return
+	}
+
+	if len(d.AddedSteps) > 0 || len(d.RemovedSteps) > 0 || len(d.ChangedSteps) > 0 {
+		fmt.Fprintln(w, style.Bold.Render("Steps:"))
+		for _, id := range d.AddedSteps {
+			fmt.Fprintf(w, "  + %s\n", id)
+		}
+		for _, id := range d.RemovedSteps {
+			fmt.Fprintf(w, "  - %s\n", id)
+		}
+		for _, s := range d.ChangedSteps {
+			fmt.Fprintf(w, "  ~ %s\n", s.ID)
+			if s.TitleChanged() {
+				fmt.Fprintf(w, "      title: %q → %q\n", s.OldTitle, s.NewTitle)
+			}
+			if s.DescriptionChanged {
+				fmt.Fprintln(w, "      instructions changed")
+			}
+			if s.NeedsChanged {
+				fmt.Fprintf(w, "      needs: [%s] → [%s]\n", strings.Join(s.OldNeeds, ", "), strings.Join(s.NewNeeds, ", "))
+			}
+			if s.ParallelChanged {
+				fmt.Fprintln(w, "      parallel flag changed")
+			}
+			if s.TierChanged() {
+				fmt.Fprintf(w, "      tier: %q → %q\n", s.OldTier, s.NewTier)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(d.AddedVars) > 0 || len(d.RemovedVars) > 0 || len(d.ChangedVars) > 0 {
+		fmt.Fprintln(w, style.Bold.Render("Vars:"))
+		for _, name := range d.AddedVars {
+			fmt.Fprintf(w, "  + %s\n", name)
+		}
+		for _, name := range d.RemovedVars {
+			fmt.Fprintf(w, "  - %s\n", name)
+		}
+		for _, v := range d.ChangedVars {
+			fmt.Fprintf(w, "  ~ %s\n", v.Name)
+			if v.Old.Default != v.New.Default {
+				fmt.Fprintf(w, "      default: %q → %q\n", v.Old.Default, v.New.Default)
+			}
+			if v.Old.Required != v.New.Required {
+				fmt.Fprintf(w, "      required: %t → %t\n", v.Old.Required, v.New.Required)
+			}
+			if v.Old.Description != v.New.Description {
+				fmt.Fprintln(w, "      description changed")
+			}
+		}
+	}
+}
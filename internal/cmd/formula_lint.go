@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/formula"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+var formulaLintCmd = &cobra.Command{
+	Use:   "lint <formula>...",
+	Short: "Check authored formulas for errors",
+	Long: `Check formulas for problems before they are cooked or slung.
+
+Errors:
+  - The file doesn't parse or validate (missing ids, unknown needs,
+    dependency cycles, which are reported with the path that loops)
+  - Step, leg, template, or aspect text uses {{variables}} that aren't
+    defined in [vars] (the same check gt sling makes)
+
+Warnings:
+  - Formulas that compose others (extends, compose, advice), which bd
+    resolves when cooking; check those with 'bd cook'
+  - Steps that aren't connected to the rest of the formula's dependency
+    graph (usually a missing needs entry)
+
+Each argument is a path to a formula file or a formula name (looked up
+like 'gt formula run'). Exits non-zero if any formula has errors, so it
+can gate CI for custom molecules.
+
+Examples:
+  gt formula lint .beads/formulas/my-workflow.formula.toml
+  gt formula lint .beads/formulas/*.formula.toml`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runFormulaLint,
+}
+
+func init() {
+	formulaCmd.AddCommand(formulaLintCmd)
+}
+
+// formulaLintResult holds the problems found in one formula.
+type formulaLintResult struct {
+	Errors   []string
+	Warnings []string
+}
+
+func runFormulaLint(cmd *cobra.Command, args []string) error {
+	failed := 0
+	for _, arg := range args {
+		res := lintFormula(arg)
+		printFormulaLint(os.Stdout, arg, res)
+		if len(res.Errors) > 0 {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d formula(s) have errors", failed, len(args))
+	}
+	return nil
+}
+
+// lintFormula parses and checks one formula, given a path or a name.
+func lintFormula(nameOrPath string) formulaLintResult {
+	var res formulaLintResult
+
+	path, err := formulaPathFor(nameOrPath)
+	if err != nil {
+		res.Errors = append(res.Errors, err.Error())
+		return res
+	}
+	if composesFormulas(path) {
+		res.Warnings = append(res.Warnings, "composes other formulas (extends, compose, or advice); check it with 'bd cook' instead")
+		return res
+	}
+
+	f, err := parseFormulaFile(path)
+	if err != nil {
+		res.Errors = append(res.Errors, err.Error())
+		return res
+	}
+
+	// Parse already rejects cycles; this covers formulas Validate skips
+	if _, err := f.TopologicalSort(); err != nil {
+		res.Errors = append(res.Errors, err.Error())
+	}
+
+	// Resolve finds undefined variables the same way gt sling does.
+	// Required variables get a stand-in value; only the warnings matter.
+	vars := make(map[string]string)
+	for name, v := range f.Vars {
+		if v.Required {
+			vars[name] = name
+		}
+	}
+	_, undefined, err := f.Resolve(vars)
+	if err != nil {
+		res.Errors = append(res.Errors, err.Error())
+	}
+	for _, u := range undefined {
+		res.Errors = append(res.Errors, u.String()+" (add them to [vars])")
+	}
+
+	for _, id := range disconnectedSteps(f) {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("%q isn't connected to the rest of the dependency graph", id))
+	}
+
+	return res
+}
+
+// composesFormulas reports whether a formula file uses composition, which
+// bd resolves when cooking and the formula package doesn't parse.
+func composesFormulas(path string) bool {
+	var raw map[string]interface{}
+	if _, err := toml.DecodeFile(path, &raw); err != nil {
+		return false
+	}
+	for _, key := range []string{"extends", "compose", "advice"} {
+		if _, ok := raw[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// disconnectedSteps returns the steps (or templates) of a workflow or
+// expansion formula that share no dependency path, in either direction,
+// with its first step. Convoy legs and aspects are independent by design.
+func disconnectedSteps(f *formula.Formula) []string {
+	ids := f.GetAllIDs()
+	if len(ids) < 2 || (f.Type != formula.TypeWorkflow && f.Type != formula.TypeExpansion) {
+		return nil
+	}
+
+	linked := make(map[string][]string)
+	for _, id := range ids {
+		for _, dep := range f.GetDependencies(id) {
+			linked[id] = append(linked[id], dep)
+			linked[dep] = append(linked[dep], id)
+		}
+	}
+
+	connected := map[string]bool{ids[0]: true}
+	queue := []string{ids[0]}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range linked[id] {
+			if !connected[next] {
+				connected[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	var orphans []string
+	for _, id := range ids {
+		if !connected[id] {
+			orphans = append(orphans, id)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
+
+// printFormulaLint writes the problems found in one formula.
+func printFormulaLint(w io.Writer, name string, res formulaLintResult) {
+	if len(res.Errors) == 0 && len(res.Warnings) == 0 {
+		fmt.Fprintf(w, "%s %s\n", style.SuccessPrefix, name)
+		return
+	}
+	for _, e := range res.Errors {
+		fmt.Fprintf(w, "%s %s: %s\n", style.ErrorPrefix, name, e)
+	}
+	for _, warning := range res.Warnings {
+		fmt.Fprintf(w, "%s %s: %s\n", style.WarningPrefix, name, warning)
+	}
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeLintFormula(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.formula.toml")
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLintFormula(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		errors   []string
+		warnings []string
+	}{
+		{
+			name: "clean",
+			body: `formula = "ok"
+[[steps]]
+id = "a"
+title = "Work on {{issue}}"
+[[steps]]
+id = "b"
+needs = ["a"]
+[vars.issue]
+required = true
+`,
+		},
+		{
+			name: "cycle",
+			body: `formula = "cyclic"
+[[steps]]
+id = "a"
+needs = ["b"]
+[[steps]]
+id = "b"
+needs = ["a"]
+`,
+			errors: []string{"a → b → a"},
+		},
+		{
+			name: "undefined variable",
+			body: `formula = "undef"
+[[steps]]
+id = "a"
+description = "Use {{model}}"
+`,
+			errors: []string{"step a uses undefined variables: model"},
+		},
+		{
+			name: "undefined variable in a leg",
+			body: `formula = "legs"
+type = "convoy"
+[[legs]]
+id = "review"
+focus = "{{area}} and {{#if strict}}style{{/if}}"
+`,
+			errors: []string{"leg review uses undefined variables: area"},
+		},
+		{
+			name: "disconnected step",
+			body: `formula = "orphan"
+[[steps]]
+id = "a"
+[[steps]]
+id = "b"
+needs = ["a"]
+[[steps]]
+id = "stray"
+`,
+			warnings: []string{`"stray"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := lintFormula(writeLintFormula(t, tt.body))
+			assertLintMessages(t, "errors", res.Errors, tt.errors)
+			assertLintMessages(t, "warnings", res.Warnings, tt.warnings)
+		})
+	}
+}
+
+func assertLintMessages(t *testing.T, kind string, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s = %v, want %d matching %v", kind, got, len(want), want)
+	}
+	for i := range want {
+		if !strings.Contains(got[i], want[i]) {
+			t.Errorf("%s[%d] = %q, want it to contain %q", kind, i, got[i], want[i])
+		}
+	}
+}
+
+// TestLintFormula_Embedded checks the shipped formulas lint without errors.
+func TestLintFormula_Embedded(t *testing.T) {
+	_, testFile, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("cannot determine test file path")
+	}
+	paths, err := filepath.Glob(filepath.Join(filepath.Dir(testFile), "..", "formula", "formulas", "*.formula.toml"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no embedded formulas found: %v", err)
+	}
+	for _, path := range paths {
+		res := lintFormula(path)
+		if len(res.Errors) > 0 {
+			t.Errorf("%s: %v", filepath.Base(path), res.Errors)
+		}
+		if len(res.Warnings) > 0 {
+			t.Logf("%s: %v", filepath.Base(path), res.Warnings)
+		}
+	}
+}
@@ -25,6 +25,8 @@ var (
 	mailInboxIdentity string
 	mailCheckInject   bool
 	mailCheckJSON     bool
+	mailCheckLimit    int
+	mailCheckQuiet    bool
 	mailCheckIdentity string
 	mailThreadJSON    bool
 	mailReplySubject  string
@@ -267,14 +269,25 @@ Exit codes (normal mode):
   0 - New mail available
   1 - No new mail
 
+Exit codes (--quiet mode, no output):
+  0 - New mail available
+  1 - No new mail
+  2 - Check failed (error on stderr)
+
 Exit codes (--inject mode):
   0 - Always (hooks should never block)
   Output: system-reminder if mail exists, silent if no mail
 
+With --json, prints the address, unread count, and has_new, plus a
+messages array with the id, sender, subject, timestamp, and age in seconds
+of up to --limit unread messages.
+
 Use --identity for polecats to explicitly specify their identity.
 
 Examples:
   gt mail check                           # Simple check (auto-detect identity)
+  gt mail check --json --limit 5          # Unread summary for tooling
+  gt mail check --quiet && echo "mail"    # For scripts
   gt mail check --inject                  # For hooks
   gt mail check --identity greenplace/Toast  # Explicit polecat identity`,
 	RunE: runMailCheck,
@@ -480,6 +493,8 @@ func init() {
 	// Check flags
 	mailCheckCmd.Flags().BoolVar(&mailCheckInject, "inject", false, "Output format for Claude Code hooks")
 	mailCheckCmd.Flags().BoolVar(&mailCheckJSON, "json", false, "Output as JSON")
+	mailCheckCmd.Flags().BoolVarP(&mailCheckQuiet, "quiet", "q", false, "No output; exit 0 if there is new mail, 1 if none, 2 on error")
+	mailCheckCmd.Flags().IntVar(&mailCheckLimit, "limit", 20, "Maximum unread messages listed in --json output (0 = no limit)")
 	mailCheckCmd.Flags().StringVar(&mailCheckIdentity, "identity", "", "Explicit identity for inbox (e.g., greenplace/Toast)")
 	mailCheckCmd.Flags().StringVar(&mailCheckIdentity, "address", "", "Alias for --identity")
 
@@ -3,7 +3,9 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/steveyegge/gastown/internal/mail"
@@ -26,7 +28,7 @@ func runMailCheck(cmd *cobra.Command, args []string) error {
 			fmt.Fprintf(os.Stderr, "gt mail check: workspace lookup failed: %v\n", err)
 			return nil
 		}
-		return fmt.Errorf("not in a Gas Town workspace: %w", err)
+		return mailCheckError(fmt.Errorf("not in a Gas Town workspace: %w", err))
 	}
 
 	// Get mailbox
@@ -37,9 +39,31 @@ func runMailCheck(cmd *cobra.Command, args []string) error {
 			fmt.Fprintf(os.Stderr, "gt mail check: mailbox error for %s: %v\n", address, err)
 			return nil
 		}
-		return fmt.Errorf("getting mailbox: %w", err)
+		return mailCheckError(fmt.Errorf("getting mailbox: %w", err))
 	}
 
+	return checkMailbox(os.Stdout, address, mailbox)
+}
+
+// mailCheckMailbox is the part of a mailbox 'gt mail check' reads.
+type mailCheckMailbox interface {
+	Count() (total, unread int, err error)
+	ListUnread() ([]*mail.Message, error)
+}
+
+// mailCheckError reports a failed check. In --quiet mode it exits 2, so a
+// failure isn't mistaken for "no mail".
+func mailCheckError(err error) error {
+	if mailCheckQuiet {
+		fmt.Fprintf(os.Stderr, "gt mail check: %v\n", err)
+		return NewSilentExit(2)
+	}
+	return err
+}
+
+// checkMailbox reports on address's unread mail in the selected mode,
+// writing to w.
+func checkMailbox(w io.Writer, address string, mailbox mailCheckMailbox) error {
 	// Count unread
 	_, unread, err := mailbox.Count()
 	if err != nil {
@@ -47,17 +71,34 @@ func runMailCheck(cmd *cobra.Command, args []string) error {
 			fmt.Fprintf(os.Stderr, "gt mail check: count error for %s: %v\n", address, err)
 			return nil
 		}
-		return fmt.Errorf("counting messages: %w", err)
+		return mailCheckError(fmt.Errorf("counting messages: %w", err))
+	}
+
+	// Quiet mode: the exit code is the whole answer
+	if mailCheckQuiet {
+		if unread > 0 {
+			return NewSilentExit(0)
+		}
+		return NewSilentExit(1)
 	}
 
 	// JSON output
 	if mailCheckJSON {
+		messages := []mailCheckMessage{}
+		if unread > 0 {
+			unreadMsgs, err := mailbox.ListUnread()
+			if err != nil {
+				return fmt.Errorf("listing unread messages: %w", err)
+			}
+			messages = mailCheckMessages(unreadMsgs, mailCheckLimit, time.Now())
+		}
 		result := map[string]interface{}{
-			"address": address,
-			"unread":  unread,
-			"has_new": unread > 0,
+			"address":  address,
+			"unread":   unread,
+			"has_new":  unread > 0,
+			"messages": messages,
 		}
-		enc := json.NewEncoder(os.Stdout)
+		enc := json.NewEncoder(w)
 		enc.SetIndent("", "  ")
 		return enc.Encode(result)
 	}
@@ -85,29 +126,29 @@ func runMailCheck(cmd *cobra.Command, args []string) error {
 
 			if len(urgent) > 0 {
 				// Urgent mail: interrupt — agent should stop and read
-				fmt.Println("<system-reminder>")
-				fmt.Printf("URGENT: %d urgent message(s) require immediate attention.\n\n", len(urgent))
+				fmt.Fprintln(w, "<system-reminder>")
+				fmt.Fprintf(w, "URGENT: %d urgent message(s) require immediate attention.\n\n", len(urgent))
 				for _, msg := range urgent {
-					fmt.Printf("- %s from %s: %s\n", msg.ID, msg.From, msg.Subject)
+					fmt.Fprintf(w, "- %s from %s: %s\n", msg.ID, msg.From, msg.Subject)
 				}
 				if len(normal) > 0 {
-					fmt.Printf("\n(Plus %d non-urgent message(s) — read after current task.)\n", len(normal))
+					fmt.Fprintf(w, "\n(Plus %d non-urgent message(s) — read after current task.)\n", len(normal))
 				}
-				fmt.Println()
-				fmt.Println("Run 'gt mail read <id>' to read urgent messages.")
-				fmt.Println("</system-reminder>")
+				fmt.Fprintln(w)
+				fmt.Fprintln(w, "Run 'gt mail read <id>' to read urgent messages.")
+				fmt.Fprintln(w, "</system-reminder>")
 			} else {
 				// Non-urgent mail only: deliver as background notification.
 				// Explicitly tell the agent NOT to interrupt current work.
-				fmt.Println("<system-reminder>")
-				fmt.Printf("You have %d unread message(s) in your inbox.\n\n", len(normal))
+				fmt.Fprintln(w, "<system-reminder>")
+				fmt.Fprintf(w, "You have %d unread message(s) in your inbox.\n\n", len(normal))
 				for _, msg := range normal {
-					fmt.Printf("- %s from %s: %s\n", msg.ID, msg.From, msg.Subject)
+					fmt.Fprintf(w, "- %s from %s: %s\n", msg.ID, msg.From, msg.Subject)
 				}
-				fmt.Println()
-				fmt.Println("This is a background notification. Do NOT stop or interrupt your current task.")
-				fmt.Println("Read these messages when your current work is complete: 'gt mail inbox'")
-				fmt.Println("</system-reminder>")
+				fmt.Fprintln(w)
+				fmt.Fprintln(w, "This is a background notification. Do NOT stop or interrupt your current task.")
+				fmt.Fprintln(w, "Read these messages when your current work is complete: 'gt mail inbox'")
+				fmt.Fprintln(w, "</system-reminder>")
 			}
 		}
 		return nil
@@ -115,9 +156,37 @@ func runMailCheck(cmd *cobra.Command, args []string) error {
 
 	// Normal mode
 	if unread > 0 {
-		fmt.Printf("%s %d unread message(s)\n", style.Bold.Render("📬"), unread)
+		fmt.Fprintf(w, "%s %d unread message(s)\n", style.Bold.Render("📬"), unread)
 		return NewSilentExit(0)
 	}
-	fmt.Println("No new mail")
+	fmt.Fprintln(w, "No new mail")
 	return NewSilentExit(1)
 }
+
+// mailCheckMessage summarizes an unread message in 'gt mail check --json'.
+type mailCheckMessage struct {
+	ID         string    `json:"id"`
+	From       string    `json:"from"`
+	Subject    string    `json:"subject"`
+	Timestamp  time.Time `json:"timestamp"`
+	AgeSeconds int64     `json:"age_seconds"`
+}
+
+// mailCheckMessages summarizes up to limit messages (all if limit <= 0),
+// with ages relative to now.
+func mailCheckMessages(msgs []*mail.Message, limit int, now time.Time) []mailCheckMessage {
+	if limit > 0 && len(msgs) > limit {
+		msgs = msgs[:limit]
+	}
+	out := make([]mailCheckMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		out = append(out, mailCheckMessage{
+			ID:         msg.ID,
+			From:       msg.From,
+			Subject:    msg.Subject,
+			Timestamp:  msg.Timestamp,
+			AgeSeconds: int64(now.Sub(msg.Timestamp).Seconds()),
+		})
+	}
+	return out
+}
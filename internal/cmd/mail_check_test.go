@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/mail"
+)
+
+func TestMailCheckMessages(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	msgs := []*mail.Message{
+		{ID: "hq-1", From: "mayor/", Subject: "First", Timestamp: now.Add(-90 * time.Second)},
+		{ID: "hq-2", From: "gastown/Toast", Subject: "Second", Timestamp: now.Add(-time.Hour)},
+		{ID: "hq-3", From: "gastown/witness", Subject: "Third", Timestamp: now},
+	}
+
+	got := mailCheckMessages(msgs, 2, now)
+	if len(got) != 2 {
+		t.Fatalf("len = %d, want 2 (limited)", len(got))
+	}
+	if got[0].ID != "hq-1" || got[0].From != "mayor/" || got[0].Subject != "First" || got[0].AgeSeconds != 90 {
+		t.Errorf("got[0] = %+v", got[0])
+	}
+	if got[1].AgeSeconds != 3600 {
+		t.Errorf("got[1].AgeSeconds = %d, want 3600", got[1].AgeSeconds)
+	}
+
+	if all := mailCheckMessages(msgs, 0, now); len(all) != 3 {
+		t.Errorf("limit 0: len = %d, want 3", len(all))
+	}
+}
+
+// fakeMailCheckMailbox is a mailCheckMailbox with canned results.
+type fakeMailCheckMailbox struct {
+	unread []*mail.Message
+	err    error
+}
+
+func (f *fakeMailCheckMailbox) Count() (int, int, error) {
+	return len(f.unread), len(f.unread), f.err
+}
+
+func (f *fakeMailCheckMailbox) ListUnread() ([]*mail.Message, error) {
+	return f.unread, f.err
+}
+
+func TestCheckMailbox_ExitCodes(t *testing.T) {
+	oneMessage := []*mail.Message{{ID: "hq-1", From: "mayor/", Subject: "Hi", Timestamp: time.Now()}}
+
+	tests := []struct {
+		name       string
+		quiet      bool
+		mailbox    *fakeMailCheckMailbox
+		wantCode   int
+		wantOutput bool
+	}{
+		{"normal with mail", false, &fakeMailCheckMailbox{unread: oneMessage}, 0, true},
+		{"normal without mail", false, &fakeMailCheckMailbox{}, 1, true},
+		{"quiet with mail", true, &fakeMailCheckMailbox{unread: oneMessage}, 0, false},
+		{"quiet without mail", true, &fakeMailCheckMailbox{}, 1, false},
+		{"quiet error", true, &fakeMailCheckMailbox{err: errors.New("bd failed")}, 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldQuiet, oldJSON, oldInject := mailCheckQuiet, mailCheckJSON, mailCheckInject
+			defer func() { mailCheckQuiet, mailCheckJSON, mailCheckInject = oldQuiet, oldJSON, oldInject }()
+			mailCheckQuiet, mailCheckJSON, mailCheckInject = tt.quiet, false, false
+
+			var buf bytes.Buffer
+			err := checkMailbox(&buf, "gastown/Toast", tt.mailbox)
+			code, ok := IsSilentExit(err)
+			if !ok {
+				t.Fatalf("checkMailbox() = %v, want a silent exit", err)
+			}
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			if got := buf.Len() > 0; got != tt.wantOutput {
+				t.Errorf("output %q, want output: %v", buf.String(), tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestCheckMailbox_NormalErrorIsReturned(t *testing.T) {
+	oldQuiet, oldJSON, oldInject := mailCheckQuiet, mailCheckJSON, mailCheckInject
+	defer func() { mailCheckQuiet, mailCheckJSON, mailCheckInject = oldQuiet, oldJSON, oldInject }()
+	mailCheckQuiet, mailCheckJSON, mailCheckInject = false, false, false
+
+	err := checkMailbox(&bytes.Buffer{}, "gastown/Toast", &fakeMailCheckMailbox{err: errors.New("bd failed")})
+	if err == nil {
+		t.Fatal("checkMailbox() = nil, want error")
+	}
+	if _, ok := IsSilentExit(err); ok {
+		t.Errorf("checkMailbox() = %v, want a plain error", err)
+	}
+}
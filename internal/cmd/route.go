@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/backend"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var routeExplainJSON bool
+
+var routeCmd = &cobra.Command{
+	Use:     "route",
+	GroupID: GroupWork,
+	Short:   "Inspect how beads are routed between API backends and CLI agents",
+	Long: `Inspect the hybrid router that decides whether gt sling sends a bead to
+an API backend or a CLI agent.
+
+Subcommands:
+  gt route explain <bead-id>   # Show why a bead routes where it does`,
+	RunE: requireSubcommand,
+}
+
+var routeExplainCmd = &cobra.Command{
+	Use:   "explain <bead-id>",
+	Short: "Show why a bead routes to an API backend or a CLI agent",
+	Long: `Show the full routing decision for a bead without dispatching it.
+
+Prints the task analysis (complexity score, minimum tier, detected signals,
+whether tool use is required), the intent read from labels, the routing
+decision with its reason, and the estimated cost of an API route. No API
+backend is invoked.
+
+Examples:
+  gt route explain gt-abc12
+  gt route explain gt-abc12 --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRouteExplain,
+}
+
+func init() {
+	routeExplainCmd.Flags().BoolVar(&routeExplainJSON, "json", false, "Output as JSON")
+
+	routeCmd.AddCommand(routeExplainCmd)
+	rootCmd.AddCommand(routeCmd)
+}
+
+// routeAnalysis is the router's task analysis in 'gt route explain' output.
+type routeAnalysis struct {
+	Score           int               `json:"score"`
+	MinTier         backend.ModelTier `json:"min_tier"`
+	RequiresToolUse bool              `json:"requires_tool_use"`
+	Signals         []string          `json:"signals"`
+}
+
+// routeExplanation is the JSON output of 'gt route explain'.
+type routeExplanation struct {
+	Bead            string               `json:"bead"`
+	Title           string               `json:"title"`
+	Labels          []string             `json:"labels,omitempty"`
+	EstimatedTokens int                  `json:"estimated_tokens"`
+	ModelTag        string               `json:"model_tag,omitempty"`
+	Intent          backend.Intent       `json:"intent"`
+	Analysis        routeAnalysis        `json:"analysis"`
+	Route           *backend.RouteResult `json:"route"`
+	EstimatedCost   *float64             `json:"estimated_cost,omitempty"`
+	CostThreshold   float64              `json:"cost_threshold"`
+	CostError       string               `json:"cost_error,omitempty"`
+}
+
+func runRouteExplain(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return err
+	}
+
+	dispatcher := InitializeBackendDispatcher(townRoot, "")
+	explanation, err := explainRoute(dispatcher, args[0], townRoot)
+	if err != nil {
+		return err
+	}
+
+	if routeExplainJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(explanation)
+	}
+	printRouteExplanation(os.Stdout, explanation)
+	return nil
+}
+
+// explainRoute runs the dispatcher's routing decision for a bead and
+// collects what went into it. Backends are never invoked.
+func explainRoute(dispatcher *BackendDispatcher, beadID, townRoot string) (*routeExplanation, error) {
+	issue, err := dispatcher.issues.FetchIssue(beadID, townRoot)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", beadID, err)
+	}
+
+	hints := dispatcher.extractHints(issue, nil)
+	complexity := dispatcher.router.Analyzer().Analyze(hints.Title, hints.Description, hints.Labels)
+
+	explanation := &routeExplanation{
+		Bead:            beadID,
+		Title:           issue.Title,
+		Labels:          issue.Labels,
+		EstimatedTokens: hints.EstimatedTokens,
+		ModelTag:        hints.ModelTag,
+		Intent:          hints.Intent,
+		Analysis: routeAnalysis{
+			Score:           complexity.Score,
+			MinTier:         complexity.MinTier,
+			RequiresToolUse: complexity.RequiresToolUse,
+			Signals:         complexity.Signals,
+		},
+		CostThreshold: dispatcher.config.CostThreshold,
+	}
+
+	route, shouldRoute := dispatcher.ShouldRouteToAPI(issue, nil)
+	if route == nil {
+		route = &backend.RouteResult{Decision: backend.RouteCLI, Reason: "hybrid routing disabled"}
+	}
+	explanation.Route = route
+
+	if shouldRoute {
+		estimate, err := dispatcher.PreviewAPIBackend(route, issue, nil)
+		if err != nil {
+			explanation.CostError = err.Error()
+		} else {
+			explanation.EstimatedCost = &estimate.TotalCost
+		}
+	}
+	return explanation, nil
+}
+
+// printRouteExplanation writes the human-readable routing explanation.
+func printRouteExplanation(w io.Writer, e *routeExplanation) {
+	fmt.Fprintf(w, "%s %s\n", style.Bold.Render(e.Bead), e.Title)
+	if len(e.Labels) > 0 {
+		fmt.Fprintf(w, "  Labels:     %s\n", strings.Join(e.Labels, ", "))
+	}
+	fmt.Fprintf(w, "  Tokens:     ~%d (estimated from description)\n", e.EstimatedTokens)
+	if e.ModelTag != "" {
+		fmt.Fprintf(w, "  Model tag:  %s\n", e.ModelTag)
+	}
+	fmt.Fprintf(w, "  Intent:     %s\n", e.Intent)
+
+	fmt.Fprintf(w, "\n%s\n", style.Bold.Render("Analysis"))
+	fmt.Fprintf(w, "  Score:      %d/100\n", e.Analysis.Score)
+	fmt.Fprintf(w, "  Min tier:   %s\n", e.Analysis.MinTier)
+	fmt.Fprintf(w, "  Tool use:   %t\n", e.Analysis.RequiresToolUse)
+	signals := "none"
+	if len(e.Analysis.Signals) > 0 {
+		signals = strings.Join(e.Analysis.Signals, ", ")
+	}
+	fmt.Fprintf(w, "  Signals:    %s\n", signals)
+
+	fmt.Fprintf(w, "\n%s\n", style.Bold.Render("Decision"))
+	if e.Route.Decision == backend.RouteAPI {
+		fmt.Fprintf(w, "  Route:      API %s/%s\n", e.Route.Backend, e.Route.Model)
+	} else {
+		fmt.Fprintf(w, "  Route:      CLI agent\n")
+	}
+	fmt.Fprintf(w, "  Reason:     %s\n", e.Route.Reason)
+
+	switch {
+	case e.EstimatedCost != nil:
+		fmt.Fprintf(w, "  Cost:       ~$%.4f (threshold $%.2f)\n", *e.EstimatedCost, e.CostThreshold)
+	case e.CostError != "":
+		fmt.Fprintf(w, "  Cost:       %s\n", style.Dim.Render("unavailable: "+e.CostError))
+	}
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/backend"
+	"github.com/steveyegge/gastown/internal/beads"
+)
+
+func TestExplainRouteAPI(t *testing.T) {
+	d, fake := newTestAPIDispatcher(t)
+
+	e, err := explainRoute(d, "gt-abc123", t.TempDir())
+	if err != nil {
+		t.Fatalf("explainRoute: %v", err)
+	}
+	if n := fake.invokes.Load(); n != 0 {
+		t.Errorf("expected no invocations, got %d", n)
+	}
+	if e.Route.Decision != backend.RouteAPI || e.Route.Backend != "bedrock" {
+		t.Errorf("route = %+v, want API via bedrock", e.Route)
+	}
+	if e.Analysis.MinTier != backend.TierSimple || e.Analysis.RequiresToolUse {
+		t.Errorf("analysis = %+v, want a simple task without tool use", e.Analysis)
+	}
+	if e.EstimatedCost == nil || *e.EstimatedCost != 0.0123 {
+		t.Errorf("estimated cost = %v, want 0.0123", e.EstimatedCost)
+	}
+
+	var buf bytes.Buffer
+	printRouteExplanation(&buf, e)
+	for _, want := range []string{"Route:      API bedrock/", "Min tier:   simple", "Cost:       ~$0.0123"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestExplainRouteCLI(t *testing.T) {
+	d, _ := newTestAPIDispatcher(t)
+	d.SetIssueFetcher(&fakeIssueFetcher{issues: map[string]*beads.Issue{
+		"gt-sec": {ID: "gt-sec", Title: "Rotate credentials", Labels: []string{"security"}},
+	}})
+
+	e, err := explainRoute(d, "gt-sec", t.TempDir())
+	if err != nil {
+		t.Fatalf("explainRoute: %v", err)
+	}
+	if e.Route.Decision != backend.RouteCLI || !strings.Contains(e.Route.Reason, `"security"`) {
+		t.Errorf("route = %+v, want CLI forced by the security label", e.Route)
+	}
+	if e.EstimatedCost != nil || e.CostError != "" {
+		t.Errorf("CLI route should carry no cost estimate, got %v %q", e.EstimatedCost, e.CostError)
+	}
+
+	if _, err := explainRoute(d, "gt-missing", t.TempDir()); err == nil {
+		t.Error("expected an error for an unknown bead")
+	}
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/slack"
+	"github.com/steveyegge/gastown/internal/style"
+	"github.com/steveyegge/gastown/internal/workspace"
+)
+
+var (
+	slackTestAll     bool
+	slackTestTimeout time.Duration
+)
+
+var slackCmd = &cobra.Command{
+	Use:     "slack",
+	GroupID: GroupConfig,
+	Short:   "Check Slack notifications",
+	Long: `Check the Slack notifications gt posts for job lifecycle events.
+
+Slack is configured in settings/slack.json.
+
+Subcommands:
+  gt slack test     # Post a sample notification to check delivery`,
+	RunE: requireSubcommand,
+}
+
+var slackTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Post a sample notification to check delivery",
+	Long: `Post a sample Job Completed notification using settings/slack.json,
+so a new webhook or bot token can be checked without running a real job.
+
+Samples are sent regardless of the notify_on settings. With --all, one
+sample of each event type is posted, to check how each is formatted; with
+use_threads set, they land in one thread, as a bead's events would.
+
+Exits non-zero if any notification fails.
+
+Examples:
+  gt slack test
+  gt slack test --all`,
+	Args: cobra.NoArgs,
+	RunE: runSlackTest,
+}
+
+func init() {
+	slackTestCmd.Flags().BoolVar(&slackTestAll, "all", false, "Post a sample of every event type")
+	slackTestCmd.Flags().DurationVar(&slackTestTimeout, "timeout", 30*time.Second, "Time limit for each notification, retries included")
+
+	slackCmd.AddCommand(slackTestCmd)
+	rootCmd.AddCommand(slackCmd)
+}
+
+// slackTestBead is the bead ID used in sample notifications.
+const slackTestBead = "gt-slack-test"
+
+// slackTestEvents lists the event types --all posts, in lifecycle order.
+var slackTestEvents = []slack.EventType{
+	slack.EventJobQueued,
+	slack.EventJobStarted,
+	slack.EventPRCreated,
+	slack.EventJobCompleted,
+	slack.EventJobFailed,
+	slack.EventEscalation,
+}
+
+func runSlackTest(cmd *cobra.Command, args []string) error {
+	townRoot, err := workspace.FindFromCwdOrError()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := slack.LoadConfig(townRoot)
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", slack.ConfigPath(townRoot), err)
+	}
+	client := slack.NewClient(cfg)
+	if !client.Enabled() {
+		return fmt.Errorf("slack is not configured: set enabled and webhook_url (or bot_token and channel) in %s", slack.ConfigPath(townRoot))
+	}
+
+	events := []slack.EventType{slack.EventJobCompleted}
+	if slackTestAll {
+		events = slackTestEvents
+	}
+	return sendSlackTests(client, events, slackTestTimeout, os.Stdout)
+}
+
+// sendSlackTests posts a sample of each event and reports how each went.
+func sendSlackTests(client *slack.Client, events []slack.EventType, timeout time.Duration, w io.Writer) error {
+	failed := 0
+	for _, event := range events {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		status, err := client.Send(ctx, event, slackTestFields(event))
+		cancel()
+
+		if err != nil {
+			failed++
+			_, _ = fmt.Fprintf(w, "%s %s: %s\n", style.ErrorPrefix, event, describeSlackError(err))
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%s %s delivered (HTTP %d)\n", style.SuccessPrefix, event, status)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d test notification(s) failed", failed, len(events))
+	}
+	return nil
+}
+
+// describeSlackError explains a delivery failure, with hints for the
+// usual configuration mistakes.
+func describeSlackError(err error) string {
+	var statusErr *slack.StatusError
+	var apiErr *slack.APIError
+	switch {
+	case errors.As(err, &statusErr) && (statusErr.StatusCode == 403 || statusErr.StatusCode == 404):
+		return fmt.Sprintf("HTTP %d (check webhook_url; the webhook may have been revoked)", statusErr.StatusCode)
+	case errors.As(err, &statusErr):
+		return fmt.Sprintf("HTTP %d (%v)", statusErr.StatusCode, err)
+	case errors.As(err, &apiErr) && (apiErr.Code == "invalid_auth" || apiErr.Code == "not_authed" || apiErr.Code == "token_revoked"):
+		return fmt.Sprintf("%v (check bot_token)", err)
+	case errors.As(err, &apiErr) && (apiErr.Code == "channel_not_found" || apiErr.Code == "not_in_channel"):
+		return fmt.Sprintf("%v (check channel, and invite the bot to it)", err)
+	default:
+		return err.Error()
+	}
+}
+
+// slackTestFields returns sample fields for event.
+func slackTestFields(event slack.EventType) map[string]string {
+	fields := map[string]string{
+		slack.FieldBead:  slackTestBead,
+		slack.FieldTitle: "Sample notification from gt slack test",
+	}
+	switch event {
+	case slack.EventJobQueued, slack.EventJobStarted:
+		fields[slack.FieldAssignee] = "gastown/polecats/example"
+	case slack.EventPRCreated:
+		fields[slack.FieldBranch] = "polecat/example"
+		fields[slack.FieldMR] = "gt-slack-test-mr"
+	case slack.EventJobCompleted:
+		fields[slack.FieldBranch] = "polecat/example"
+		fields[slack.FieldCommit] = "0123456789abcdef"
+	case slack.EventJobFailed:
+		fields[slack.FieldReason] = "sample failure"
+		fields[slack.FieldError] = "this is a test notification; nothing failed"
+	case slack.EventEscalation:
+		fields[slack.FieldSeverity] = "low"
+		fields[slack.FieldDescription] = "This is a test notification; no action is needed."
+	}
+	return fields
+}
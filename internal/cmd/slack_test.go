@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/slack"
+)
+
+func TestSendSlackTestsPostsEachEvent(t *testing.T) {
+	var texts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		texts = append(texts, msg.Text)
+	}))
+	defer server.Close()
+
+	// Samples go out even for events notify_on turns off
+	client := slack.NewClient(&slack.Config{Enabled: true, WebhookURL: server.URL})
+
+	var out bytes.Buffer
+	if err := sendSlackTests(client, slackTestEvents, 5*time.Second, &out); err != nil {
+		t.Fatalf("sendSlackTests: %v\n%s", err, out.String())
+	}
+	if len(texts) != len(slackTestEvents) {
+		t.Fatalf("posted %d messages, want %d", len(texts), len(slackTestEvents))
+	}
+	if !strings.Contains(texts[3], "Job Completed") {
+		t.Errorf("fourth message = %q, want Job Completed", texts[3])
+	}
+	if got := strings.Count(out.String(), "delivered (HTTP 200)"); got != len(slackTestEvents) {
+		t.Errorf("reported %d deliveries:\n%s", got, out.String())
+	}
+}
+
+func TestSendSlackTestsReportsActualStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := slack.NewClient(&slack.Config{Enabled: true, WebhookURL: server.URL})
+
+	var out bytes.Buffer
+	if err := sendSlackTests(client, []slack.EventType{slack.EventJobCompleted}, 5*time.Second, &out); err != nil {
+		t.Fatalf("sendSlackTests: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "delivered (HTTP 204)") {
+		t.Errorf("output should report the server's status:\n%s", out.String())
+	}
+}
+
+func TestSendSlackTestsReportsRevokedWebhook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := slack.NewClient(&slack.Config{Enabled: true, WebhookURL: server.URL})
+
+	var out bytes.Buffer
+	err := sendSlackTests(client, []slack.EventType{slack.EventJobCompleted}, 5*time.Second, &out)
+	if err == nil {
+		t.Fatal("expected an error for a failed notification")
+	}
+	if !strings.Contains(out.String(), "HTTP 404 (check webhook_url") {
+		t.Errorf("output missing status and hint:\n%s", out.String())
+	}
+}
@@ -120,10 +120,11 @@ var (
 	slingMaxConcurrent int    // --max-concurrent: limit concurrent spawns in batch mode
 
 	// Agent teams flags
-	slingTeam         bool   // --team: enable Claude Code agent teams for this polecat
-	slingTeamSize     int    // --team-size: max teammates (default 3)
-	slingTeammateTier string // --teammate-tier: model tier for teammates (default "sonnet")
-	slingNoTeam       bool   // --no-team: override rig-level team defaults
+	slingTeam           bool    // --team: enable Claude Code agent teams for this polecat
+	slingTeamSize       int     // --team-size: max teammates (default 3)
+	slingTeammateTier   string  // --teammate-tier: model tier for teammates (default "sonnet")
+	slingTeammateBudget float64 // --teammate-budget: API spend budget (USD) per teammate (0 = none)
+	slingNoTeam         bool    // --no-team: override rig-level team defaults
 )
 
 func init() {
@@ -150,12 +151,17 @@ func init() {
 	// Agent teams flags
 	slingCmd.Flags().BoolVar(&slingTeam, "team", false, "Enable Claude Code agent teams (polecat spawns teammates for parallel work)")
 	slingCmd.Flags().IntVar(&slingTeamSize, "team-size", 3, "Max teammates when --team is enabled")
-	slingCmd.Flags().StringVar(&slingTeammateTier, "teammate-tier", "sonnet", "Model tier for teammates: opus, sonnet, haiku")
+	slingCmd.Flags().StringVar(&slingTeammateTier, "teammate-tier", "sonnet", "Model tier for teammates: opus, sonnet, haiku, or a model from a configured backend (e.g., grok-3, gpt-4o)")
+	slingCmd.Flags().Float64Var(&slingTeammateBudget, "teammate-budget", 0, "Daily API spend budget in USD per teammate; a teammate's API calls are refused once it is used up (0 = no budget)")
 	slingCmd.Flags().BoolVar(&slingNoTeam, "no-team", false, "Override rig-level team defaults (force single-agent mode)")
 
 	rootCmd.AddCommand(slingCmd)
 }
 
+// maxTeamSize is the largest --team-size allowed. Rigs can set a lower
+// cap with max_teammates_cap in their team settings.
+const maxTeamSize = 10
+
 func runSling(cmd *cobra.Command, args []string) error {
 	// Polecats cannot sling - check early before writing anything
 	if polecatName := os.Getenv("GT_POLECAT"); polecatName != "" {
@@ -183,27 +189,12 @@ func runSling(cmd *cobra.Command, args []string) error {
 	if slingTeam && slingNoTeam {
 		return fmt.Errorf("cannot use both --team and --no-team flags")
 	}
-	if slingTeamSize < 1 || slingTeamSize > 10 {
-		return fmt.Errorf("--team-size must be between 1 and 10 (got %d)", slingTeamSize)
-	}
-	switch strings.ToLower(slingTeammateTier) {
-	case "opus", "sonnet", "haiku":
-		// valid
-	default:
-		return fmt.Errorf("invalid --teammate-tier '%s': must be opus, sonnet, or haiku", slingTeammateTier)
+	if slingTeamSize < 1 || slingTeamSize > maxTeamSize {
+		return fmt.Errorf("--team-size must be between 1 and %d (got %d)", maxTeamSize, slingTeamSize)
 	}
-
-	// Build TeamConfig from flags. If --team is not explicitly set, check rig-level
-	// defaults from settings/config.json. --no-team suppresses rig defaults.
-	var teamConfig *config.TeamConfig
-	if slingTeam {
-		teamConfig = &config.TeamConfig{
-			Enabled:       true,
-			MaxTeammates:  slingTeamSize,
-			TeammateModel: slingTeammateTier,
-		}
+	if slingTeammateBudget < 0 {
+		return fmt.Errorf("--teammate-budget must not be negative (got %.2f)", slingTeammateBudget)
 	}
-	// Rig-level team defaults are resolved later (after townRoot and target are known).
 
 	// Disable Dolt auto-commit for all bd commands run during sling (gt-u6n6a).
 	// Under concurrent load (batch slinging), auto-commits from individual bd writes
@@ -246,6 +237,30 @@ func runSling(cmd *cobra.Command, args []string) error {
 		args[i] = strings.TrimRight(args[i], "/")
 	}
 
+	// Teammate models can come from any backend the town or target rig
+	// enables, so --teammate-tier is validated once those are known
+	var targetRigPath string
+	if len(args) > 1 {
+		_, targetRigPath, _ = targetRig(args[len(args)-1], townRoot)
+	}
+	teammateModel, err := resolveTeammateModel(slingTeammateTier, townRoot, targetRigPath)
+	if err != nil {
+		return err
+	}
+
+	// Build TeamConfig from flags. If --team is not explicitly set, check rig-level
+	// defaults from settings/config.json. --no-team suppresses rig defaults.
+	var teamConfig *config.TeamConfig
+	if slingTeam {
+		teamConfig = &config.TeamConfig{
+			Enabled:       true,
+			MaxTeammates:  slingTeamSize,
+			TeammateModel: teammateModel,
+			BudgetUSD:     slingTeammateBudget,
+		}
+	}
+	// Rig-level team defaults are resolved later (after the target is known).
+
 	// Batch mode detection: multiple beads with rig target
 	// Pattern: gt sling gt-abc gt-def gt-ghi gastown
 	// When len(args) > 2 and last arg is a rig, sling each bead to its own polecat
@@ -298,11 +313,20 @@ func runSling(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Resolve target agent using shared dispatch logic
+	var target string
+	if len(args) > 1 {
+		target = args[1]
+	}
+
 	// Check if this bead should be handled by API backend (hybrid routing).
-	// This is an opt-in feature controlled by settings/backend.json.
-	// If the bead is successfully handled by API, we return early.
-	if beadID != "" && !slingDryRun {
-		handled, err := TryAPIBackendForBead(beadID, townRoot, "")
+	// This is an opt-in feature controlled by settings/backend.json, which a
+	// target rig can override. If the bead is successfully handled by API,
+	// we return early. In dry-run mode this only prints the routing decision
+	// and cost estimate.
+	if beadID != "" {
+		_, rigPath, _ := targetRig(target, townRoot)
+		handled, err := TryAPIBackendForBead(beadID, townRoot, rigPath, slingDryRun)
 		if err != nil {
 			return fmt.Errorf("API backend error: %w", err)
 		}
@@ -312,12 +336,6 @@ func runSling(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Resolve target agent using shared dispatch logic
-	var target string
-	if len(args) > 1 {
-		target = args[1]
-	}
-
 	// Rig-level team defaults: if --team was not explicitly set and --no-team was not
 	// passed, check the target rig's settings for default team configuration.
 	if teamConfig == nil && !slingNoTeam && target != "" {
@@ -328,10 +346,38 @@ func runSling(cmd *cobra.Command, args []string) error {
 				teamConfig.MaxTeammates = slingTeamSize
 			}
 			if slingTeammateTier != "sonnet" { // "sonnet" is the flag default
-				teamConfig.TeammateModel = slingTeammateTier
+				teamConfig.TeammateModel = teammateModel
+			} else if err := resolveRigTeammateModel(teamConfig, target, townRoot); err != nil {
+				return err
+			}
+			if slingTeammateBudget > 0 {
+				teamConfig.BudgetUSD = slingTeammateBudget
 			}
-			fmt.Printf("  Using rig-level team defaults (max_teammates=%d, teammate_model=%s)\n",
-				teamConfig.MaxTeammates, teamConfig.TeammateModel)
+			fmt.Printf("  Using rig-level team defaults (%s)\n", formatTeamConfig(teamConfig))
+		}
+	}
+
+	// Rigs can cap team size below the global maximum
+	if teamConfig != nil && teamConfig.Enabled {
+		if err := checkRigTeamSize(teamConfig.MaxTeammates, target, townRoot); err != nil {
+			return err
+		}
+	}
+
+	// Check formula variables before resolveTarget spawns anything. With
+	// agent teams, polecat work uses mol-polecat-work-team unless a formula
+	// was named.
+	checkFormula := formulaName
+	if checkFormula == "" && teamConfig != nil && teamConfig.Enabled && !slingHookRawBead {
+		checkFormula = "mol-polecat-work-team"
+	}
+	if checkFormula != "" {
+		vars := append([]string{"feature=" + beadID, "issue=" + beadID}, slingVars...)
+		if teamConfig != nil && teamConfig.Enabled {
+			vars = append(vars, teamFormulaVars(teamConfig)...)
+		}
+		if err := checkFormulaVars(checkFormula, vars); err != nil {
+			return err
 		}
 	}
 
@@ -498,8 +544,7 @@ func runSling(cmd *cobra.Command, args []string) error {
 			fmt.Printf("  args (in nudge): %s\n", slingArgs)
 		}
 		if teamConfig != nil && teamConfig.Enabled {
-			fmt.Printf("  team: enabled (max_teammates=%d, teammate_model=%s)\n",
-				teamConfig.MaxTeammates, teamConfig.TeammateModel)
+			fmt.Printf("  team: enabled (%s)\n", formatTeamConfig(teamConfig))
 		}
 		fmt.Printf("Would inject start prompt to pane: %s\n", targetPane)
 		return nil
@@ -509,10 +554,7 @@ func runSling(cmd *cobra.Command, args []string) error {
 	if formulaName != "" {
 		// Inject team variables into formula when agent teams are enabled
 		if teamConfig != nil && teamConfig.Enabled {
-			slingVars = append(slingVars,
-				fmt.Sprintf("max_teammates=%d", teamConfig.MaxTeammates),
-				fmt.Sprintf("teammate_model=%s", teamConfig.TeammateModel),
-			)
+			slingVars = append(slingVars, teamFormulaVars(teamConfig)...)
 		}
 
 		fmt.Printf("  Instantiating formula %s...\n", formulaName)
@@ -719,36 +761,99 @@ func rollbackSlingArtifacts(spawnInfo *SpawnedPolecatInfo, beadID, hookWorkDir s
 	cleanupSpawnedPolecat(spawnInfo, spawnInfo.RigName)
 }
 
-// loadRigTeamDefaults extracts a rig name from a target string and loads
-// team defaults from the rig's settings/config.json. Returns nil if the
-// target isn't a rig, settings don't exist, or team isn't configured.
+// loadRigTeamDefaults loads team defaults from the target rig's
+// settings/config.json. Returns nil if the target isn't a rig, settings
+// don't exist, or team isn't enabled.
 func loadRigTeamDefaults(target, townRoot string) *config.TeamConfig {
-	// Extract rig name from target: bare name ("gastown") or path ("gastown/polecats/Toast")
-	rigName := target
-	if strings.Contains(target, "/") {
-		rigName = strings.SplitN(target, "/", 2)[0]
+	_, team := loadRigTeamSettings(target, townRoot)
+	if team == nil || !team.Enabled {
+		return nil
 	}
 
-	// Verify it's actually a rig (avoid loading settings for "mayor", "deacon", etc.)
-	if _, isRig := IsRigName(rigName); !isRig {
+	// Return a copy so callers can modify without affecting the loaded settings
+	return &config.TeamConfig{
+		Enabled:         true,
+		MaxTeammates:    team.MaxTeammates,
+		MaxTeammatesCap: team.MaxTeammatesCap,
+		TeammateModel:   team.TeammateModel,
+		BudgetUSD:       team.BudgetUSD,
+		DelegateMode:    team.DelegateMode,
+	}
+}
+
+// resolveRigTeammateModel checks a rig's default teammate_model the way
+// --teammate-tier is checked, replacing it with the model it resolves to.
+func resolveRigTeammateModel(teamConfig *config.TeamConfig, target, townRoot string) error {
+	if teamConfig.TeammateModel == "" {
 		return nil
 	}
+	_, rigPath, _ := targetRig(target, townRoot)
+	model, ok := lookupTeammateModel(teamConfig.TeammateModel, townRoot, rigPath)
+	if !ok {
+		return fmt.Errorf("invalid team.teammate_model '%s' in %s: must be opus, sonnet, haiku, or a model from a configured backend (see 'gt backend list')",
+			teamConfig.TeammateModel, config.RigSettingsPath(rigPath))
+	}
+	teamConfig.TeammateModel = model
+	return nil
+}
+
+// formatTeamConfig describes a team config's settings for sling output.
+func formatTeamConfig(teamConfig *config.TeamConfig) string {
+	desc := fmt.Sprintf("max_teammates=%d, teammate_model=%s", teamConfig.MaxTeammates, teamConfig.TeammateModel)
+	if teamConfig.BudgetUSD > 0 {
+		desc += fmt.Sprintf(", budget_usd=%.2f per teammate", teamConfig.BudgetUSD)
+	}
+	return desc
+}
+
+// checkRigTeamSize returns an error if teamSize exceeds the target rig's
+// max_teammates_cap. Targets that aren't rigs, or rigs without a cap, only
+// get the global maximum checked when the flag is parsed.
+func checkRigTeamSize(teamSize int, target, townRoot string) error {
+	if target == "" {
+		return nil
+	}
+	rigName, team := loadRigTeamSettings(target, townRoot)
+	if team == nil || team.MaxTeammatesCap <= 0 {
+		return nil
+	}
+	if limit := min(maxTeamSize, team.MaxTeammatesCap); teamSize > limit {
+		return fmt.Errorf("--team-size must be between 1 and %d for rig %s (got %d)", limit, rigName, teamSize)
+	}
+	return nil
+}
+
+// loadRigTeamSettings loads the team block from the target rig's
+// settings/config.json, enabled or not. Returns a nil TeamConfig if the
+// target isn't a rig, settings don't exist, or there is no team block.
+func loadRigTeamSettings(target, townRoot string) (string, *config.TeamConfig) {
+	rigName, rigPath, ok := targetRig(target, townRoot)
+	if !ok {
+		return rigName, nil
+	}
 
-	rigPath := filepath.Join(townRoot, rigName)
 	settings, err := config.LoadRigSettings(config.RigSettingsPath(rigPath))
 	if err != nil {
-		return nil // No settings file or parse error — no defaults
+		return rigName, nil // No settings file or parse error — no team settings
 	}
+	return rigName, settings.Team
+}
 
-	if settings.Team == nil || !settings.Team.Enabled {
-		return nil
+// targetRig extracts the rig name from a sling target, bare ("gastown") or
+// a path ("gastown/polecats/Toast"), and returns it with the rig's path.
+// The path is empty and ok is false if the target isn't a rig.
+func targetRig(target, townRoot string) (rigName, rigPath string, ok bool) {
+	rigName = target
+	if strings.Contains(target, "/") {
+		rigName = strings.SplitN(target, "/", 2)[0]
 	}
 
-	// Return a copy so callers can modify without affecting the loaded settings
-	return &config.TeamConfig{
-		Enabled:       true,
-		MaxTeammates:  settings.Team.MaxTeammates,
-		TeammateModel: settings.Team.TeammateModel,
-		DelegateMode:  settings.Team.DelegateMode,
+	// Verify it's actually a rig (avoid loading settings for "mayor", "deacon", etc.)
+	if rigName == "" {
+		return rigName, "", false
+	}
+	if _, isRig := IsRigName(rigName); !isRig {
+		return rigName, "", false
 	}
+	return rigName, filepath.Join(townRoot, rigName), true
 }
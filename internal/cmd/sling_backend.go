@@ -4,21 +4,26 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/steveyegge/gastown/internal/backend"
-	"github.com/steveyegge/gastown/internal/backend/bedrock"
-	"github.com/steveyegge/gastown/internal/backend/claude"
-	"github.com/steveyegge/gastown/internal/backend/grok"
-	"github.com/steveyegge/gastown/internal/backend/openai"
+	"github.com/steveyegge/gastown/internal/backend/backends"
 	"github.com/steveyegge/gastown/internal/beads"
 	"github.com/steveyegge/gastown/internal/config"
+	"github.com/steveyegge/gastown/internal/style"
 )
 
+// defaultAPIResponseTokens is the response limit for API invocations of
+// models without a reserve_tokens entry.
+const defaultAPIResponseTokens = 4096
+
 // BackendDispatcher handles API backend routing and execution.
 type BackendDispatcher struct {
 	config         *config.BackendConfig
@@ -26,6 +31,29 @@ type BackendDispatcher struct {
 	contextManager *backend.ContextManager
 	costTracker    *backend.CostTracker
 	initialized    bool
+
+	// townRoot is where unavailable models are recorded (optional).
+	townRoot string
+
+	// rigCostTracker records costs for the dispatching rig alongside the
+	// global costTracker (nil outside a rig).
+	rigCostTracker *backend.CostTracker
+
+	// issues loads beads for routing decisions. Defaults to shelling out to bd.
+	issues IssueFetcher
+}
+
+// IssueFetcher loads the issue a bead refers to for routing decisions.
+type IssueFetcher interface {
+	FetchIssue(beadID, townRoot string) (*beads.Issue, error)
+}
+
+// bdIssueFetcher fetches issues by running bd show.
+type bdIssueFetcher struct{}
+
+// FetchIssue implements IssueFetcher.
+func (bdIssueFetcher) FetchIssue(beadID, townRoot string) (*beads.Issue, error) {
+	return fetchIssueForRouting(beadID, townRoot)
 }
 
 // NewBackendDispatcher creates a dispatcher with the given config.
@@ -34,21 +62,78 @@ func NewBackendDispatcher(cfg *config.BackendConfig) *BackendDispatcher {
 		cfg = config.NewBackendConfig()
 	}
 
-	// Convert config to routing config
+	routingCfg := routingConfigFromBackendConfig(cfg)
+
+	applyModelAliases(cfg)
+
+	contextManager := backend.NewContextManager()
+	for model, reserve := range cfg.ReserveTokens {
+		contextManager.ModelReserveTokens[model] = reserve
+	}
+
+	return &BackendDispatcher{
+		config:         cfg,
+		router:         backend.NewRouter(routingCfg),
+		contextManager: contextManager,
+		costTracker:    backend.GetCostTracker(),
+		issues:         bdIssueFetcher{},
+	}
+}
+
+// routingConfigFromBackendConfig converts the user-facing backend config into
+// the router's config. With no user settings it matches
+// backend.DefaultRoutingConfig.
+func routingConfigFromBackendConfig(cfg *config.BackendConfig) *backend.RoutingConfig {
 	routingCfg := &backend.RoutingConfig{
 		Enabled:        cfg.Enabled,
+		DefaultRoute:   backend.RouteCLI,
 		DefaultBackend: cfg.DefaultBackend,
 		DefaultModel:   cfg.DefaultModel,
 		CostThreshold:  cfg.CostThreshold,
 		TokenThreshold: cfg.TokenThreshold,
 		FallbackToCLI:  cfg.FallbackToCLI,
+
+		BalancedCostWeight: cfg.BalancedCostWeight,
+		ForceCLILabels:     cfg.ForceCLILabels,
+		Patterns:           cfg.AnalyzerPatterns,
+	}
+
+	// Convert model table entries; the router validates the rest
+	for _, m := range cfg.Models {
+		if m == nil {
+			continue
+		}
+		tier, err := backend.ParseModelTier(m.Tier)
+		if err != nil {
+			log.Printf("[backend] Ignoring model %s/%s: %v", m.Backend, m.Model, err)
+			continue
+		}
+		routingCfg.Models = append(routingCfg.Models, backend.ModelCapability{
+			Backend:    m.Backend,
+			Model:      m.Model,
+			Tier:       tier,
+			CostPer1K:  m.CostPer1K,
+			SpeedScore: m.SpeedScore,
+		})
+	}
+
+	// Convert per-model speed/cost overrides
+	if len(cfg.ModelOverrides) > 0 {
+		routingCfg.ModelOverrides = make(map[string]backend.ModelOverride, len(cfg.ModelOverrides))
+		for key, o := range cfg.ModelOverrides {
+			if o == nil {
+				continue
+			}
+			routingCfg.ModelOverrides[key] = backend.ModelOverride{
+				SpeedScore: o.SpeedScore,
+				CostPer1K:  o.CostPer1K,
+			}
+		}
 	}
 
 	if cfg.Routing != nil {
 		if cfg.Routing.DefaultRoute == "api" {
 			routingCfg.DefaultRoute = backend.RouteAPI
-		} else {
-			routingCfg.DefaultRoute = backend.RouteCLI
 		}
 
 		// Convert routing rules
@@ -68,57 +153,109 @@ func NewBackendDispatcher(cfg *config.BackendConfig) *BackendDispatcher {
 				Route:         route,
 				Backend:       rule.Backend,
 				Model:         rule.Model,
+				WriteBack:     rule.WriteBack,
 			})
 		}
 	}
 
-	return &BackendDispatcher{
-		config:         cfg,
-		router:         backend.NewRouter(routingCfg),
-		contextManager: backend.NewContextManager(),
-		costTracker:    backend.GetCostTracker(),
+	return routingCfg
+}
+
+// applyModelAliases installs configured model aliases in the backend package.
+func applyModelAliases(cfg *config.BackendConfig) {
+	backend.SetModelAliases("", cfg.ModelAliases)
+	for name, entry := range cfg.Backends {
+		if entry != nil {
+			backend.SetModelAliases(name, entry.ModelAliases)
+		}
 	}
 }
 
-// Initialize registers available backends based on config.
-func (d *BackendDispatcher) Initialize() error {
-	if d.initialized {
-		return nil
+// SetIssueFetcher replaces how the dispatcher loads beads for routing.
+func (d *BackendDispatcher) SetIssueFetcher(f IssueFetcher) {
+	d.issues = f
+}
+
+// resolveTeammateModel maps a --teammate-tier value to the model teammates
+// run. The Claude tiers (opus, sonnet, haiku) are kept as-is; other
+// TierToBackend tags (e.g. "gpt4") map to their model, and model IDs known
+// to TierToBackend or advertised by a backend enabled in the town's or
+// rig's backend config (e.g. "grok-3", "gpt-4o") are accepted directly.
+// Matching is case-insensitive.
+func resolveTeammateModel(tier, townRoot, rigPath string) (string, error) {
+	if model, ok := lookupTeammateModel(tier, townRoot, rigPath); ok {
+		return model, nil
+	}
+	return "", fmt.Errorf("invalid --teammate-tier '%s': must be opus, sonnet, haiku, or a model from a configured backend (see 'gt backend list')", tier)
+}
+
+// lookupTeammateModel resolves a teammate tier or model name the way
+// resolveTeammateModel does, reporting whether it is known.
+func lookupTeammateModel(tier, townRoot, rigPath string) (string, bool) {
+	key := strings.ToLower(tier)
+	switch key {
+	case "opus", "sonnet", "haiku":
+		return key, true
+	}
+	if mapping, ok := backend.TierToBackend[key]; ok {
+		return mapping.Model, true
 	}
 
-	// Register Claude backend if enabled
-	if entry, ok := d.config.Backends["claude"]; ok && entry.Enabled {
-		if err := claude.Register(); err != nil {
-			log.Printf("[backend] Claude backend unavailable: %v", err)
-		} else {
-			log.Printf("[backend] Claude backend registered")
-		}
+	known := make(map[string]string)
+	for _, mapping := range backend.TierToBackend {
+		known[strings.ToLower(mapping.Model)] = mapping.Model
+	}
+	if model, ok := known[key]; ok {
+		return model, true
 	}
 
-	// Register OpenAI backend if enabled
-	if entry, ok := d.config.Backends["openai"]; ok && entry.Enabled {
-		if err := openai.Register(); err != nil {
-			log.Printf("[backend] OpenAI backend unavailable: %v", err)
-		} else {
-			log.Printf("[backend] OpenAI backend registered")
+	// Nothing has registered backends this early in sling, so load the
+	// configured ones before asking the registry for their models
+	registerConfiguredBackends(townRoot, rigPath)
+	registry := backend.GetRegistry()
+	for _, name := range registry.List() {
+		b, err := registry.Get(name)
+		if err != nil {
+			continue
+		}
+		for _, model := range b.AvailableModels() {
+			known[strings.ToLower(model)] = model
 		}
 	}
+	if model, ok := known[key]; ok {
+		return model, true
+	}
+	return "", false
+}
 
-	// Register Grok backend if enabled
-	if entry, ok := d.config.Backends["grok"]; ok && entry.Enabled {
-		if err := grok.Register(); err != nil {
-			log.Printf("[backend] Grok backend unavailable: %v", err)
-		} else {
-			log.Printf("[backend] Grok backend registered")
-		}
+// registerConfiguredBackends registers the backends enabled in the
+// resolved backend config for townRoot and rigPath. A no-op outside a town.
+func registerConfiguredBackends(townRoot, rigPath string) {
+	if townRoot == "" {
+		return
+	}
+	d := NewBackendDispatcher(config.ResolveBackendConfig(townRoot, rigPath))
+	if err := d.Initialize(); err != nil {
+		log.Printf("[backend] Failed to initialize backends: %v", err)
 	}
+}
 
-	// Register Bedrock backend if enabled
-	if entry, ok := d.config.Backends["bedrock"]; ok && entry.Enabled {
-		if err := bedrock.Register(); err != nil {
-			log.Printf("[backend] Bedrock backend unavailable: %v", err)
+// Initialize registers available backends based on config.
+func (d *BackendDispatcher) Initialize() error {
+	if d.initialized {
+		return nil
+	}
+
+	// Register each enabled backend the way gt doctor checks it
+	for _, spec := range backends.Known {
+		entry, ok := d.config.Backends[spec.Name]
+		if !ok || !entry.Enabled {
+			continue
+		}
+		if err := spec.Register(entry); err != nil {
+			log.Printf("[backend] %s backend unavailable: %v", spec.Label, err)
 		} else {
-			log.Printf("[backend] Bedrock backend registered")
+			log.Printf("[backend] %s backend registered", spec.Label)
 		}
 	}
 
@@ -162,19 +299,90 @@ func (d *BackendDispatcher) extractHints(issue *beads.Issue, step *beads.Molecul
 
 		// Extract intent from labels
 		hints.Intent = backend.ExtractIntent(issue.Labels)
-
-		// Estimate tokens from description
-		hints.EstimatedTokens = len(issue.Description) / 4 // Rough estimate
 	}
 
-	if step != nil {
+	// A step's declared tier is more specific than the issue's model
+	// label, which the router would otherwise consult first
+	if step != nil && step.Tier != "" {
 		hints.Tier = step.Tier
+		hints.ModelTag = ""
+	}
+
+	// Estimate the prompt with the tokenizer for the hinted model (the
+	// heuristic counter when no model is hinted)
+	if issue != nil {
+		model := hints.ModelTag
+		if model == "" {
+			model = hints.Tier
+		}
+		if tokens, err := backend.CountTokens(d.buildMessages(issue, step), model); err == nil {
+			hints.EstimatedTokens = tokens
+		} else {
+			log.Printf("[backend] Could not count tokens for %s: %v", issue.ID, err)
+		}
 	}
 
 	return hints
 }
 
-// ExecuteAPIBackend executes a task via API backend.
+// writeBackAPIResult records an API response on the bead and closes it,
+// mirroring what a CLI agent does when it finishes work.
+func writeBackAPIResult(bd *beads.Beads, beadID string, result *BackendExecutionResult) error {
+	comment := fmt.Sprintf("API backend response (%s):\n\n%s", result.Model, result.Content)
+	if _, err := bd.Run("comment", beadID, comment); err != nil {
+		return fmt.Errorf("adding comment: %w", err)
+	}
+
+	reason := fmt.Sprintf("Completed via API backend (%s)", result.Model)
+	if err := bd.CloseWithReason(reason, beadID); err != nil {
+		return fmt.Errorf("closing bead: %w", err)
+	}
+
+	return nil
+}
+
+// responseTokens is the response limit sent with API invocations of model:
+// the configured reserve_tokens entry, or defaultAPIResponseTokens. Context
+// is trimmed to leave exactly this much room for the reply.
+func (d *BackendDispatcher) responseTokens(model string) int {
+	if n := d.contextManager.ModelReserveTokens[model]; n > 0 {
+		return n
+	}
+	return defaultAPIResponseTokens
+}
+
+// PreviewAPIBackend estimates the cost of an API route without invoking it.
+func (d *BackendDispatcher) PreviewAPIBackend(
+	route *backend.RouteResult,
+	issue *beads.Issue,
+	step *beads.MoleculeStep,
+) (backend.CostEstimate, error) {
+	if err := d.Initialize(); err != nil {
+		return backend.CostEstimate{}, fmt.Errorf("initializing backends: %w", err)
+	}
+
+	b, err := backend.GetRegistry().Get(route.Backend)
+	if err != nil {
+		return backend.CostEstimate{}, fmt.Errorf("backend %s not available: %w", route.Backend, err)
+	}
+
+	model := route.Model
+	if model == "" {
+		model = b.DefaultModel()
+	}
+
+	messages, err := d.contextManager.PrepareContextForResponse(d.buildMessages(issue, step), model, b.MaxContextTokens(model), d.responseTokens(model), backend.TruncateOldest)
+	if err != nil {
+		return backend.CostEstimate{}, fmt.Errorf("preparing context: %w", err)
+	}
+
+	tokenEstimate, _ := b.CountTokens(messages, model)
+	return b.EstimateCost(tokenEstimate, tokenEstimate/4, model), nil
+}
+
+// ExecuteAPIBackend executes a task via API backend. If the routed backend
+// fails, each backend in the configured fallback chain is tried in turn
+// before giving up.
 func (d *BackendDispatcher) ExecuteAPIBackend(
 	ctx context.Context,
 	route *backend.RouteResult,
@@ -185,38 +393,87 @@ func (d *BackendDispatcher) ExecuteAPIBackend(
 		return nil, fmt.Errorf("initializing backends: %w", err)
 	}
 
-	// Get the backend
-	registry := backend.GetRegistry()
-	b, err := registry.Get(route.Backend)
-	if err != nil {
-		if route.FallbackToCLI {
-			return &BackendExecutionResult{
-				FallbackToCLI: true,
-				Reason:        fmt.Sprintf("backend %s not available: %v", route.Backend, err),
-			}, nil
+	attempts := d.backendAttempts(route)
+	var lastErr error
+	for i, attempt := range attempts {
+		result, err := d.executeOnBackend(ctx, attempt.backend, attempt.model, route, issue, step)
+		if err == nil {
+			if i > 0 {
+				log.Printf("[backend] %s served the request after %d failed backend(s)", attempt.backend, i)
+			}
+			return result, nil
+		}
+
+		// The budget applies to every backend, so don't shop around
+		if errors.Is(err, backend.ErrBudgetExceeded) {
+			return nil, err
+		}
+
+		lastErr = err
+		if i < len(attempts)-1 {
+			log.Printf("[backend] %v; trying %s", err, attempts[i+1].backend)
+		}
+	}
+
+	if route.FallbackToCLI {
+		return &BackendExecutionResult{
+			FallbackToCLI: true,
+			Reason:        lastErr.Error(),
+		}, nil
+	}
+	return nil, lastErr
+}
+
+// backendAttempt is a backend/model pair ExecuteAPIBackend may try.
+type backendAttempt struct {
+	backend string
+	model   string // empty uses the backend's default model
+}
+
+// backendAttempts returns the routed backend followed by the configured
+// fallback chain. Fallback backends use their default model, since the
+// routed model ID is specific to its provider.
+func (d *BackendDispatcher) backendAttempts(route *backend.RouteResult) []backendAttempt {
+	attempts := []backendAttempt{{backend: route.Backend, model: route.Model}}
+	seen := map[string]bool{route.Backend: true}
+	for _, name := range d.config.FallbackChain {
+		if seen[name] {
+			continue
 		}
-		return nil, fmt.Errorf("backend %s not available: %w", route.Backend, err)
+		seen[name] = true
+		attempts = append(attempts, backendAttempt{backend: name})
+	}
+	return attempts
+}
+
+// executeOnBackend runs a task on one backend, preparing context for that
+// model's window. Cost gates may return a CLI-fallback result; an error
+// means this backend failed and another may be tried.
+func (d *BackendDispatcher) executeOnBackend(
+	ctx context.Context,
+	backendName, model string,
+	route *backend.RouteResult,
+	issue *beads.Issue,
+	step *beads.MoleculeStep,
+) (*BackendExecutionResult, error) {
+	b, err := backend.GetRegistry().Get(backendName)
+	if err != nil {
+		return nil, fmt.Errorf("backend %s not available: %w", backendName, err)
 	}
 
 	// Build messages from issue context
 	messages := d.buildMessages(issue, step)
 
 	// Prepare context (trim if needed)
-	model := route.Model
 	if model == "" {
 		model = b.DefaultModel()
 	}
 
 	maxTokens := b.MaxContextTokens(model)
-	messages, err = d.contextManager.PrepareContext(messages, maxTokens, backend.TruncateOldest)
+	responseTokens := d.responseTokens(model)
+	messages, err = d.contextManager.PrepareContextForResponse(messages, model, maxTokens, responseTokens, backend.TruncateOldest)
 	if err != nil {
-		if route.FallbackToCLI {
-			return &BackendExecutionResult{
-				FallbackToCLI: true,
-				Reason:        fmt.Sprintf("context preparation failed: %v", err),
-			}, nil
-		}
-		return nil, fmt.Errorf("preparing context: %w", err)
+		return nil, fmt.Errorf("context preparation failed on %s/%s: %w", backendName, model, err)
 	}
 
 	// Estimate cost before invocation
@@ -234,42 +491,120 @@ func (d *BackendDispatcher) ExecuteAPIBackend(
 		log.Printf("[backend] Warning: estimated cost $%.4f exceeds threshold $%.2f", costEstimate.TotalCost, d.config.CostThreshold)
 	}
 
+	// Unlike the threshold, the session budget is a hard stop
+	if d.config.SessionBudget > 0 {
+		// Pick up spend other gt processes recorded since we loaded
+		if err := d.costTracker.Refresh(); err != nil {
+			log.Printf("[backend] Could not refresh recorded spend: %v", err)
+		}
+		spent := d.costTracker.Total()
+		if spent+costEstimate.TotalCost > d.config.SessionBudget {
+			reason := fmt.Sprintf("estimated cost $%.4f would exceed session budget ($%.2f of $%.2f spent since the last reset; run 'gt cost --reset' to start a new budget)",
+				costEstimate.TotalCost, spent, d.config.SessionBudget)
+			if route.FallbackToCLI {
+				return &BackendExecutionResult{
+					FallbackToCLI: true,
+					Reason:        reason,
+				}, nil
+			}
+			return nil, fmt.Errorf("%w: %s", backend.ErrBudgetExceeded, reason)
+		}
+	}
+
+	// Agent-team members are each held to the team's per-teammate budget
+	if err := d.costTracker.CheckTeammateBudget(costEstimate.TotalCost); err != nil {
+		if route.FallbackToCLI {
+			return &BackendExecutionResult{
+				FallbackToCLI: true,
+				Reason:        err.Error(),
+			}, nil
+		}
+		return nil, err
+	}
+
 	// Invoke the backend
 	startTime := time.Now()
 	result, err := b.Invoke(ctx, messages, backend.InvokeOptions{
 		Model:     model,
-		MaxTokens: 4096, // Default response limit
+		MaxTokens: responseTokens,
 	})
 	duration := time.Since(startTime)
 
 	if err != nil {
-		if route.FallbackToCLI {
-			return &BackendExecutionResult{
-				FallbackToCLI: true,
-				Reason:        fmt.Sprintf("backend invocation failed: %v", err),
-			}, nil
+		if errors.Is(err, backend.ErrModelUnavailable) {
+			d.markModelUnavailable(backendName, model, err)
 		}
-		return nil, fmt.Errorf("backend invocation failed: %w", err)
+		return nil, fmt.Errorf("backend %s invocation failed: %w", backendName, err)
 	}
 
-	// Record actual cost
+	// Record actual cost against the issue, tagged with its labels for analytics
+	var beadID string
+	var labels []string
+	if issue != nil {
+		beadID = issue.ID
+		labels = issue.Labels
+	}
 	actualCost := b.EstimateCost(result.InputTokens, result.OutputTokens, model)
-	d.costTracker.Record(route.Backend, model, result, actualCost)
+	d.costTracker.RecordForBead(backendName, model, beadID, labels, result, actualCost)
+	if d.rigCostTracker != nil {
+		d.rigCostTracker.RecordForBead(backendName, model, beadID, labels, result, actualCost)
+	}
+
+	log.Printf("[backend] %s/%s completed in %v (in=%d, out=%d, cost=$%.4f, labels=%s)",
+		backendName, model, duration, result.InputTokens, result.OutputTokens, actualCost.TotalCost,
+		strings.Join(labels, ","))
+	if d.config.SessionBudget > 0 {
+		log.Printf("[backend] Session budget: $%.4f of $%.2f remaining",
+			sessionBudgetRemaining(d.config.SessionBudget, d.costTracker.Total()), d.config.SessionBudget)
+	}
 
-	log.Printf("[backend] %s/%s completed in %v (in=%d, out=%d, cost=$%.4f)",
-		route.Backend, model, duration, result.InputTokens, result.OutputTokens, actualCost.TotalCost)
+	truncated := backend.TruncatedByLength(result.FinishReason)
+	if truncated {
+		log.Printf("[backend] %s/%s response stopped at the %d-token limit (finish reason %q); raise reserve_tokens for %s",
+			backendName, model, responseTokens, result.FinishReason, model)
+	}
 
 	return &BackendExecutionResult{
 		Success:      true,
+		Backend:      backendName,
 		Content:      result.Content,
 		Model:        result.Model,
 		InputTokens:  result.InputTokens,
 		OutputTokens: result.OutputTokens,
 		Cost:         actualCost,
 		Duration:     duration,
+		FinishReason: result.FinishReason,
+		Truncated:    truncated,
 	}, nil
 }
 
+// sessionBudgetRemaining returns how much of budget is left after spent,
+// never less than zero.
+func sessionBudgetRemaining(budget, spent float64) float64 {
+	if spent >= budget {
+		return 0
+	}
+	return budget - spent
+}
+
+// markModelUnavailable stops routing to a model the provider reported as
+// retired, and records it so gt doctor can surface it.
+func (d *BackendDispatcher) markModelUnavailable(backendName, model string, cause error) {
+	d.router.ExcludeModel(backendName, model)
+
+	if d.townRoot == "" {
+		return
+	}
+	if err := backend.RecordUnavailableModel(d.townRoot, backend.UnavailableModel{
+		Backend:    backendName,
+		Model:      model,
+		Reason:     cause.Error(),
+		DetectedAt: time.Now(),
+	}); err != nil {
+		log.Printf("[backend] Could not record unavailable model %s/%s: %v", backendName, model, err)
+	}
+}
+
 // buildMessages constructs the message list for API invocation.
 func (d *BackendDispatcher) buildMessages(issue *beads.Issue, step *beads.MoleculeStep) []backend.Message {
 	var messages []backend.Message
@@ -351,6 +686,10 @@ type BackendExecutionResult struct {
 	// Reason explains the result (success message or failure reason).
 	Reason string
 
+	// Backend is the backend that served the request, which differs from
+	// the routed backend when the fallback chain was used.
+	Backend string
+
 	// Content is the response from the API.
 	Content string
 
@@ -368,6 +707,12 @@ type BackendExecutionResult struct {
 
 	// Duration is how long the API call took.
 	Duration time.Duration
+
+	// FinishReason is why the model stopped, as reported by the backend.
+	FinishReason string
+
+	// Truncated indicates the response stopped at the token limit.
+	Truncated bool
 }
 
 // globalDispatcher is the singleton dispatcher instance.
@@ -390,25 +735,64 @@ func SetBackendDispatcher(d *BackendDispatcher) {
 // InitializeBackendDispatcher initializes the global dispatcher with config.
 func InitializeBackendDispatcher(townRoot, rigPath string) *BackendDispatcher {
 	cfg := config.ResolveBackendConfig(townRoot, rigPath)
+	if townRoot != "" {
+		backend.SetCostTrackerPath(backend.CostTrackerPath(townRoot))
+	}
 	d := NewBackendDispatcher(cfg)
+	d.townRoot = townRoot
+	if rigPath != "" {
+		d.rigCostTracker = backend.GetRigCostTracker(rigPath)
+	}
+	if teammate, budget := teammateBudgetFromEnv(); budget > 0 {
+		d.costTracker.SetTeammateBudget(teammate, budget)
+	}
 	SetBackendDispatcher(d)
 	return d
 }
 
+// teammateBudgetFromEnv returns the teammate this process runs for and its
+// budget, as set in an agent-team session (see config.EnvTeammateBudget).
+// Outside a team session, or with an unparseable budget, it returns 0.
+func teammateBudgetFromEnv() (string, float64) {
+	value := os.Getenv(config.EnvTeammateBudget)
+	if value == "" {
+		return "", 0
+	}
+	budget, err := strconv.ParseFloat(value, 64)
+	if err != nil || budget <= 0 {
+		log.Printf("[backend] Ignoring invalid %s=%q", config.EnvTeammateBudget, value)
+		return "", 0
+	}
+	teammate := os.Getenv(config.EnvTeammate)
+	if teammate == "" {
+		teammate = os.Getenv("BD_ACTOR")
+	}
+	return teammate, budget
+}
+
 // TryAPIBackendForBead checks if a bead should be handled by API backend.
 // Returns (handled, error) - if handled is true, the bead was processed via API.
 // If handled is false, the caller should continue with CLI dispatch.
-func TryAPIBackendForBead(beadID, townRoot, rigPath string) (bool, error) {
+//
+// In dry-run mode the routing decision and cost estimate are printed but the
+// backend is never invoked. A dry-run API route still reports handled=true so
+// the caller doesn't describe a CLI dispatch that wouldn't happen.
+func TryAPIBackendForBead(beadID, townRoot, rigPath string, dryRun bool) (bool, error) {
 	// Initialize dispatcher with config
 	dispatcher := InitializeBackendDispatcher(townRoot, rigPath)
+	return tryAPIBackendForBead(dispatcher, beadID, townRoot, dryRun)
+}
 
+// tryAPIBackendForBead is TryAPIBackendForBead with the dispatcher supplied,
+// so tests can inject a fake IssueFetcher.
+func tryAPIBackendForBead(dispatcher *BackendDispatcher, beadID, townRoot string, dryRun bool) (bool, error) {
 	// Check if API routing is enabled at all
 	if !dispatcher.config.Enabled {
 		return false, nil
 	}
 
 	// Fetch the issue to check routing hints
-	issue, err := fetchIssueForRouting(beadID, townRoot)
+	issue, err := dispatcher.issues.FetchIssue(beadID, townRoot)
 	if err != nil {
 		// Can't fetch issue - fall back to CLI
 		log.Printf("[backend] Could not fetch issue %s for routing: %v", beadID, err)
@@ -417,12 +801,15 @@ func TryAPIBackendForBead(beadID, townRoot, rigPath string) (bool, error) {
 
 	// Check if we should route to API
 	route, shouldRoute := dispatcher.ShouldRouteToAPI(issue, nil)
+	if dryRun {
+		return previewAPIRoute(dispatcher, beadID, route, shouldRoute, issue)
+	}
 	if !shouldRoute {
 		return false, nil
 	}
 
-	log.Printf("[backend] Routing bead %s to API backend: %s/%s (reason: %s)",
-		beadID, route.Backend, route.Model, route.Reason)
+	log.Printf("[backend] Routing bead %s to API backend: %s/%s (reason: %s, labels=%s)",
+		beadID, route.Backend, route.Model, route.Reason, strings.Join(issue.Labels, ","))
 
 	// Execute via API backend
 	ctx := context.Background()
@@ -444,14 +831,48 @@ func TryAPIBackendForBead(beadID, townRoot, rigPath string) (bool, error) {
 	if result.Success {
 		log.Printf("[backend] API backend completed successfully for %s", beadID)
 		// The bead is handled - caller should not dispatch to CLI
-		fmt.Printf("Bead %s completed via API backend (%s)\n", beadID, result.Model)
+		fmt.Printf("Bead %s completed via API backend (%s/%s)\n", beadID, result.Backend, result.Model)
 		fmt.Printf("Response:\n%s\n", result.Content)
+		if result.Truncated {
+			fmt.Printf("%s Response stopped at the token limit; raise reserve_tokens for %s in settings/backend.json\n",
+				style.WarningPrefix, result.Model)
+		}
+
+		// Record the work product on the bead if a rule opted in
+		if route.Rule != nil && route.Rule.WriteBack {
+			if err := writeBackAPIResult(beads.New(townRoot), beadID, result); err != nil {
+				return true, fmt.Errorf("recording API result on %s: %w", beadID, err)
+			}
+		}
 		return true, nil
 	}
 
 	return false, nil
 }
 
+// previewAPIRoute prints what the dispatcher would do for a bead in dry-run mode.
+func previewAPIRoute(dispatcher *BackendDispatcher, beadID string, route *backend.RouteResult, shouldRoute bool, issue *beads.Issue) (bool, error) {
+	if !shouldRoute {
+		reason := "hybrid routing disabled"
+		if route != nil {
+			reason = route.Reason
+		}
+		fmt.Printf("Would dispatch %s to CLI agent (%s)\n", beadID, reason)
+		return false, nil
+	}
+
+	fmt.Printf("Would route %s to API backend %s/%s\n", beadID, route.Backend, route.Model)
+	fmt.Printf("  reason: %s\n", route.Reason)
+
+	estimate, err := dispatcher.PreviewAPIBackend(route, issue, nil)
+	if err != nil {
+		fmt.Printf("  cost estimate unavailable: %v\n", err)
+		return true, nil
+	}
+	fmt.Printf("  estimated cost: ~$%.4f (threshold $%.2f)\n", estimate.TotalCost, dispatcher.config.CostThreshold)
+	return true, nil
+}
+
 // fetchIssueForRouting fetches an issue's details for routing decisions.
 func fetchIssueForRouting(beadID, townRoot string) (*beads.Issue, error) {
 	cmd := exec.Command("bd", "--no-daemon", "show", beadID, "--json", "--allow-stale")
@@ -0,0 +1,633 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/backend"
+	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+// countingBackend is a fake AgentBackend that records invocations.
+type countingBackend struct {
+	name         string
+	finishReason string // FinishReason reported by Invoke
+	invokes      atomic.Int32
+	maxTokens    atomic.Int32 // MaxTokens of the last Invoke
+}
+
+func (b *countingBackend) Name() string                     { return b.name }
+func (b *countingBackend) Capabilities() backend.Capability { return 0 }
+func (b *countingBackend) AvailableModels() []string        { return []string{"haiku"} }
+func (b *countingBackend) DefaultModel() string             { return "haiku" }
+func (b *countingBackend) MaxContextTokens(string) int      { return 200000 }
+func (b *countingBackend) Healthy(context.Context) error    { return nil }
+func (b *countingBackend) CountTokens(messages []backend.Message, _ string) (int, error) {
+	return 1000, nil
+}
+func (b *countingBackend) EstimateCost(in, out int, model string) backend.CostEstimate {
+	return backend.CostEstimate{TotalCost: 0.0123, Currency: "USD", Model: model}
+}
+func (b *countingBackend) Invoke(_ context.Context, _ []backend.Message, opts backend.InvokeOptions) (*backend.InvokeResult, error) {
+	b.invokes.Add(1)
+	b.maxTokens.Store(int32(opts.MaxTokens))
+	return &backend.InvokeResult{Content: "done", Model: opts.Model, InputTokens: 10, OutputTokens: 5, FinishReason: b.finishReason}, nil
+}
+func (b *countingBackend) InvokeStream(_ context.Context, _ []backend.Message, _ backend.InvokeOptions) (<-chan backend.StreamChunk, error) {
+	b.invokes.Add(1)
+	ch := make(chan backend.StreamChunk, 1)
+	ch <- backend.StreamChunk{Content: "done", Done: true}
+	close(ch)
+	return ch, nil
+}
+
+func TestWriteBackAPIResult(t *testing.T) {
+	townRoot := t.TempDir()
+	binDir := filepath.Join(townRoot, "bin")
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		t.Fatalf("mkdir binDir: %v", err)
+	}
+	logPath := filepath.Join(townRoot, "bd.log")
+
+	bdScript := `#!/bin/sh
+echo "CMD:$*" >> "${BD_LOG}"
+echo ok
+exit 0
+`
+	bdScriptWindows := `@echo off
+echo CMD:%*>>"%BD_LOG%"
+echo ok
+exit /b 0
+`
+	_ = writeBDStub(t, binDir, bdScript, bdScriptWindows)
+	t.Setenv("BD_LOG", logPath)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	result := &BackendExecutionResult{
+		Success: true,
+		Content: "The answer is 42",
+		Model:   "haiku",
+	}
+	if err := writeBackAPIResult(beads.New(townRoot), "gt-abc123", result); err != nil {
+		t.Fatalf("writeBackAPIResult: %v", err)
+	}
+
+	logBytes, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read bd log: %v", err)
+	}
+	logContent := string(logBytes)
+
+	if !strings.Contains(logContent, "comment gt-abc123") {
+		t.Errorf("expected bd comment on gt-abc123, got:\n%s", logContent)
+	}
+	if !strings.Contains(logContent, "The answer is 42") {
+		t.Errorf("expected response content in comment, got:\n%s", logContent)
+	}
+	if !strings.Contains(logContent, "close gt-abc123") {
+		t.Errorf("expected bd close on gt-abc123, got:\n%s", logContent)
+	}
+}
+
+func TestRouteCarriesWriteBackRule(t *testing.T) {
+	cfg := config.NewBackendConfig()
+	cfg.Enabled = true
+	cfg.Routing = &config.BackendRoutingConfig{
+		DefaultRoute: "cli",
+		Rules: []config.BackendRoutingRule{
+			{Name: "docs", TypeMatch: []string{"docs"}, Route: "api", WriteBack: true},
+			{Name: "tasks", TypeMatch: []string{"task"}, Route: "api"},
+		},
+	}
+	d := NewBackendDispatcher(cfg)
+
+	tests := []struct {
+		name      string
+		issueType string
+		want      bool
+	}{
+		{"opted-in rule", "docs", true},
+		{"rule without write-back", "task", false},
+		{"no matching rule", "bug", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := d.router.Route(&backend.RoutingHints{Type: tt.issueType})
+			got := route.Rule != nil && route.Rule.WriteBack
+			if got != tt.want {
+				t.Errorf("write-back for type %q = %v, want %v (rule %+v)", tt.issueType, got, tt.want, route.Rule)
+			}
+		})
+	}
+}
+
+func TestExtractHintsPrefersStepTier(t *testing.T) {
+	d := NewBackendDispatcher(config.NewBackendConfig())
+	issue := &beads.Issue{Title: "Refactor", Labels: []string{"model:grok-fast"}}
+
+	hints := d.extractHints(issue, &beads.MoleculeStep{Tier: "opus"})
+	if hints.Tier != "opus" || hints.ModelTag != "" {
+		t.Errorf("with step tier: Tier=%q ModelTag=%q, want opus and no model tag", hints.Tier, hints.ModelTag)
+	}
+
+	hints = d.extractHints(issue, &beads.MoleculeStep{})
+	if hints.Tier != "" || hints.ModelTag != "grok-fast" {
+		t.Errorf("without step tier: Tier=%q ModelTag=%q, want the issue's model tag", hints.Tier, hints.ModelTag)
+	}
+}
+
+func TestExtractHintsCountsWithModelTokenizer(t *testing.T) {
+	t.Cleanup(backend.ResetTokenCountersForTesting)
+	var counted string
+	backend.RegisterTokenCounter(backend.FamilyGrok, backend.TokenCounterFunc(func(_ []backend.Message, model string) (int, error) {
+		counted = model
+		return 4242, nil
+	}))
+
+	d := NewBackendDispatcher(config.NewBackendConfig())
+	issue := &beads.Issue{Title: "Refactor", Description: "Split the parser", Labels: []string{"model:grok-3"}}
+	if hints := d.extractHints(issue, nil); hints.EstimatedTokens != 4242 || counted != "grok-3" {
+		t.Errorf("EstimatedTokens = %d counted with %q, want the grok counter's 4242 for grok-3", hints.EstimatedTokens, counted)
+	}
+
+	// Without a model hint the heuristic counts the full prompt, not just
+	// the description
+	issue.Labels = nil
+	hints := d.extractHints(issue, nil)
+	want, _ := backend.HeuristicTokenCounter.CountTokens(d.buildMessages(issue, nil), "")
+	if hints.EstimatedTokens != want || want <= len(issue.Description)/4 {
+		t.Errorf("EstimatedTokens = %d, want heuristic count %d of the prompt", hints.EstimatedTokens, want)
+	}
+}
+
+// fakeIssueFetcher is an IssueFetcher that serves issues from memory.
+type fakeIssueFetcher struct {
+	issues map[string]*beads.Issue
+}
+
+func (f *fakeIssueFetcher) FetchIssue(beadID, _ string) (*beads.Issue, error) {
+	issue, ok := f.issues[beadID]
+	if !ok {
+		return nil, fmt.Errorf("bead not found")
+	}
+	return issue, nil
+}
+
+// newTestAPIDispatcher returns a dispatcher with hybrid routing enabled,
+// no real backends, and a fake backend plus issue fetcher.
+func newTestAPIDispatcher(t *testing.T) (*BackendDispatcher, *countingBackend) {
+	t.Helper()
+
+	cfg := config.NewBackendConfig()
+	cfg.Enabled = true
+	for _, entry := range cfg.Backends {
+		entry.Enabled = false
+	}
+
+	backend.ResetRegistryForTesting()
+	t.Cleanup(backend.ResetRegistryForTesting)
+	fake := &countingBackend{name: "bedrock"}
+	backend.GetRegistry().Register(fake)
+
+	d := NewBackendDispatcher(cfg)
+	d.SetIssueFetcher(&fakeIssueFetcher{issues: map[string]*beads.Issue{
+		"gt-abc123": {
+			ID:          "gt-abc123",
+			Title:       "Summarize the release notes",
+			Type:        "task",
+			Description: "Summarize the changes",
+		},
+	}})
+	return d, fake
+}
+
+func TestTryAPIBackendForBeadDryRun(t *testing.T) {
+	d, fake := newTestAPIDispatcher(t)
+
+	var handled bool
+	var err error
+	output := captureStdout(t, func() {
+		handled, err = tryAPIBackendForBead(d, "gt-abc123", t.TempDir(), true)
+	})
+	if err != nil {
+		t.Fatalf("tryAPIBackendForBead: %v", err)
+	}
+	if !handled {
+		t.Error("expected dry-run API route to report handled")
+	}
+	if n := fake.invokes.Load(); n != 0 {
+		t.Errorf("expected no invocations in dry-run, got %d", n)
+	}
+	if !strings.Contains(output, "Would route gt-abc123 to API backend bedrock/") {
+		t.Errorf("expected routing decision in output, got:\n%s", output)
+	}
+	if !strings.Contains(output, "estimated cost: ~$0.0123") {
+		t.Errorf("expected cost estimate in output, got:\n%s", output)
+	}
+}
+
+func TestTryAPIBackendForBeadSucceeds(t *testing.T) {
+	d, fake := newTestAPIDispatcher(t)
+
+	var handled bool
+	var err error
+	output := captureStdout(t, func() {
+		handled, err = tryAPIBackendForBead(d, "gt-abc123", t.TempDir(), false)
+	})
+	if err != nil {
+		t.Fatalf("tryAPIBackendForBead: %v", err)
+	}
+	if !handled {
+		t.Error("expected API route to report handled")
+	}
+	if n := fake.invokes.Load(); n != 1 {
+		t.Errorf("expected 1 invocation, got %d", n)
+	}
+	if !strings.Contains(output, "Bead gt-abc123 completed via API backend") {
+		t.Errorf("expected completion in output, got:\n%s", output)
+	}
+	if !strings.Contains(output, "done") {
+		t.Errorf("expected response content in output, got:\n%s", output)
+	}
+}
+
+func TestTryAPIBackendForBeadRecordsLabels(t *testing.T) {
+	d, _ := newTestAPIDispatcher(t)
+	d.SetIssueFetcher(&fakeIssueFetcher{issues: map[string]*beads.Issue{
+		"gt-lbl": {
+			ID:          "gt-lbl",
+			Title:       "Summarize the release notes",
+			Type:        "task",
+			Description: "Summarize the changes",
+			Labels:      []string{"area/docs", "docs"},
+		},
+	}})
+	d.costTracker = backend.NewCostTracker()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	captureStdout(t, func() {
+		if _, err := tryAPIBackendForBead(d, "gt-lbl", t.TempDir(), false); err != nil {
+			t.Errorf("tryAPIBackendForBead: %v", err)
+		}
+	})
+
+	entries := d.costTracker.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 cost entry, got %d", len(entries))
+	}
+	if got := strings.Join(entries[0].Labels, ","); got != "area/docs,docs" {
+		t.Errorf("cost entry labels = %q, want area/docs,docs", got)
+	}
+	if s := d.costTracker.SummaryByLabel("area/"); s["area/docs"].Invocations != 1 || len(s) != 1 {
+		t.Errorf("SummaryByLabel(area/) = %+v, want one area/docs invocation", s)
+	}
+
+	// API spend stays out of the session costs log read by 'gt costs'
+	if _, err := os.Stat(getCostsLogPath()); !os.IsNotExist(err) {
+		t.Errorf("costs log %s written for an API invocation (stat err %v)", getCostsLogPath(), err)
+	}
+}
+
+func TestTryAPIBackendForBeadFetchFailureFallsBack(t *testing.T) {
+	d, fake := newTestAPIDispatcher(t)
+
+	handled, err := tryAPIBackendForBead(d, "gt-missing", t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("tryAPIBackendForBead: %v", err)
+	}
+	if handled {
+		t.Error("expected CLI fallback when the bead can't be fetched")
+	}
+	if n := fake.invokes.Load(); n != 0 {
+		t.Errorf("expected no invocations, got %d", n)
+	}
+}
+
+func TestExecuteAPIBackendEnforcesSessionBudget(t *testing.T) {
+	d, fake := newTestAPIDispatcher(t)
+	d.costTracker = backend.NewCostTracker()
+	d.config.SessionBudget = 0.02
+	issue := &beads.Issue{ID: "gt-abc123", Title: "Summarize the release notes"}
+
+	// The first task fits: $0.0123 of $0.02
+	route := &backend.RouteResult{Backend: "bedrock", Model: "haiku"}
+	if _, err := d.ExecuteAPIBackend(context.Background(), route, issue, nil); err != nil {
+		t.Fatalf("first task within budget: %v", err)
+	}
+
+	// A second $0.0123 task would exceed it
+	_, err := d.ExecuteAPIBackend(context.Background(), route, issue, nil)
+	if !errors.Is(err, backend.ErrBudgetExceeded) {
+		t.Errorf("over-budget task error = %v, want ErrBudgetExceeded", err)
+	}
+
+	route.FallbackToCLI = true
+	result, err := d.ExecuteAPIBackend(context.Background(), route, issue, nil)
+	if err != nil {
+		t.Fatalf("over-budget task with fallback: %v", err)
+	}
+	if !result.FallbackToCLI || !strings.Contains(result.Reason, "session budget") || !strings.Contains(result.Reason, "gt cost --reset") {
+		t.Errorf("result = %+v, want CLI fallback citing the session budget and how to reset it", result)
+	}
+	if n := fake.invokes.Load(); n != 1 {
+		t.Errorf("expected only the in-budget task to invoke, got %d", n)
+	}
+
+	status := budgetStatus(d.config, d.costTracker)
+	if status.Budget != 0.02 || status.Spent != 0.0123 || status.Remaining != 0.02-0.0123 {
+		t.Errorf("budgetStatus = %+v", status)
+	}
+}
+
+func TestExecuteAPIBackendEnforcesTeammateBudget(t *testing.T) {
+	d, fake := newTestAPIDispatcher(t)
+	d.costTracker = backend.NewCostTracker()
+	issue := &beads.Issue{ID: "gt-abc123", Title: "Summarize the release notes"}
+	route := &backend.RouteResult{Backend: "bedrock", Model: "haiku"}
+
+	// Each teammate gets its own $0.02: one $0.0123 task apiece fits
+	for _, teammate := range []string{"researcher", "tester"} {
+		d.costTracker.SetTeammateBudget(teammate, 0.02)
+		if _, err := d.ExecuteAPIBackend(context.Background(), route, issue, nil); err != nil {
+			t.Fatalf("%s's first task: %v", teammate, err)
+		}
+	}
+
+	_, err := d.ExecuteAPIBackend(context.Background(), route, issue, nil)
+	if !errors.Is(err, backend.ErrBudgetExceeded) || !strings.Contains(err.Error(), "teammate tester") {
+		t.Errorf("over-budget teammate error = %v, want ErrBudgetExceeded for tester", err)
+	}
+	if n := fake.invokes.Load(); n != 2 {
+		t.Errorf("invokes = %d, want 2", n)
+	}
+	if spent := d.costTracker.TeammateSpent("researcher"); spent != 0.0123 {
+		t.Errorf("TeammateSpent(researcher) = %v, want 0.0123", spent)
+	}
+}
+
+func TestTeammateBudgetFromEnv(t *testing.T) {
+	t.Setenv("BD_ACTOR", "gastown/polecats/toast")
+	t.Setenv(config.EnvTeammate, "")
+
+	t.Setenv(config.EnvTeammateBudget, "")
+	if _, budget := teammateBudgetFromEnv(); budget != 0 {
+		t.Errorf("budget outside a team session = %v, want 0", budget)
+	}
+
+	t.Setenv(config.EnvTeammateBudget, "not-a-number")
+	if _, budget := teammateBudgetFromEnv(); budget != 0 {
+		t.Errorf("budget for an invalid value = %v, want 0", budget)
+	}
+
+	t.Setenv(config.EnvTeammateBudget, "1.5")
+	if teammate, budget := teammateBudgetFromEnv(); teammate != "gastown/polecats/toast" || budget != 1.5 {
+		t.Errorf("teammateBudgetFromEnv() = %q, %v; want the lead's actor and 1.5", teammate, budget)
+	}
+
+	t.Setenv(config.EnvTeammate, "researcher")
+	if teammate, _ := teammateBudgetFromEnv(); teammate != "researcher" {
+		t.Errorf("teammate = %q, want researcher", teammate)
+	}
+}
+
+func TestExecuteAPIBackendResponseLimit(t *testing.T) {
+	d, fake := newTestAPIDispatcher(t)
+	d.costTracker = backend.NewCostTracker()
+	issue := &beads.Issue{ID: "gt-abc123", Title: "Summarize the release notes"}
+	route := &backend.RouteResult{Backend: "bedrock", Model: "haiku"}
+
+	if _, err := d.ExecuteAPIBackend(context.Background(), route, issue, nil); err != nil {
+		t.Fatalf("ExecuteAPIBackend: %v", err)
+	}
+	if got := fake.maxTokens.Load(); got != defaultAPIResponseTokens {
+		t.Errorf("MaxTokens = %d, want default %d", got, defaultAPIResponseTokens)
+	}
+
+	// A reserve_tokens entry is the response limit sent for that model
+	d.contextManager.ModelReserveTokens["haiku"] = 1024
+	if _, err := d.ExecuteAPIBackend(context.Background(), route, issue, nil); err != nil {
+		t.Fatalf("ExecuteAPIBackend: %v", err)
+	}
+	if got := fake.maxTokens.Load(); got != 1024 {
+		t.Errorf("MaxTokens = %d, want configured 1024", got)
+	}
+
+	// A limit that fills the context window is rejected before invoking
+	d.contextManager.ModelReserveTokens["haiku"] = 200000
+	if _, err := d.ExecuteAPIBackend(context.Background(), route, issue, nil); err == nil {
+		t.Error("expected an error for a response limit filling the window")
+	}
+	if n := fake.invokes.Load(); n != 2 {
+		t.Errorf("invokes = %d, want 2", n)
+	}
+}
+
+func TestExecuteAPIBackendFlagsTruncation(t *testing.T) {
+	d, fake := newTestAPIDispatcher(t)
+	d.costTracker = backend.NewCostTracker()
+	issue := &beads.Issue{ID: "gt-abc123", Title: "Summarize the release notes"}
+	route := &backend.RouteResult{Backend: "bedrock", Model: "haiku"}
+
+	fake.finishReason = "end_turn"
+	result, err := d.ExecuteAPIBackend(context.Background(), route, issue, nil)
+	if err != nil {
+		t.Fatalf("ExecuteAPIBackend: %v", err)
+	}
+	if result.Truncated {
+		t.Error("complete response flagged as truncated")
+	}
+
+	fake.finishReason = "max_tokens"
+	result, err = d.ExecuteAPIBackend(context.Background(), route, issue, nil)
+	if err != nil {
+		t.Fatalf("ExecuteAPIBackend: %v", err)
+	}
+	if !result.Truncated || result.FinishReason != "max_tokens" {
+		t.Errorf("Truncated = %v, FinishReason = %q; want true, max_tokens", result.Truncated, result.FinishReason)
+	}
+}
+
+// failingBackend is a fake AgentBackend whose invocations always fail.
+type failingBackend struct {
+	countingBackend
+}
+
+func (b *failingBackend) Invoke(_ context.Context, _ []backend.Message, _ backend.InvokeOptions) (*backend.InvokeResult, error) {
+	b.invokes.Add(1)
+	return nil, fmt.Errorf("status 429: throttled")
+}
+
+func TestExecuteAPIBackendFallbackChain(t *testing.T) {
+	d, _ := newTestAPIDispatcher(t)
+	d.costTracker = backend.NewCostTracker()
+	backend.ResetRegistryForTesting()
+	throttled := &failingBackend{countingBackend{name: "bedrock"}}
+	healthy := &countingBackend{name: "grok"}
+	backend.GetRegistry().Register(throttled)
+	backend.GetRegistry().Register(healthy)
+
+	// "bedrock" repeats the routed backend and "openai" isn't registered
+	d.config.FallbackChain = []string{"bedrock", "openai", "grok"}
+	issue := &beads.Issue{ID: "gt-abc123", Title: "Summarize the release notes"}
+	route := &backend.RouteResult{Backend: "bedrock", Model: "haiku"}
+
+	result, err := d.ExecuteAPIBackend(context.Background(), route, issue, nil)
+	if err != nil {
+		t.Fatalf("ExecuteAPIBackend: %v", err)
+	}
+	if !result.Success || result.Backend != "grok" {
+		t.Errorf("result = %+v, want success served by grok", result)
+	}
+	if throttled.invokes.Load() != 1 || healthy.invokes.Load() != 1 {
+		t.Errorf("invokes bedrock=%d grok=%d, want 1 each", throttled.invokes.Load(), healthy.invokes.Load())
+	}
+	if entries := d.costTracker.Entries(); len(entries) != 1 || entries[0].Backend != "grok" {
+		t.Errorf("cost entries = %+v, want one grok entry", entries)
+	}
+
+	// With every backend failing, the last error decides the outcome
+	d.config.FallbackChain = []string{"openai"}
+	if _, err := d.ExecuteAPIBackend(context.Background(), route, issue, nil); err == nil || !strings.Contains(err.Error(), "openai not available") {
+		t.Errorf("err = %v, want the last backend's failure", err)
+	}
+	route.FallbackToCLI = true
+	result, err = d.ExecuteAPIBackend(context.Background(), route, issue, nil)
+	if err != nil || !result.FallbackToCLI {
+		t.Errorf("result = %+v, err = %v, want CLI fallback", result, err)
+	}
+}
+
+func TestResolvedRoutingDefaultsMatchDefaultRoutingConfig(t *testing.T) {
+	// A town with no settings/backend.json
+	got := routingConfigFromBackendConfig(config.ResolveBackendConfig(t.TempDir(), ""))
+	want := backend.DefaultRoutingConfig()
+
+	// Documented defaults: opt-in, $0.50 per task, 50k tokens
+	if got.Enabled {
+		t.Error("hybrid routing should be opt-in (disabled by default)")
+	}
+	if got.CostThreshold != 0.50 {
+		t.Errorf("CostThreshold = %v, want 0.50", got.CostThreshold)
+	}
+	if got.TokenThreshold != 50000 {
+		t.Errorf("TokenThreshold = %d, want 50000", got.TokenThreshold)
+	}
+
+	if got.Enabled != want.Enabled ||
+		got.DefaultRoute != want.DefaultRoute ||
+		got.DefaultBackend != want.DefaultBackend ||
+		got.DefaultModel != want.DefaultModel ||
+		got.CostThreshold != want.CostThreshold ||
+		got.TokenThreshold != want.TokenThreshold ||
+		got.FallbackToCLI != want.FallbackToCLI {
+		t.Errorf("resolved routing config %+v disagrees with DefaultRoutingConfig %+v", got, want)
+	}
+}
+
+func TestRoutingConfigKeepsExplicitZeroCostOverride(t *testing.T) {
+	townRoot := t.TempDir()
+	settings := filepath.Join(townRoot, "settings")
+	if err := os.MkdirAll(settings, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := `{"model_overrides": {"grok/grok-3": {"cost_per_1k": 0}, "bedrock/haiku": {"speed_score": 10}}}`
+	if err := os.WriteFile(filepath.Join(settings, "backend.json"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := routingConfigFromBackendConfig(config.ResolveBackendConfig(townRoot, ""))
+	grok := got.ModelOverrides["grok/grok-3"]
+	if grok.CostPer1K == nil || *grok.CostPer1K != 0 || grok.SpeedScore != nil {
+		t.Errorf("grok/grok-3 override = %+v, want explicit 0 cost and unset speed", grok)
+	}
+	haiku := got.ModelOverrides["bedrock/haiku"]
+	if haiku.SpeedScore == nil || *haiku.SpeedScore != 10 || haiku.CostPer1K != nil {
+		t.Errorf("bedrock/haiku override = %+v, want speed 10 and unset cost", haiku)
+	}
+}
+
+// modelsBackend is a countingBackend advertising its own models.
+type modelsBackend struct {
+	countingBackend
+	models []string
+}
+
+func (b *modelsBackend) AvailableModels() []string { return b.models }
+
+func TestResolveTeammateModel(t *testing.T) {
+	backend.ResetRegistryForTesting()
+	t.Cleanup(backend.ResetRegistryForTesting)
+	backend.GetRegistry().Register(&modelsBackend{countingBackend: countingBackend{name: "ollama"}, models: []string{"Llama3.1"}})
+
+	tests := []struct {
+		tier    string
+		want    string
+		wantErr bool
+	}{
+		{tier: "Opus", want: "opus"},
+		{tier: "haiku", want: "haiku"},
+		{tier: "gpt4", want: "gpt-4o"},
+		{tier: "grok-fast", want: "grok-3-mini"},
+		{tier: "grok-3", want: "grok-3"},
+		{tier: "GPT-4o", want: "gpt-4o"},
+		{tier: "llama3.1", want: "Llama3.1"},
+		{tier: "gpt-5-ultra", wantErr: true},
+		{tier: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := resolveTeammateModel(tt.tier, "", "")
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "invalid --teammate-tier") {
+				t.Errorf("resolveTeammateModel(%q) error = %v, want invalid --teammate-tier", tt.tier, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("resolveTeammateModel(%q) error = %v", tt.tier, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolveTeammateModel(%q) = %q, want %q", tt.tier, got, tt.want)
+		}
+	}
+}
+
+func TestResolveTeammateModelLoadsConfiguredBackends(t *testing.T) {
+	backend.ResetRegistryForTesting()
+	t.Cleanup(backend.ResetRegistryForTesting)
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+
+	writeBackendJSON := func(dir, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(dir, "settings"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "settings", "backend.json"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	townRoot := t.TempDir()
+	rigPath := filepath.Join(townRoot, "gastown")
+	writeBackendJSON(rigPath, `{"backends": {"openai": {"enabled": true}}}`)
+
+	// gpt-4-turbo is only known to the OpenAI backend, which only the rig enables
+	if _, err := resolveTeammateModel("gpt-4-turbo", townRoot, ""); err == nil {
+		t.Error("resolveTeammateModel accepted a model from a backend the town doesn't enable")
+	}
+	got, err := resolveTeammateModel("GPT-4-Turbo", townRoot, rigPath)
+	if err != nil {
+		t.Fatalf("resolveTeammateModel with the rig's config: %v", err)
+	}
+	if got != "gpt-4-turbo" {
+		t.Errorf("resolveTeammateModel = %q, want gpt-4-turbo", got)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/steveyegge/gastown/internal/beads"
+	"github.com/steveyegge/gastown/internal/config"
 	"github.com/steveyegge/gastown/internal/events"
 	"github.com/steveyegge/gastown/internal/style"
 	"github.com/steveyegge/gastown/internal/tmux"
@@ -70,6 +71,46 @@ func verifyFormulaExists(formulaName string) error {
 	return fmt.Errorf("formula '%s' not found (check 'bd formula list')", formulaName)
 }
 
+// checkFormulaVars resolves a formula against the variables it will be
+// instantiated with, so a missing required variable fails before a polecat
+// is spawned rather than at 'bd mol wisp'. Formulas gt can't find locally
+// are left for bd to check.
+func checkFormulaVars(formulaName string, vars []string) error {
+	path, err := findFormulaFile(formulaName)
+	if err != nil {
+		return nil
+	}
+	f, err := parseFormulaFile(path)
+	if err != nil {
+		return nil
+	}
+
+	values := make(map[string]string, len(vars))
+	for _, v := range vars {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return fmt.Errorf("invalid --var %q: want key=value", v)
+		}
+		values[key] = value
+	}
+	_, warnings, err := f.Resolve(values)
+	if err != nil {
+		return fmt.Errorf("%w (pass with --var name=value)", err)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "%s formula %s: %s\n", style.WarningPrefix, f.Name, w)
+	}
+	return nil
+}
+
+// teamFormulaVars returns the formula variables that configure agent teams.
+func teamFormulaVars(teamConfig *config.TeamConfig) []string {
+	return []string{
+		fmt.Sprintf("max_teammates=%d", teamConfig.MaxTeammates),
+		fmt.Sprintf("teammate_model=%s", teamConfig.TeammateModel),
+	}
+}
+
 // runSlingFormula handles standalone formula slinging.
 // Flow: cook → wisp → attach to hook → nudge
 func runSlingFormula(args []string) error {
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/steveyegge/gastown/internal/backend"
 	"github.com/steveyegge/gastown/internal/config"
 )
 
@@ -130,7 +131,8 @@ func TestTeamSizeValidation(t *testing.T) {
 	}
 }
 
-// TestTeammateTierValidation verifies --teammate-tier accepts only valid values.
+// TestTeammateTierValidation verifies --teammate-tier accepts only Claude
+// tiers and backend models.
 func TestTeammateTierValidation(t *testing.T) {
 	prevTeam := slingTeam
 	prevNoTeam := slingNoTeam
@@ -157,7 +159,10 @@ func TestTeammateTierValidation(t *testing.T) {
 		{"haiku - valid", "haiku", false},
 		{"Opus - valid case insensitive", "Opus", false},
 		{"SONNET - valid case insensitive", "SONNET", false},
-		{"gpt4 - invalid", "gpt4", true},
+		{"gpt4 - valid backend tier", "gpt4", false},
+		{"grok-3 - valid backend model", "grok-3", false},
+		{"gpt-4o - valid backend model", "gpt-4o", false},
+		{"gpt-5-ultra - invalid", "gpt-5-ultra", true},
 		{"empty - invalid", "", true},
 	}
 
@@ -523,7 +528,9 @@ func TestLoadRigTeamDefaults(t *testing.T) {
 		"team": {
 			"enabled": true,
 			"max_teammates": 5,
+			"max_teammates_cap": 6,
 			"teammate_model": "opus",
+			"budget_usd": 2.5,
 			"delegate_mode": true
 		}
 	}`
@@ -547,6 +554,12 @@ func TestLoadRigTeamDefaults(t *testing.T) {
 	if tc.MaxTeammates != 5 {
 		t.Errorf("MaxTeammates = %d, want 5", tc.MaxTeammates)
 	}
+	if tc.BudgetUSD != 2.5 {
+		t.Errorf("BudgetUSD = %v, want 2.5", tc.BudgetUSD)
+	}
+	if tc.MaxTeammatesCap != 6 {
+		t.Errorf("MaxTeammatesCap = %d, want 6", tc.MaxTeammatesCap)
+	}
 	if tc.TeammateModel != "opus" {
 		t.Errorf("TeammateModel = %q, want %q", tc.TeammateModel, "opus")
 	}
@@ -591,6 +604,64 @@ func TestLoadRigTeamDefaults_NoSettings(t *testing.T) {
 	}
 }
 
+// TestResolveRigTeammateModel verifies a rig's teammate_model is checked
+// like --teammate-tier, including models from backends the rig enables.
+func TestResolveRigTeammateModel(t *testing.T) {
+	backend.ResetRegistryForTesting()
+	t.Cleanup(backend.ResetRegistryForTesting)
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+
+	townRoot := t.TempDir()
+	rigName := "modelrig"
+	rigPath := filepath.Join(townRoot, rigName)
+	if err := os.MkdirAll(filepath.Join(rigPath, "settings"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(townRoot, "mayor", "rig"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	rigsJSON := `{"rigs":{"modelrig":{"path":"modelrig"}}}`
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(rigsJSON), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	backendJSON := `{"backends": {"openai": {"enabled": true}}}`
+	if err := os.WriteFile(filepath.Join(rigPath, "settings", "backend.json"), []byte(backendJSON), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	_ = os.Chdir(filepath.Join(townRoot, "mayor", "rig"))
+
+	tests := []struct {
+		model   string
+		want    string
+		wantErr bool
+	}{
+		{model: "", want: ""},
+		{model: "Opus", want: "opus"},
+		{model: "GPT-4-Turbo", want: "gpt-4-turbo"}, // only the rig's OpenAI backend knows it
+		{model: "gpt-5-ultra", wantErr: true},
+	}
+	for _, tt := range tests {
+		tc := &config.TeamConfig{Enabled: true, TeammateModel: tt.model}
+		err := resolveRigTeammateModel(tc, rigName, townRoot)
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "invalid team.teammate_model") {
+				t.Errorf("resolveRigTeammateModel(%q) error = %v, want invalid team.teammate_model", tt.model, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("resolveRigTeammateModel(%q): %v", tt.model, err)
+			continue
+		}
+		if tc.TeammateModel != tt.want {
+			t.Errorf("resolveRigTeammateModel(%q) set %q, want %q", tt.model, tc.TeammateModel, tt.want)
+		}
+	}
+}
+
 // TestLoadRigTeamDefaults_TeamDisabled verifies nil return when team is not enabled.
 func TestLoadRigTeamDefaults_TeamDisabled(t *testing.T) {
 	townRoot := t.TempDir()
@@ -658,3 +729,104 @@ func TestLoadRigTeamDefaults_NonRigTarget(t *testing.T) {
 		t.Errorf("expected nil for empty target, got %+v", tc2)
 	}
 }
+
+// TestCheckRigTeamSize verifies a rig's max_teammates_cap limits team size.
+func TestCheckRigTeamSize(t *testing.T) {
+	townRoot := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(townRoot, "mayor", "rig"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	rigsJSON := `{"rigs":{"smallrig":{"path":"smallrig"},"bigcaprig":{"path":"bigcaprig"},"nocaprig":{"path":"nocaprig"}}}`
+	if err := os.WriteFile(filepath.Join(townRoot, "mayor", "rigs.json"), []byte(rigsJSON), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	// Caps apply whether or not the rig enables teams by default
+	for rigName, team := range map[string]string{
+		"smallrig":  `{"enabled": false, "max_teammates_cap": 2}`,
+		"bigcaprig": `{"enabled": true, "max_teammates_cap": 20}`,
+		"nocaprig":  `{"enabled": true, "max_teammates": 4}`,
+	} {
+		settingsDir := filepath.Join(townRoot, rigName, "settings")
+		if err := os.MkdirAll(settingsDir, 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		settingsJSON := `{"type": "rig-settings", "version": 1, "team": ` + team + `}`
+		if err := os.WriteFile(filepath.Join(settingsDir, "config.json"), []byte(settingsJSON), 0644); err != nil {
+			t.Fatalf("write settings: %v", err)
+		}
+	}
+
+	cwd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(cwd) })
+	_ = os.Chdir(filepath.Join(townRoot, "mayor", "rig"))
+
+	tests := []struct {
+		name     string
+		target   string
+		teamSize int
+		wantErr  string
+	}{
+		{"under cap", "smallrig", 2, ""},
+		{"over cap", "smallrig", 3, "--team-size must be between 1 and 2 for rig smallrig (got 3)"},
+		{"over cap via polecat path", "smallrig/polecats/Toast", 3, "for rig smallrig"},
+		{"cap above global max", "bigcaprig", 10, ""},
+		{"no cap", "nocaprig", 10, ""},
+		{"not a rig", "mayor", 10, ""},
+		{"no target", "", 10, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRigTeamSize(tt.teamSize, tt.target, townRoot)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestFormatTeamConfig verifies the budget is shown only when set.
+func TestFormatTeamConfig(t *testing.T) {
+	tc := &config.TeamConfig{Enabled: true, MaxTeammates: 3, TeammateModel: "sonnet"}
+	if got, want := formatTeamConfig(tc), "max_teammates=3, teammate_model=sonnet"; got != want {
+		t.Errorf("formatTeamConfig() = %q, want %q", got, want)
+	}
+
+	tc.BudgetUSD = 1.5
+	if got, want := formatTeamConfig(tc), "max_teammates=3, teammate_model=sonnet, budget_usd=1.50 per teammate"; got != want {
+		t.Errorf("formatTeamConfig() = %q, want %q", got, want)
+	}
+}
+
+// TestTeammateBudgetValidation verifies --teammate-budget rejects negative values.
+func TestTeammateBudgetValidation(t *testing.T) {
+	prevTeamSize := slingTeamSize
+	prevTeammateTier := slingTeammateTier
+	prevBudget := slingTeammateBudget
+	prevDryRun := slingDryRun
+	t.Cleanup(func() {
+		slingTeamSize = prevTeamSize
+		slingTeammateTier = prevTeammateTier
+		slingTeammateBudget = prevBudget
+		slingDryRun = prevDryRun
+	})
+
+	t.Setenv("GT_POLECAT", "")
+
+	slingTeamSize = 3
+	slingTeammateTier = "sonnet"
+	slingTeammateBudget = -1
+	slingDryRun = true
+
+	err := runSling(nil, []string{"gt-test123"})
+	if err == nil || !strings.Contains(err.Error(), "--teammate-budget must not be negative") {
+		t.Errorf("runSling() error = %v, want negative budget error", err)
+	}
+}
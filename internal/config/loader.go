@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/steveyegge/gastown/internal/backend"
 	"github.com/steveyegge/gastown/internal/constants"
 )
 
@@ -1997,6 +1998,14 @@ func LoadBackendConfig(path string) (*BackendConfig, error) {
 	if err := json.Unmarshal(data, &c); err != nil {
 		return nil, fmt.Errorf("parsing backend config: %w", err)
 	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parsing backend config: %w", err)
+	}
+	c.setFields = make(map[string]bool, len(keys))
+	for key := range keys {
+		c.setFields[key] = true
+	}
 
 	return &c, nil
 }
@@ -2039,7 +2048,9 @@ func SaveBackendConfig(path string, c *BackendConfig) error {
 }
 
 // ResolveBackendConfig loads and merges town + rig backend configs.
-// Rig config takes precedence over town config.
+// Rig config takes precedence over town config field by field: fields a
+// rig file omits keep the town's value, and rig routing rules are matched
+// before town rules.
 func ResolveBackendConfig(townRoot, rigPath string) *BackendConfig {
 	// Start with defaults
 	result := NewBackendConfig()
@@ -2065,6 +2076,28 @@ func ResolveBackendConfig(townRoot, rigPath string) *BackendConfig {
 	return result
 }
 
+// mergeBackendModels merges model entries by backend/model: an override
+// entry replaces the base entry for the same model, and new ones are added.
+func mergeBackendModels(base, override []*BackendModelEntry) []*BackendModelEntry {
+	var result []*BackendModelEntry
+	index := make(map[string]int)
+	for _, entries := range [][]*BackendModelEntry{base, override} {
+		for _, m := range entries {
+			if m == nil {
+				continue
+			}
+			key := m.Backend + "/" + m.Model
+			if i, ok := index[key]; ok {
+				result[i] = m
+				continue
+			}
+			index[key] = len(result)
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
 // mergeBackendConfig merges two backend configs (right takes precedence).
 func mergeBackendConfig(base, override *BackendConfig) *BackendConfig {
 	if override == nil {
@@ -2081,13 +2114,27 @@ func mergeBackendConfig(base, override *BackendConfig) *BackendConfig {
 		DefaultBackend: override.DefaultBackend,
 		DefaultModel:   override.DefaultModel,
 		CostThreshold:  override.CostThreshold,
+		SessionBudget:  override.SessionBudget,
 		TokenThreshold: override.TokenThreshold,
 		FallbackToCLI:  override.FallbackToCLI,
+		FallbackChain:  override.FallbackChain,
 		Backends:       make(map[string]*BackendEntry),
-		Routing:        override.Routing,
+		Routing:        mergeBackendRouting(base.Routing, override.Routing),
+		ModelOverrides: make(map[string]*BackendModelOverride),
+		ReserveTokens:  make(map[string]int),
+		ModelAliases:   make(map[string]string),
+
+		BalancedCostWeight: override.BalancedCostWeight,
+		AskStream:          override.AskStream,
 	}
 
 	// Use base defaults if override is empty
+	if !override.isSet("enabled") {
+		result.Enabled = base.Enabled
+	}
+	if !override.isSet("fallback_to_cli") {
+		result.FallbackToCLI = base.FallbackToCLI
+	}
 	if result.DefaultBackend == "" {
 		result.DefaultBackend = base.DefaultBackend
 	}
@@ -2097,11 +2144,20 @@ func mergeBackendConfig(base, override *BackendConfig) *BackendConfig {
 	if result.CostThreshold == 0 {
 		result.CostThreshold = base.CostThreshold
 	}
+	if result.SessionBudget == 0 {
+		result.SessionBudget = base.SessionBudget
+	}
 	if result.TokenThreshold == 0 {
 		result.TokenThreshold = base.TokenThreshold
 	}
-	if result.Routing == nil {
-		result.Routing = base.Routing
+	if result.FallbackChain == nil {
+		result.FallbackChain = base.FallbackChain
+	}
+	if result.BalancedCostWeight == nil {
+		result.BalancedCostWeight = base.BalancedCostWeight
+	}
+	if result.AskStream == nil {
+		result.AskStream = base.AskStream
 	}
 
 	// Merge backends (copy base first, then override)
@@ -2112,5 +2168,87 @@ func mergeBackendConfig(base, override *BackendConfig) *BackendConfig {
 		result.Backends[name] = entry
 	}
 
+	// Model entries merge by backend/model, override entries winning
+	result.Models = mergeBackendModels(base.Models, override.Models)
+
+	// Merge model overrides the same way
+	for key, o := range base.ModelOverrides {
+		result.ModelOverrides[key] = o
+	}
+	for key, o := range override.ModelOverrides {
+		result.ModelOverrides[key] = o
+	}
+	for model, reserve := range base.ReserveTokens {
+		result.ReserveTokens[model] = reserve
+	}
+	for model, reserve := range override.ReserveTokens {
+		result.ReserveTokens[model] = reserve
+	}
+	for alias, model := range base.ModelAliases {
+		result.ModelAliases[alias] = model
+	}
+	for alias, model := range override.ModelAliases {
+		result.ModelAliases[alias] = model
+	}
+
+	// Safety labels accumulate rather than override
+	seenLabels := make(map[string]bool)
+	for _, labels := range [][]string{base.ForceCLILabels, override.ForceCLILabels} {
+		for _, label := range labels {
+			if key := strings.ToLower(label); !seenLabels[key] {
+				seenLabels[key] = true
+				result.ForceCLILabels = append(result.ForceCLILabels, label)
+			}
+		}
+	}
+
+	result.AnalyzerPatterns = mergeAnalyzerPatterns(base.AnalyzerPatterns, override.AnalyzerPatterns)
+
+	return result
+}
+
+// mergeBackendRouting layers override's routing over base's: its default
+// route wins if set, and its rules come first so they're matched before
+// base's.
+func mergeBackendRouting(base, override *BackendRoutingConfig) *BackendRoutingConfig {
+	if base == nil {
+		return override
+	}
+	if override == nil {
+		return base
+	}
+
+	result := &BackendRoutingConfig{DefaultRoute: override.DefaultRoute}
+	if result.DefaultRoute == "" {
+		result.DefaultRoute = base.DefaultRoute
+	}
+	result.Rules = append(append([]BackendRoutingRule(nil), override.Rules...), base.Rules...)
+	return result
+}
+
+// mergeAnalyzerPatterns overlays override's keyword weights on base's and
+// accumulates tool phrases. Zero weights are kept, so a rig can still
+// remove a built-in keyword the town left alone.
+func mergeAnalyzerPatterns(base, override *backend.AnalyzerPatterns) *backend.AnalyzerPatterns {
+	if base == nil {
+		return override
+	}
+	if override == nil {
+		return base
+	}
+
+	result := &backend.AnalyzerPatterns{
+		Complex: make(map[string]int),
+		Simple:  make(map[string]int),
+	}
+	for _, p := range []*backend.AnalyzerPatterns{base, override} {
+		for keyword, weight := range p.Complex {
+			result.Complex[keyword] = weight
+		}
+		for keyword, weight := range p.Simple {
+			result.Simple[keyword] = weight
+		}
+		result.Tool = append(result.Tool, p.Tool...)
+	}
 	return result
 }
@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/steveyegge/gastown/internal/backend"
 	"github.com/steveyegge/gastown/internal/constants"
 )
 
@@ -3842,3 +3843,207 @@ func TestBuildStartupCommandWithAgentOverride_NoGTAgentWhenNoOverride(t *testing
 		t.Errorf("expected no GT_AGENT in command when no override, got: %q", cmd)
 	}
 }
+
+func TestMergeBackendConfigAccumulatesForceCLILabels(t *testing.T) {
+	t.Parallel()
+	town := &BackendConfig{ForceCLILabels: []string{"pii"}}
+	rig := &BackendConfig{ForceCLILabels: []string{"Security", "export-controlled"}}
+
+	merged := mergeBackendConfig(mergeBackendConfig(NewBackendConfig(), town), rig)
+
+	want := []string{"security", "secrets", "pii", "export-controlled"}
+	if len(merged.ForceCLILabels) != len(want) {
+		t.Fatalf("ForceCLILabels = %v, want %v", merged.ForceCLILabels, want)
+	}
+	for i, label := range want {
+		if merged.ForceCLILabels[i] != label {
+			t.Errorf("ForceCLILabels[%d] = %q, want %q", i, merged.ForceCLILabels[i], label)
+		}
+	}
+}
+
+func TestMergeBackendConfigKeepsZeroBalancedCostWeight(t *testing.T) {
+	t.Parallel()
+	townWeight, rigWeight := 0.3, 0.0
+	town := &BackendConfig{BalancedCostWeight: &townWeight}
+
+	merged := mergeBackendConfig(NewBackendConfig(), town)
+	if merged.BalancedCostWeight == nil || *merged.BalancedCostWeight != 0.3 {
+		t.Errorf("town-only weight = %v, want 0.3", merged.BalancedCostWeight)
+	}
+
+	// A rig's explicit 0 (speed only) overrides the town's weight
+	merged = mergeBackendConfig(merged, &BackendConfig{BalancedCostWeight: &rigWeight})
+	if merged.BalancedCostWeight == nil || *merged.BalancedCostWeight != 0 {
+		t.Errorf("rig weight = %v, want explicit 0", merged.BalancedCostWeight)
+	}
+}
+
+func TestMergeBackendConfigMergesAnalyzerPatterns(t *testing.T) {
+	t.Parallel()
+	town := &BackendConfig{AnalyzerPatterns: &backend.AnalyzerPatterns{
+		Complex: map[string]int{"etl": 25, "backfill": 20},
+		Tool:    []string{"terraform apply"},
+	}}
+	rig := &BackendConfig{AnalyzerPatterns: &backend.AnalyzerPatterns{
+		Complex: map[string]int{"backfill": 35, "design": 0},
+		Tool:    []string{"dbt run"},
+	}}
+
+	merged := mergeBackendConfig(mergeBackendConfig(NewBackendConfig(), town), rig)
+
+	p := merged.AnalyzerPatterns
+	if p == nil {
+		t.Fatal("AnalyzerPatterns = nil, want the merged patterns")
+	}
+	if p.Complex["etl"] != 25 || p.Complex["backfill"] != 35 {
+		t.Errorf("Complex = %v, want town etl and the rig's backfill weight", p.Complex)
+	}
+	if w, ok := p.Complex["design"]; !ok || w != 0 {
+		t.Errorf("Complex[design] = %d, %v; want the rig's 0 kept so it removes the built-in", w, ok)
+	}
+	if strings.Join(p.Tool, ",") != "terraform apply,dbt run" {
+		t.Errorf("Tool = %v, want both layers' phrases", p.Tool)
+	}
+}
+
+func TestMergeBackendConfigMergesModels(t *testing.T) {
+	t.Parallel()
+	town := &BackendConfig{Models: []*BackendModelEntry{
+		{Backend: "grok", Model: "grok-4", Tier: "complex", CostPer1K: 0.03, SpeedScore: 5},
+		{Backend: "openai", Model: "gpt-4o-mini", Tier: "simple", CostPer1K: 0.0003, SpeedScore: 8},
+	}}
+	rig := &BackendConfig{Models: []*BackendModelEntry{
+		{Backend: "grok", Model: "grok-4", Tier: "complex", CostPer1K: 0.02, SpeedScore: 6},
+		{Backend: "ollama", Model: "qwen2.5", Tier: "moderate", SpeedScore: 4},
+	}}
+
+	merged := mergeBackendConfig(mergeBackendConfig(NewBackendConfig(), town), rig)
+
+	if len(merged.Models) != 3 {
+		t.Fatalf("Models = %d entries, want 3", len(merged.Models))
+	}
+	if m := merged.Models[0]; m.Model != "grok-4" || m.CostPer1K != 0.02 {
+		t.Errorf("grok-4 = %+v, want the rig entry in the town entry's place", m)
+	}
+	if merged.Models[2].Model != "qwen2.5" {
+		t.Errorf("Models[2] = %+v, want the rig's new qwen2.5", merged.Models[2])
+	}
+}
+
+// writeBackendConfigFile writes settings/backend.json under dir.
+func writeBackendConfigFile(t *testing.T, dir, content string) {
+	t.Helper()
+	settingsDir := filepath.Join(dir, "settings")
+	if err := os.MkdirAll(settingsDir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(settingsDir, "backend.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("write backend.json: %v", err)
+	}
+}
+
+// TestResolveBackendConfig_RigEnablesRouting verifies a rig can turn on
+// routing the town leaves off, keeping the town's other settings.
+func TestResolveBackendConfig_RigEnablesRouting(t *testing.T) {
+	t.Parallel()
+	townRoot := t.TempDir()
+	rigPath := filepath.Join(townRoot, "testrig")
+
+	writeBackendConfigFile(t, townRoot, `{
+		"enabled": false,
+		"default_backend": "grok",
+		"cost_threshold": 0.5,
+		"fallback_to_cli": false
+	}`)
+	writeBackendConfigFile(t, rigPath, `{"enabled": true, "default_model": "grok-3-mini"}`)
+
+	town := ResolveBackendConfig(townRoot, "")
+	if town.Enabled {
+		t.Error("town Enabled = true, want false")
+	}
+
+	cfg := ResolveBackendConfig(townRoot, rigPath)
+	if !cfg.Enabled {
+		t.Error("Enabled = false, want the rig's true")
+	}
+	if cfg.DefaultBackend != "grok" {
+		t.Errorf("DefaultBackend = %q, want the town's %q", cfg.DefaultBackend, "grok")
+	}
+	if cfg.DefaultModel != "grok-3-mini" {
+		t.Errorf("DefaultModel = %q, want the rig's %q", cfg.DefaultModel, "grok-3-mini")
+	}
+	if cfg.CostThreshold != 0.5 {
+		t.Errorf("CostThreshold = %v, want the town's 0.5", cfg.CostThreshold)
+	}
+	if cfg.FallbackToCLI {
+		t.Error("FallbackToCLI = true, want the town's false (the rig omits it)")
+	}
+}
+
+// TestResolveBackendConfig_RigDisablesRouting verifies a rig can turn off
+// routing the town enables, and that omitting enabled inherits it.
+func TestResolveBackendConfig_RigDisablesRouting(t *testing.T) {
+	t.Parallel()
+	townRoot := t.TempDir()
+	offRig := filepath.Join(townRoot, "offrig")
+	quietRig := filepath.Join(townRoot, "quietrig")
+
+	writeBackendConfigFile(t, townRoot, `{"enabled": true, "token_threshold": 50000}`)
+	writeBackendConfigFile(t, offRig, `{"enabled": false}`)
+	writeBackendConfigFile(t, quietRig, `{"token_threshold": 8000}`)
+
+	if cfg := ResolveBackendConfig(townRoot, offRig); cfg.Enabled {
+		t.Error("offrig Enabled = true, want the rig's false")
+	}
+
+	cfg := ResolveBackendConfig(townRoot, quietRig)
+	if !cfg.Enabled {
+		t.Error("quietrig Enabled = false, want the town's true (the rig omits it)")
+	}
+	if cfg.TokenThreshold != 8000 {
+		t.Errorf("quietrig TokenThreshold = %d, want the rig's 8000", cfg.TokenThreshold)
+	}
+}
+
+// TestResolveBackendConfig_RigRulesFirst verifies rig routing rules are
+// matched before town rules, and the town's default route is kept.
+func TestResolveBackendConfig_RigRulesFirst(t *testing.T) {
+	t.Parallel()
+	townRoot := t.TempDir()
+	rigPath := filepath.Join(townRoot, "testrig")
+
+	writeBackendConfigFile(t, townRoot, `{
+		"enabled": true,
+		"routing": {
+			"default_route": "api",
+			"rules": [{"name": "town-docs", "type_match": ["docs"], "route": "api", "backend": "grok"}]
+		}
+	}`)
+	writeBackendConfigFile(t, rigPath, `{
+		"routing": {
+			"rules": [{"name": "rig-docs", "type_match": ["docs"], "route": "cli"}]
+		}
+	}`)
+
+	cfg := ResolveBackendConfig(townRoot, rigPath)
+	if cfg.Routing == nil {
+		t.Fatal("Routing = nil, want the merged routing")
+	}
+	if cfg.Routing.DefaultRoute != "api" {
+		t.Errorf("DefaultRoute = %q, want the town's %q", cfg.Routing.DefaultRoute, "api")
+	}
+	var names []string
+	for _, rule := range cfg.Routing.Rules {
+		names = append(names, rule.Name)
+	}
+	if strings.Join(names, ",") != "rig-docs,town-docs" {
+		t.Errorf("Rules = %v, want rig-docs before town-docs", names)
+	}
+
+	// The town's own config is unaffected
+	town := ResolveBackendConfig(townRoot, "")
+	if len(town.Routing.Rules) != 1 || town.Routing.Rules[0].Name != "town-docs" {
+		t.Errorf("town Rules = %+v, want only town-docs", town.Routing.Rules)
+	}
+}
@@ -94,9 +94,11 @@ func validateOverseerConfig(c *OverseerConfig) error {
 // DetectOverseer attempts to detect the overseer's identity from available sources.
 // Priority order:
 //  1. Existing config file (if path provided and exists)
-//  2. Git config (user.name + user.email)
-//  3. GitHub CLI (gh api user)
-//  4. Environment ($USER or whoami)
+//  2. Repo-local git config (git config --local user.name + user.email)
+//  3. Jujutsu config (jj config get user.name + user.email)
+//  4. Git config from any scope (global, system, includeIf)
+//  5. GitHub CLI (gh api user)
+//  6. Environment ($USER or whoami)
 func DetectOverseer(townRoot string) (*OverseerConfig, error) {
 	configPath := OverseerConfigPath(townRoot)
 
@@ -105,67 +107,91 @@ func DetectOverseer(townRoot string) (*OverseerConfig, error) {
 		return existing, nil
 	}
 
-	// Priority 2: Try git config
+	// Priority 2: Try repo-local git config, so a rig-local identity wins
+	if config := detectFromGitConfig(townRoot, "--local"); config != nil {
+		return config, nil
+	}
+
+	// Priority 3: Try jj config
+	if config := detectFromJujutsu(townRoot); config != nil {
+		return config, nil
+	}
+
+	// Priority 4: Try git config from any scope
 	if config := detectFromGitConfig(townRoot); config != nil {
 		return config, nil
 	}
 
-	// Priority 3: Try GitHub CLI
+	// Priority 5: Try GitHub CLI
 	if config := detectFromGitHub(); config != nil {
 		return config, nil
 	}
 
-	// Priority 4: Fall back to environment
+	// Priority 6: Fall back to environment
 	return detectFromEnvironment(), nil
 }
 
-// detectFromGitConfig attempts to get identity from git config.
-func detectFromGitConfig(dir string) *OverseerConfig {
-	// Try to get user.name
-	nameCmd := exec.Command("git", "config", "user.name")
-	nameCmd.Dir = dir
-	nameOut, err := nameCmd.Output()
+// overseerCommandOutput runs an identity lookup command in dir and returns
+// its trimmed output. Tests replace it to fake git, jj, and gh.
+var overseerCommandOutput = func(dir, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
 	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// detectFromGitConfig attempts to get identity from git config, limited
+// to the scope flags given (e.g. "--local"); none reads every scope.
+func detectFromGitConfig(dir string, scope ...string) *OverseerConfig {
+	// Try to get user.name
+	name, err := overseerCommandOutput(dir, "git", append(append([]string{"config"}, scope...), "user.name")...)
+	if err != nil || name == "" {
 		return nil
 	}
-	name := strings.TrimSpace(string(nameOut))
-	if name == "" {
+
+	// Try to get user.email (optional)
+	email, _ := overseerCommandOutput(dir, "git", append(append([]string{"config"}, scope...), "user.email")...)
+	return newDetectedOverseer(name, email, "git-config")
+}
+
+// detectFromJujutsu attempts to get identity from jj config, which resolves
+// repo config over user config itself.
+func detectFromJujutsu(dir string) *OverseerConfig {
+	name, err := overseerCommandOutput(dir, "jj", "config", "get", "user.name")
+	if err != nil || name == "" {
 		return nil
 	}
+	email, _ := overseerCommandOutput(dir, "jj", "config", "get", "user.email")
+	return newDetectedOverseer(name, email, "jj-config")
+}
 
+// newDetectedOverseer builds an overseer config from a VCS identity,
+// taking the username from the email's local part if available.
+func newDetectedOverseer(name, email, source string) *OverseerConfig {
 	config := &OverseerConfig{
 		Type:    "overseer",
 		Version: CurrentOverseerVersion,
 		Name:    name,
-		Source:  "git-config",
-	}
-
-	// Try to get user.email (optional)
-	emailCmd := exec.Command("git", "config", "user.email")
-	emailCmd.Dir = dir
-	if emailOut, err := emailCmd.Output(); err == nil {
-		config.Email = strings.TrimSpace(string(emailOut))
+		Email:   email,
+		Source:  source,
 	}
-
-	// Extract username from email if available
-	if config.Email != "" {
-		if idx := strings.Index(config.Email, "@"); idx > 0 {
-			config.Username = config.Email[:idx]
-		}
+	if idx := strings.Index(email, "@"); idx > 0 {
+		config.Username = email[:idx]
 	}
-
 	return config
 }
 
 // detectFromGitHub attempts to get identity from GitHub CLI.
 func detectFromGitHub() *OverseerConfig {
-	cmd := exec.Command("gh", "api", "user", "--jq", ".login + \"|\" + .name + \"|\" + .email")
-	out, err := cmd.Output()
+	out, err := overseerCommandOutput("", "gh", "api", "user", "--jq", ".login + \"|\" + .name + \"|\" + .email")
 	if err != nil {
 		return nil
 	}
 
-	parts := strings.Split(strings.TrimSpace(string(out)), "|")
+	parts := strings.Split(out, "|")
 	if len(parts) < 1 || parts[0] == "" {
 		return nil
 	}
@@ -197,8 +223,8 @@ func detectFromEnvironment() *OverseerConfig {
 	username := os.Getenv("USER")
 	if username == "" {
 		// Try whoami as last resort
-		if out, err := exec.Command("whoami").Output(); err == nil {
-			username = strings.TrimSpace(string(out))
+		if out, err := overseerCommandOutput("", "whoami"); err == nil {
+			username = out
 		}
 	}
 	if username == "" {
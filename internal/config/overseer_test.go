@@ -0,0 +1,135 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeOverseerCommands replaces identity lookups with canned output, keyed
+// by the command line (e.g. "git config --local user.name"). Commands not
+// in outputs fail, like a missing tool or unset key.
+func fakeOverseerCommands(t *testing.T, outputs map[string]string) {
+	t.Helper()
+	saved := overseerCommandOutput
+	t.Cleanup(func() { overseerCommandOutput = saved })
+	overseerCommandOutput = func(dir, name string, args ...string) (string, error) {
+		key := strings.Join(append([]string{name}, args...), " ")
+		if out, ok := outputs[key]; ok {
+			return out, nil
+		}
+		return "", errors.New("not configured: " + key)
+	}
+}
+
+var (
+	localGitIdentity = map[string]string{
+		"git config --local user.name":  "Rig Local",
+		"git config --local user.email": "rig@example.com",
+	}
+	jjIdentity = map[string]string{
+		"jj config get user.name":  "JJ User",
+		"jj config get user.email": "jj@example.com",
+	}
+	globalGitIdentity = map[string]string{
+		"git config user.name":  "Global User",
+		"git config user.email": "global@example.com",
+	}
+	githubIdentity = map[string]string{
+		`gh api user --jq .login + "|" + .name + "|" + .email`: "octocat|The Octocat|octo@example.com",
+	}
+)
+
+// mergeIdentities combines fake command outputs.
+func mergeIdentities(sources ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, source := range sources {
+		for k, v := range source {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+func TestDetectOverseer_Sources(t *testing.T) {
+	tests := []struct {
+		name       string
+		outputs    map[string]string
+		wantName   string
+		wantEmail  string
+		wantUser   string
+		wantSource string
+	}{
+		{"local git", localGitIdentity, "Rig Local", "rig@example.com", "rig", "git-config"},
+		{"jj", jjIdentity, "JJ User", "jj@example.com", "jj", "jj-config"},
+		{"global git", globalGitIdentity, "Global User", "global@example.com", "global", "git-config"},
+		{"github cli", githubIdentity, "The Octocat", "octo@example.com", "octocat", "github-cli"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeOverseerCommands(t, tt.outputs)
+			got, err := DetectOverseer(t.TempDir())
+			if err != nil {
+				t.Fatalf("DetectOverseer: %v", err)
+			}
+			if got.Name != tt.wantName || got.Email != tt.wantEmail || got.Username != tt.wantUser || got.Source != tt.wantSource {
+				t.Errorf("DetectOverseer() = %+v, want %s <%s> (@%s) from %s",
+					got, tt.wantName, tt.wantEmail, tt.wantUser, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestDetectOverseer_Environment(t *testing.T) {
+	fakeOverseerCommands(t, nil)
+	t.Setenv("USER", "mayor")
+
+	got, err := DetectOverseer(t.TempDir())
+	if err != nil {
+		t.Fatalf("DetectOverseer: %v", err)
+	}
+	if got.Name != "mayor" || got.Username != "mayor" || got.Source != "environment" {
+		t.Errorf("DetectOverseer() = %+v, want mayor from environment", got)
+	}
+}
+
+func TestDetectOverseer_Precedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		outputs    map[string]string
+		wantName   string
+		wantSource string
+	}{
+		{"local git beats jj and global git", mergeIdentities(globalGitIdentity, jjIdentity, localGitIdentity, githubIdentity), "Rig Local", "git-config"},
+		{"jj beats global git", mergeIdentities(globalGitIdentity, jjIdentity, githubIdentity), "JJ User", "jj-config"},
+		{"global git beats github cli", mergeIdentities(globalGitIdentity, githubIdentity), "Global User", "git-config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeOverseerCommands(t, tt.outputs)
+			got, err := DetectOverseer(t.TempDir())
+			if err != nil {
+				t.Fatalf("DetectOverseer: %v", err)
+			}
+			if got.Name != tt.wantName || got.Source != tt.wantSource {
+				t.Errorf("DetectOverseer() = %s from %s, want %s from %s", got.Name, got.Source, tt.wantName, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestDetectOverseer_ExistingConfigWins(t *testing.T) {
+	fakeOverseerCommands(t, localGitIdentity)
+	townRoot := t.TempDir()
+	if err := SaveOverseerConfig(OverseerConfigPath(townRoot), &OverseerConfig{Name: "Saved", Source: "manual"}); err != nil {
+		t.Fatalf("SaveOverseerConfig: %v", err)
+	}
+
+	got, err := DetectOverseer(townRoot)
+	if err != nil {
+		t.Fatalf("DetectOverseer: %v", err)
+	}
+	if got.Name != "Saved" || got.Source != "manual" {
+		t.Errorf("DetectOverseer() = %+v, want the saved config", got)
+	}
+}
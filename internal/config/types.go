@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/steveyegge/gastown/internal/backend"
 )
 
 // TownConfig represents the main town identity (mayor/town.json).
@@ -1121,13 +1123,34 @@ type TeamConfig struct {
 	// MaxTeammates limits the number of teammate instances (default 3).
 	MaxTeammates int `json:"max_teammates,omitempty"`
 
+	// MaxTeammatesCap caps --team-size for slings to this rig, below the
+	// global maximum of 10. Zero means no rig-specific cap.
+	MaxTeammatesCap int `json:"max_teammates_cap,omitempty"`
+
 	// TeammateModel specifies the model tier for teammates (e.g., "sonnet").
 	TeammateModel string `json:"teammate_model,omitempty"`
 
+	// BudgetUSD is the API spend budget (USD) for each teammate. It is
+	// passed to the team's session in EnvTeammateBudget, and the backend
+	// dispatcher refuses a teammate's API calls once its spend today would
+	// exceed it. 0 means no budget.
+	BudgetUSD float64 `json:"budget_usd,omitempty"`
+
 	// DelegateMode enables Shift+Tab delegate mode for full delegation.
 	DelegateMode bool `json:"delegate_mode,omitempty"`
 }
 
+// Environment variables that carry TeamConfig.BudgetUSD to the gt commands
+// an agent team runs.
+const (
+	// EnvTeammateBudget holds the per-teammate budget in USD.
+	EnvTeammateBudget = "GT_TEAMMATE_BUDGET_USD"
+
+	// EnvTeammate names the teammate a gt command runs for. Commands
+	// without it are charged to BD_ACTOR, i.e. the lead polecat.
+	EnvTeammate = "GT_TEAMMATE"
+)
+
 // CurrentBackendConfigVersion is the current schema version for BackendConfig.
 const CurrentBackendConfigVersion = 1
 
@@ -1143,7 +1166,7 @@ type BackendConfig struct {
 	Enabled bool `json:"enabled"`
 
 	// DefaultBackend is the API backend to use when not specified.
-	// Valid values: "claude", "openai", "grok"
+	// Valid values: "claude", "openai", "grok", "bedrock", "ollama"
 	DefaultBackend string `json:"default_backend"`
 
 	// DefaultModel is the model to use within the default backend.
@@ -1153,6 +1176,13 @@ type BackendConfig struct {
 	// Tasks estimated to exceed this cost will route to CLI agents.
 	CostThreshold float64 `json:"cost_threshold"`
 
+	// SessionBudget is a hard cap (USD) on the town's total recorded API
+	// spend (mayor/costs.json), counted over its lifetime rather than per
+	// process or day; 'gt cost --reset' starts it over. Once a task's
+	// estimate would push spend past it, API dispatch is refused. 0
+	// disables the budget.
+	SessionBudget float64 `json:"session_budget,omitempty"`
+
 	// TokenThreshold is the maximum estimated tokens before routing to CLI.
 	// Large context tasks automatically route to CLI agents.
 	TokenThreshold int `json:"token_threshold"`
@@ -1161,11 +1191,96 @@ type BackendConfig struct {
 	// When true, API failures will retry with CLI agent instead of failing.
 	FallbackToCLI bool `json:"fallback_to_cli"`
 
+	// FallbackChain lists backends to try, in order, when the routed
+	// backend fails (e.g., ["grok", "claude"]). Each uses its default model.
+	FallbackChain []string `json:"fallback_chain,omitempty"`
+
 	// Backends configures individual API backends.
 	Backends map[string]*BackendEntry `json:"backends,omitempty"`
 
 	// Routing contains custom routing rules.
 	Routing *BackendRoutingConfig `json:"routing,omitempty"`
+
+	// ReserveTokens sets the response limit per model ID for API dispatch
+	// (default 4096). Context is trimmed to leave exactly this much room.
+	ReserveTokens map[string]int `json:"reserve_tokens,omitempty"`
+
+	// Models adds to or replaces entries in the router's built-in model
+	// table, matched by backend and model: a matching entry replaces the
+	// built-in one, anything else is added. Rig entries replace town
+	// entries the same way.
+	Models []*BackendModelEntry `json:"models,omitempty"`
+
+	// ModelOverrides corrects the router's built-in speed/cost profile
+	// for specific models, keyed by "backend/model" (e.g., "grok/grok-3").
+	ModelOverrides map[string]*BackendModelOverride `json:"model_overrides,omitempty"`
+
+	// BalancedCostWeight is how much cost (vs. speed) counts when picking
+	// a model for "tier:balanced" tasks, from 0 (speed only) to 1 (cost
+	// only). Unset uses the default of 0.5.
+	BalancedCostWeight *float64 `json:"balanced_cost_weight,omitempty"`
+
+	// AskStream sets whether gt ask streams by default. The --stream flag
+	// overrides it; nil keeps the built-in default (stream).
+	AskStream *bool `json:"ask_stream,omitempty"`
+
+	// ModelAliases maps friendly names to model IDs for any backend.
+	// Per-backend aliases (built-in or configured) take precedence.
+	ModelAliases map[string]string `json:"model_aliases,omitempty"`
+
+	// ForceCLILabels is a safety list, separate from routing rules: beads
+	// with any of these labels always go to CLI agents and are never sent
+	// to a third-party API. Lists from town and rig config accumulate, so a
+	// rig cannot drop a label the town requires.
+	ForceCLILabels []string `json:"force_cli_labels,omitempty"`
+
+	// AnalyzerPatterns add keywords to the task analyzer, or reweight
+	// built-in ones: complex and simple keywords map to score weights (0
+	// removes a built-in keyword), and tool phrases force CLI routing.
+	AnalyzerPatterns *backend.AnalyzerPatterns `json:"analyzer_patterns,omitempty"`
+
+	// setFields records the top-level keys present in the file this config
+	// was loaded from, so merging can tell an omitted bool from false. Nil
+	// for configs built in code, where every field counts as set.
+	setFields map[string]bool
+}
+
+// isSet reports whether key was present in the config's file.
+func (c *BackendConfig) isSet(key string) bool {
+	return c.setFields == nil || c.setFields[key]
+}
+
+// DefaultForceCLILabels are labels that keep work off API backends by default.
+var DefaultForceCLILabels = []string{"security", "secrets"}
+
+// BackendModelEntry describes a model the router may select.
+type BackendModelEntry struct {
+	// Backend is the backend serving the model ("claude", "openai",
+	// "grok", "bedrock", or "ollama").
+	Backend string `json:"backend"`
+
+	// Model is the model ID or alias passed to the backend.
+	Model string `json:"model"`
+
+	// Tier is the hardest task tier the model handles confidently:
+	// "simple", "moderate", or "complex".
+	Tier string `json:"tier"`
+
+	// CostPer1K is the approximate cost per 1K tokens in USD.
+	CostPer1K float64 `json:"cost_per_1k"`
+
+	// SpeedScore is the relative speed (1-10, higher = faster).
+	SpeedScore int `json:"speed_score"`
+}
+
+// BackendModelOverride overrides the router's speed/cost profile for a model.
+// Omitted fields keep the built-in value; an explicit 0 cost is honored.
+type BackendModelOverride struct {
+	// SpeedScore is the relative speed (1-10, higher = faster).
+	SpeedScore *int `json:"speed_score,omitempty"`
+
+	// CostPer1K is the approximate cost per 1K tokens in USD.
+	CostPer1K *float64 `json:"cost_per_1k,omitempty"`
 }
 
 // BackendEntry configures a specific API backend.
@@ -1179,12 +1294,49 @@ type BackendEntry struct {
 	// APIKeyEnv is the environment variable name for the API key.
 	APIKeyEnv string `json:"api_key_env"`
 
-	// RateLimitRPM is the rate limit in requests per minute.
+	// RateLimitRPM is the rate limit in requests per minute, shared by
+	// models without their own limit in ModelLimits.
 	RateLimitRPM int `json:"rate_limit_rpm,omitempty"`
 
+	// ModelLimits sets per-model requests_per_minute and max_concurrent
+	// caps, keyed by model ID or alias.
+	ModelLimits map[string]backend.ModelLimits `json:"model_limits,omitempty"`
+
+	// BaseURL overrides the API endpoint for backends that honor it
+	// (ollama, e.g., a daemon on another host, and openai, e.g., an
+	// OpenAI-compatible gateway).
+	BaseURL string `json:"base_url,omitempty"`
+
+	// CompatibilityMode makes the openai backend send the legacy max_tokens
+	// field only, for OpenAI-compatible servers such as vLLM or LiteLLM.
+	CompatibilityMode bool `json:"compatibility_mode,omitempty"`
+
+	// LiveSearch sets the grok backend's xAI live search mode ("auto" or
+	// "on"; empty or "off" disables it). Answers then carry citations.
+	LiveSearch string `json:"live_search,omitempty"`
+
+	// APIVersion overrides the claude backend's anthropic-version header.
+	APIVersion string `json:"api_version,omitempty"`
+
+	// BetaFeatures are sent in the claude backend's anthropic-beta header
+	// (e.g. "context-1m-2025-08-07" for the 1M-token context window).
+	BetaFeatures []string `json:"beta_features,omitempty"`
+
+	// Region sets the bedrock backend's AWS region (default us-east-1).
+	// Model tiers use the inference profiles of its geography.
+	Region string `json:"region,omitempty"`
+
+	// InferenceProfiles maps bedrock models (or aliases) to the inference
+	// profile ID or ARN to invoke, replacing the one derived from Region.
+	InferenceProfiles map[string]string `json:"inference_profiles,omitempty"`
+
 	// Models lists enabled models for this backend.
 	// If empty, all models are enabled.
 	Models map[string]bool `json:"models,omitempty"`
+
+	// ModelAliases maps friendly names to model IDs for this backend
+	// (e.g., "opus" -> "claude-opus-4-5-20251101"), replacing built-ins.
+	ModelAliases map[string]string `json:"model_aliases,omitempty"`
 }
 
 // BackendRoutingConfig contains custom routing rules.
@@ -1210,6 +1362,10 @@ type BackendRoutingRule struct {
 	Route   string `json:"route"`             // "api" or "cli"
 	Backend string `json:"backend,omitempty"` // Backend name for API routes
 	Model   string `json:"model,omitempty"`   // Specific model override
+
+	// WriteBack records the API response on the bead as a comment and
+	// closes it, mirroring what a CLI agent does on completion.
+	WriteBack bool `json:"write_back,omitempty"`
 }
 
 // NewBackendConfig creates a new BackendConfig with sensible defaults.
@@ -1218,15 +1374,15 @@ func NewBackendConfig() *BackendConfig {
 		Type:           "backend-config",
 		Version:        CurrentBackendConfigVersion,
 		Enabled:        false, // Opt-in
-		DefaultBackend: "claude",
-		DefaultModel:   "claude-haiku-3-5-20241022",
-		CostThreshold:  0.50,  // $0.50 max per API task
-		TokenThreshold: 50000, // 50k tokens before CLI
+		DefaultBackend: backend.DefaultBackendName,
+		DefaultModel:   backend.DefaultModelName,
+		CostThreshold:  backend.DefaultCostThreshold,
+		TokenThreshold: backend.DefaultTokenThreshold,
 		FallbackToCLI:  true,
 		Backends: map[string]*BackendEntry{
 			"claude": {
 				Enabled:      true,
-				DefaultModel: "claude-haiku-3-5-20241022",
+				DefaultModel: backend.DefaultModelName,
 				APIKeyEnv:    "ANTHROPIC_API_KEY",
 				RateLimitRPM: 60,
 			},
@@ -1242,9 +1398,14 @@ func NewBackendConfig() *BackendConfig {
 				APIKeyEnv:    "XAI_API_KEY",
 				RateLimitRPM: 60,
 			},
+			"ollama": {
+				Enabled:      false,
+				DefaultModel: "llama3.2",
+			},
 		},
 		Routing: &BackendRoutingConfig{
 			DefaultRoute: "cli",
 		},
+		ForceCLILabels: append([]string(nil), DefaultForceCLILabels...),
 	}
 }
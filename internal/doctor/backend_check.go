@@ -0,0 +1,229 @@
+package doctor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/backend"
+	"github.com/steveyegge/gastown/internal/backend/backends"
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+// BackendConfigCheck verifies that each API backend enabled in
+// settings/backend.json can start: its credentials are in the environment
+// and its settings are accepted. It makes no network calls, so a revoked
+// key still passes; 'gt backend health' checks against the APIs.
+type BackendConfigCheck struct {
+	BaseCheck
+}
+
+// NewBackendConfigCheck creates a new backend config check.
+func NewBackendConfigCheck() *BackendConfigCheck {
+	return &BackendConfigCheck{
+		BaseCheck: BaseCheck{
+			CheckName:        "backend-config",
+			CheckDescription: "Check API backend credentials and settings",
+			CheckCategory:    CategoryConfig,
+		},
+	}
+}
+
+// backendKeyEnv is the environment variable each hosted backend reads its
+// API key from.
+var backendKeyEnv = map[string]string{
+	"claude": "ANTHROPIC_API_KEY",
+	"openai": "OPENAI_API_KEY",
+	"grok":   "XAI_API_KEY",
+}
+
+// Run checks every enabled backend in the town's backend config.
+func (c *BackendConfigCheck) Run(ctx *CheckContext) *CheckResult {
+	path := config.BackendConfigPath(ctx.TownRoot)
+	townConfig, err := config.LoadBackendConfig(path)
+	if err != nil {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusError,
+			Message: "Could not load settings/backend.json",
+			Details: []string{err.Error()},
+			FixHint: "Fix the JSON in " + path,
+		}
+	}
+	if townConfig == nil {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusOK,
+			Message: "No API backends configured",
+		}
+	}
+
+	cfg := config.ResolveBackendConfig(ctx.TownRoot, "")
+	names := make([]string, 0, len(cfg.Backends))
+	for name, entry := range cfg.Backends {
+		if entry != nil && entry.Enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusOK,
+			Message: "No API backends enabled",
+		}
+	}
+
+	var details, missing []string
+	for _, name := range names {
+		if env := missingBackendCredentials(name); env != "" {
+			details = append(details, fmt.Sprintf("%s: %s is not set", name, env))
+			missing = append(missing, env)
+			continue
+		}
+		if err := checkBackendEntry(name, cfg.Backends[name]); err != nil {
+			details = append(details, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	if len(details) == 0 {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusOK,
+			Message: fmt.Sprintf("%d API backend(s) configured: %s", len(names), strings.Join(names, ", ")),
+		}
+	}
+
+	hint := "Fix the settings above in settings/backend.json, or disable those backends"
+	if len(missing) > 0 {
+		hint = fmt.Sprintf("Set %s in the environment, or disable those backends in settings/backend.json", strings.Join(missing, ", "))
+	}
+	return &CheckResult{
+		Name:    c.Name(),
+		Status:  StatusWarning,
+		Message: fmt.Sprintf("%d of %d enabled API backend(s) can't start", len(details), len(names)),
+		Details: details,
+		FixHint: hint,
+	}
+}
+
+// missingBackendCredentials names the credentials a backend needs that
+// aren't set, or returns "" when they are (or none are needed).
+func missingBackendCredentials(name string) string {
+	if env, ok := backendKeyEnv[name]; ok {
+		if os.Getenv(env) == "" {
+			return env
+		}
+		return ""
+	}
+	if name == "bedrock" && !awsCredentialsConfigured() {
+		return "AWS credentials (AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY or AWS_PROFILE)"
+	}
+	return ""
+}
+
+// awsCredentialsConfigured reports whether the AWS default credential
+// chain has something to find without a network call: static keys, a
+// profile, web identity or container credentials, or a shared config file.
+// Instance roles can't be detected offline.
+func awsCredentialsConfigured() bool {
+	if os.Getenv("AWS_ACCESS_KEY_ID") != "" && os.Getenv("AWS_SECRET_ACCESS_KEY") != "" {
+		return true
+	}
+	for _, env := range []string{"AWS_PROFILE", "AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI"} {
+		if os.Getenv(env) != "" {
+			return true
+		}
+	}
+
+	files := []string{os.Getenv("AWS_SHARED_CREDENTIALS_FILE"), os.Getenv("AWS_CONFIG_FILE")}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".aws", "credentials"), filepath.Join(home, ".aws", "config"))
+	}
+	for _, f := range files {
+		if f == "" {
+			continue
+		}
+		if _, err := os.Stat(f); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBackendEntry builds a backend from its entry, with the options the
+// dispatcher registers it with, without registering it. Unknown names are
+// reported.
+func checkBackendEntry(name string, entry *config.BackendEntry) error {
+	spec, ok := backends.Lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown backend (known: %s)", strings.Join(backends.Names(), ", "))
+	}
+	return spec.Check(entry)
+}
+
+// UnavailableModelsCheck reports API models that a provider rejected as
+// unknown or retired during dispatch. Routing excludes them for the session
+// they fail in, but config still selects them until it is updated.
+type UnavailableModelsCheck struct {
+	FixableCheck
+}
+
+// NewUnavailableModelsCheck creates a new unavailable models check.
+func NewUnavailableModelsCheck() *UnavailableModelsCheck {
+	return &UnavailableModelsCheck{
+		FixableCheck: FixableCheck{
+			BaseCheck: BaseCheck{
+				CheckName:        "api-models",
+				CheckDescription: "Check for API models reported as retired or not found",
+				CheckCategory:    CategoryConfig,
+			},
+		},
+	}
+}
+
+// Run lists models recorded as unavailable by the backend dispatcher.
+func (c *UnavailableModelsCheck) Run(ctx *CheckContext) *CheckResult {
+	models, err := backend.LoadUnavailableModels(ctx.TownRoot)
+	if err != nil {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusWarning,
+			Message: "Could not read unavailable model records",
+			Details: []string{err.Error()},
+		}
+	}
+
+	if len(models) == 0 {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusOK,
+			Message: "No API models reported unavailable",
+		}
+	}
+
+	details := make([]string, 0, len(models))
+	for _, m := range models {
+		details = append(details, fmt.Sprintf("%s/%s (since %s): %s",
+			m.Backend, m.Model, m.DetectedAt.Format("2006-01-02"), m.Reason))
+	}
+
+	return &CheckResult{
+		Name:    c.Name(),
+		Status:  StatusWarning,
+		Message: fmt.Sprintf("%d API model(s) reported retired or not found", len(models)),
+		Details: details,
+		FixHint: "Update settings/backend.json to stop selecting these models, then run 'gt doctor --fix' to clear the records",
+	}
+}
+
+// Fix clears the unavailable model records.
+func (c *UnavailableModelsCheck) Fix(ctx *CheckContext) error {
+	err := os.Remove(backend.UnavailableModelsPath(ctx.TownRoot))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package doctor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeBackendConfig(t *testing.T, body string) string {
+	t.Helper()
+	townRoot := t.TempDir()
+	dir := filepath.Join(townRoot, "settings")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "backend.json"), []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return townRoot
+}
+
+func TestBackendConfigCheck_NoConfig(t *testing.T) {
+	result := NewBackendConfigCheck().Run(&CheckContext{TownRoot: t.TempDir()})
+	if result.Status != StatusOK {
+		t.Errorf("status = %v, want OK: %s", result.Status, result.Message)
+	}
+}
+
+func TestBackendConfigCheck_MissingKey(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	townRoot := writeBackendConfig(t, `{"type":"backend-config","version":1,"enabled":true,
+		"backends":{"openai":{"enabled":true}}}`)
+
+	result := NewBackendConfigCheck().Run(&CheckContext{TownRoot: townRoot})
+	if result.Status != StatusWarning {
+		t.Fatalf("status = %v, want warning: %s", result.Status, result.Message)
+	}
+	if !strings.Contains(result.FixHint, "OPENAI_API_KEY") {
+		t.Errorf("fix hint %q doesn't name OPENAI_API_KEY", result.FixHint)
+	}
+}
+
+func TestBackendConfigCheck_KeySet(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	townRoot := writeBackendConfig(t, `{"type":"backend-config","version":1,"enabled":true,
+		"backends":{"claude":{"enabled":false},"openai":{"enabled":true}}}`)
+
+	result := NewBackendConfigCheck().Run(&CheckContext{TownRoot: townRoot})
+	if result.Status != StatusOK {
+		t.Errorf("status = %v, want OK: %s %v", result.Status, result.Message, result.Details)
+	}
+}
+
+func TestBackendConfigCheck_BadBedrockRegion(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIATEST")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	townRoot := writeBackendConfig(t, `{"type":"backend-config","version":1,"enabled":true,
+		"backends":{"claude":{"enabled":false},"bedrock":{"enabled":true,"region":"not a region"}}}`)
+
+	result := NewBackendConfigCheck().Run(&CheckContext{TownRoot: townRoot})
+	if result.Status != StatusWarning {
+		t.Fatalf("status = %v, want warning: %s", result.Status, result.Message)
+	}
+	if len(result.Details) != 1 || !strings.HasPrefix(result.Details[0], "bedrock:") {
+		t.Errorf("details = %v, want one bedrock line", result.Details)
+	}
+}
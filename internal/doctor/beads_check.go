@@ -1,7 +1,6 @@
 package doctor
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -116,54 +115,54 @@ func (c *BeadsDatabaseCheck) Run(ctx *CheckContext) *CheckResult {
 // Fix attempts to rebuild the database from JSONL.
 // Note: This fix is for SQLite backend. With Dolt backend, this is a no-op.
 func (c *BeadsDatabaseCheck) Fix(ctx *CheckContext) error {
-	beadsDir := filepath.Join(ctx.TownRoot, ".beads")
+	// Check if we need to fix town-level database
+	if err := rebuildEmptyBeadsDB(ctx.TownRoot, filepath.Join(ctx.TownRoot, ".beads")); err != nil {
+		return err
+	}
+
+	// Also fix rig-level if specified (follows redirect if present)
+	if ctx.RigName != "" {
+		if err := rebuildEmptyBeadsDB(ctx.RigPath(), beads.ResolveBeadsDir(ctx.RigPath())); err != nil {
+			return fmt.Errorf("rig %s: %w", ctx.RigName, err)
+		}
+	}
+
+	return nil
+}
+
+// rebuildEmptyBeadsDB rebuilds beadsDir's issues.db from issues.jsonl when
+// the database is empty and the JSONL isn't, running 'bd import' from
+// workDir. It verifies the rebuilt database has content, since bd can exit
+// cleanly without importing anything.
+func rebuildEmptyBeadsDB(workDir, beadsDir string) error {
 	issuesDB := filepath.Join(beadsDir, "issues.db")
 	issuesJSONL := filepath.Join(beadsDir, "issues.jsonl")
 
-	// Check if we need to fix town-level database
 	dbInfo, dbErr := os.Stat(issuesDB)
 	jsonlInfo, jsonlErr := os.Stat(issuesJSONL)
-
-	if dbErr == nil && dbInfo.Size() == 0 && jsonlErr == nil && jsonlInfo.Size() > 0 {
-		// Delete the empty database file
-		if err := os.Remove(issuesDB); err != nil {
-			return err
-		}
-
-		// Run bd import to rebuild from JSONL
-		cmd := exec.Command("bd", "import")
-		cmd.Dir = ctx.TownRoot
-		var stderr bytes.Buffer
-		cmd.Stderr = &stderr
-		if err := cmd.Run(); err != nil {
-			return err
-		}
+	if dbErr != nil || dbInfo.Size() != 0 || jsonlErr != nil || jsonlInfo.Size() == 0 {
+		return nil
 	}
 
-	// Also fix rig-level if specified (follows redirect if present)
-	if ctx.RigName != "" {
-		rigBeadsDir := beads.ResolveBeadsDir(ctx.RigPath())
-		rigDB := filepath.Join(rigBeadsDir, "issues.db")
-		rigJSONL := filepath.Join(rigBeadsDir, "issues.jsonl")
-
-		rigDBInfo, rigDBErr := os.Stat(rigDB)
-		rigJSONLInfo, rigJSONLErr := os.Stat(rigJSONL)
+	// Check for bd before deleting anything it would need to recreate
+	if _, err := exec.LookPath("bd"); err != nil {
+		return fmt.Errorf("rebuilding %s: %w", issuesDB, beads.ErrNotInstalled)
+	}
 
-		if rigDBErr == nil && rigDBInfo.Size() == 0 && rigJSONLErr == nil && rigJSONLInfo.Size() > 0 {
-			if err := os.Remove(rigDB); err != nil {
-				return err
-			}
+	// Delete the empty database file
+	if err := os.Remove(issuesDB); err != nil {
+		return err
+	}
 
-			cmd := exec.Command("bd", "import")
-			cmd.Dir = ctx.RigPath()
-			var stderr bytes.Buffer
-			cmd.Stderr = &stderr
-			if err := cmd.Run(); err != nil {
-				return err
-			}
-		}
+	// Rebuild from JSONL; the wrapper reports bd's stderr on failure
+	if _, err := beads.New(workDir).Run("import"); err != nil {
+		return fmt.Errorf("rebuilding %s: %w", issuesDB, err)
 	}
 
+	info, err := os.Stat(issuesDB)
+	if err != nil || info.Size() == 0 {
+		return fmt.Errorf("fix ran but %s is still empty; run 'bd import' in %s to see why", issuesDB, workDir)
+	}
 	return nil
 }
 
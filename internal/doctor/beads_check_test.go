@@ -1,8 +1,11 @@
 package doctor
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/steveyegge/gastown/internal/beads"
@@ -79,6 +82,79 @@ func TestBeadsDatabaseCheck_EmptyDatabase(t *testing.T) {
 	}
 }
 
+// setupEmptyBeadsDB creates a town whose issues.db is empty while
+// issues.jsonl has content, and returns the town root.
+func setupEmptyBeadsDB(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	beadsDir := filepath.Join(tmpDir, ".beads")
+	if err := os.MkdirAll(beadsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(beadsDir, "issues.db"), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(beadsDir, "issues.jsonl"), []byte(`{"id":"test-1","title":"Test"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return tmpDir
+}
+
+// useFakeBd puts a bd script with the given body first on PATH.
+func useFakeBd(t *testing.T, body string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake bd script requires a POSIX shell")
+	}
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "bd"), []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestBeadsDatabaseCheck_FixWithoutBd(t *testing.T) {
+	tmpDir := setupEmptyBeadsDB(t)
+	t.Setenv("PATH", t.TempDir())
+
+	err := NewBeadsDatabaseCheck().Fix(&CheckContext{TownRoot: tmpDir})
+	if !errors.Is(err, beads.ErrNotInstalled) {
+		t.Fatalf("Fix() error = %v, want ErrNotInstalled", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(tmpDir, ".beads", "issues.db")); statErr != nil {
+		t.Errorf("issues.db was removed before finding bd: %v", statErr)
+	}
+}
+
+func TestBeadsDatabaseCheck_FixReportsStderr(t *testing.T) {
+	tmpDir := setupEmptyBeadsDB(t)
+	useFakeBd(t, "echo 'import: corrupt line 3' >&2\nexit 1\n")
+
+	err := NewBeadsDatabaseCheck().Fix(&CheckContext{TownRoot: tmpDir})
+	if err == nil || !strings.Contains(err.Error(), "corrupt line 3") {
+		t.Fatalf("Fix() error = %v, want bd's stderr", err)
+	}
+}
+
+func TestBeadsDatabaseCheck_FixVerifiesRebuild(t *testing.T) {
+	tmpDir := setupEmptyBeadsDB(t)
+	useFakeBd(t, "echo '{}'\nexit 0\n")
+
+	err := NewBeadsDatabaseCheck().Fix(&CheckContext{TownRoot: tmpDir})
+	if err == nil || !strings.Contains(err.Error(), "still empty") {
+		t.Fatalf("Fix() error = %v, want a still-empty error", err)
+	}
+}
+
+func TestBeadsDatabaseCheck_FixRebuilds(t *testing.T) {
+	tmpDir := setupEmptyBeadsDB(t)
+	useFakeBd(t, "echo 'SQLite format 3' > \"$BEADS_DIR/issues.db\"\necho '{}'\n")
+
+	if err := NewBeadsDatabaseCheck().Fix(&CheckContext{TownRoot: tmpDir}); err != nil {
+		t.Fatalf("Fix() error = %v", err)
+	}
+}
+
 func TestBeadsDatabaseCheck_PopulatedDatabase(t *testing.T) {
 	tmpDir := t.TempDir()
 	beadsDir := filepath.Join(tmpDir, ".beads")
@@ -2,6 +2,8 @@ package doctor
 
 import (
 	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -65,6 +67,34 @@ func TestCheckStatus_String(t *testing.T) {
 	}
 }
 
+func TestReport_JSON(t *testing.T) {
+	r := NewReport()
+	r.Add(&CheckResult{Name: "a", Status: StatusOK, Message: "fine"})
+	r.Add(&CheckResult{Name: "b", Status: StatusError, Message: "broken", Details: []string{"x"}, FixHint: "fix it"})
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	for _, want := range []string{`"status":"ok"`, `"status":"error"`, `"fix_hint":"fix it"`, `"errors":1`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON %s missing %s", data, want)
+		}
+	}
+
+	var decoded Report
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(decoded.Checks) != 2 || decoded.Checks[1].Status != StatusError || decoded.Summary.Errors != 1 {
+		t.Errorf("round trip = %+v", decoded)
+	}
+
+	if _, err := json.Marshal(CheckStatus(99)); err == nil {
+		t.Error("Marshal(CheckStatus(99)) succeeded, want error")
+	}
+}
+
 func TestCheckContext_RigPath(t *testing.T) {
 	tests := []struct {
 		name     string
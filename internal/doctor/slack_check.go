@@ -0,0 +1,111 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/slack"
+)
+
+// slackTestPostTimeout bounds the test notification posted under --fix,
+// retries included.
+const slackTestPostTimeout = 30 * time.Second
+
+// SlackConfigCheck validates settings/slack.json when Slack is enabled,
+// catching a missing or malformed webhook before jobs try to notify. With
+// --fix it also posts a test notification, since a well-formed webhook can
+// still have been revoked.
+type SlackConfigCheck struct {
+	BaseCheck
+}
+
+// NewSlackConfigCheck creates a new Slack config check.
+func NewSlackConfigCheck() *SlackConfigCheck {
+	return &SlackConfigCheck{
+		BaseCheck: BaseCheck{
+			CheckName:        "slack-config",
+			CheckDescription: "Check Slack notification settings",
+			CheckCategory:    CategoryConfig,
+		},
+	}
+}
+
+// Run validates the Slack config, and posts a test notification when fixing.
+func (c *SlackConfigCheck) Run(ctx *CheckContext) *CheckResult {
+	path := slack.ConfigPath(ctx.TownRoot)
+	cfg, err := slack.LoadConfig(ctx.TownRoot)
+	if err != nil {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusError,
+			Message: "Could not load settings/slack.json",
+			Details: []string{err.Error()},
+			FixHint: "Fix the settings in " + path,
+		}
+	}
+	if !cfg.Enabled {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusOK,
+			Message: "Slack notifications disabled",
+		}
+	}
+
+	mode := "bot token"
+	if cfg.BotToken == "" || cfg.Channel == "" {
+		mode = "webhook"
+		if problem := webhookURLProblem(cfg.WebhookURL); problem != "" {
+			return &CheckResult{
+				Name:    c.Name(),
+				Status:  StatusError,
+				Message: "Slack is enabled but " + problem,
+				FixHint: "Set webhook_url to a Slack incoming webhook (https://hooks.slack.com/services/...) in " + path + ", or set enabled to false",
+			}
+		}
+	}
+
+	if !ctx.Fixing {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusOK,
+			Message: fmt.Sprintf("Slack notifications enabled (%s)", mode),
+		}
+	}
+
+	postCtx, cancel := context.WithTimeout(context.Background(), slackTestPostTimeout)
+	defer cancel()
+	_, err = slack.NewClient(cfg).Send(postCtx, slack.EventJobCompleted, map[string]string{
+		slack.FieldBead:  "gt-doctor",
+		slack.FieldTitle: "Test notification from gt doctor --fix",
+	})
+	if err != nil {
+		return &CheckResult{
+			Name:    c.Name(),
+			Status:  StatusError,
+			Message: "Slack test notification failed",
+			Details: []string{err.Error()},
+			FixHint: "Check the webhook_url (or bot_token and channel) in " + path + ", or run 'gt slack test' for details",
+		}
+	}
+	return &CheckResult{
+		Name:    c.Name(),
+		Status:  StatusOK,
+		Message: fmt.Sprintf("Slack notifications enabled (%s); test notification delivered", mode),
+	}
+}
+
+// webhookURLProblem describes what is wrong with a webhook URL, or returns
+// "" if it looks like a Slack incoming webhook.
+func webhookURLProblem(raw string) string {
+	if raw == "" {
+		return "webhook_url is empty"
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host != "hooks.slack.com" || strings.Trim(u.Path, "/") == "" {
+		return fmt.Sprintf("webhook_url %q is not a Slack webhook (want https://hooks.slack.com/...)", raw)
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package doctor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runSlackConfigCheck(t *testing.T, body string) *CheckResult {
+	t.Helper()
+	townRoot := t.TempDir()
+	if body != "" {
+		dir := filepath.Join(townRoot, "settings")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "slack.json"), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewSlackConfigCheck().Run(&CheckContext{TownRoot: townRoot})
+}
+
+func TestSlackConfigCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status CheckStatus
+	}{
+		{"no config", "", StatusOK},
+		{"disabled", `{"enabled": false}`, StatusOK},
+		{"webhook", `{"enabled": true, "webhook_url": "https://hooks.slack.com/services/T0/B0/x"}`, StatusOK},
+		{"bot token", `{"enabled": true, "bot_token": "xoxb-1", "channel": "#ops"}`, StatusOK},
+		{"empty webhook", `{"enabled": true}`, StatusError},
+		{"not slack", `{"enabled": true, "webhook_url": "https://example.com/hook"}`, StatusError},
+		{"plain http", `{"enabled": true, "webhook_url": "http://hooks.slack.com/services/T0/B0/x"}`, StatusError},
+		{"bot token without channel", `{"enabled": true, "bot_token": "xoxb-1"}`, StatusError},
+		{"invalid json", `{"enabled": true`, StatusError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runSlackConfigCheck(t, tt.body)
+			if result.Status != tt.status {
+				t.Errorf("status = %v, want %v: %s", result.Status, tt.status, result.Message)
+			}
+			if result.Status == StatusError && !strings.Contains(result.FixHint, "slack.json") {
+				t.Errorf("fix hint %q doesn't point at settings/slack.json", result.FixHint)
+			}
+		})
+	}
+}
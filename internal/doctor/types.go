@@ -57,12 +57,43 @@ func (s CheckStatus) String() string {
 	}
 }
 
+// MarshalText encodes the status as a stable lowercase name ("ok",
+// "warning", "error") for JSON output.
+func (s CheckStatus) MarshalText() ([]byte, error) {
+	switch s {
+	case StatusOK:
+		return []byte("ok"), nil
+	case StatusWarning:
+		return []byte("warning"), nil
+	case StatusError:
+		return []byte("error"), nil
+	default:
+		return nil, fmt.Errorf("unknown check status %d", int(s))
+	}
+}
+
+// UnmarshalText decodes a status name written by MarshalText.
+func (s *CheckStatus) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "ok":
+		*s = StatusOK
+	case "warning":
+		*s = StatusWarning
+	case "error":
+		*s = StatusError
+	default:
+		return fmt.Errorf("unknown check status %q", text)
+	}
+	return nil
+}
+
 // CheckContext provides context for running checks.
 type CheckContext struct {
 	TownRoot        string // Root directory of the Gas Town workspace
 	RigName         string // Rig name (empty for town-level checks)
 	Verbose         bool   // Enable verbose output
 	RestartSessions bool   // Restart patrol sessions when fixing (requires explicit --restart-sessions flag)
+	Fixing          bool   // Running with --fix; checks may take actions they otherwise only report
 }
 
 // RigPath returns the full path to the rig directory.
@@ -79,14 +110,14 @@ const DefaultSlowThreshold = 1 * time.Second
 
 // CheckResult represents the outcome of a health check.
 type CheckResult struct {
-	Name     string        // Check name
-	Status   CheckStatus   // Result status
-	Message  string        // Primary result message
-	Details  []string      // Additional information
-	FixHint  string        // Suggestion if not auto-fixable
-	Category string        // Category for grouping (e.g., CategoryCore)
-	Elapsed  time.Duration // How long the check took to run
-	Fixed    bool          // True if this check was auto-fixed
+	Name     string        `json:"name"`               // Check name
+	Status   CheckStatus   `json:"status"`             // Result status
+	Message  string        `json:"message"`            // Primary result message
+	Details  []string      `json:"details,omitempty"`  // Additional information
+	FixHint  string        `json:"fix_hint,omitempty"` // Suggestion if not auto-fixable
+	Category string        `json:"category,omitempty"` // Category for grouping (e.g., CategoryCore)
+	Elapsed  time.Duration `json:"-"`                  // How long the check took to run
+	Fixed    bool          `json:"fixed,omitempty"`    // True if this check was auto-fixed
 }
 
 // Check defines the interface for a health check.
@@ -110,21 +141,21 @@ type Check interface {
 
 // ReportSummary summarizes the results of all checks.
 type ReportSummary struct {
-	Total       int
-	OK          int
-	Warnings    int
-	Errors      int
-	Fixed       int           // Checks that were auto-fixed
-	Slow        int           // Checks that took longer than threshold (counted during Print)
-	SlowestName string        // Name of the slowest check
-	SlowestTime time.Duration // Duration of the slowest check
+	Total       int           `json:"total"`
+	OK          int           `json:"ok"`
+	Warnings    int           `json:"warnings"`
+	Errors      int           `json:"errors"`
+	Fixed       int           `json:"fixed"` // Checks that were auto-fixed
+	Slow        int           `json:"-"`     // Checks that took longer than threshold (counted during Print)
+	SlowestName string        `json:"-"`     // Name of the slowest check
+	SlowestTime time.Duration `json:"-"`     // Duration of the slowest check
 }
 
 // Report contains all check results and a summary.
 type Report struct {
-	Timestamp time.Time
-	Checks    []*CheckResult
-	Summary   ReportSummary
+	Timestamp time.Time      `json:"timestamp"`
+	Checks    []*CheckResult `json:"checks"`
+	Summary   ReportSummary  `json:"summary"`
 }
 
 // NewReport creates an empty report with the current timestamp.
@@ -1,6 +1,7 @@
 package doctor
 
 import (
+	"os"
 	"testing"
 )
 
@@ -66,6 +67,17 @@ func TestZombieSessionCheck_FixProtectsCrewSessions(t *testing.T) {
 
 	ctx := &CheckContext{TownRoot: t.TempDir()}
 
+	// Fix logs session deaths to the town found from the cwd; run it from
+	// the temp dir so the log isn't written into the source tree.
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(originalWd) })
+	if err := os.Chdir(ctx.TownRoot); err != nil {
+		t.Fatal(err)
+	}
+
 	// Fix should skip crew sessions due to safeguard
 	// (We can't fully test this without mocking tmux, but the safeguard is in place)
 	_ = check.Fix(ctx)
@@ -0,0 +1,139 @@
+package formula
+
+import (
+	"slices"
+	"sort"
+)
+
+// Diff describes the step and variable differences between two formulas.
+type Diff struct {
+	// AddedSteps and RemovedSteps are step IDs present in only one formula,
+	// in the order they appear in that formula.
+	AddedSteps   []string
+	RemovedSteps []string
+
+	// ChangedSteps lists steps present in both formulas that differ,
+	// in the order they appear in the second formula.
+	ChangedSteps []StepDiff
+
+	// AddedVars and RemovedVars are sorted variable names present in only
+	// one formula.
+	AddedVars   []string
+	RemovedVars []string
+
+	// ChangedVars lists variables present in both formulas that differ,
+	// sorted by name.
+	ChangedVars []VarDiff
+}
+
+// StepDiff describes how a step with the same ID differs between formulas.
+type StepDiff struct {
+	ID       string
+	OldTitle string
+	NewTitle string
+
+	// DescriptionChanged is true if the step instructions differ.
+	DescriptionChanged bool
+
+	// NeedsChanged is true if the step dependencies differ.
+	NeedsChanged bool
+	OldNeeds     []string
+	NewNeeds     []string
+
+	// ParallelChanged is true if the step's parallel flag differs.
+	ParallelChanged bool
+
+	// OldTier and NewTier are the step's model tiers.
+	OldTier string
+	NewTier string
+}
+
+// TitleChanged reports whether the step title differs.
+func (d StepDiff) TitleChanged() bool {
+	return d.OldTitle != d.NewTitle
+}
+
+// TierChanged reports whether the step's model tier differs.
+func (d StepDiff) TierChanged() bool {
+	return d.OldTier != d.NewTier
+}
+
+// VarDiff describes how a variable with the same name differs.
+type VarDiff struct {
+	Name string
+	Old  Var
+	New  Var
+}
+
+// Empty reports whether the formulas have no step or variable differences.
+func (d *Diff) Empty() bool {
+	return len(d.AddedSteps) == 0 && len(d.RemovedSteps) == 0 && len(d.ChangedSteps) == 0 &&
+		len(d.AddedVars) == 0 && len(d.RemovedVars) == 0 && len(d.ChangedVars) == 0
+}
+
+// Compare reports the step and variable differences from a to b.
+// Steps are matched by ID.
+func Compare(a, b *Formula) *Diff {
+	d := &Diff{}
+
+	oldSteps := make(map[string]Step, len(a.Steps))
+	for _, s := range a.Steps {
+		oldSteps[s.ID] = s
+	}
+	newSteps := make(map[string]bool, len(b.Steps))
+	for _, s := range b.Steps {
+		newSteps[s.ID] = true
+		old, ok := oldSteps[s.ID]
+		if !ok {
+			d.AddedSteps = append(d.AddedSteps, s.ID)
+			continue
+		}
+		sd := StepDiff{
+			ID:                 s.ID,
+			OldTitle:           old.Title,
+			NewTitle:           s.Title,
+			DescriptionChanged: old.Description != s.Description,
+			NeedsChanged:       !slices.Equal(old.Needs, s.Needs),
+			OldNeeds:           old.Needs,
+			NewNeeds:           s.Needs,
+			ParallelChanged:    old.Parallel != s.Parallel,
+			OldTier:            old.Tier,
+			NewTier:            s.Tier,
+		}
+		if sd.TitleChanged() || sd.DescriptionChanged || sd.NeedsChanged || sd.ParallelChanged || sd.TierChanged() {
+			d.ChangedSteps = append(d.ChangedSteps, sd)
+		}
+	}
+	for _, s := range a.Steps {
+		if !newSteps[s.ID] {
+			d.RemovedSteps = append(d.RemovedSteps, s.ID)
+		}
+	}
+
+	for _, name := range sortedVarNames(b.Vars) {
+		old, ok := a.Vars[name]
+		if !ok {
+			d.AddedVars = append(d.AddedVars, name)
+			continue
+		}
+		if old != b.Vars[name] {
+			d.ChangedVars = append(d.ChangedVars, VarDiff{Name: name, Old: old, New: b.Vars[name]})
+		}
+	}
+	for _, name := range sortedVarNames(a.Vars) {
+		if _, ok := b.Vars[name]; !ok {
+			d.RemovedVars = append(d.RemovedVars, name)
+		}
+	}
+
+	return d
+}
+
+func sortedVarNames(vars map[string]Var) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,97 @@
+package formula
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCompareShippedPolecatWorkFormulas(t *testing.T) {
+	_, testFile, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("cannot determine test file path")
+	}
+	formulaDir := filepath.Join(filepath.Dir(testFile), "formulas")
+
+	solo, err := ParseFile(filepath.Join(formulaDir, "mol-polecat-work.formula.toml"))
+	if err != nil {
+		t.Fatalf("ParseFile(mol-polecat-work): %v", err)
+	}
+	team, err := ParseFile(filepath.Join(formulaDir, "mol-polecat-work-team.formula.toml"))
+	if err != nil {
+		t.Fatalf("ParseFile(mol-polecat-work-team): %v", err)
+	}
+
+	d := Compare(solo, team)
+	if len(d.AddedSteps) != 0 || len(d.RemovedSteps) != 0 {
+		t.Errorf("formulas share step IDs, got added=%v removed=%v", d.AddedSteps, d.RemovedSteps)
+	}
+
+	var implement *StepDiff
+	for i := range d.ChangedSteps {
+		if d.ChangedSteps[i].ID == "implement" {
+			implement = &d.ChangedSteps[i]
+		}
+	}
+	if implement == nil {
+		t.Fatalf("implement step not reported as changed: %+v", d.ChangedSteps)
+	}
+	if !implement.TitleChanged() {
+		t.Error("implement step title change not reported")
+	}
+	if implement.OldTitle != "Implement the solution" || implement.NewTitle != "Implement the solution (team-coordinated)" {
+		t.Errorf("implement titles = %q -> %q", implement.OldTitle, implement.NewTitle)
+	}
+
+	if !Compare(solo, solo).Empty() {
+		t.Error("a formula compared with itself should have no differences")
+	}
+}
+
+func TestCompareStepsAndVars(t *testing.T) {
+	a := &Formula{
+		Steps: []Step{{ID: "build", Title: "Build"}, {ID: "lint", Title: "Lint"}},
+		Vars: map[string]Var{
+			"issue":  {Required: true},
+			"branch": {Default: "main"},
+		},
+	}
+	b := &Formula{
+		Steps: []Step{{ID: "build", Title: "Build", Needs: []string{"setup"}}, {ID: "deploy", Title: "Deploy"}},
+		Vars: map[string]Var{
+			"issue":  {Required: true},
+			"branch": {Default: "develop"},
+			"target": {},
+		},
+	}
+
+	d := Compare(a, b)
+	if len(d.AddedSteps) != 1 || d.AddedSteps[0] != "deploy" {
+		t.Errorf("AddedSteps = %v, want [deploy]", d.AddedSteps)
+	}
+	if len(d.RemovedSteps) != 1 || d.RemovedSteps[0] != "lint" {
+		t.Errorf("RemovedSteps = %v, want [lint]", d.RemovedSteps)
+	}
+	if len(d.ChangedSteps) != 1 || !d.ChangedSteps[0].NeedsChanged || d.ChangedSteps[0].TitleChanged() {
+		t.Errorf("ChangedSteps = %+v, want build with changed needs only", d.ChangedSteps)
+	}
+	if len(d.AddedVars) != 1 || d.AddedVars[0] != "target" {
+		t.Errorf("AddedVars = %v, want [target]", d.AddedVars)
+	}
+	if len(d.RemovedVars) != 0 {
+		t.Errorf("RemovedVars = %v, want none", d.RemovedVars)
+	}
+	if len(d.ChangedVars) != 1 || d.ChangedVars[0].Name != "branch" || d.ChangedVars[0].New.Default != "develop" {
+		t.Errorf("ChangedVars = %+v, want branch default change", d.ChangedVars)
+	}
+}
+
+func TestCompareStepTier(t *testing.T) {
+	a := &Formula{Steps: []Step{{ID: "implement", Title: "Implement"}}}
+	b := &Formula{Steps: []Step{{ID: "implement", Title: "Implement", Tier: "opus"}}}
+
+	d := Compare(a, b)
+	if len(d.ChangedSteps) != 1 || !d.ChangedSteps[0].TierChanged() || d.ChangedSteps[0].NewTier != "opus" {
+		t.Errorf("ChangedSteps = %+v, want implement with tier opus", d.ChangedSteps)
+	}
+}
@@ -89,10 +89,11 @@
 // # Cycle Detection
 //
 // Workflow and expansion formulas are validated for circular dependencies
-// using depth-first search. Cycles are reported with the offending step ID:
+// using depth-first search. Cycles are reported as the path of needs that
+// closes the loop:
 //
 //	f, err := formula.Parse([]byte(tomlContent))
-//	// Returns: "cycle detected involving: build"
+//	// Returns: "cycle detected: build → test → build"
 //
 // # Topological Sorting
 //
@@ -188,7 +188,7 @@ needs = ["first"]
 }
 
 func ExampleFormula_Validate_cycleDetection() {
-	// This formula has a cycle: a -> b -> c -> a
+	// This formula has a cycle: a needs c, c needs b, and b needs a
 	toml := `
 formula = "cyclic"
 type = "workflow"
@@ -214,7 +214,7 @@ needs = ["b"]
 	}
 
 	// Output:
-	// Validation error: cycle detected involving: a
+	// Validation error: cycle detected: a → c → b → a
 }
 
 func ExampleFormula_GetStep() {
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -180,6 +181,69 @@ func TestTeamFormulaReadySteps(t *testing.T) {
 	}
 }
 
+// TestTeamFormulaExecutionLevels verifies steps are grouped into waves
+// that respect their dependencies.
+func TestTeamFormulaExecutionLevels(t *testing.T) {
+	_, testFile, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("cannot determine test file path")
+	}
+	formulaDir := filepath.Join(filepath.Dir(testFile), "formulas")
+	formulaPath := filepath.Join(formulaDir, "mol-polecat-work-team.formula.toml")
+
+	f, err := ParseFile(formulaPath)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	levels := f.ExecutionLevels()
+	if len(levels) == 0 || len(levels[0]) != 1 || levels[0][0] != "load-context" {
+		t.Fatalf("level 0 = %v, want [load-context]", levels)
+	}
+
+	levelOf := make(map[string]int)
+	for i, level := range levels {
+		for _, id := range level {
+			levelOf[id] = i
+		}
+	}
+	if len(levelOf) != len(f.Steps) {
+		t.Errorf("levels cover %d steps, want %d", len(levelOf), len(f.Steps))
+	}
+	for _, step := range f.Steps {
+		for _, need := range step.Needs {
+			if levelOf[need] >= levelOf[step.ID] {
+				t.Errorf("step %s (level %d) needs %s (level %d)", step.ID, levelOf[step.ID], need, levelOf[need])
+			}
+		}
+	}
+}
+
+func TestExecutionLevels_Waves(t *testing.T) {
+	f := &Formula{
+		Name: "fan-out",
+		Type: TypeWorkflow,
+		Steps: []Step{
+			{ID: "setup"},
+			{ID: "lint", Needs: []string{"setup"}},
+			{ID: "test", Needs: []string{"setup"}},
+			{ID: "docs"},
+			{ID: "merge", Needs: []string{"lint", "test"}},
+		},
+	}
+
+	got := f.ExecutionLevels()
+	want := [][]string{{"setup", "docs"}, {"lint", "test"}, {"merge"}}
+	if len(got) != len(want) {
+		t.Fatalf("ExecutionLevels() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if strings.Join(got[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("level %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
 // TestOriginalFormulaStillParses ensures the original formula isn't broken.
 func TestOriginalFormulaStillParses(t *testing.T) {
 	_, testFile, _, ok := runtime.Caller(0)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 )
@@ -142,6 +143,15 @@ func (f *Formula) validateWorkflow() error {
 		}
 	}
 
+	// Validate step tiers
+	for _, step := range f.Steps {
+		switch strings.ToLower(step.Tier) {
+		case "", "haiku", "sonnet", "opus":
+		default:
+			return fmt.Errorf("step %q has invalid tier %q (must be haiku, sonnet, or opus)", step.ID, step.Tier)
+		}
+	}
+
 	// Check for cycles
 	if err := f.checkCycles(); err != nil {
 		return err
@@ -222,21 +232,30 @@ func (f *Formula) checkExpansionCycles() error {
 	return checkDependencyCycles(deps)
 }
 
-// checkDependencyCycles detects cycles in a dependency graph.
+// checkDependencyCycles detects cycles in a dependency graph. The error
+// names the cycle in needs order: "a → b → a" means a needs b and b needs a.
 func checkDependencyCycles(deps map[string][]string) error {
 	visited := make(map[string]bool)
 	inStack := make(map[string]bool)
+	var stack []string
 
 	var visit func(id string) error
 	visit = func(id string) error {
 		if inStack[id] {
-			return fmt.Errorf("cycle detected involving: %s", id)
+			// The cycle runs from id's position in the stack back to id
+			start := len(stack) - 1
+			for stack[start] != id {
+				start--
+			}
+			path := append(append([]string(nil), stack[start:]...), id)
+			return fmt.Errorf("cycle detected: %s", strings.Join(path, " → "))
 		}
 		if visited[id] {
 			return nil
 		}
 		visited[id] = true
 		inStack[id] = true
+		stack = append(stack, id)
 
 		for _, dep := range deps[id] {
 			if err := visit(dep); err != nil {
@@ -244,6 +263,7 @@ func checkDependencyCycles(deps map[string][]string) error {
 			}
 		}
 
+		stack = stack[:len(stack)-1]
 		inStack[id] = false
 		return nil
 	}
@@ -266,7 +286,7 @@ func checkDependencyCycles(deps map[string][]string) error {
 
 // TopologicalSort returns steps in dependency order (dependencies before dependents).
 // Only applicable to workflow and expansion formulas.
-// Returns an error if there are cycles.
+// Returns an error naming the cycle if there is one.
 func (f *Formula) TopologicalSort() ([]string, error) {
 	var items []string
 	var deps map[string][]string
@@ -349,6 +369,10 @@ func (f *Formula) TopologicalSort() ([]string, error) {
 	}
 
 	if len(result) != len(items) {
+		// Name the cycle for the formula author
+		if err := checkDependencyCycles(deps); err != nil {
+			return nil, err
+		}
 		return nil, fmt.Errorf("cycle detected in dependencies")
 	}
 
@@ -412,6 +436,26 @@ func (f *Formula) ReadySteps(completed map[string]bool) []string {
 	return ready
 }
 
+// ExecutionLevels groups steps into waves that can run concurrently: every
+// step's dependencies are in earlier waves, and each step is in the
+// earliest wave it can be. Within a wave, steps keep their formula order.
+// Convoy legs and aspects form a single wave. Steps in a dependency cycle,
+// which Parse rejects, are left out.
+func (f *Formula) ExecutionLevels() [][]string {
+	var levels [][]string
+	completed := make(map[string]bool)
+	for {
+		ready := f.ReadySteps(completed)
+		if len(ready) == 0 {
+			return levels
+		}
+		levels = append(levels, ready)
+		for _, id := range ready {
+			completed[id] = true
+		}
+	}
+}
+
 // GetStep returns a step by ID, or nil if not found.
 func (f *Formula) GetStep(id string) *Step {
 	for i := range f.Steps {
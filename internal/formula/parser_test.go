@@ -1,6 +1,7 @@
 package formula
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -201,6 +202,42 @@ needs = ["nonexistent"]
 	}
 }
 
+func TestParse_StepTier(t *testing.T) {
+	data := []byte(`
+formula = "test"
+[[steps]]
+id = "implement"
+title = "Implement"
+tier = "opus"
+[[steps]]
+id = "review"
+title = "Review"
+needs = ["implement"]
+`)
+
+	f, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := f.GetStep("implement").Tier; got != "opus" {
+		t.Errorf("implement tier = %q, want opus", got)
+	}
+	if got := f.GetStep("review").Tier; got != "" {
+		t.Errorf("review tier = %q, want none", got)
+	}
+
+	_, err = Parse([]byte(`
+formula = "test"
+[[steps]]
+id = "implement"
+title = "Implement"
+tier = "gpt"
+`))
+	if err == nil || !strings.Contains(err.Error(), "invalid tier") {
+		t.Errorf("Parse with tier gpt: error = %v, want invalid tier", err)
+	}
+}
+
 func TestValidate_Cycle(t *testing.T) {
 	data := []byte(`
 formula = "test"
@@ -218,7 +255,33 @@ needs = ["step1"]
 
 	_, err := Parse(data)
 	if err == nil {
-		t.Error("expected error for cycle")
+		t.Fatal("expected error for cycle")
+	}
+	if !strings.Contains(err.Error(), "step1 → step2 → step1") {
+		t.Errorf("error %q doesn't name the cycle", err)
+	}
+}
+
+func TestTopologicalSort_CyclePath(t *testing.T) {
+	// Built directly, since Parse rejects cycles before sorting
+	f := &Formula{
+		Name: "cyclic",
+		Type: TypeWorkflow,
+		Steps: []Step{
+			{ID: "setup"},
+			{ID: "a", Needs: []string{"setup", "c"}},
+			{ID: "b", Needs: []string{"a"}},
+			{ID: "c", Needs: []string{"b"}},
+			{ID: "report", Needs: []string{"c"}},
+		},
+	}
+
+	_, err := f.TopologicalSort()
+	if err == nil {
+		t.Fatal("expected error for cycle")
+	}
+	if got, want := err.Error(), "cycle detected: a → c → b → a"; got != want {
+		t.Errorf("error = %q, want %q", got, want)
 	}
 }
 
@@ -0,0 +1,124 @@
+package formula
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// UndefinedVars reports placeholders in one part of a formula for
+// variables that aren't defined in [vars] or passed to Resolve.
+type UndefinedVars struct {
+	// Where names the part of the formula, e.g. "step build" or "synthesis".
+	Where string
+
+	// Names are the undefined variables, sorted.
+	Names []string
+}
+
+// String describes the warning, e.g. "step build uses undefined variables: model".
+func (u UndefinedVars) String() string {
+	return fmt.Sprintf("%s uses undefined variables: %s", u.Where, strings.Join(u.Names, ", "))
+}
+
+// Resolve returns a copy of the formula with {{variable}} placeholders in
+// step, leg, synthesis, template, and aspect text replaced by their values.
+//
+// A variable's value comes from vars, falling back to its default in
+// [vars]. Resolve fails if a required variable has neither. Variables
+// defined with an empty default and not passed in vars are left as
+// placeholders, since they're computed when the molecule is poured.
+// Placeholders for variables that aren't defined in [vars] or passed in
+// vars are left as-is and reported in the returned warnings, one per step,
+// leg, synthesis, template, or aspect that uses them.
+func (f *Formula) Resolve(vars map[string]string) (*Formula, []UndefinedVars, error) {
+	values := make(map[string]string, len(f.Vars)+len(vars))
+	var missing []string
+	for name, v := range f.Vars {
+		switch {
+		case hasKey(vars, name):
+			values[name] = vars[name]
+		case v.Default != "":
+			values[name] = v.Default
+		case v.Required:
+			missing = append(missing, name)
+		}
+	}
+	for name, value := range vars {
+		values[name] = value
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, nil, fmt.Errorf("formula %s: missing required variables: %s", f.Name, strings.Join(missing, ", "))
+	}
+
+	var warnings []UndefinedVars
+	var undefined map[string]bool
+	subst := func(text string) string {
+		return variablePattern.ReplaceAllStringFunc(text, func(match string) string {
+			name := match[2 : len(match)-2]
+			if isHandlebarsKeyword(name) {
+				return match
+			}
+			if value, ok := values[name]; ok {
+				return value
+			}
+			if _, defined := f.Vars[name]; !defined {
+				undefined[name] = true
+			}
+			return match
+		})
+	}
+	// substAll substitutes each of texts, recording a warning for where if
+	// any of them use undefined variables
+	substAll := func(where string, texts ...*string) {
+		undefined = make(map[string]bool)
+		for _, text := range texts {
+			*text = subst(*text)
+		}
+		if len(undefined) == 0 {
+			return
+		}
+		names := make([]string, 0, len(undefined))
+		for name := range undefined {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		warnings = append(warnings, UndefinedVars{Where: where, Names: names})
+	}
+
+	resolved := *f
+	resolved.Steps = append([]Step(nil), f.Steps...)
+	for i := range resolved.Steps {
+		step := &resolved.Steps[i]
+		substAll("step "+step.ID, &step.Title, &step.Description)
+	}
+	resolved.Legs = append([]Leg(nil), f.Legs...)
+	for i := range resolved.Legs {
+		leg := &resolved.Legs[i]
+		substAll("leg "+leg.ID, &leg.Title, &leg.Focus, &leg.Description)
+	}
+	if f.Synthesis != nil {
+		synthesis := *f.Synthesis
+		substAll("synthesis", &synthesis.Title, &synthesis.Description)
+		resolved.Synthesis = &synthesis
+	}
+	resolved.Template = append([]Template(nil), f.Template...)
+	for i := range resolved.Template {
+		tmpl := &resolved.Template[i]
+		substAll("template "+tmpl.ID, &tmpl.Title, &tmpl.Description)
+	}
+	resolved.Aspects = append([]Aspect(nil), f.Aspects...)
+	for i := range resolved.Aspects {
+		aspect := &resolved.Aspects[i]
+		substAll("aspect "+aspect.ID, &aspect.Title, &aspect.Focus, &aspect.Description)
+	}
+
+	return &resolved, warnings, nil
+}
+
+// hasKey reports whether m has an entry for key.
+func hasKey(m map[string]string, key string) bool {
+	_, ok := m[key]
+	return ok
+}
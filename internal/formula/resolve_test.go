@@ -0,0 +1,106 @@
+package formula
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	f := &Formula{
+		Name: "test",
+		Type: TypeWorkflow,
+		Steps: []Step{
+			{ID: "a", Title: "Work on {{issue}}", Description: "Use {{model}} with {{count}} and {{ready_count}}{{#if x}}{{else}}{{/if}}"},
+		},
+		Vars: map[string]Var{
+			"issue":       {Required: true},
+			"model":       {Default: "sonnet"},
+			"count":       {Default: "3"},
+			"ready_count": {Default: ""},
+		},
+	}
+
+	resolved, warnings, err := f.Resolve(map[string]string{"issue": "gt-1", "count": "5"})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %v, want none", warnings)
+	}
+	if got := resolved.Steps[0].Title; got != "Work on gt-1" {
+		t.Errorf("Title = %q", got)
+	}
+	if got, want := resolved.Steps[0].Description, "Use sonnet with 5 and {{ready_count}}{{#if x}}{{else}}{{/if}}"; got != want {
+		t.Errorf("Description = %q, want %q", got, want)
+	}
+	if f.Steps[0].Title != "Work on {{issue}}" {
+		t.Errorf("Resolve modified the original formula: %q", f.Steps[0].Title)
+	}
+}
+
+func TestResolve_MissingRequired(t *testing.T) {
+	f := &Formula{
+		Name: "test",
+		Type: TypeWorkflow,
+		Vars: map[string]Var{"issue": {Required: true}, "base": {Required: true}, "model": {Default: "sonnet"}},
+	}
+
+	_, _, err := f.Resolve(map[string]string{"model": "opus"})
+	if err == nil || !strings.Contains(err.Error(), "missing required variables: base, issue") {
+		t.Fatalf("Resolve() error = %v, want missing base and issue", err)
+	}
+}
+
+func TestResolve_UndefinedPlaceholderKept(t *testing.T) {
+	f := &Formula{
+		Name: "test",
+		Type: TypeWorkflow,
+		Steps: []Step{
+			{ID: "a", Title: "{{mystery}}", Description: "{{zeta}} {{alpha}} {{mystery}}"},
+			{ID: "b", Title: "{{issue}}"},
+			{ID: "c", Title: "{{passed}}"},
+		},
+		Vars: map[string]Var{"issue": {Default: "gt-1"}},
+	}
+
+	resolved, warnings, err := f.Resolve(map[string]string{"passed": "yes"})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if got := resolved.Steps[0].Title; got != "{{mystery}}" {
+		t.Errorf("Title = %q, want the placeholder kept", got)
+	}
+	if len(warnings) != 1 || warnings[0].String() != "step a uses undefined variables: alpha, mystery, zeta" {
+		t.Errorf("warnings = %v, want one for step a", warnings)
+	}
+}
+
+// TestResolve_EmbeddedFormulas checks every shipped formula resolves with
+// its required variables set.
+func TestResolve_EmbeddedFormulas(t *testing.T) {
+	_, testFile, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("cannot determine test file path")
+	}
+	paths, err := filepath.Glob(filepath.Join(filepath.Dir(testFile), "formulas", "*.formula.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		f, err := ParseFile(path)
+		if err != nil {
+			continue // parse coverage lives in the embed tests
+		}
+		vars := make(map[string]string)
+		for name, v := range f.Vars {
+			if v.Required {
+				vars[name] = "x"
+			}
+		}
+		if _, _, err := f.Resolve(vars); err != nil {
+			t.Errorf("%s: %v", filepath.Base(path), err)
+		}
+	}
+}
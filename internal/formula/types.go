@@ -94,6 +94,7 @@ type Step struct {
 	Description string   `toml:"description"`
 	Needs       []string `toml:"needs"`
 	Parallel    bool     `toml:"parallel"` // If true, this step can run concurrently with other parallel steps that share the same needs
+	Tier        string   `toml:"tier"`     // Optional model tier for API routing: haiku, sonnet, or opus
 }
 
 // Template represents a template step in an expansion formula.
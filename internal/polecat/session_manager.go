@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -237,9 +238,7 @@ func (m *SessionManager) Start(polecat string, opts SessionStartOptions) error {
 	// Agent teams: inject experimental flag so Claude Code enables teammate spawning.
 	// Uses in-process display mode to avoid tmux nesting issues.
 	if opts.TeamConfig != nil && opts.TeamConfig.Enabled {
-		command = config.PrependEnv(command, map[string]string{
-			"CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS": "1",
-		})
+		command = config.PrependEnv(command, teamEnv(opts.TeamConfig))
 	}
 
 	// Create session with command directly to avoid send-keys race condition.
@@ -274,8 +273,9 @@ func (m *SessionManager) Start(polecat string, opts SessionStartOptions) error {
 
 	// Agent teams: set tmux env so respawned processes also inherit the flag.
 	if opts.TeamConfig != nil && opts.TeamConfig.Enabled {
-		debugSession("SetEnvironment CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS",
-			m.tmux.SetEnvironment(sessionID, "CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS", "1"))
+		for k, v := range teamEnv(opts.TeamConfig) {
+			debugSession("SetEnvironment "+k, m.tmux.SetEnvironment(sessionID, k, v))
+		}
 	}
 
 	// Hook the issue to the polecat if provided via --issue flag
@@ -333,6 +333,12 @@ func (m *SessionManager) Start(polecat string, opts SessionStartOptions) error {
 			"Use Shift+Tab to delegate tasks to teammates. "+
 			"Only YOU (the lead polecat) can run `gt done`.",
 			opts.TeamConfig.MaxTeammates, opts.TeamConfig.TeammateModel)
+		if opts.TeamConfig.BudgetUSD > 0 {
+			teamNudge += fmt.Sprintf(" API budget: $%.2f per teammate per day. "+
+				"Have each teammate set %s=<its name> when it runs gt; "+
+				"its API calls are refused once its spend reaches the budget.",
+				opts.TeamConfig.BudgetUSD, config.EnvTeammate)
+		}
 		debugSession("SendTeamNudge", m.tmux.NudgeSession(sessionID, teamNudge))
 	}
 
@@ -355,6 +361,17 @@ func (m *SessionManager) Start(polecat string, opts SessionStartOptions) error {
 	return nil
 }
 
+// teamEnv returns the environment an agent-team session needs: the flag
+// that enables teammate spawning and, with a budget, the per-teammate
+// limit the backend dispatcher enforces.
+func teamEnv(teamConfig *config.TeamConfig) map[string]string {
+	env := map[string]string{"CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS": "1"}
+	if teamConfig.BudgetUSD > 0 {
+		env[config.EnvTeammateBudget] = strconv.FormatFloat(teamConfig.BudgetUSD, 'f', -1, 64)
+	}
+	return env
+}
+
 // isSessionStale checks if a tmux session's pane process has died.
 // A stale session exists in tmux but its main process (the agent) is no longer running.
 // This happens when the agent crashes during startup but tmux keeps the dead pane.
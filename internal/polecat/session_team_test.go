@@ -155,3 +155,19 @@ func itoa(n int) string {
 	}
 	return s
 }
+
+// TestTeamEnv verifies the team session carries the per-teammate budget.
+func TestTeamEnv(t *testing.T) {
+	env := teamEnv(&config.TeamConfig{Enabled: true, MaxTeammates: 3})
+	if env["CLAUDE_CODE_EXPERIMENTAL_AGENT_TEAMS"] != "1" {
+		t.Errorf("teamEnv() = %v, want agent teams enabled", env)
+	}
+	if _, ok := env[config.EnvTeammateBudget]; ok {
+		t.Errorf("teamEnv() without a budget set %s", config.EnvTeammateBudget)
+	}
+
+	env = teamEnv(&config.TeamConfig{Enabled: true, MaxTeammates: 3, BudgetUSD: 1.5})
+	if got := env[config.EnvTeammateBudget]; got != "1.5" {
+		t.Errorf("%s = %q, want 1.5", config.EnvTeammateBudget, got)
+	}
+}
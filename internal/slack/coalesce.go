@@ -0,0 +1,122 @@
+package slack
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Coalescing defaults, used when the coalesce block leaves them unset.
+const (
+	defaultCoalesceWindow    = 10 * time.Second
+	defaultCoalesceThreshold = 10
+)
+
+// coalescer batches same-event notifications that arrive within a window,
+// so a burst (twenty beads slung at once) posts one summary message instead
+// of tripping Slack's rate limit.
+type coalescer struct {
+	window    time.Duration
+	threshold int
+
+	mu      sync.Mutex
+	pending map[EventType]*pendingBatch
+}
+
+// pendingBatch is a batch waiting for its window to close.
+type pendingBatch struct {
+	items []map[string]string
+	timer *time.Timer
+}
+
+func newCoalescer(s CoalesceSettings) *coalescer {
+	co := &coalescer{
+		window:    s.WindowDuration(),
+		threshold: s.Threshold,
+		pending:   make(map[EventType]*pendingBatch),
+	}
+	if co.threshold <= 0 {
+		co.threshold = defaultCoalesceThreshold
+	}
+	return co
+}
+
+// add holds a notification for event. The first one starts the window;
+// the batch is flushed when the window closes or it reaches the threshold.
+func (co *coalescer) add(c *Client, event EventType, fields map[string]string) {
+	co.mu.Lock()
+	b := co.pending[event]
+	if b == nil {
+		b = &pendingBatch{}
+		b.timer = time.AfterFunc(co.window, func() { co.flush(c, event) })
+		co.pending[event] = b
+	}
+	b.items = append(b.items, fields)
+	if len(b.items) < co.threshold {
+		co.mu.Unlock()
+		return
+	}
+	b.timer.Stop()
+	delete(co.pending, event)
+	co.mu.Unlock()
+
+	enqueueBatch(c, event, b.items)
+}
+
+// flush sends event's pending batch, if any.
+func (co *coalescer) flush(c *Client, event EventType) {
+	co.mu.Lock()
+	b := co.pending[event]
+	delete(co.pending, event)
+	co.mu.Unlock()
+
+	if b != nil {
+		b.timer.Stop()
+		enqueueBatch(c, event, b.items)
+	}
+}
+
+// flushAll sends every pending batch.
+func (co *coalescer) flushAll(c *Client) {
+	co.mu.Lock()
+	events := make([]EventType, 0, len(co.pending))
+	for event := range co.pending {
+		events = append(events, event)
+	}
+	co.mu.Unlock()
+
+	for _, event := range events {
+		co.flush(c, event)
+	}
+}
+
+// enqueueBatch queues a lone notification as itself and larger batches as
+// one summary.
+func enqueueBatch(c *Client, event EventType, items []map[string]string) {
+	if len(items) == 1 {
+		enqueue(notifyJob{client: c, event: event, fields: items[0]})
+		return
+	}
+	enqueue(notifyJob{client: c, event: event, batch: items})
+}
+
+// Flush sends any notifications the client is holding for coalescing
+// without waiting for their window to close. Drain calls it for the global
+// client before a command exits.
+func (c *Client) Flush() {
+	if c.coalesce != nil {
+		c.coalesce.flushAll(c)
+	}
+}
+
+// postBatch posts one summary message for a batch of event notifications.
+func (c *Client) postBatch(ctx context.Context, event EventType, items []map[string]string) error {
+	if !c.enabled || !c.shouldNotify(event) {
+		return nil
+	}
+
+	msg := formatBatchMessage(event, items, c.location)
+	msg.Channel = c.channelFor(event)
+	_, err := c.send(ctx, msg, "")
+	return err
+}
@@ -3,8 +3,13 @@ package slack
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 )
 
 // Config holds Slack notification configuration.
@@ -16,10 +21,110 @@ type Config struct {
 	WebhookURL string `json:"webhook_url"`
 
 	// Channel is the default channel (can be overridden by webhook config).
+	// Required when posting with BotToken.
 	Channel string `json:"channel,omitempty"`
 
+	// EventChannels routes event types to their own channels, e.g.
+	// {"job_failed": "#alerts"}. Events without an entry go to Channel.
+	// Incoming webhooks are tied to one channel, so overrides need BotToken
+	// (or a legacy webhook that accepts a channel).
+	EventChannels map[EventType]string `json:"event_channels,omitempty"`
+
+	// EscalationMentions maps escalation severities to the mentions
+	// prepended to their notifications, e.g. {"critical": "<!here>"}.
+	// Mentions use Slack's syntax: <!here>, <!channel>, <@U0123ABCD> for a
+	// user, or <!subteam^S0123ABCD> for a user group; separate several with
+	// spaces. Severities without an entry mention no one.
+	EscalationMentions map[string]string `json:"escalation_mentions,omitempty"`
+
+	// BotToken is a Slack bot token (xoxb-...) with the chat:write scope.
+	// When set along with Channel, messages are posted with chat.postMessage
+	// instead of the webhook.
+	BotToken string `json:"bot_token,omitempty"`
+
+	// UseThreads posts each bead's later events as replies in the thread
+	// started by its first event. Requires BotToken, since webhooks don't
+	// return the message timestamp a thread is keyed by.
+	UseThreads bool `json:"use_threads,omitempty"`
+
 	// NotifyOn controls which events trigger notifications.
 	NotifyOn NotifySettings `json:"notify_on"`
+
+	// RetryAttempts is the number of delivery attempts per notification,
+	// retrying network errors, rate limiting, and server errors with
+	// exponential backoff. Defaults to 3; 1 disables retries.
+	RetryAttempts int `json:"retry_attempts,omitempty"`
+
+	// Coalesce batches bursts of the same event into one summary message.
+	Coalesce CoalesceSettings `json:"coalesce"`
+
+	// ProxyURL routes requests to Slack through an HTTP(S) or SOCKS5 proxy,
+	// e.g. "http://proxy.corp.example:3128". When unset, the standard
+	// HTTPS_PROXY/NO_PROXY environment variables apply.
+	ProxyURL string `json:"proxy_url,omitempty"`
+
+	// Timeout bounds each request to Slack, as a Go duration. Default: "5s".
+	Timeout string `json:"timeout,omitempty"`
+
+	// Timezone is the IANA zone name (e.g., "America/New_York") used for
+	// timestamps in notifications. Defaults to UTC.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Location returns the configured display timezone, falling back to UTC
+// when unset or invalid.
+func (c *Config) Location() *time.Location {
+	if c == nil || c.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// defaultRequestTimeout bounds each request to Slack when Timeout is unset.
+const defaultRequestTimeout = 5 * time.Second
+
+// RequestTimeout returns the configured per-request timeout, falling back
+// to 5s when unset or invalid.
+func (c *Config) RequestTimeout() time.Duration {
+	if c == nil || c.Timeout == "" {
+		return defaultRequestTimeout
+	}
+	d, err := time.ParseDuration(c.Timeout)
+	if err != nil || d <= 0 {
+		return defaultRequestTimeout
+	}
+	return d
+}
+
+// CoalesceSettings controls batching of notification bursts. Coalescing
+// holds notifications in memory for up to Window; gt flushes whatever is
+// still held when a command exits (see Drain), so a short-lived command
+// posts its notifications without waiting out the window.
+type CoalesceSettings struct {
+	// Enabled turns coalescing on. Off by default.
+	Enabled bool `json:"enabled"`
+
+	// Window is how long the first notification of an event waits for
+	// others to join it, as a Go duration. Default: "10s".
+	Window string `json:"window,omitempty"`
+
+	// Threshold flushes a batch early once it holds this many
+	// notifications. Default: 10.
+	Threshold int `json:"threshold,omitempty"`
+}
+
+// WindowDuration returns the coalescing window, falling back to 10s when
+// unset or invalid.
+func (s CoalesceSettings) WindowDuration() time.Duration {
+	d, err := time.ParseDuration(s.Window)
+	if err != nil || d <= 0 {
+		return defaultCoalesceWindow
+	}
+	return d
 }
 
 // NotifySettings controls which events trigger Slack notifications.
@@ -38,20 +143,25 @@ type NotifySettings struct {
 
 	// JobFailed notifies when merge fails or escalation occurs.
 	JobFailed bool `json:"job_failed"`
+
+	// Custom allows messages sent with SendCustom.
+	Custom bool `json:"custom"`
 }
 
 // DefaultConfig returns a config with sensible defaults.
 func DefaultConfig() *Config {
 	return &Config{
-		Enabled:    false,
-		WebhookURL: "",
-		Channel:    "",
+		Enabled:       false,
+		WebhookURL:    "",
+		Channel:       "",
+		RetryAttempts: notifyMaxAttempts,
 		NotifyOn: NotifySettings{
 			JobQueued:    true,
 			JobStarted:   false, // Too noisy by default
 			PRCreated:    true,
 			JobCompleted: true,
 			JobFailed:    true,
+			Custom:       true,
 		},
 	}
 }
@@ -80,9 +190,108 @@ func LoadConfig(townRoot string) (*Config, error) {
 		return nil, err
 	}
 
+	if cfg.RetryAttempts < 0 {
+		return nil, fmt.Errorf("invalid retry_attempts %d: must not be negative", cfg.RetryAttempts)
+	}
+
+	for event, channel := range cfg.EventChannels {
+		if event == "" {
+			return nil, fmt.Errorf("event_channels: empty event type")
+		}
+		if !validChannel(channel) {
+			return nil, fmt.Errorf("event_channels: invalid channel %q for %s (want #name or a channel ID)", channel, event)
+		}
+	}
+
+	for severity, mention := range cfg.EscalationMentions {
+		if !knownSeverities[strings.ToLower(severity)] {
+			return nil, fmt.Errorf("escalation_mentions: unknown severity %q (want critical, high, medium, or low)", severity)
+		}
+		if !validMentions(mention) {
+			return nil, fmt.Errorf("escalation_mentions: invalid mention %q for %s (want <!here>, <@U...>, or <!subteam^S...>)", mention, severity)
+		}
+	}
+
+	if cfg.Coalesce.Window != "" {
+		if d, err := time.ParseDuration(cfg.Coalesce.Window); err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid coalesce window %q: want a positive duration like \"10s\"", cfg.Coalesce.Window)
+		}
+	}
+	if cfg.Coalesce.Threshold < 0 {
+		return nil, fmt.Errorf("invalid coalesce threshold %d: must not be negative", cfg.Coalesce.Threshold)
+	}
+
+	if cfg.ProxyURL != "" {
+		if _, err := parseProxyURL(cfg.ProxyURL); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Timeout != "" {
+		if d, err := time.ParseDuration(cfg.Timeout); err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q: want a positive duration like \"10s\"", cfg.Timeout)
+		}
+	}
+
+	if cfg.Timezone != "" {
+		if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", cfg.Timezone, err)
+		}
+	}
+
 	return cfg, nil
 }
 
+// channelNamePattern matches Slack channel names, with or without the
+// leading '#', and channelIDPattern matches channel, group, and DM IDs.
+var (
+	channelNamePattern = regexp.MustCompile(`^#?[a-z0-9][a-z0-9_-]{0,79}$`)
+	channelIDPattern   = regexp.MustCompile(`^[CGD][A-Z0-9]{8,}$`)
+)
+
+// validChannel reports whether s names a Slack channel.
+func validChannel(s string) bool {
+	return channelNamePattern.MatchString(s) || channelIDPattern.MatchString(s)
+}
+
+// knownSeverities are the escalation severities mentions can target.
+var knownSeverities = map[string]bool{"critical": true, "high": true, "medium": true, "low": true}
+
+// mentionPattern matches one Slack mention: a special mention, a user, or a
+// user group.
+var mentionPattern = regexp.MustCompile(`^(<!here>|<!channel>|<!everyone>|<@[UW][A-Z0-9]+>|<!subteam\^S[A-Z0-9]+>)$`)
+
+// validMentions reports whether s is one or more space-separated mentions.
+func validMentions(s string) bool {
+	tokens := strings.Fields(s)
+	if len(tokens) == 0 {
+		return false
+	}
+	for _, t := range tokens {
+		if !mentionPattern.MatchString(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseProxyURL parses a proxy_url setting.
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy_url %q: %w", raw, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy_url %q: scheme must be http, https, or socks5", raw)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy_url %q: missing host", raw)
+	}
+	return u, nil
+}
+
 // SaveConfig writes Slack configuration to a town's settings directory.
 func SaveConfig(townRoot string, cfg *Config) error {
 	path := ConfigPath(townRoot)
@@ -2,7 +2,9 @@ package slack
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -43,6 +45,9 @@ type eventConfig struct {
 	emoji string
 	title string
 	color string // For attachment color (not used in blocks)
+
+	// fieldOrder lists payload keys to show first for custom event types.
+	fieldOrder []string
 }
 
 var eventConfigs = map[EventType]eventConfig{
@@ -54,16 +59,76 @@ var eventConfigs = map[EventType]eventConfig{
 	EventEscalation:   {emoji: "🚨", title: "Escalation"},
 }
 
-// formatMessage creates a Slack message for the given event.
-func formatMessage(event EventType, fields map[string]string) *slackMessage {
+// builtinEvents are the event types with dedicated formatters.
+var builtinEvents = map[EventType]bool{
+	EventJobQueued:    true,
+	EventJobStarted:   true,
+	EventPRCreated:    true,
+	EventJobCompleted: true,
+	EventJobFailed:    true,
+	EventEscalation:   true,
+}
+
+// eventConfigsMu guards eventConfigs against runtime registration.
+var eventConfigsMu sync.RWMutex
+
+// EventSpec describes a custom event type registered at runtime.
+type EventSpec struct {
+	// Emoji is shown before the title (defaults to 📢).
+	Emoji string
+
+	// Title is the notification heading.
+	Title string
+
+	// Fields lists payload keys to show first, in order.
+	// Remaining keys follow in alphabetical order.
+	Fields []string
+}
+
+// RegisterEventType adds a custom event type so plugins and hooks can emit
+// new notification kinds. Custom events always pass the NotifyOn filter and
+// render through the generic field formatter. Registering a name again
+// replaces its spec; built-in event types cannot be redefined.
+func RegisterEventType(event EventType, spec EventSpec) error {
+	if event == "" {
+		return fmt.Errorf("event type name is required")
+	}
+	if builtinEvents[event] {
+		return fmt.Errorf("cannot redefine built-in event type %q", event)
+	}
+	if spec.Title == "" {
+		return fmt.Errorf("event type %q: title is required", event)
+	}
+	if spec.Emoji == "" {
+		spec.Emoji = "📢"
+	}
+
+	eventConfigsMu.Lock()
+	defer eventConfigsMu.Unlock()
+	eventConfigs[event] = eventConfig{
+		emoji:      spec.Emoji,
+		title:      spec.Title,
+		fieldOrder: append([]string(nil), spec.Fields...),
+	}
+	return nil
+}
+
+// lookupEventConfig returns the display config for an event type.
+func lookupEventConfig(event EventType) (eventConfig, bool) {
+	eventConfigsMu.RLock()
+	defer eventConfigsMu.RUnlock()
 	cfg, ok := eventConfigs[event]
+	return cfg, ok
+}
+
+// formatMessage creates a Slack message for the given event.
+// Timestamps are rendered in loc (UTC when nil).
+func formatMessage(event EventType, fields map[string]string, loc *time.Location) *slackMessage {
+	cfg, ok := lookupEventConfig(event)
 	if !ok {
 		cfg = eventConfig{emoji: "📢", title: string(event)}
 	}
 
-	// Build header
-	header := fmt.Sprintf("%s *%s*", cfg.emoji, cfg.title)
-
 	// Build field blocks
 	var fieldBlocks []slackText
 	switch event {
@@ -80,9 +145,23 @@ func formatMessage(event EventType, fields map[string]string) *slackMessage {
 	case EventEscalation:
 		fieldBlocks = formatEscalationFields(fields)
 	default:
-		fieldBlocks = formatGenericFields(fields)
+		fieldBlocks = formatGenericFields(fields, cfg.fieldOrder)
+	}
+
+	return buildMessage(cfg, fieldBlocks, loc)
+}
+
+// buildMessage assembles a Block Kit message: a header from cfg, the field
+// blocks, and a timestamp in loc (UTC when nil).
+func buildMessage(cfg eventConfig, fieldBlocks []slackText, loc *time.Location) *slackMessage {
+	if loc == nil {
+		loc = time.UTC
 	}
 
+	// Build header; titles of custom events and messages are caller-supplied
+	title := sanitizeMrkdwn(cfg.title)
+	header := fmt.Sprintf("%s *%s*", cfg.emoji, title)
+
 	// Build blocks
 	blocks := []slackBlock{
 		{
@@ -102,29 +181,81 @@ func formatMessage(event EventType, fields map[string]string) *slackMessage {
 	blocks = append(blocks, slackBlock{
 		Type: "context",
 		Fields: []slackText{
-			{Type: "mrkdwn", Text: fmt.Sprintf("_Gas Town • %s_", time.Now().Format("Jan 2, 15:04 MST"))},
+			{Type: "mrkdwn", Text: fmt.Sprintf("_Gas Town • %s_", time.Now().In(loc).Format("Jan 2, 15:04 MST"))},
 		},
 	})
 
 	return &slackMessage{
-		Text:   fmt.Sprintf("%s %s", cfg.emoji, cfg.title), // Fallback text
+		Text:   fmt.Sprintf("%s %s", cfg.emoji, title), // Fallback text
 		Blocks: blocks,
 	}
 }
 
+// maxBatchLines caps the notifications listed in a summary message.
+const maxBatchLines = 15
+
+// formatBatchMessage creates one summary message for a burst of event
+// notifications, listing each by bead and title.
+func formatBatchMessage(event EventType, items []map[string]string, loc *time.Location) *slackMessage {
+	cfg, ok := lookupEventConfig(event)
+	if !ok {
+		cfg = eventConfig{emoji: "📢", title: string(event)}
+	}
+	msg := buildMessage(eventConfig{emoji: cfg.emoji, title: fmt.Sprintf("%d × %s", len(items), cfg.title)}, nil, loc)
+
+	var lines []string
+	for i, fields := range items {
+		if i == maxBatchLines {
+			lines = append(lines, fmt.Sprintf("_…and %d more_", len(items)-maxBatchLines))
+			break
+		}
+		lines = append(lines, batchLine(fields))
+	}
+	list := slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: strings.Join(lines, "\n")}}
+	msg.Blocks = append(msg.Blocks[:1], append([]slackBlock{list}, msg.Blocks[1:]...)...)
+	return msg
+}
+
+// batchLine summarizes one notification of a batch.
+func batchLine(fields map[string]string) string {
+	line := "•"
+	if v := fields[FieldBead]; v != "" {
+		line += fmt.Sprintf(" `%s`", sanitizeCode(v))
+	}
+	for _, key := range []string{FieldTitle, FieldReason, FieldDescription, FieldBranch} {
+		if v := fields[key]; v != "" {
+			return line + " " + sanitizeMrkdwn(truncate(v, 80))
+		}
+	}
+	return line
+}
+
+// addMention prepends mention to a message's header and fallback text, so
+// it both shows in the message and triggers the notification. A no-op for
+// an empty mention.
+func addMention(msg *slackMessage, mention string) {
+	if mention == "" {
+		return
+	}
+	msg.Text = mention + " " + msg.Text
+	if len(msg.Blocks) > 0 && msg.Blocks[0].Text != nil {
+		msg.Blocks[0].Text.Text = mention + " " + msg.Blocks[0].Text.Text
+	}
+}
+
 func formatJobQueuedFields(fields map[string]string) []slackText {
 	var result []slackText
 	if v := fields[FieldBead]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Bead:*\n`%s`", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Bead:*\n`%s`", sanitizeCode(v))})
 	}
 	if v := fields[FieldTitle]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Title:*\n%s", truncate(v, 50))})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Title:*\n%s", sanitizeMrkdwn(truncate(v, 50)))})
 	}
 	if v := fields[FieldAssignee]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Assignee:*\n%s", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Assignee:*\n%s", sanitizeMrkdwn(v))})
 	}
 	if v := fields[FieldRepo]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Repo:*\n%s", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Repo:*\n%s", sanitizeMrkdwn(v))})
 	}
 	return result
 }
@@ -132,10 +263,10 @@ func formatJobQueuedFields(fields map[string]string) []slackText {
 func formatJobStartedFields(fields map[string]string) []slackText {
 	var result []slackText
 	if v := fields[FieldBead]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Bead:*\n`%s`", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Bead:*\n`%s`", sanitizeCode(v))})
 	}
 	if v := fields[FieldAssignee]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Worker:*\n%s", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Worker:*\n%s", sanitizeMrkdwn(v))})
 	}
 	return result
 }
@@ -143,15 +274,15 @@ func formatJobStartedFields(fields map[string]string) []slackText {
 func formatPRCreatedFields(fields map[string]string) []slackText {
 	var result []slackText
 	if v := fields[FieldBead]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Bead:*\n`%s`", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Bead:*\n`%s`", sanitizeCode(v))})
 	}
 	if v := fields[FieldBranch]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Branch:*\n`%s`", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Branch:*\n`%s`", sanitizeCode(v))})
 	}
 	if v := fields[FieldPRURL]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*PR:*\n<%s|View PR>", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*PR:*\n<%s|View PR>", sanitizeURL(v))})
 	} else if v := fields[FieldMR]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*MR:*\n`%s`", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*MR:*\n`%s`", sanitizeCode(v))})
 	}
 	return result
 }
@@ -159,16 +290,16 @@ func formatPRCreatedFields(fields map[string]string) []slackText {
 func formatJobCompletedFields(fields map[string]string) []slackText {
 	var result []slackText
 	if v := fields[FieldBead]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Bead:*\n`%s`", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Bead:*\n`%s`", sanitizeCode(v))})
 	}
 	if v := fields[FieldBranch]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Branch:*\n`%s`", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Branch:*\n`%s`", sanitizeCode(v))})
 	}
 	if v := fields[FieldCommit]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Commit:*\n`%s`", truncate(v, 8))})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Commit:*\n`%s`", sanitizeCode(truncate(v, 8)))})
 	}
 	if v := fields[FieldPRURL]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*PR:*\n<%s|View PR>", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*PR:*\n<%s|View PR>", sanitizeURL(v))})
 	}
 	return result
 }
@@ -176,16 +307,16 @@ func formatJobCompletedFields(fields map[string]string) []slackText {
 func formatJobFailedFields(fields map[string]string) []slackText {
 	var result []slackText
 	if v := fields[FieldBead]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Bead:*\n`%s`", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Bead:*\n`%s`", sanitizeCode(v))})
 	}
 	if v := fields[FieldMR]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*MR:*\n`%s`", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*MR:*\n`%s`", sanitizeCode(v))})
 	}
 	if v := fields[FieldReason]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Reason:*\n%s", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Reason:*\n%s", sanitizeMrkdwn(v))})
 	}
 	if v := fields[FieldError]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Error:*\n```%s```", truncate(v, 200))})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Error:*\n```%s```", sanitizeCode(truncate(v, 200)))})
 	}
 	return result
 }
@@ -200,27 +331,69 @@ func formatEscalationFields(fields map[string]string) []slackText {
 			"low":      "🟢",
 		}
 		emoji := severityEmoji[strings.ToLower(v)]
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Severity:*\n%s %s", emoji, strings.ToUpper(v))})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Severity:*\n%s %s", emoji, sanitizeMrkdwn(strings.ToUpper(v)))})
 	}
 	if v := fields[FieldBead]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Bead:*\n`%s`", v)})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Bead:*\n`%s`", sanitizeCode(v))})
 	}
 	if v := fields[FieldDescription]; v != "" {
-		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Description:*\n%s", truncate(v, 200))})
+		result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Description:*\n%s", sanitizeMrkdwn(truncate(v, 200)))})
 	}
 	return result
 }
 
-func formatGenericFields(fields map[string]string) []slackText {
+// formatGenericFields renders the keys in order first, then the remaining
+// keys alphabetically so output is stable.
+func formatGenericFields(fields map[string]string, order []string) []slackText {
 	var result []slackText
-	for k, v := range fields {
-		if v != "" {
-			result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*%s:*\n%s", k, truncate(v, 100))})
+	seen := make(map[string]bool, len(order))
+	add := func(k string) {
+		if v := fields[k]; v != "" && !seen[k] {
+			result = append(result, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*%s:*\n%s", sanitizeMrkdwn(k), sanitizeMrkdwn(truncate(v, 100)))})
 		}
+		seen[k] = true
+	}
+
+	for _, k := range order {
+		add(k)
+	}
+
+	rest := make([]string, 0, len(fields))
+	for k := range fields {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	for _, k := range rest {
+		add(k)
 	}
 	return result
 }
 
+// mrkdwnEscaper escapes the characters Slack's mrkdwn treats as control
+// sequences (links, mentions, entities).
+var mrkdwnEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// sanitizeMrkdwn escapes a user-supplied value for mrkdwn text, so error
+// text like "<html>" shows as written instead of being parsed as a link.
+func sanitizeMrkdwn(s string) string {
+	return mrkdwnEscaper.Replace(s)
+}
+
+// sanitizeCode escapes a value for an inline code span or code block.
+// Backticks become look-alike modifier graves (ˋ) so a run inside the value
+// can't close the span early and spill the rest into the message.
+func sanitizeCode(s string) string {
+	return strings.ReplaceAll(sanitizeMrkdwn(s), "`", "ˋ")
+}
+
+// sanitizeURL escapes a URL for a <url|label> link. A '|' would end the
+// URL early, so it is percent-encoded.
+func sanitizeURL(s string) string {
+	return sanitizeMrkdwn(strings.ReplaceAll(s, "|", "%7C"))
+}
+
 // truncate shortens a string to maxLen, adding "..." if truncated.
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
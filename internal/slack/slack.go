@@ -4,42 +4,118 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Client sends notifications to Slack via incoming webhooks.
+// postMessageURL is the Slack Web API method used with a bot token.
+const postMessageURL = "https://slack.com/api/chat.postMessage"
+
+// Client sends notifications to Slack via incoming webhooks, or via
+// chat.postMessage when a bot token is configured.
 type Client struct {
 	webhookURL string
 	channel    string
 	enabled    bool
 	httpClient *http.Client
 	notifyOn   NotifySettings
+	location   *time.Location
+	attempts   int
+
+	// eventChannels overrides channel per event type
+	eventChannels map[EventType]string
+
+	// escalationMentions maps lowercase severities to mentions
+	escalationMentions map[string]string
+
+	// coalesce batches bursts from Notify; nil when coalescing is off
+	coalesce *coalescer
+
+	// Bot token posting; apiURL is overridden in tests
+	botToken   string
+	apiURL     string
+	useThreads bool
+	threads    *threadStore
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used to reach Slack, e.g. one with
+// custom TLS settings. It replaces the client built from ProxyURL and
+// Timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
 }
 
 // NewClient creates a new Slack client from configuration.
-func NewClient(cfg *Config) *Client {
-	if cfg == nil || !cfg.Enabled || cfg.WebhookURL == "" {
+func NewClient(cfg *Config, opts ...Option) *Client {
+	if cfg == nil || !cfg.Enabled {
+		return &Client{enabled: false}
+	}
+	useAPI := cfg.BotToken != "" && cfg.Channel != ""
+	if cfg.WebhookURL == "" && !useAPI {
 		return &Client{enabled: false}
 	}
 
-	return &Client{
+	c := &Client{
 		webhookURL: cfg.WebhookURL,
 		channel:    cfg.Channel,
 		enabled:    true,
 		notifyOn:   cfg.NotifyOn,
-		httpClient: &http.Client{
-			Timeout: 5 * time.Second,
-		},
+		location:   cfg.Location(),
+		attempts:   cfg.RetryAttempts,
+		httpClient: newHTTPClient(cfg),
+		threads:    newThreadStore(),
+
+		eventChannels:      cfg.EventChannels,
+		escalationMentions: lowerKeys(cfg.EscalationMentions),
+	}
+	if useAPI {
+		c.botToken = cfg.BotToken
+		c.apiURL = postMessageURL
+		c.useThreads = cfg.UseThreads
+	}
+	if cfg.Coalesce.Enabled {
+		c.coalesce = newCoalescer(cfg.Coalesce)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// newHTTPClient builds the HTTP client for cfg's timeout and proxy. An
+// invalid proxy URL (LoadConfig rejects them) falls back to the
+// environment's proxy settings.
+func newHTTPClient(cfg *Config) *http.Client {
+	client := &http.Client{Timeout: cfg.RequestTimeout()}
+	if cfg.ProxyURL == "" {
+		return client
+	}
+	proxy, err := parseProxyURL(cfg.ProxyURL)
+	if err != nil {
+		return client
 	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxy)
+	client.Transport = transport
+	return client
 }
 
 // slackMessage represents a Slack webhook payload.
 type slackMessage struct {
 	Channel     string        `json:"channel,omitempty"`
+	ThreadTS    string        `json:"thread_ts,omitempty"`
 	Text        string        `json:"text,omitempty"`
 	Blocks      []slackBlock  `json:"blocks,omitempty"`
 	Attachments []interface{} `json:"attachments,omitempty"`
@@ -58,7 +134,9 @@ type slackText struct {
 	Text string `json:"text"`
 }
 
-// Post sends a message to Slack.
+// Post sends a message to Slack, retrying network errors, rate limiting,
+// and server errors with exponential backoff (or the Retry-After Slack
+// sends with a 429) until the attempts run out or ctx is done.
 // Returns error if the request fails, but callers should generally ignore errors
 // since Slack notifications are best-effort.
 func (c *Client) Post(ctx context.Context, event EventType, fields map[string]string) error {
@@ -71,33 +149,265 @@ func (c *Client) Post(ctx context.Context, event EventType, fields map[string]st
 		return nil
 	}
 
-	msg := formatMessage(event, fields)
-	if c.channel != "" {
-		msg.Channel = c.channel
+	_, err := c.Send(ctx, event, fields)
+	return err
+}
+
+// Enabled reports whether the client is configured to post.
+func (c *Client) Enabled() bool {
+	return c.enabled
+}
+
+// Send posts a message like Post, but regardless of the NotifyOn settings,
+// and returns the HTTP status Slack accepted it with. It is for checking
+// delivery ('gt slack test'); hook points use Post or Notify. Returns 0 and
+// nil without posting if the client is disabled.
+func (c *Client) Send(ctx context.Context, event EventType, fields map[string]string) (int, error) {
+	if !c.enabled {
+		return 0, nil
+	}
+
+	msg := formatMessage(event, fields, c.location)
+	msg.Channel = c.channelFor(event)
+	if event == EventEscalation {
+		addMention(msg, c.escalationMentions[strings.ToLower(fields[FieldSeverity])])
+	}
+	return c.send(ctx, msg, fields[FieldBead])
+}
+
+// SendCustom posts a message outside the fixed event types, with a
+// caller-supplied title and emoji (📢 when empty) as the header and fields
+// rendered like a custom event's. It is gated by the notify_on custom
+// setting rather than an event type, and goes to the default channel.
+func (c *Client) SendCustom(ctx context.Context, title string, fields map[string]string, emoji string) error {
+	if !c.enabled || !c.notifyOn.Custom {
+		return nil
+	}
+	if title == "" {
+		return fmt.Errorf("slack message title is required")
+	}
+	if emoji == "" {
+		emoji = "📢"
+	}
+
+	msg := buildMessage(eventConfig{emoji: emoji, title: title}, formatGenericFields(fields, nil), c.location)
+	msg.Channel = c.channel
+	_, err := c.send(ctx, msg, fields[FieldBead])
+	return err
+}
+
+// send posts a formatted message, in bead's thread when threading is on,
+// and returns the HTTP status of the response that delivered it.
+func (c *Client) send(ctx context.Context, msg *slackMessage, bead string) (int, error) {
+	var status int
+	if c.botToken == "" {
+		err := c.withRetry(ctx, func() error {
+			var err error
+			status, err = c.postWebhook(ctx, msg)
+			return err
+		})
+		return status, err
+	}
+
+	// With threading, a bead's first event in a channel starts a thread
+	// that its later events there reply in
+	threaded := c.useThreads && bead != ""
+	threadKey := msg.Channel + "/" + bead
+	if threaded {
+		msg.ThreadTS = c.threads.lookup(threadKey)
+	}
+	var ts string
+	err := c.withRetry(ctx, func() error {
+		var err error
+		ts, status, err = c.postAPI(ctx, msg)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if threaded && msg.ThreadTS == "" && ts != "" {
+		if err := c.threads.record(threadKey, ts); err != nil {
+			log.Printf("[slack] recording thread for %s: %v", bead, err)
+		}
+	}
+	return status, nil
+}
+
+// lowerKeys returns m with its keys lowercased.
+func lowerKeys(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[strings.ToLower(k)] = v
+	}
+	return out
+}
+
+// channelFor returns the channel for event: its event_channels override,
+// else the default channel ("" leaves the webhook's own channel).
+func (c *Client) channelFor(event EventType) string {
+	if ch := c.eventChannels[event]; ch != "" {
+		return ch
+	}
+	return c.channel
+}
+
+// withRetry calls attempt until it succeeds, fails permanently, or the
+// client's attempts run out. It doesn't sleep past ctx's deadline: when the
+// next wait wouldn't fit, the last error is returned instead.
+func (c *Client) withRetry(ctx context.Context, attempt func() error) error {
+	attempts := c.attempts
+	if attempts <= 0 {
+		attempts = notifyMaxAttempts
 	}
 
+	backoff := notifyRetryBackoff
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil || !isTransient(err) || ctx.Err() != nil {
+			return err
+		}
+		if n >= attempts {
+			return fmt.Errorf("giving up after %d attempts: %w", n, err)
+		}
+
+		wait := backoff
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+			wait = statusErr.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+// postWebhook sends a message to the incoming webhook and returns the
+// response's HTTP status.
+func (c *Client) postWebhook(ctx context.Context, msg *slackMessage) (int, error) {
 	payload, err := json.Marshal(msg)
 	if err != nil {
-		return fmt.Errorf("marshaling slack message: %w", err)
+		return 0, fmt.Errorf("marshaling slack message: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.webhookURL, bytes.NewReader(payload))
 	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+		return 0, fmt.Errorf("creating request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("sending to slack: %w", err)
+		return 0, fmt.Errorf("sending to slack: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("slack returned status %d", resp.StatusCode)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, newStatusError(resp)
 	}
 
-	return nil
+	return resp.StatusCode, nil
+}
+
+// postMessageResponse is the chat.postMessage response.
+type postMessageResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	TS    string `json:"ts,omitempty"`
+}
+
+// postAPI sends a message with chat.postMessage and returns its ts and the
+// response's HTTP status.
+func (c *Client) postAPI(ctx context.Context, msg *slackMessage) (string, int, error) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return "", 0, fmt.Errorf("marshaling slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", 0, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+c.botToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("sending to slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", 0, newStatusError(resp)
+	}
+
+	var result postMessageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", 0, fmt.Errorf("decoding slack response: %w", err)
+	}
+	if !result.OK {
+		return "", 0, &APIError{Code: result.Error}
+	}
+	return result.TS, resp.StatusCode, nil
+}
+
+// StatusError is returned by Post when Slack responds with a non-200 status.
+type StatusError struct {
+	StatusCode int
+
+	// RetryAfter is how long Slack asked to wait before retrying a 429.
+	RetryAfter time.Duration
+}
+
+// newStatusError builds a StatusError from a response, reading the
+// Retry-After header (in seconds) Slack sends when rate limiting.
+func newStatusError(resp *http.Response) *StatusError {
+	e := &StatusError{StatusCode: resp.StatusCode}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return e
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("slack returned status %d", e.StatusCode)
+}
+
+// APIError is returned by Post when chat.postMessage responds with
+// "ok": false, e.g. for an invalid token or a channel the bot isn't in.
+type APIError struct {
+	Code string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("slack API error: %s", e.Code)
+}
+
+// isTransient reports whether a Post error is worth retrying.
+// Network errors, rate limiting, and server errors are transient;
+// other HTTP statuses (bad payload, revoked webhook) and API errors
+// (bad token, unknown channel) are not.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == "ratelimited" || apiErr.Code == "service_unavailable" || apiErr.Code == "internal_error"
+	}
+	return true
 }
 
 // shouldNotify checks if the given event type should trigger a notification.
@@ -139,9 +449,136 @@ func GetGlobalClient() *Client {
 	return globalClient
 }
 
+// Notification queue settings.
+const (
+	// notifyQueueSize bounds the number of pending notifications.
+	// Notifications arriving while the queue is full are dropped.
+	notifyQueueSize = 100
+
+	// notifyWorkers is the number of goroutines draining the queue.
+	notifyWorkers = 2
+
+	// notifyMaxAttempts is the default number of delivery attempts per
+	// notification.
+	notifyMaxAttempts = 3
+
+	// notifyDeliverTimeout bounds one notification, retries included.
+	notifyDeliverTimeout = 30 * time.Second
+
+	// DrainTimeout is how long Drain waits for queued notifications when
+	// a command exits.
+	DrainTimeout = 10 * time.Second
+)
+
+// notifyRetryBackoff is the base delay between delivery attempts.
+// It doubles on each retry. Variable so tests can shorten it.
+var notifyRetryBackoff = 500 * time.Millisecond
+
+// notifyJob is a pending notification on the queue. A coalesced burst
+// carries its notifications in batch instead of fields.
+type notifyJob struct {
+	client *Client
+	event  EventType
+	fields map[string]string
+	batch  []map[string]string
+
+	// pending is marked done once the job is delivered
+	pending *pendingJobs
+}
+
+// pendingJobs counts queued notifications not yet delivered. Unlike a
+// sync.WaitGroup, jobs may be added while Drain is waiting, as happens when
+// a coalescing window closes mid-drain.
+type pendingJobs struct {
+	mu    sync.Mutex
+	count int
+	idle  chan struct{} // closed while count is zero
+}
+
+func newPendingJobs() *pendingJobs {
+	idle := make(chan struct{})
+	close(idle)
+	return &pendingJobs{idle: idle}
+}
+
+func (p *pendingJobs) add() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.count == 0 {
+		p.idle = make(chan struct{})
+	}
+	p.count++
+}
+
+func (p *pendingJobs) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.count--
+	if p.count == 0 {
+		close(p.idle)
+	}
+}
+
+// wait returns a channel that is closed once no jobs are pending. Jobs
+// added before that happens are waited for too.
+func (p *pendingJobs) wait() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.idle
+}
+
+// Notification queue state, started lazily on first Notify.
+var (
+	notifyQueue     chan notifyJob
+	notifyQueueOnce sync.Once
+	notifyDropped   atomic.Int64
+
+	// notifyPending counts queued notifications not yet delivered.
+	notifyPending = newPendingJobs()
+)
+
+// startNotifyWorkers creates the queue and its worker pool.
+func startNotifyWorkers() {
+	notifyQueue = make(chan notifyJob, notifyQueueSize)
+	for i := 0; i < notifyWorkers; i++ {
+		go notifyWorker(notifyQueue)
+	}
+}
+
+// notifyWorker delivers queued notifications until the queue is closed.
+func notifyWorker(queue <-chan notifyJob) {
+	for job := range queue {
+		deliver(job)
+		job.pending.done()
+	}
+}
+
+// deliver posts a notification; Post retries transient failures.
+func deliver(job notifyJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyDeliverTimeout)
+	defer cancel()
+
+	var err error
+	if job.batch != nil {
+		err = job.client.postBatch(ctx, job.event, job.batch)
+	} else {
+		err = job.client.Post(ctx, job.event, job.fields)
+	}
+	if err != nil {
+		log.Printf("[slack] notification failed: %v", err)
+	}
+}
+
 // Notify sends a notification using the global client.
 // This is fire-and-forget - errors are logged but not returned.
 // Safe to call even if Slack is not configured.
+//
+// Notifications are delivered by a small worker pool from a bounded queue,
+// so a slow or failing webhook cannot cause unbounded goroutine growth.
+// When the queue is full the notification is dropped and logged.
+//
+// With coalescing configured, notifications other than escalations are
+// held briefly so a burst of the same event posts as one summary.
 func Notify(event EventType, fields map[string]string) {
 	globalMu.RLock()
 	client := globalClient
@@ -151,15 +588,59 @@ func Notify(event EventType, fields map[string]string) {
 		return
 	}
 
-	// Fire and forget in a goroutine to avoid blocking
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+	if client.coalesce != nil && event != EventEscalation && client.shouldNotify(event) {
+		client.coalesce.add(client, event, fields)
+		return
+	}
+	enqueue(notifyJob{client: client, event: event, fields: fields})
+}
+
+// enqueue queues a notification for the workers, dropping it if the queue
+// is full.
+func enqueue(job notifyJob) {
+	notifyQueueOnce.Do(startNotifyWorkers)
 
-		if err := client.Post(ctx, event, fields); err != nil {
-			log.Printf("[slack] notification failed: %v", err)
+	job.pending = notifyPending
+	job.pending.add()
+	select {
+	case notifyQueue <- job:
+	default:
+		job.pending.done()
+		notifyDropped.Add(1)
+		log.Printf("[slack] notification queue full, dropping %s event", job.event)
+	}
+}
+
+// Drain flushes the notifications the global client holds for coalescing,
+// then waits up to timeout for the queue to be delivered. The workers die
+// with the process, so commands call it before exiting; otherwise anything
+// still queued or held in a coalescing window is lost. Reports whether
+// everything was delivered in time.
+func Drain(timeout time.Duration) bool {
+	if client := GetGlobalClient(); client != nil {
+		client.Flush()
+	}
+
+	select {
+	case <-notifyPending.wait():
+		return true
+	case <-time.After(timeout):
+		log.Printf("[slack] gave up after %v waiting for queued notifications", timeout)
+		return false
+	}
+}
+
+// NotifyData is Notify for structured payloads whose values aren't strings,
+// such as build durations or test counts. Values are rendered with fmt.
+// Pair it with RegisterEventType to emit custom notification kinds.
+func NotifyData(event EventType, data map[string]any) {
+	fields := make(map[string]string, len(data))
+	for k, v := range data {
+		if v != nil {
+			fields[k] = fmt.Sprint(v)
 		}
-	}()
+	}
+	Notify(event, fields)
 }
 
 // Initialize loads config and sets up the global client.
@@ -170,6 +651,10 @@ func Initialize(townRoot string) error {
 		return fmt.Errorf("loading slack config: %w", err)
 	}
 
-	SetGlobalClient(NewClient(cfg))
+	client := NewClient(cfg)
+	if client.threads != nil {
+		client.threads.path = ThreadsPath(townRoot)
+	}
+	SetGlobalClient(client)
 	return nil
 }
@@ -3,10 +3,18 @@ package slack
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -46,6 +54,23 @@ func TestNewClient(t *testing.T) {
 			},
 			enabled: true,
 		},
+		{
+			name: "bot token without webhook",
+			cfg: &Config{
+				Enabled:  true,
+				BotToken: "xoxb-test",
+				Channel:  "#gastown",
+			},
+			enabled: true,
+		},
+		{
+			name: "bot token without channel",
+			cfg: &Config{
+				Enabled:  true,
+				BotToken: "xoxb-test",
+			},
+			enabled: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -305,7 +330,7 @@ func TestFormatMessage(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			msg := formatMessage(tt.event, tt.fields)
+			msg := formatMessage(tt.event, tt.fields, time.UTC)
 
 			if msg.Text == "" {
 				t.Error("expected non-empty fallback text")
@@ -326,6 +351,99 @@ func TestFormatMessage(t *testing.T) {
 	}
 }
 
+func TestRegisterEventTypeFormatsGeneric(t *testing.T) {
+	const event EventType = "build_finished"
+	t.Cleanup(func() {
+		eventConfigsMu.Lock()
+		delete(eventConfigs, event)
+		eventConfigsMu.Unlock()
+	})
+
+	if err := RegisterEventType(event, EventSpec{
+		Emoji:  "🏗️",
+		Title:  "Build Finished",
+		Fields: []string{"duration", "tests"},
+	}); err != nil {
+		t.Fatalf("RegisterEventType: %v", err)
+	}
+
+	msg := formatMessage(event, map[string]string{
+		"tests":    "412 passed",
+		"duration": "3m12s",
+		"commit":   "a1b2c3d",
+	}, time.UTC)
+
+	if msg.Text != "🏗️ Build Finished" {
+		t.Errorf("fallback text = %q, want registered title", msg.Text)
+	}
+	if got := msg.Blocks[0].Text.Text; got != "🏗️ *Build Finished*" {
+		t.Errorf("header = %q, want registered title", got)
+	}
+
+	var got []string
+	for _, f := range msg.Blocks[1].Fields {
+		got = append(got, f.Text)
+	}
+	want := []string{"*duration:*\n3m12s", "*tests:*\n412 passed", "*commit:*\na1b2c3d"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("fields = %q, want %q", got, want)
+	}
+
+	// Custom events aren't filtered by NotifyOn
+	client := NewClient(&Config{Enabled: true, WebhookURL: "http://example.invalid"})
+	if !client.shouldNotify(event) {
+		t.Error("expected custom event to pass the NotifyOn filter")
+	}
+}
+
+func TestRegisterEventTypeRejectsBuiltin(t *testing.T) {
+	if err := RegisterEventType(EventJobFailed, EventSpec{Title: "Nope"}); err == nil {
+		t.Error("expected error redefining a built-in event type")
+	}
+	if err := RegisterEventType("custom", EventSpec{}); err == nil {
+		t.Error("expected error for missing title")
+	}
+}
+
+func TestFormatMessageTimezone(t *testing.T) {
+	cfg := &Config{Timezone: "America/New_York"}
+	loc := cfg.Location()
+	if loc.String() != "America/New_York" {
+		t.Fatalf("Location() = %s, want America/New_York", loc)
+	}
+
+	msg := formatMessage(EventJobQueued, map[string]string{FieldBead: "gt-abc123"}, loc)
+	ctxBlock := msg.Blocks[len(msg.Blocks)-1]
+	if ctxBlock.Type != "context" {
+		t.Fatalf("expected trailing context block, got %s", ctxBlock.Type)
+	}
+
+	// EST or EDT depending on the current date
+	wantZone := time.Now().In(loc).Format("MST")
+	if got := ctxBlock.Fields[0].Text; !strings.Contains(got, wantZone) {
+		t.Errorf("context timestamp %q does not contain zone %q", got, wantZone)
+	}
+}
+
+func TestFormatMessageDefaultsToUTC(t *testing.T) {
+	msg := formatMessage(EventJobQueued, map[string]string{}, (&Config{}).Location())
+	ctxBlock := msg.Blocks[len(msg.Blocks)-1]
+	if got := ctxBlock.Fields[0].Text; !strings.Contains(got, "UTC") {
+		t.Errorf("context timestamp %q should default to UTC", got)
+	}
+}
+
+func TestLoadConfigRejectsInvalidTimezone(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := SaveConfig(tmpDir, &Config{Enabled: true, Timezone: "Mars/Olympus_Mons"}); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	if _, err := LoadConfig(tmpDir); err == nil {
+		t.Error("expected error for invalid timezone")
+	}
+}
+
 func TestGlobalClient(t *testing.T) {
 	// Reset global client
 	SetGlobalClient(nil)
@@ -358,6 +476,746 @@ func TestNotifyWithNilClient(t *testing.T) {
 	})
 }
 
+func TestNotifyFloodIsBounded(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	SetGlobalClient(NewClient(&Config{
+		Enabled:    true,
+		WebhookURL: server.URL,
+		NotifyOn:   NotifySettings{JobQueued: true},
+	}))
+	defer SetGlobalClient(nil)
+
+	// Start the workers before measuring so they aren't counted as growth.
+	notifyQueueOnce.Do(startNotifyWorkers)
+	before := runtime.NumGoroutine()
+	droppedBefore := notifyDropped.Load()
+
+	const flood = 1000
+	for i := 0; i < flood; i++ {
+		Notify(EventJobQueued, map[string]string{FieldBead: "gt-flood"})
+	}
+
+	// Allow for in-flight HTTP connection goroutines, but nothing per-event.
+	if growth := runtime.NumGoroutine() - before; growth > 4*notifyWorkers+4 {
+		t.Errorf("goroutines grew by %d during flood, want bounded", growth)
+	}
+	if n := len(notifyQueue); n > notifyQueueSize {
+		t.Errorf("queue length %d exceeds bound %d", n, notifyQueueSize)
+	}
+	if dropped := notifyDropped.Load() - droppedBefore; dropped < flood-notifyQueueSize-notifyWorkers {
+		t.Errorf("dropped %d notifications, want at least %d", dropped, flood-notifyQueueSize-notifyWorkers)
+	}
+}
+
+func TestDeliverRetriesTransientFailures(t *testing.T) {
+	oldBackoff := notifyRetryBackoff
+	notifyRetryBackoff = time.Millisecond
+	defer func() { notifyRetryBackoff = oldBackoff }()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < notifyMaxAttempts {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{
+		Enabled:    true,
+		WebhookURL: server.URL,
+		NotifyOn:   NotifySettings{JobQueued: true},
+	})
+
+	deliver(notifyJob{client: client, event: EventJobQueued, fields: map[string]string{}})
+
+	if got := calls.Load(); got != notifyMaxAttempts {
+		t.Errorf("expected %d attempts, got %d", notifyMaxAttempts, got)
+	}
+}
+
+func TestDeliverDoesNotRetryPermanentFailures(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{
+		Enabled:    true,
+		WebhookURL: server.URL,
+		NotifyOn:   NotifySettings{JobQueued: true},
+	})
+
+	deliver(notifyJob{client: client, event: EventJobQueued, fields: map[string]string{}})
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected 1 attempt for permanent failure, got %d", got)
+	}
+}
+
+func TestClientPostThreadsBeadEvents(t *testing.T) {
+	var received []slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer xoxb-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var msg slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		received = append(received, msg)
+		_ = json.NewEncoder(w).Encode(postMessageResponse{OK: true, TS: fmt.Sprintf("1700000000.00000%d", len(received))})
+	}))
+	defer server.Close()
+
+	cfg := &Config{
+		Enabled:    true,
+		BotToken:   "xoxb-test",
+		Channel:    "#gastown",
+		UseThreads: true,
+		NotifyOn:   NotifySettings{JobQueued: true, JobCompleted: true},
+	}
+	path := filepath.Join(t.TempDir(), ".runtime", "slack-threads.json")
+	newClient := func() *Client {
+		c := NewClient(cfg)
+		c.apiURL = server.URL
+		c.threads.path = path
+		return c
+	}
+
+	ctx := context.Background()
+	if err := newClient().Post(ctx, EventJobQueued, map[string]string{FieldBead: "gt-abc"}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if err := newClient().Post(ctx, EventJobQueued, map[string]string{FieldBead: "gt-xyz"}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	// A separate client (another gt process) replies in the bead's thread
+	if err := newClient().Post(ctx, EventJobCompleted, map[string]string{FieldBead: "gt-abc"}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	if len(received) != 3 {
+		t.Fatalf("received %d messages, want 3", len(received))
+	}
+	if received[0].ThreadTS != "" || received[1].ThreadTS != "" {
+		t.Errorf("first events should start threads, got thread_ts %q, %q", received[0].ThreadTS, received[1].ThreadTS)
+	}
+	if received[2].ThreadTS != "1700000000.000001" {
+		t.Errorf("completed event thread_ts = %q, want the queued message's ts", received[2].ThreadTS)
+	}
+	if received[2].Channel != "#gastown" {
+		t.Errorf("channel = %q, want #gastown", received[2].Channel)
+	}
+}
+
+func TestThreadStoreConcurrentRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".runtime", "slack-threads.json")
+
+	// Each store stands in for a separate gt process sharing the file
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			store := newThreadStore()
+			store.path = path
+			errs <- store.record(fmt.Sprintf("#gastown/gt-%d", i), fmt.Sprintf("1700000000.%06d", i))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	store := newThreadStore()
+	store.path = path
+	for i := 0; i < writers; i++ {
+		if got, want := store.lookup(fmt.Sprintf("#gastown/gt-%d", i)), fmt.Sprintf("1700000000.%06d", i); got != want {
+			t.Errorf("thread for gt-%d = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestClientPostAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(postMessageResponse{Error: "channel_not_found"})
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{
+		Enabled:  true,
+		BotToken: "xoxb-test",
+		Channel:  "#missing",
+		NotifyOn: NotifySettings{JobQueued: true},
+	})
+	client.apiURL = server.URL
+
+	err := client.Post(context.Background(), EventJobQueued, map[string]string{FieldBead: "gt-abc"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "channel_not_found" {
+		t.Fatalf("Post error = %v, want channel_not_found APIError", err)
+	}
+	if isTransient(err) {
+		t.Error("channel_not_found should not be retried")
+	}
+}
+
+func TestClientPostRetryAttempts(t *testing.T) {
+	oldBackoff := notifyRetryBackoff
+	notifyRetryBackoff = time.Millisecond
+	defer func() { notifyRetryBackoff = oldBackoff }()
+
+	for _, attempts := range []int{1, 2, 5} {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+
+		client := NewClient(&Config{
+			Enabled:       true,
+			WebhookURL:    server.URL,
+			RetryAttempts: attempts,
+			NotifyOn:      NotifySettings{JobQueued: true},
+		})
+		err := client.Post(context.Background(), EventJobQueued, map[string]string{})
+		server.Close()
+
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+			t.Errorf("attempts=%d: Post error = %v, want status 500", attempts, err)
+		}
+		if got := calls.Load(); got != int32(attempts) {
+			t.Errorf("attempts=%d: got %d calls", attempts, got)
+		}
+	}
+}
+
+func TestClientPostRetryAfterBeyondDeadline(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{
+		Enabled:    true,
+		WebhookURL: server.URL,
+		NotifyOn:   NotifySettings{JobQueued: true},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	err := client.Post(ctx, EventJobQueued, map[string]string{})
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.RetryAfter != 30*time.Second {
+		t.Fatalf("Post error = %v, want 429 with a 30s Retry-After", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("got %d calls; a Retry-After past the deadline should not be waited out", got)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Post took %v, want it to return without waiting", elapsed)
+	}
+}
+
+func TestClientPostRoutesEventChannels(t *testing.T) {
+	var channels []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		channels = append(channels, msg.Channel)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{
+		Enabled:       true,
+		WebhookURL:    server.URL,
+		Channel:       "#gastown-log",
+		EventChannels: map[EventType]string{EventJobFailed: "#alerts", EventEscalation: "#alerts"},
+		NotifyOn:      NotifySettings{JobCompleted: true, JobFailed: true},
+	})
+
+	ctx := context.Background()
+	for _, event := range []EventType{EventJobCompleted, EventJobFailed, EventEscalation} {
+		if err := client.Post(ctx, event, map[string]string{FieldBead: "gt-abc"}); err != nil {
+			t.Fatalf("Post(%s): %v", event, err)
+		}
+	}
+
+	want := []string{"#gastown-log", "#alerts", "#alerts"}
+	if strings.Join(channels, ",") != strings.Join(want, ",") {
+		t.Errorf("channels = %v, want %v", channels, want)
+	}
+}
+
+func TestLoadConfigValidatesEventChannels(t *testing.T) {
+	tests := []struct {
+		channel string
+		valid   bool
+	}{
+		{"#alerts", true},
+		{"gastown-log", true},
+		{"C0123ABCDE", true},
+		{"", false},
+		{"#Alerts", false},
+		{"#has space", false},
+		{"https://hooks.slack.com/services/T000/B000/XXX", false},
+	}
+
+	for _, tt := range tests {
+		tmpDir := t.TempDir()
+		cfg := &Config{Enabled: true, EventChannels: map[EventType]string{EventJobFailed: tt.channel}}
+		if err := SaveConfig(tmpDir, cfg); err != nil {
+			t.Fatalf("SaveConfig failed: %v", err)
+		}
+		_, err := LoadConfig(tmpDir)
+		if (err == nil) != tt.valid {
+			t.Errorf("channel %q: LoadConfig error = %v, want valid=%v", tt.channel, err, tt.valid)
+		}
+	}
+}
+
+func TestClientPostEscalationMentions(t *testing.T) {
+	var received []slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		received = append(received, msg)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{
+		Enabled:            true,
+		WebhookURL:         server.URL,
+		EscalationMentions: map[string]string{"Critical": "<!here> <!subteam^S0123ABCD>"},
+		NotifyOn:           NotifySettings{JobFailed: true},
+	})
+
+	ctx := context.Background()
+	for _, severity := range []string{"critical", "high"} {
+		if err := client.Post(ctx, EventEscalation, map[string]string{FieldSeverity: severity}); err != nil {
+			t.Fatalf("Post: %v", err)
+		}
+	}
+	if err := client.Post(ctx, EventJobFailed, map[string]string{FieldSeverity: "critical"}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	if len(received) != 3 {
+		t.Fatalf("received %d messages, want 3", len(received))
+	}
+	critical := received[0]
+	if !strings.HasPrefix(critical.Text, "<!here> <!subteam^S0123ABCD> ") {
+		t.Errorf("critical fallback text = %q, want mentions first", critical.Text)
+	}
+	if !strings.HasPrefix(critical.Blocks[0].Text.Text, "<!here> <!subteam^S0123ABCD> ") {
+		t.Errorf("critical header = %q, want mentions first", critical.Blocks[0].Text.Text)
+	}
+	for _, msg := range received[1:] {
+		if strings.Contains(msg.Text, "<!") || strings.Contains(msg.Blocks[0].Text.Text, "<!") {
+			t.Errorf("unexpected mention in %q", msg.Text)
+		}
+	}
+}
+
+func TestLoadConfigValidatesEscalationMentions(t *testing.T) {
+	tests := []struct {
+		mentions map[string]string
+		valid    bool
+	}{
+		{map[string]string{"critical": "<!here>"}, true},
+		{map[string]string{"high": "<@U0123ABCD>"}, true},
+		{map[string]string{"CRITICAL": "<!subteam^S0123ABCD> <@W0123ABCD>"}, true},
+		{map[string]string{"critical": "@here"}, false},
+		{map[string]string{"critical": ""}, false},
+		{map[string]string{"urgent": "<!here>"}, false},
+	}
+
+	for _, tt := range tests {
+		tmpDir := t.TempDir()
+		if err := SaveConfig(tmpDir, &Config{Enabled: true, EscalationMentions: tt.mentions}); err != nil {
+			t.Fatalf("SaveConfig failed: %v", err)
+		}
+		_, err := LoadConfig(tmpDir)
+		if (err == nil) != tt.valid {
+			t.Errorf("mentions %v: LoadConfig error = %v, want valid=%v", tt.mentions, err, tt.valid)
+		}
+	}
+}
+
+func TestClientSendCustom(t *testing.T) {
+	var received []slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		received = append(received, msg)
+	}))
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.Enabled = true
+	cfg.WebhookURL = server.URL
+	client := NewClient(cfg)
+
+	err := client.SendCustom(context.Background(), "Nightly Build", map[string]string{"duration": "4m12s", "tests": "812"}, "🏗️")
+	if err != nil {
+		t.Fatalf("SendCustom: %v", err)
+	}
+	if len(received) != 1 {
+		t.Fatalf("received %d messages, want 1", len(received))
+	}
+	msg := received[0]
+	if msg.Blocks[0].Text.Text != "🏗️ *Nightly Build*" {
+		t.Errorf("header = %q", msg.Blocks[0].Text.Text)
+	}
+	if len(msg.Blocks[1].Fields) != 2 || msg.Blocks[1].Fields[0].Text != "*duration:*\n4m12s" {
+		t.Errorf("fields = %+v", msg.Blocks[1].Fields)
+	}
+
+	if err := client.SendCustom(context.Background(), "", nil, ""); err == nil {
+		t.Error("expected an error for an empty title")
+	}
+
+	// The custom toggle turns SendCustom off
+	cfg.NotifyOn.Custom = false
+	if err := NewClient(cfg).SendCustom(context.Background(), "Nightly Build", nil, ""); err != nil {
+		t.Fatalf("SendCustom: %v", err)
+	}
+	if len(received) != 1 {
+		t.Errorf("received %d messages with custom off, want 1", len(received))
+	}
+}
+
+// roundTripFunc stubs an http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestWithHTTPClient(t *testing.T) {
+	var requested string
+	stub := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requested = r.URL.String()
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: make(http.Header)}, nil
+	})}
+
+	client := NewClient(&Config{
+		Enabled:    true,
+		WebhookURL: "https://hooks.slack.com/services/T000/B000/XXX",
+		NotifyOn:   NotifySettings{JobQueued: true},
+	}, WithHTTPClient(stub))
+
+	if err := client.Post(context.Background(), EventJobQueued, map[string]string{}); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if requested != "https://hooks.slack.com/services/T000/B000/XXX" {
+		t.Errorf("stub saw %q, want the webhook URL", requested)
+	}
+}
+
+func TestNewClientProxyAndTimeout(t *testing.T) {
+	client := NewClient(&Config{
+		Enabled:    true,
+		WebhookURL: "https://hooks.slack.com/test",
+		ProxyURL:   "http://proxy.corp.example:3128",
+		Timeout:    "12s",
+	})
+	if client.httpClient.Timeout != 12*time.Second {
+		t.Errorf("Timeout = %v, want 12s", client.httpClient.Timeout)
+	}
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Transport = %T, want *http.Transport", client.httpClient.Transport)
+	}
+	req, _ := http.NewRequest(http.MethodPost, "https://hooks.slack.com/test", nil)
+	proxy, err := transport.Proxy(req)
+	if err != nil || proxy == nil || proxy.Host != "proxy.corp.example:3128" {
+		t.Errorf("Proxy = %v, %v; want proxy.corp.example:3128", proxy, err)
+	}
+
+	if got := NewClient(&Config{Enabled: true, WebhookURL: "https://hooks.slack.com/test"}).httpClient.Timeout; got != 5*time.Second {
+		t.Errorf("default Timeout = %v, want 5s", got)
+	}
+}
+
+func TestLoadConfigValidatesProxyAndTimeout(t *testing.T) {
+	tests := []struct {
+		cfg   Config
+		valid bool
+	}{
+		{Config{ProxyURL: "http://proxy:3128", Timeout: "10s"}, true},
+		{Config{ProxyURL: "socks5://proxy:1080"}, true},
+		{Config{ProxyURL: "proxy:3128"}, false},
+		{Config{ProxyURL: "ftp://proxy:21"}, false},
+		{Config{Timeout: "soon"}, false},
+		{Config{Timeout: "-1s"}, false},
+	}
+
+	for _, tt := range tests {
+		tmpDir := t.TempDir()
+		if err := SaveConfig(tmpDir, &tt.cfg); err != nil {
+			t.Fatalf("SaveConfig failed: %v", err)
+		}
+		_, err := LoadConfig(tmpDir)
+		if (err == nil) != tt.valid {
+			t.Errorf("%+v: LoadConfig error = %v, want valid=%v", tt.cfg, err, tt.valid)
+		}
+	}
+}
+
+// useFreshNotifyQueue points Notify at a new queue with its own workers,
+// so a test isn't stuck behind jobs earlier tests left on the queue.
+func useFreshNotifyQueue(t *testing.T) {
+	t.Helper()
+	notifyQueueOnce.Do(startNotifyWorkers)
+	queue := make(chan notifyJob, notifyQueueSize)
+	for i := 0; i < notifyWorkers; i++ {
+		go notifyWorker(queue)
+	}
+	old, oldPending := notifyQueue, notifyPending
+	notifyQueue, notifyPending = queue, newPendingJobs()
+	t.Cleanup(func() { notifyQueue, notifyPending = old, oldPending })
+}
+
+func TestNotifyCoalescesBursts(t *testing.T) {
+	received := make(chan slackMessage, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		received <- msg
+	}))
+	defer server.Close()
+
+	receive := func() slackMessage {
+		t.Helper()
+		select {
+		case msg := <-received:
+			return msg
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a notification")
+			return slackMessage{}
+		}
+	}
+
+	useFreshNotifyQueue(t)
+	SetGlobalClient(NewClient(&Config{
+		Enabled:    true,
+		WebhookURL: server.URL,
+		Coalesce:   CoalesceSettings{Enabled: true, Window: "50ms", Threshold: 100},
+		NotifyOn:   NotifySettings{JobQueued: true, JobFailed: true},
+	}))
+	defer SetGlobalClient(nil)
+
+	// A burst within the window posts one summary
+	for _, bead := range []string{"gt-a", "gt-b", "gt-c"} {
+		Notify(EventJobQueued, map[string]string{FieldBead: bead, FieldTitle: "Fix " + bead})
+	}
+	summary := receive()
+	if summary.Text != "📋 3 × Job Queued" {
+		t.Errorf("summary text = %q", summary.Text)
+	}
+	if got := summary.Blocks[1].Text.Text; got != "• `gt-a` Fix gt-a\n• `gt-b` Fix gt-b\n• `gt-c` Fix gt-c" {
+		t.Errorf("summary list = %q", got)
+	}
+
+	// A lone notification posts as itself, and escalations skip the window
+	Notify(EventJobQueued, map[string]string{FieldBead: "gt-d"})
+	if msg := receive(); msg.Text != "📋 Job Queued" {
+		t.Errorf("lone notification text = %q", msg.Text)
+	}
+	Notify(EventEscalation, map[string]string{FieldSeverity: "high"})
+	if msg := receive(); msg.Text != "🚨 Escalation" {
+		t.Errorf("escalation text = %q", msg.Text)
+	}
+}
+
+func TestNotifyCoalesceThreshold(t *testing.T) {
+	received := make(chan slackMessage, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		received <- msg
+	}))
+	defer server.Close()
+
+	useFreshNotifyQueue(t)
+	client := NewClient(&Config{
+		Enabled:    true,
+		WebhookURL: server.URL,
+		Coalesce:   CoalesceSettings{Enabled: true, Window: "1h", Threshold: 2},
+		NotifyOn:   NotifySettings{JobFailed: true},
+	})
+	SetGlobalClient(client)
+	defer SetGlobalClient(nil)
+
+	Notify(EventJobFailed, map[string]string{FieldBead: "gt-a", FieldReason: "conflict"})
+	Notify(EventJobFailed, map[string]string{FieldBead: "gt-b", FieldReason: "tests failed"})
+	select {
+	case msg := <-received:
+		if msg.Text != "❌ 2 × Job Failed" {
+			t.Errorf("summary text = %q", msg.Text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("threshold did not flush the batch")
+	}
+
+	// Flush sends a batch without waiting out the window
+	Notify(EventJobFailed, map[string]string{FieldBead: "gt-c"})
+	client.Flush()
+	select {
+	case msg := <-received:
+		if msg.Text != "❌ Job Failed" {
+			t.Errorf("flushed text = %q", msg.Text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Flush did not send the pending notification")
+	}
+}
+
+func TestPendingJobsAddDuringWait(t *testing.T) {
+	p := newPendingJobs()
+	select {
+	case <-p.wait():
+	default:
+		t.Fatal("no pending jobs should mean idle")
+	}
+
+	p.add()
+	idle := p.wait()
+
+	// A job added while someone waits (a coalescing timer firing during
+	// Drain) is waited for as well, without panicking
+	p.add()
+	p.done()
+	select {
+	case <-idle:
+		t.Fatal("idle with a job still pending")
+	default:
+	}
+	p.done()
+	select {
+	case <-idle:
+	case <-time.After(time.Second):
+		t.Fatal("not idle after every job finished")
+	}
+}
+
+func TestDrainDeliversHeldNotifications(t *testing.T) {
+	var mu sync.Mutex
+	var texts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond) // delivery still in flight when Drain starts
+		mu.Lock()
+		texts = append(texts, msg.Text)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	useFreshNotifyQueue(t)
+	SetGlobalClient(NewClient(&Config{
+		Enabled:    true,
+		WebhookURL: server.URL,
+		Coalesce:   CoalesceSettings{Enabled: true, Window: "1h", Threshold: 100},
+		NotifyOn:   NotifySettings{JobQueued: true, JobFailed: true},
+	}))
+	defer SetGlobalClient(nil)
+
+	// Both would be lost at exit: one is held for an hour-long window,
+	// the other is only queued
+	Notify(EventJobQueued, map[string]string{FieldBead: "gt-a"})
+	Notify(EventJobQueued, map[string]string{FieldBead: "gt-b"})
+	Notify(EventEscalation, map[string]string{FieldSeverity: "high"})
+
+	if !Drain(5 * time.Second) {
+		t.Fatal("Drain timed out")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Strings(texts)
+	if want := []string{"📋 2 × Job Queued", "🚨 Escalation"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("delivered %q before Drain returned, want %q", texts, want)
+	}
+}
+
+func TestFormatMessageSanitizesFieldValues(t *testing.T) {
+	msg := formatMessage(EventJobFailed, map[string]string{
+		FieldBead:   "gt-abc",
+		FieldReason: "tests <failed> & more",
+		FieldError:  "unexpected <html> in response: ```\nrm -rf `pwd`",
+	}, nil)
+
+	fields := msg.Blocks[1].Fields
+	if len(fields) != 3 {
+		t.Fatalf("got %d fields, want 3", len(fields))
+	}
+	if got := fields[1].Text; got != "*Reason:*\ntests &lt;failed&gt; &amp; more" {
+		t.Errorf("reason = %q", got)
+	}
+
+	errText := fields[2].Text
+	want := "*Error:*\n```unexpected &lt;html&gt; in response: ˋˋˋ\nrm -rf ˋpwdˋ```"
+	if errText != want {
+		t.Errorf("error = %q, want %q", errText, want)
+	}
+	// Only the block's own fences remain, so it can't close early
+	if n := strings.Count(errText, "```"); n != 2 {
+		t.Errorf("error has %d fences, want 2: %q", n, errText)
+	}
+}
+
+func TestFormatMessageSanitizesLinksAndTitles(t *testing.T) {
+	msg := formatMessage(EventPRCreated, map[string]string{
+		FieldBead:  "gt-`x`",
+		FieldPRURL: "https://example.com/pr?a=1&b=2|evil",
+	}, nil)
+	fields := msg.Blocks[1].Fields
+	if got := fields[0].Text; got != "*Bead:*\n`gt-ˋxˋ`" {
+		t.Errorf("bead = %q", got)
+	}
+	if got := fields[1].Text; got != "*PR:*\n<https://example.com/pr?a=1&amp;b=2%7Cevil|View PR>" {
+		t.Errorf("pr = %q", got)
+	}
+
+	custom := buildMessage(eventConfig{emoji: "📢", title: "Deploy <prod> & staging"}, nil, nil)
+	if got := custom.Blocks[0].Text.Text; got != "📢 *Deploy &lt;prod&gt; &amp; staging*" {
+		t.Errorf("header = %q", got)
+	}
+}
+
 func TestTruncate(t *testing.T) {
 	tests := []struct {
 		input  string
@@ -0,0 +1,110 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/steveyegge/gastown/internal/util"
+)
+
+// threadRetention is how long a bead's thread is remembered. Events for a
+// bead after that start a new thread.
+const threadRetention = 14 * 24 * time.Hour
+
+// ThreadsPath returns where a town records the Slack threads of its beads.
+func ThreadsPath(townRoot string) string {
+	return filepath.Join(townRoot, ".runtime", "slack-threads.json")
+}
+
+// beadThread records the message that started a bead's thread.
+type beadThread struct {
+	TS        string    `json:"ts"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// threadStore maps beads to their Slack threads, keyed by channel and bead
+// ID since a thread lives in one channel. With a path set, threads are
+// persisted so events posted by separate gt processes (sling, done, the
+// refinery) land in the same thread.
+type threadStore struct {
+	mu      sync.Mutex
+	path    string
+	threads map[string]beadThread
+}
+
+func newThreadStore() *threadStore {
+	return &threadStore{threads: make(map[string]beadThread)}
+}
+
+// lookup returns the ts of the thread recorded under key, or "" if there
+// is none yet.
+func (s *threadStore) lookup(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.load()
+	t, ok := s.threads[key]
+	if !ok || time.Since(t.StartedAt) > threadRetention {
+		return ""
+	}
+	return t.TS
+}
+
+// record remembers ts as the start of the thread under key. A persisted
+// store is re-read and rewritten under a file lock, so threads recorded by
+// concurrent gt processes aren't lost.
+func (s *threadStore) record(key, ts string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path != "" {
+		if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+			return fmt.Errorf("creating runtime dir: %w", err)
+		}
+		fl := flock.New(s.path + ".lock")
+		if err := fl.Lock(); err != nil {
+			return fmt.Errorf("acquiring slack threads lock: %w", err)
+		}
+		defer func() { _ = fl.Unlock() }()
+	}
+
+	s.load()
+	for id, t := range s.threads {
+		if time.Since(t.StartedAt) > threadRetention {
+			delete(s.threads, id)
+		}
+	}
+	s.threads[key] = beadThread{TS: ts, StartedAt: time.Now()}
+
+	if s.path == "" {
+		return nil
+	}
+	if err := util.AtomicWriteJSON(s.path, s.threads); err != nil {
+		return fmt.Errorf("writing slack threads: %w", err)
+	}
+	return nil
+}
+
+// load merges threads recorded by other processes. A missing or unreadable
+// file leaves the in-memory threads as they are.
+func (s *threadStore) load() {
+	if s.path == "" {
+		return
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+	var threads map[string]beadThread
+	if err := json.Unmarshal(data, &threads); err != nil {
+		return
+	}
+	for id, t := range threads {
+		s.threads[id] = t
+	}
+}